@@ -0,0 +1,157 @@
+// Command ldfirestore-admin provides tools for inspecting the data that the LaunchDarkly Firestore
+// integration stores.
+//
+// Usage:
+//
+//	ldfirestore-admin <command> [flags]
+//
+// The commands are:
+//
+//	watch              print changes to flags and segments as they happen
+//	snapshot           export flags and segments as they were stored at a past time
+//	purge              delete every document of a data kind
+//	purge-big-segments delete the Big Segment documents for a prefix
+//
+// Every command accepts -project, -database, -collection, -prefix, and -parent flags, which have the
+// same meaning as the parameters of ldfirestore.DataStore, StoreBuilder.DatabaseID, StoreBuilder.Prefix,
+// and StoreBuilder.ParentDocument. Credentials are taken from the
+// environment as for any Google Cloud client, and FIRESTORE_EMULATOR_HOST is honored.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+
+	ldfirestore "github.com/launchdarkly/go-server-sdk-firestore"
+)
+
+// These must match the document format used by the ldfirestore package.
+const (
+	fieldNamespace = "namespace"
+	fieldKey       = "key"
+	fieldVersion   = "version"
+	fieldItem      = "item"
+)
+
+type command struct {
+	name    string
+	summary string
+	// addFlags registers any flags specific to the command, and returns the function that runs it.
+	addFlags func(flags *flag.FlagSet) commandFunc
+}
+
+type commandFunc func(ctx context.Context, client *firestore.Client, opts commonOptions, out io.Writer) error
+
+var commands = []command{
+	{"watch", "print changes to flags and segments as they happen", addWatchFlags},
+	{"snapshot", "export flags and segments as they were stored at a past time", addSnapshotFlags},
+	{"purge", "delete every document of a data kind", addPurgeFlags},
+	{"purge-big-segments", "delete the Big Segment documents for a prefix", addPurgeBigSegmentsFlags},
+}
+
+type commonOptions struct {
+	projectID  string
+	databaseID string
+	collection string
+	prefix     string
+	parent     string
+}
+
+// collectionRef returns the data store's collection, which is nested under the parent document if
+// one was specified.
+func (opts commonOptions) collectionRef(client *firestore.Client) *firestore.CollectionRef {
+	if parent := strings.Trim(opts.parent, "/"); parent != "" {
+		return client.Doc(parent).Collection(opts.collection)
+	}
+	return client.Collection(opts.collection)
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		usage(out)
+		return fmt.Errorf("no command specified")
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		var opts commonOptions
+		flags.StringVar(&opts.projectID, "project", "", "Google Cloud project ID (required)")
+		flags.StringVar(&opts.databaseID, "database", "", "Firestore database ID, if not the default database")
+		flags.StringVar(&opts.collection, "collection", "", "Firestore collection name (required)")
+		flags.StringVar(&opts.prefix, "prefix", "", "key prefix, if the data store was configured with one")
+		flags.StringVar(&opts.parent, "parent", "", "path of the parent document, if the data store was configured with one")
+		runCommand := cmd.addFlags(flags)
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if opts.projectID == "" || opts.collection == "" {
+			return fmt.Errorf("-project and -collection are required")
+		}
+		if parent := strings.Trim(opts.parent, "/"); parent != "" && strings.Count(parent, "/")%2 == 0 {
+			return fmt.Errorf("-parent must be the path of a document, such as apps/myapp")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		databaseID := opts.databaseID
+		if databaseID == "" {
+			databaseID = firestore.DefaultDatabaseID
+		}
+		client, err := firestore.NewClientWithDatabase(ctx, opts.projectID, databaseID)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = client.Close() }()
+
+		return runCommand(ctx, client, opts, out)
+	}
+
+	usage(out)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func usage(out io.Writer) {
+	fmt.Fprintln(out, "usage: ldfirestore-admin <command> [flags]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-18s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// itemJSON returns the serialized item in a document, decoding it as the data store would, or nil if
+// it cannot be decoded.
+func itemJSON(data map[string]any) []byte {
+	item, err := ldfirestore.DecodeItem(data)
+	if err != nil {
+		return nil
+	}
+	return item
+}
+
+// prefixedNamespace returns the value of the namespace field for a data kind, as the data store
+// computes it.
+func prefixedNamespace(prefix, kindName string) string {
+	if prefix == "" {
+		return kindName
+	}
+	return prefix + ":" + kindName
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemJSONWithCompression(t *testing.T) {
+	item := []byte(`{"key":"f","version":2,"on":true}`)
+
+	var gzipped bytes.Buffer
+	w := gzip.NewWriter(&gzipped)
+	_, err := w.Write(item)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	enc, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	zstdEncoded := enc.EncodeAll(item, nil)
+	require.NoError(t, enc.Close())
+
+	for encoding, encoded := range map[string][]byte{"gzip": gzipped.Bytes(), "zstd": zstdEncoded} {
+		t.Run(encoding, func(t *testing.T) {
+			data := map[string]any{fieldKey: "f", fieldItem: encoded, "encoding": encoding}
+			assert.Equal(t, item, itemJSON(data))
+
+			old := parseItem(map[string]any{fieldItem: `{"key":"f","version":1,"on":false}`})
+			assert.Equal(t, "changed: on", diffSummary(old, parseItem(data)))
+		})
+	}
+
+	t.Run("custom codec", func(t *testing.T) {
+		assert.Nil(t, itemJSON(map[string]any{fieldItem: []byte("encrypted"), "encoding": "my-codec"}))
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+
+	ldfirestore "github.com/launchdarkly/go-server-sdk-firestore"
+)
+
+// dataKindName is a data kind that is only identified by name, which is all the store needs in order
+// to find its documents.
+type dataKindName string
+
+func (k dataKindName) GetName() string { return string(k) }
+func (k dataKindName) String() string  { return string(k) }
+func (k dataKindName) Serialize(ldstoretypes.ItemDescriptor) []byte {
+	return nil
+}
+func (k dataKindName) Deserialize([]byte) (ldstoretypes.ItemDescriptor, error) {
+	return ldstoretypes.ItemDescriptor{}, nil
+}
+
+func addPurgeFlags(flags *flag.FlagSet) commandFunc {
+	kind := flags.String("kind", "", `the data kind to delete, such as "features" or "segments" (required)`)
+	confirm := flags.Bool("yes", false, "confirm that the documents should be deleted (required)")
+	return func(ctx context.Context, client *firestore.Client, opts commonOptions, out io.Writer) error {
+		if *kind == "" {
+			return fmt.Errorf("-kind is required")
+		}
+		if !*confirm {
+			return fmt.Errorf("-yes is required to confirm that every %s document should be deleted", *kind)
+		}
+		return runPurge(client, opts, dataKindName(*kind), out)
+	}
+}
+
+// runPurge deletes every document of a data kind, reporting progress as it goes. Any SDK instances
+// using the data store will not see the change until their caches expire.
+func runPurge(client *firestore.Client, opts commonOptions, kind ldstoretypes.DataKind, out io.Writer) error {
+	store, err := ldfirestore.DataStore(opts.projectID, opts.collection).
+		FirestoreClient(client).
+		Prefix(opts.prefix).
+		ParentDocument(opts.parent).
+		Build(subsystems.BasicClientContext{
+			Logging: subsystems.LoggingConfiguration{Loggers: ldlog.NewDisabledLoggers()},
+		})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	deleted, err := store.(ldfirestore.ExtendedDataStore).DeleteAll(kind, func(deleted int) {
+		fmt.Fprintf(out, "deleted %d document(s) so far\n", deleted)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "deleted %d %s document(s)\n", deleted, kind)
+	return nil
+}
+
+func addPurgeBigSegmentsFlags(flags *flag.FlagSet) commandFunc {
+	confirm := flags.Bool("yes", false, "confirm that the documents should be deleted (required)")
+	return func(ctx context.Context, client *firestore.Client, opts commonOptions, out io.Writer) error {
+		if !*confirm {
+			return fmt.Errorf("-yes is required to confirm that every Big Segment document should be deleted")
+		}
+		return runPurgeBigSegments(client, opts, out)
+	}
+}
+
+// runPurgeBigSegments deletes the Big Segment metadata and membership documents for the prefix.
+func runPurgeBigSegments(client *firestore.Client, opts commonOptions, out io.Writer) error {
+	store, err := ldfirestore.BigSegmentStore(opts.projectID, opts.collection).
+		FirestoreClient(client).
+		Prefix(opts.prefix).
+		ParentDocument(opts.parent).
+		Build(subsystems.BasicClientContext{
+			Logging: subsystems.LoggingConfiguration{Loggers: ldlog.NewDisabledLoggers()},
+		})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	deleted, err := store.(ldfirestore.ExtendedBigSegmentStore).Purge(func(deleted int) {
+		fmt.Fprintf(out, "deleted %d document(s) so far\n", deleted)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "deleted %d Big Segment document(s)\n", deleted)
+	return nil
+}
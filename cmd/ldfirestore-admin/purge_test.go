@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeFlags(t *testing.T) {
+	// The emulator setting lets the client be created without credentials; no connection is made
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	var out bytes.Buffer
+
+	err := run([]string{"purge", "-project", "p", "-collection", "c"}, &out)
+	assert.EqualError(t, err, "-kind is required")
+
+	err = run([]string{"purge", "-project", "p", "-collection", "c", "-kind", "features"}, &out)
+	assert.ErrorContains(t, err, "-yes is required")
+}
+
+func TestPurgeBigSegmentsFlags(t *testing.T) {
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	var out bytes.Buffer
+
+	err := run([]string{"purge-big-segments", "-project", "p", "-collection", "c", "-prefix", "old-env"}, &out)
+	assert.ErrorContains(t, err, "-yes is required")
+}
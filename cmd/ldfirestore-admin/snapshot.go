@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// snapshotOutput is the format written by the snapshot command. The flags and segments properties have
+// the same layout as the LaunchDarkly "latest-all" polling endpoint.
+type snapshotOutput struct {
+	ReadTime time.Time                  `json:"readTime"`
+	Flags    map[string]json.RawMessage `json:"flags"`
+	Segments map[string]json.RawMessage `json:"segments"`
+}
+
+func addSnapshotFlags(flags *flag.FlagSet) commandFunc {
+	at := flags.String("at", "", "the time to read the data at, in RFC 3339 format (required); "+
+		"times more than an hour ago must be a whole minute")
+	key := flags.String("key", "", "only export the flag or segment with this key")
+	return func(ctx context.Context, client *firestore.Client, opts commonOptions, out io.Writer) error {
+		if *at == "" {
+			return fmt.Errorf("-at is required")
+		}
+		readTime, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			return fmt.Errorf("invalid -at value: %w", err)
+		}
+		return runSnapshot(ctx, client, opts, readTime, *key, out)
+	}
+}
+
+// runSnapshot exports the flags and segments as they were stored at a past time, using Firestore's
+// point-in-time recovery (PITR). Without PITR, Firestore only retains versions for the past hour.
+func runSnapshot(
+	ctx context.Context,
+	client *firestore.Client,
+	opts commonOptions,
+	readTime time.Time,
+	key string,
+	out io.Writer,
+) error {
+	result := snapshotOutput{
+		ReadTime: readTime,
+		Flags:    make(map[string]json.RawMessage),
+		Segments: make(map[string]json.RawMessage),
+	}
+	for kindName, items := range map[string]map[string]json.RawMessage{
+		"features": result.Flags,
+		"segments": result.Segments,
+	} {
+		query := opts.collectionRef(client).
+			Where(fieldNamespace, "==", prefixedNamespace(opts.prefix, kindName))
+		if key != "" {
+			query = query.Where(fieldKey, "==", key)
+		}
+		if err := readItemsAt(ctx, query.WithReadOptions(firestore.ReadTime(readTime)), items); err != nil {
+			return err
+		}
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func readItemsAt(ctx context.Context, query *firestore.Query, items map[string]json.RawMessage) error {
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		data := doc.Data()
+		key, _ := data[fieldKey].(string)
+		item := itemJSON(data)
+		if key == "" || !json.Valid(item) {
+			return fmt.Errorf("document %q could not be decoded", doc.Ref.ID)
+		}
+		items[key] = json.RawMessage(item)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotFlags(t *testing.T) {
+	// The emulator setting lets the client be created without credentials; no connection is made
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	var out bytes.Buffer
+
+	err := run([]string{"snapshot", "-project", "p", "-collection", "c"}, &out)
+	assert.EqualError(t, err, "-at is required")
+
+	err = run([]string{"snapshot", "-project", "p", "-collection", "c", "-at", "yesterday"}, &out)
+	assert.ErrorContains(t, err, "invalid -at value")
+}
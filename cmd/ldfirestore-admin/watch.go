@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func addWatchFlags(_ *flag.FlagSet) commandFunc {
+	return runWatch
+}
+
+// runWatch attaches a snapshot listener to the flag and segment documents and prints each change
+// until the context is canceled.
+func runWatch(ctx context.Context, client *firestore.Client, opts commonOptions, out io.Writer) error {
+	namespaces := []string{prefixedNamespace(opts.prefix, "features"), prefixedNamespace(opts.prefix, "segments")}
+	query := opts.collectionRef(client).Where(fieldNamespace, "in", namespaces)
+
+	iter := query.Snapshots(ctx)
+	defer iter.Stop()
+
+	fmt.Fprintf(out, "watching %s in collection %q (press Ctrl-C to stop)\n", strings.Join(namespaces, ", "),
+		opts.collection)
+
+	items := make(map[string]map[string]json.RawMessage)
+	first := true
+	for {
+		snap, err := iter.Next()
+		if err != nil {
+			if ctx.Err() != nil || status.Code(err) == codes.Canceled {
+				return nil
+			}
+			return err
+		}
+
+		if first {
+			// The first snapshot reports every existing document as added; just remember them
+			for _, change := range snap.Changes {
+				items[change.Doc.Ref.ID] = parseItem(change.Doc.Data())
+			}
+			fmt.Fprintf(out, "%d existing item(s)\n", len(snap.Changes))
+			first = false
+			continue
+		}
+
+		for _, change := range snap.Changes {
+			fmt.Fprintln(out, describeChange(change, items, snap.ReadTime))
+		}
+	}
+}
+
+func describeChange(
+	change firestore.DocumentChange,
+	items map[string]map[string]json.RawMessage,
+	readTime time.Time,
+) string {
+	data := change.Doc.Data()
+	docID := change.Doc.Ref.ID
+	namespace, _ := data[fieldNamespace].(string)
+	key, _ := data[fieldKey].(string)
+	version, _ := data[fieldVersion].(int64)
+	newItem := parseItem(data)
+	oldItem := items[docID]
+
+	var action, summary string
+	switch change.Kind {
+	case firestore.DocumentAdded:
+		action = "added"
+		items[docID] = newItem
+	case firestore.DocumentModified:
+		action = "modified"
+		summary = diffSummary(oldItem, newItem)
+		items[docID] = newItem
+	case firestore.DocumentRemoved:
+		action = "removed"
+		delete(items, docID)
+	}
+
+	line := fmt.Sprintf("%s %-8s %s %q version=%d", readTime.Format(time.RFC3339), action, namespace, key, version)
+	if summary != "" {
+		line += " " + summary
+	}
+	return line
+}
+
+// parseItem returns the top-level properties of the serialized item in a document, or nil if it
+// could not be parsed.
+func parseItem(data map[string]any) map[string]json.RawMessage {
+	var props map[string]json.RawMessage
+	if json.Unmarshal(itemJSON(data), &props) != nil {
+		return nil
+	}
+	return props
+}
+
+// diffSummary describes which top-level properties of an item changed, ignoring the version.
+func diffSummary(oldItem, newItem map[string]json.RawMessage) string {
+	if oldItem == nil || newItem == nil {
+		return ""
+	}
+	if deleted := string(newItem["deleted"]); deleted == "true" && string(oldItem["deleted"]) != "true" {
+		return "(deleted)"
+	}
+
+	var changed []string
+	for name, newValue := range newItem {
+		if name == "version" {
+			continue
+		}
+		if oldValue, ok := oldItem[name]; !ok || string(oldValue) != string(newValue) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range oldItem {
+		if _, ok := newItem[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) == 0 {
+		return "(no property changes)"
+	}
+	sort.Strings(changed)
+	return "changed: " + strings.Join(changed, ", ")
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSummary(t *testing.T) {
+	parse := func(s string) map[string]json.RawMessage {
+		return parseItem(map[string]any{fieldItem: s})
+	}
+
+	assert.Equal(t, "changed: on, rules",
+		diffSummary(parse(`{"key":"f","version":1,"on":false,"rules":[]}`),
+			parse(`{"key":"f","version":2,"on":true,"rules":[{"id":"r"}]}`)))
+
+	assert.Equal(t, "changed: offVariation",
+		diffSummary(parse(`{"key":"f","version":1,"offVariation":0}`), parse(`{"key":"f","version":2}`)))
+
+	assert.Equal(t, "(no property changes)",
+		diffSummary(parse(`{"key":"f","version":1}`), parse(`{"key":"f","version":2}`)))
+
+	assert.Equal(t, "(deleted)",
+		diffSummary(parse(`{"key":"f","version":1}`), parse(`{"key":"f","version":2,"deleted":true}`)))
+
+	assert.Equal(t, "", diffSummary(nil, parse(`{"key":"f"}`)))
+
+	fromBytes := parseItem(map[string]any{fieldItem: []byte(`{"key":"f","on":true}`)})
+	assert.Equal(t, json.RawMessage("true"), fromBytes["on"])
+}
+
+func TestRunWithUnknownCommand(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"bogus"}, &out)
+	assert.EqualError(t, err, `unknown command "bogus"`)
+	assert.Contains(t, out.String(), "watch")
+}
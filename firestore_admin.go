@@ -0,0 +1,426 @@
+package ldfirestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ExtendedDataStore is implemented by the data store that is created by [DataStore]. It provides
+// operations beyond the standard [subsystems.PersistentDataStore] interface, for use by tooling and
+// custom writers rather than by the SDK itself.
+//
+// To use these operations, build the store yourself and use a type assertion:
+//
+//	store, err := ldfirestore.DataStore("my-project", "launchdarkly").Build(clientContext)
+//	if err != nil { ... }
+//	ext := store.(ldfirestore.ExtendedDataStore)
+type ExtendedDataStore interface {
+	subsystems.PersistentDataStore
+
+	// UpsertWithResult behaves like Upsert, but also reports the version that was previously stored
+	// and whether the update was rejected because of a version conflict.
+	UpsertWithResult(
+		kind ldstoretypes.DataKind,
+		key string,
+		newItem ldstoretypes.SerializedItemDescriptor,
+	) (UpsertResult, error)
+
+	// Delete physically removes the document for an item, rather than replacing it with a deleted-item
+	// placeholder as the SDK does. If expectedVersion is defined, the document is only removed if its
+	// stored version is equal to that value. The return value is true if a document was removed.
+	//
+	// Deleting items out from under the SDK can cause it to see stale data from its cache, so this
+	// should only be used by administrative tooling. If [StoreBuilder.WriterElection] is enabled and
+	// this instance does not hold the writer lease, nothing is deleted and the return value is false.
+	Delete(kind ldstoretypes.DataKind, key string, expectedVersion ldvalue.OptionalInt) (bool, error)
+
+	// Touch sets the updatedAt field of an item's document to the current time, without changing
+	// the item. This can be used to keep a TTL policy from removing data that is still in use, or as
+	// a liveness marker; if [StoreBuilder.ExpireAfter] is set, the document's expiration time is also
+	// extended. The return value is false if there was no such document, or if nothing was written
+	// because [StoreBuilder.WriterElection] is enabled and this instance does not hold the writer lease.
+	Touch(kind ldstoretypes.DataKind, key string) (bool, error)
+
+	// TouchAll is like Touch, but updates every document of the given data kind. It returns the
+	// number of documents that were updated.
+	TouchAll(kind ldstoretypes.DataKind) (int, error)
+
+	// LastInitReport returns a report of what the most recent Init call did, including every item
+	// that was skipped and every document operation that failed. If Init has not been called, or the
+	// most recent call failed before writing anything, it returns an empty report.
+	LastInitReport() InitReport
+
+	// ThrottleStats reports how often Firestore has rejected the store's requests for exceeding a
+	// quota. When retrying failed Init writes, the store waits for the delay that Firestore suggests
+	// in such errors instead of using its own backoff.
+	ThrottleStats() ThrottleStats
+
+	// NamespaceSummary returns the summary document for a data kind, if [StoreBuilder.NamespaceSummaries]
+	// is enabled. The second return value is false if there is no summary document, either because the
+	// option is not enabled or because no items of that kind have been stored since it was enabled.
+	NamespaceSummary(kind ldstoretypes.DataKind) (NamespaceSummary, bool, error)
+
+	// NamespaceManifest returns the version of every item of a data kind, by key, from the manifest
+	// document that the store maintains if [StoreBuilder.NamespaceManifests] is enabled. The second
+	// return value is false if there is no manifest document, either because the option is disabled or
+	// because no Init has been done since it was enabled. See also GetAllVersions, which reads the
+	// same information from the items themselves.
+	NamespaceManifest(kind ldstoretypes.DataKind) (map[string]int, bool, error)
+
+	// DeleteAll physically removes every document of the given data kind, a page at a time, retrying
+	// deletes that fail with a transient error. After each page, onProgress (if not nil) is called with
+	// the number of documents deleted so far. It returns the total number of documents deleted.
+	//
+	// As with Delete, this should only be used by administrative tooling, since the SDK may still have
+	// the deleted items in its cache.
+	DeleteAll(kind ldstoretypes.DataKind, onProgress func(deleted int)) (int, error)
+
+	// ItemSizeHistogram returns the distribution of the sizes of the item documents that the store has
+	// tried to write, including items that were too large to be stored.
+	ItemSizeHistogram() ItemSizeHistogram
+
+	// VersionAnomalies returns the number of times a read returned an older version of an item than
+	// the store had already read or written, if [StoreBuilder.VersionAnomalyDetection] is enabled.
+	VersionAnomalies() int
+
+	// MigrateDocumentIDs moves every document of the given data kind whose ID is not in the format
+	// that the store is configured with (see [StoreBuilder.DocumentIDFormat]) to a document with the
+	// right ID, and returns the number of documents that were moved. If a document with the right ID
+	// already exists, it is kept and the old document is deleted. It is safe to call this more than
+	// once, for instance if an earlier call did not finish.
+	//
+	// Until the documents have been moved, the SDK will not find them when it reads a single item,
+	// although reading all items of a kind still works. The document that records whether the store
+	// has been initialized is not moved; the next Init writes it with the new ID.
+	MigrateDocumentIDs(kind ldstoretypes.DataKind) (int, error)
+
+	// MigrateSchema rewrites every document of the given data kind that was written in an older
+	// document format, and returns the number of documents that were rewritten. The store can read
+	// documents in older formats without this, by upgrading them each time they are read; migrating
+	// them avoids that cost, and allows older documents to be read by future versions of this package
+	// that drop support for the oldest formats. It is safe to call this more than once.
+	MigrateSchema(kind ldstoretypes.DataKind) (int, error)
+
+	// ForEachItem reads every item of the given data kind, like GetAll, but a page of up to pageSize
+	// items at a time, and calls fn with the items in each page; a pageSize of zero means 500. Only
+	// one page is held in memory at a time. If fn returns an error, ForEachItem stops and returns it.
+	ForEachItem(
+		kind ldstoretypes.DataKind,
+		pageSize int,
+		fn func(items []ldstoretypes.KeyedSerializedItemDescriptor) error,
+	) error
+
+	// GetMany reads the items with the given keys, like calling Get for each of them, but in as few
+	// requests as possible. The results are in the same order as the keys; for a key with no stored
+	// item, the result has a version of -1 and a nil item, as with Get.
+	GetMany(kind ldstoretypes.DataKind, keys []string) ([]ldstoretypes.SerializedItemDescriptor, error)
+
+	// GetAllVersions returns the version of every item of the given data kind, by key, including
+	// deleted items unless [StoreBuilder.ExcludeDeletedFromGetAll] is enabled. Only the key and version
+	// of each document are read, so this is much cheaper than GetAll for comparing the stored data
+	// with another data set.
+	GetAllVersions(kind ldstoretypes.DataKind) (map[string]int, error)
+
+	// RecheckInitialized is like IsInitialized, but always reads the document that marks the store as
+	// initialized. Once IsInitialized has found that document, it returns true without reading it
+	// again, since the store never removes it; use this if the document may have been removed or
+	// rewritten by something else, such as an SDK with a different payload filter.
+	RecheckInitialized() bool
+
+	// AvailabilityError returns the error from the most recent availability check (see
+	// [StoreBuilder.AvailabilityCheckTimeout]), or nil if it succeeded or there has not been one. Use
+	// errors.Is with [ErrUnreachable] or [ErrPermissionDenied] to tell what kind of failure it was.
+	AvailabilityError() error
+
+	// OnAvailabilityChange registers a function to be called whenever the store changes between
+	// available and unavailable, as shown by the outcome of each operation and availability check: it
+	// becomes unavailable when one fails because Firestore could not be reached or denied access, with
+	// that error, and available again when one succeeds, with a nil error. The store is assumed to be
+	// available until then. Unlike the SDK's data store status, this does not wait for the SDK to poll
+	// the store. The function is called synchronously, so it should return quickly, and it must not
+	// call OnAvailabilityChange. Call the returned function to unregister it.
+	OnAvailabilityChange(fn func(available bool, err error)) func()
+
+	// InvalidateItemCache removes an item from the cache that is enabled with [StoreBuilder.ItemCache],
+	// along with the cached result of GetAll for its data kind, so that the next read gets it from
+	// Firestore. If key is empty, every item of the data kind is removed. Writes made through the
+	// store invalidate the items they change automatically.
+	InvalidateItemCache(kind ldstoretypes.DataKind, key string)
+
+	// ClearItemCache removes everything from the cache that is enabled with [StoreBuilder.ItemCache].
+	ClearItemCache()
+
+	// ItemCacheStats returns the hit, miss, and eviction counts of the cache that is enabled with
+	// [StoreBuilder.ItemCache].
+	ItemCacheStats() ItemCacheStats
+
+	// WarmUp reads every flag and segment, and whether the store has been initialized, so that the
+	// connection to Firestore is established and, if [StoreBuilder.ItemCache] is enabled, the cache is
+	// filled before the first evaluations. A server can call this before reporting that it is ready.
+	// The SDK's own cache cannot be filled by the store; to do that, evaluate the flags or call the
+	// SDK's AllFlagsState once the SDK has started. WarmUp returns ctx.Err() if ctx is done first, but
+	// the reads that are in progress still finish in the background.
+	WarmUp(ctx context.Context) error
+
+	// ReadStats reports how many reads were made from Firestore and how many were answered from the
+	// store's caches: the result of IsInitialized (see RecheckInitialized), and the cache that is
+	// enabled with [StoreBuilder.ItemCache].
+	ReadStats() ReadStats
+
+	// Usage returns the number of billable document reads, writes, and deletes that the store has
+	// performed, in total and for each store method, so that its share of the Firestore bill can be
+	// estimated.
+	Usage() UsageStats
+
+	// Health returns the most recent error, the number of consecutive failures, and the time of the
+	// most recent success, over all of the store's operations and availability checks. Unlike
+	// IsStoreAvailable, which the SDK uses to decide whether the store has recovered from an outage,
+	// this shows whether the store is degraded, for instance failing some reads while still passing
+	// availability checks.
+	Health() HealthStatus
+
+	// DebugDump returns a description of every document that this package has stored for the given
+	// prefix (see [StoreBuilder.Prefix]), whether it is an item or metadata such as the document that
+	// marks the store as initialized, sorted by path. This is for troubleshooting the stored data
+	// without writing Firestore queries against the internal document layout. It reads every document,
+	// so it should not be used on a large data set in production.
+	DebugDump(ctx context.Context, prefix string) ([]DebugDocument, error)
+
+	// CloseWithContext is like Close, but first waits for operations that are already in progress,
+	// such as the batch writes of an Init or the transaction of an Upsert, to finish. New operations
+	// fail with [ErrStoreClosed] as soon as it is called. If ctx ends before the operations are done,
+	// they are canceled and the store is closed anyway. Calling Close or CloseWithContext more than
+	// once has no further effect.
+	CloseWithContext(ctx context.Context) error
+
+	// Stats returns the number of reads, writes, transactions, version conflicts, and errors since
+	// the store was created, and the latency percentiles of recent reads and writes. It is meant for
+	// reporting in an application's own health or status endpoints.
+	Stats() Stats
+}
+
+// UpsertResult describes the outcome of [ExtendedDataStore.UpsertWithResult].
+type UpsertResult struct {
+	// Updated is true if the item was written, or if the write was skipped because this instance
+	// is not the writer (see [StoreBuilder.WriterElection]).
+	Updated bool
+
+	// PreviousVersion is the version of the item that was stored before the operation, or -1 if
+	// there was no such item or the operation did not get as far as reading it (for instance, if the
+	// item was dropped for being too large).
+	PreviousVersion int
+
+	// Conflict is true if the item was not written because the stored version was greater than or
+	// equal to the new version.
+	Conflict bool
+
+	// Buffered is true if the item could not be written because Firestore was unavailable, and will
+	// be written later. Updated is also true in this case. See [StoreBuilder.OutageBuffer].
+	Buffered bool
+}
+
+func (store *firestoreDataStore) Delete(
+	kind ldstoretypes.DataKind,
+	key string,
+	expectedVersion ldvalue.OptionalInt,
+) (bool, error) {
+	if err := store.checkWritable(); err != nil {
+		return false, err
+	}
+	if !store.isWriter() {
+		return false, nil
+	}
+
+	docRef := store.docRef(kind, key)
+	release := store.keyLocks.acquire(docRef.Path)
+	defer release()
+
+	done, err := store.beginOperation(false)
+	if err != nil {
+		return false, err
+	}
+	defer done()
+
+	attempts := 0
+	err = store.retry.do(store.context, store.onRetry, func() error {
+		opCtx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+		defer cancel()
+		return store.runTransaction(opCtx, func(ctx context.Context, tx *firestore.Transaction) error {
+			attempts++
+			if log, ok := store.debugLog(LogTransactions); ok {
+				log.Debugf("Transaction attempt %d to delete item (namespace=%s key=%s)", attempts, kind, key)
+			}
+			doc, err := tx.Get(docRef)
+			store.countUsage("Delete", DocumentOperations{Reads: 1})
+			if err != nil {
+				if status.Code(err) == codes.NotFound {
+					return errNotDeleted
+				}
+				return err
+			}
+			if !doc.Exists() {
+				return errNotDeleted
+			}
+			if expectedVersion.IsDefined() {
+				version, _ := doc.Data()[fieldVersion].(int64)
+				if int(version) != expectedVersion.IntValue() {
+					if log, ok := store.debugLog(LogWrites); ok {
+						log.Debugf("Not deleting item due to version check "+
+							"(namespace=%s key=%s expected=%d, existing=%d)", kind, key, expectedVersion.IntValue(), version)
+					}
+					return errNotDeleted
+				}
+			}
+			if store.namespaceSummaries {
+				if err := store.updateSummaryInTransaction(tx, kind, -1, 0); err != nil {
+					return err
+				}
+			}
+			if store.namespaceManifests {
+				if err := store.updateManifestInTransaction(tx, kind, key, -1); err != nil {
+					return err
+				}
+			}
+			return tx.Delete(docRef)
+		})
+	})
+
+	store.cache.invalidate(store.namespaceForKind(kind), key)
+	if err == errNotDeleted {
+		return false, nil
+	}
+	if err != nil {
+		return false, newStoreError("Delete", kind, key, err)
+	}
+	store.countUsage("Delete", DocumentOperations{Writes: store.metadataWrites(), Deletes: 1})
+	if log, ok := store.debugLog(LogWrites); ok {
+		log.Debugf("Deleted item (namespace=%s key=%s)", kind, key)
+	}
+	return true, nil
+}
+
+var errNotDeleted = errors.New("document not deleted")
+
+func (store *firestoreDataStore) Touch(kind ldstoretypes.DataKind, key string) (bool, error) {
+	if err := store.checkWritable(); err != nil {
+		return false, err
+	}
+	if !store.isWriter() {
+		return false, nil
+	}
+
+	done, err := store.beginOperation(false)
+	if err != nil {
+		return false, err
+	}
+	defer done()
+
+	docRef := store.docRef(kind, key)
+	err = store.retry.do(store.context, store.onRetry, func() error {
+		ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+		defer cancel()
+		_, err := docRef.Update(ctx, store.touchUpdates(store.now()))
+		return err
+	})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return false, nil
+		}
+		return false, newStoreError("Touch", kind, key, err)
+	}
+	store.countUsage("Touch", DocumentOperations{Writes: 1})
+	return true, nil
+}
+
+func (store *firestoreDataStore) TouchAll(kind ldstoretypes.DataKind) (int, error) {
+	if err := store.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	done, err := store.beginOperation(false)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	query := store.queryForKind(kind).Select() // Select no fields, just get document IDs
+
+	var docs []*firestore.DocumentSnapshot
+	err = store.retry.do(store.context, store.onRetry, func() (err error) {
+		docs, err = query.Documents(store.context).GetAll()
+		return err
+	})
+	if err != nil {
+		return 0, newStoreError("TouchAll", kind, "", err)
+	}
+
+	now := store.now()
+	operations := make([]firestoreOperation, 0, len(docs))
+	for _, doc := range docs {
+		operations = append(operations, updateOperation{
+			ref:     doc.Ref,
+			updates: store.touchUpdates(now),
+		})
+	}
+
+	store.countUsage("TouchAll", DocumentOperations{Reads: queryReads(len(operations))})
+	failures, err := batchWriteOperations(store.context, store.firestoreClient(), operations)
+	if err != nil {
+		return 0, newStoreError("TouchAll", kind, "", err)
+	}
+	store.countUsage("TouchAll", DocumentOperations{Writes: len(operations) - len(failures)})
+	if log, ok := store.debugLog(LogBulkOperations); ok {
+		log.Debugf("Touched %d %s document(s) (%d failed)", len(operations), kind, len(failures))
+	}
+	if len(failures) > 0 {
+		return len(operations) - len(failures), newStoreError("TouchAll", kind, "",
+			fmt.Errorf("could not touch %d document(s): %w", len(failures), failures[0].err))
+	}
+	return len(operations), nil
+}
+
+func (store *firestoreDataStore) DeleteAll(kind ldstoretypes.DataKind, onProgress func(deleted int)) (int, error) {
+	if err := store.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	done, err := store.beginOperation(false)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	defer store.cache.invalidate(store.namespaceForKind(kind), "")
+
+	deleted, err := deleteByQuery(store.context, store.firestoreClient(), store.queryForKind(kind), onProgress)
+	store.countUsage("DeleteAll", DocumentOperations{Reads: queryReads(deleted), Deletes: deleted})
+	if err != nil {
+		return deleted, newStoreError("DeleteAll", kind, "", err)
+	}
+	if log, ok := store.debugLog(LogBulkOperations); ok {
+		log.Debugf("Deleted %d %s document(s)", deleted, kind)
+	}
+
+	store.countUsage("DeleteAll", DocumentOperations{Writes: store.metadataWrites()})
+	if store.namespaceSummaries {
+		op := store.summaryOperation(kind, NamespaceSummary{UpdatedAt: store.now()})
+		if _, err := op.ref.Set(store.context, op.data); err != nil {
+			return deleted, newStoreError("DeleteAll", kind, "", fmt.Errorf("could not reset summary: %w", err))
+		}
+	}
+	if store.namespaceManifests {
+		op := store.manifestOperation(kind, nil)
+		if _, err := op.ref.Set(store.context, op.data); err != nil {
+			return deleted, newStoreError("DeleteAll", kind, "", fmt.Errorf("could not reset manifest: %w", err))
+		}
+	}
+	return deleted, nil
+}
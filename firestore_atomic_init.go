@@ -0,0 +1,167 @@
+package ldfirestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	// atomicInitMaxOps is the largest number of writes that Firestore allows in a single commit.
+	atomicInitMaxOps = 500
+
+	// atomicInitMaxBytes limits the estimated size of the documents written in each commit, to stay
+	// well below Firestore's 10 MiB limit on the size of a request.
+	atomicInitMaxBytes = 8 * 1024 * 1024
+)
+
+// ErrInitNotAttempted is the error in an [InitFailure] for an operation that was not attempted,
+// because an earlier commit of an atomic Init failed. See [StoreBuilder.AtomicInit].
+var ErrInitNotAttempted = errors.New("not attempted, because an earlier part of Init failed")
+
+func (b builderOptions) validateAtomicInit() error {
+	if b.atomicInit && b.initRetry.MaxAttempts > 0 {
+		return errors.New("AtomicInit cannot be combined with InitRetry")
+	}
+	return nil
+}
+
+// initAtomically applies the operations of an Init in three phases, each made up of commits that
+// are applied entirely or not at all: first every item write, then every delete, and finally the
+// document that marks the store as initialized. If a commit fails, the later commits are not
+// attempted, so obsolete items are never deleted before all of the new items have been written, and
+// the store is never marked as initialized with an incomplete data set. The commits that were
+// already applied are not rolled back.
+//
+// The operations must be in the order that Init builds them, with all writes before all deletes.
+func (store *firestoreDataStore) initAtomically(
+	ctx context.Context,
+	operations []firestoreOperation,
+	items map[string]initItemRef,
+	metadataPaths map[string]bool,
+	report InitReport,
+) error {
+	numWrites := 0
+	for numWrites < len(operations) {
+		if _, isDelete := operations[numWrites].(deleteOperation); isDelete {
+			break
+		}
+		numWrites++
+	}
+	var chunks [][]firestoreOperation
+	for _, phase := range [][]firestoreOperation{
+		operations[:numWrites],
+		operations[numWrites:],
+		{store.initedOperation()},
+	} {
+		chunks = append(chunks, chunkOperations(phase, atomicInitMaxOps, atomicInitMaxBytes)...)
+	}
+
+	for i, chunk := range chunks {
+		err := store.retry.do(ctx, store.onRetry, func() error {
+			return store.commitOperations(ctx, chunk)
+		})
+		if err != nil {
+			for j, unapplied := range chunks[i:] {
+				failureErr := err
+				if j > 0 {
+					failureErr = ErrInitNotAttempted
+				}
+				for _, op := range unapplied {
+					report.Failures = append(report.Failures, initFailure(op, items, failureErr))
+				}
+			}
+			store.setLastInitReport(report)
+			store.loggers.Errorf("Init of collection %q stopped after writing %d item(s) and deleting %d, "+
+				"which were not rolled back; %d operation(s) were not committed, and the collection was not "+
+				"marked as initialized: %s",
+				store.collection, report.ItemsWritten, report.ItemsDeleted, len(report.Failures), err)
+			return fmt.Errorf("Init stopped at a failed commit after writing %d item(s) and deleting %d, "+
+				"which were not rolled back: %w", report.ItemsWritten, report.ItemsDeleted, &InitError{Report: report})
+		}
+
+		store.countUsage("Init", operationsUsage(chunk, nil))
+		for _, op := range chunk {
+			if metadataPaths[op.docRef().Path] {
+				continue
+			}
+			if _, isDelete := op.(deleteOperation); isDelete {
+				report.ItemsDeleted++
+			} else {
+				report.ItemsWritten++
+			}
+		}
+		if log, ok := store.debugLog(LogBulkOperations); ok {
+			log.Debugf("Init committed %d operation(s) to collection %q", len(chunk), store.collection)
+		}
+	}
+	store.setLastInitReport(report)
+
+	store.loggers.Infof("Initialized collection %q with %d item(s)", store.collection, report.ItemsWritten)
+	return nil
+}
+
+// chunkOperations divides operations into groups of at most maxOps operations, whose documents have
+// an estimated total size of at most maxBytes unless a single document is larger than that.
+func chunkOperations(operations []firestoreOperation, maxOps, maxBytes int) [][]firestoreOperation {
+	var chunks [][]firestoreOperation
+	var chunk []firestoreOperation
+	chunkBytes := 0
+	for _, op := range operations {
+		size := 0
+		if set, ok := op.(setOperation); ok {
+			size = estimateDocSize(set.data)
+		}
+		if len(chunk) > 0 && (len(chunk) >= maxOps || chunkBytes+size > maxBytes) {
+			chunks = append(chunks, chunk)
+			chunk, chunkBytes = nil, 0
+		}
+		chunk = append(chunk, op)
+		chunkBytes += size
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// commitOperations applies a group of set and delete operations in a single transaction, so that
+// either all of them take effect or none do.
+func (store *firestoreDataStore) commitOperations(ctx context.Context, operations []firestoreOperation) error {
+	return store.runTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		for _, op := range operations {
+			var err error
+			switch op := op.(type) {
+			case setOperation:
+				err = tx.Set(op.ref, op.data)
+			case deleteOperation:
+				err = tx.Delete(op.ref)
+			default:
+				err = fmt.Errorf("unexpected operation type %T", op)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// initFailure describes an Init operation that was not applied.
+func initFailure(op firestoreOperation, items map[string]initItemRef, err error) InitFailure {
+	docRef := op.docRef()
+	failure := InitFailure{
+		Operation:    InitOperationWrite,
+		DocumentID:   docRef.ID,
+		DocumentPath: docRef.Path,
+		Namespace:    items[docRef.Path].namespace,
+		Key:          items[docRef.Path].key,
+		Err:          err,
+	}
+	if _, isDelete := op.(deleteOperation); isDelete {
+		failure.Operation = InitOperationDelete
+	}
+	return failure
+}
@@ -0,0 +1,128 @@
+package ldfirestore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeAtomicInitTestData(n int) []ldstoretypes.SerializedCollection {
+	items := make([]ldstoretypes.KeyedSerializedItemDescriptor, 0, n)
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("flag%d", i)
+		items = append(items, ldstoretypes.KeyedSerializedItemDescriptor{
+			Key:  key,
+			Item: ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"` + key + `"}`)},
+		})
+	}
+	return []ldstoretypes.SerializedCollection{{Kind: ldstoreimpl.Features(), Items: items}}
+}
+
+func TestChunkOperations(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+	coll := client.Collection("c")
+
+	var ops []firestoreOperation
+	for i := 0; i < 7; i++ {
+		ops = append(ops, setOperation{ref: coll.Doc(fmt.Sprint(i)), data: map[string]any{"x": strings.Repeat("a", 100)}})
+	}
+	ops = append(ops, deleteOperation{ref: coll.Doc("d")})
+
+	sizes := func(chunks [][]firestoreOperation) []int {
+		var result []int
+		for _, chunk := range chunks {
+			result = append(result, len(chunk))
+		}
+		return result
+	}
+	assert.Equal(t, []int{3, 3, 2}, sizes(chunkOperations(ops, 3, 1000)))
+	assert.Equal(t, []int{2, 2, 2, 2}, sizes(chunkOperations(ops, 10, 250)))
+	assert.Equal(t, []int{1, 1, 1, 1, 1, 1, 1, 1}, sizes(chunkOperations(ops, 10, 50)))
+	assert.Nil(t, chunkOperations(nil, 10, 50))
+}
+
+func TestAtomicInitStopsAtFailedCommit(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close() // every commit will fail
+
+	store, err := DataStore("my-project", "my-collection").FirestoreClient(client).
+		AtomicInit(true).
+		PreserveExistingItems(true). // skip reading the existing documents
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	err = store.Init(makeAtomicInitTestData(atomicInitMaxOps + 10))
+	var initErr *InitError
+	require.True(t, errors.As(err, &initErr))
+
+	report := initErr.Report
+	assert.Equal(t, 0, report.ItemsWritten)
+	require.Len(t, report.Failures, atomicInitMaxOps+11)
+	assert.NotEqual(t, ErrInitNotAttempted, report.Failures[0].Err)
+	assert.NotEqual(t, ErrInitNotAttempted, report.Failures[atomicInitMaxOps-1].Err)
+	assert.Equal(t, ErrInitNotAttempted, report.Failures[atomicInitMaxOps].Err)
+	last := report.Failures[len(report.Failures)-1]
+	assert.Equal(t, ErrInitNotAttempted, last.Err)
+	assert.Equal(t, store.(*firestoreDataStore).initedDocID(), last.DocumentID)
+	assert.Equal(t, report, store.(ExtendedDataStore).LastInitReport())
+}
+
+func TestAtomicInit(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := baseDataStoreBuilder().AtomicInit(true).Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	require.NoError(t, store.Init(makeAtomicInitTestData(atomicInitMaxOps+10)))
+	assert.True(t, store.IsInitialized())
+	assert.Equal(t, InitReport{ItemsWritten: atomicInitMaxOps + 10}, store.(ExtendedDataStore).LastInitReport())
+
+	require.NoError(t, store.Init(makeAtomicInitTestData(3)))
+	assert.Equal(t, InitReport{ItemsWritten: 3, ItemsDeleted: atomicInitMaxOps + 7},
+		store.(ExtendedDataStore).LastInitReport())
+	all, err := store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+}
+
+func TestAtomicInitWithSummariesAndManifests(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := baseDataStoreBuilder().AtomicInit(true).NamespaceSummaries(true).NamespaceManifests(true).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	ext := store.(ExtendedDataStore)
+
+	// The summary and manifest documents are committed along with the items, as set operations
+	require.NoError(t, store.Init(makeAtomicInitTestData(atomicInitMaxOps+10)))
+	assert.True(t, store.IsInitialized())
+	assert.Equal(t, InitReport{ItemsWritten: atomicInitMaxOps + 10}, ext.LastInitReport())
+
+	summary, found, err := ext.NamespaceSummary(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, atomicInitMaxOps+10, summary.ItemCount)
+	manifest, found, err := ext.NamespaceManifest(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Len(t, manifest, atomicInitMaxOps+10)
+}
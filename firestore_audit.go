@@ -0,0 +1,115 @@
+package ldfirestore
+
+import (
+	"errors"
+	"os"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+// Field names of audit records.
+const (
+	fieldAuditOperation      = "operation"
+	fieldAuditActor          = "actor"
+	fieldAuditTimestamp      = "timestamp"
+	fieldAuditCollection     = "collection"
+	fieldAuditOldVersion     = "oldVersion"
+	fieldAuditNewVersion     = "newVersion"
+	fieldAuditItemsWritten   = "itemsWritten"
+	fieldAuditItemsDeleted   = "itemsDeleted"
+	fieldAuditItemsUnchanged = "itemsUnchanged"
+	fieldAuditItemsFailed    = "itemsFailed"
+	fieldAuditError          = "error"
+)
+
+// AuditTrailOptions configures the audit records that are enabled with [StoreBuilder.AuditTrail].
+type AuditTrailOptions struct {
+	// Collection is the name of the collection that audit records are added to. It must not be the
+	// store's own collection. Like the store's collection, it is under the parent document, if there
+	// is one (see [StoreBuilder.ParentDocument]).
+	Collection string
+
+	// Actor identifies who made the changes, and is stored in every record. If it is empty, the
+	// instance ID from [StoreBuilder.WriterElection] is used if writer election is enabled, and
+	// otherwise the host name.
+	Actor string
+}
+
+func (b builderOptions) validateAuditTrail() error {
+	options := b.auditTrail
+	if options.Collection == "" {
+		if options.Actor != "" {
+			return errors.New("audit trail collection is required")
+		}
+		return nil
+	}
+	if options.Collection == b.collection {
+		return errors.New("audit trail collection must not be the store's own collection")
+	}
+	if b.preconditionUpserts {
+		return errors.New("PreconditionUpserts cannot be combined with AuditTrail, since conditional " +
+			"updates cannot add an audit record atomically")
+	}
+	return nil
+}
+
+// defaultAuditActor returns the actor for audit records if none was specified.
+func defaultAuditActor(instanceID string) string {
+	if instanceID != "" {
+		return instanceID
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// auditFields returns the fields that every audit record has.
+func (store *firestoreDataStore) auditFields(operation string) map[string]any {
+	return map[string]any{
+		fieldAuditOperation:  operation,
+		fieldAuditActor:      store.auditActor,
+		fieldAuditTimestamp:  store.now(),
+		fieldAuditCollection: store.collection,
+	}
+}
+
+// addUpsertAuditRecord adds the audit record of an Upsert to the transaction that writes the item, so
+// that there is a record if and only if the item was written.
+func (store *firestoreDataStore) addUpsertAuditRecord(
+	tx *firestore.Transaction,
+	kind ldstoretypes.DataKind,
+	key string,
+	oldVersion, newVersion int,
+) error {
+	data := store.auditFields("Upsert")
+	data[fieldNamespace] = store.namespaceForKind(kind)
+	data[fieldKey] = key
+	data[fieldAuditOldVersion] = oldVersion
+	data[fieldAuditNewVersion] = newVersion
+	return tx.Create(store.collectionRef(store.auditCollection).NewDoc(), data)
+}
+
+// writeInitAuditRecord adds the audit record of an Init. Since an Init may write thousands of items,
+// the record summarizes what it did, from the InitReport, rather than listing every item; err is
+// the error that Init returned, if any. A failure to write the record is logged, but does not make
+// Init fail, since the items have already been written.
+func (store *firestoreDataStore) writeInitAuditRecord(err error) {
+	report := store.LastInitReport()
+	data := store.auditFields("Init")
+	data[fieldAuditItemsWritten] = report.ItemsWritten
+	data[fieldAuditItemsDeleted] = report.ItemsDeleted
+	data[fieldAuditItemsUnchanged] = report.ItemsUnchanged
+	data[fieldAuditItemsFailed] = len(report.Failures)
+	if err != nil {
+		data[fieldAuditError] = err.Error()
+	}
+
+	ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+	defer cancel()
+	if _, err := store.collectionRef(store.auditCollection).NewDoc().Create(ctx, data); err != nil {
+		store.loggers.Errorf("Failed to write the audit record of Init to collection %q: %s",
+			store.auditCollection, err)
+		return
+	}
+	store.countUsage("Init", DocumentOperations{Writes: 1})
+}
@@ -0,0 +1,85 @@
+package ldfirestore
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditTrailBuilder(t *testing.T) {
+	b := DataStore("my-project", "my-collection")
+	assert.NoError(t, b.validateAuditTrail())
+
+	b.AuditTrail(AuditTrailOptions{Actor: "me"})
+	assert.ErrorContains(t, b.validateAuditTrail(), "collection is required")
+
+	b.AuditTrail(AuditTrailOptions{Collection: "my-collection"})
+	assert.ErrorContains(t, b.validateAuditTrail(), "must not be the store's own collection")
+
+	b.AuditTrail(AuditTrailOptions{Collection: "audit"})
+	assert.NoError(t, b.validateAuditTrail())
+
+	ds, err := b.PreconditionUpserts(true).Build(subsystems.BasicClientContext{})
+	assert.Nil(t, ds)
+	assert.ErrorContains(t, err, "cannot be combined with AuditTrail")
+}
+
+func TestAuditTrailDefaultActor(t *testing.T) {
+	assert.Equal(t, "instance1", defaultAuditActor("instance1"))
+	assert.NotEmpty(t, defaultAuditActor(""))
+}
+
+func TestAuditTrail(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	actor := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	store, err := baseDataStoreBuilder().AuditTrail(AuditTrailOptions{Collection: "ld-test-audit", Actor: actor}).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	flag := func(version int) ldstoretypes.SerializedItemDescriptor {
+		return ldstoretypes.SerializedItemDescriptor{Version: version, SerializedItem: []byte(`{}`)}
+	}
+	require.NoError(t, store.Init([]ldstoretypes.SerializedCollection{
+		{Kind: ldstoreimpl.Features(), Items: []ldstoretypes.KeyedSerializedItemDescriptor{
+			{Key: "flag1", Item: flag(1)},
+		}},
+	}))
+	_, err = store.Upsert(ldstoreimpl.Features(), "flag1", flag(2))
+	require.NoError(t, err)
+	_, err = store.Upsert(ldstoreimpl.Features(), "flag1", flag(1)) // not written, so not audited
+	require.NoError(t, err)
+
+	client, err := createTestClient()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+	docs, err := client.Collection("ld-test-audit").Where(fieldAuditActor, "==", actor).
+		OrderBy(fieldAuditTimestamp, firestore.Asc).Documents(context.Background()).GetAll()
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+
+	initRecord := docs[0].Data()
+	assert.Equal(t, "Init", initRecord[fieldAuditOperation])
+	assert.Equal(t, testCollectionName, initRecord[fieldAuditCollection])
+	assert.Equal(t, int64(1), initRecord[fieldAuditItemsWritten])
+	assert.Equal(t, int64(0), initRecord[fieldAuditItemsFailed])
+
+	upsertRecord := docs[1].Data()
+	assert.Equal(t, "Upsert", upsertRecord[fieldAuditOperation])
+	assert.Equal(t, "features", upsertRecord[fieldNamespace])
+	assert.Equal(t, "flag1", upsertRecord[fieldKey])
+	assert.Equal(t, int64(1), upsertRecord[fieldAuditOldVersion])
+	assert.Equal(t, int64(2), upsertRecord[fieldAuditNewVersion])
+}
@@ -0,0 +1,169 @@
+package ldfirestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultAvailabilityCheckTimeout is the default value for [StoreBuilder.AvailabilityCheckTimeout].
+const DefaultAvailabilityCheckTimeout = 5 * time.Second
+
+var (
+	// ErrUnreachable is wrapped by the error from an availability check that failed because Firestore
+	// could not be reached in time. See [ExtendedDataStore.AvailabilityError].
+	ErrUnreachable = errors.New("could not reach Firestore")
+
+	// ErrPermissionDenied is wrapped by the error from an availability check that failed because
+	// Firestore rejected the store's credentials. See [ExtendedDataStore.AvailabilityError].
+	ErrPermissionDenied = errors.New("access to Firestore was denied")
+)
+
+// classifyAvailabilityError wraps the error from an availability check in ErrUnreachable or
+// ErrPermissionDenied, if it is one of those kinds of failure.
+func classifyAvailabilityError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch status.Code(err) {
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return fmt.Errorf("%w: %w", ErrPermissionDenied, err)
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+	return err
+}
+
+// availabilityErrorKind returns the sentinel error that describes the kind of availability failure,
+// so that a change from one kind to another can be logged without logging every failed check.
+func availabilityErrorKind(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrPermissionDenied):
+		return ErrPermissionDenied
+	case errors.Is(err, ErrUnreachable):
+		return ErrUnreachable
+	default:
+		return errUnknownAvailabilityError
+	}
+}
+
+var errUnknownAvailabilityError = errors.New("unknown availability error")
+
+// availabilityListeners calls the functions registered with OnAvailabilityChange when the store
+// changes between available and unavailable. The zero value is ready to use.
+type availabilityListeners struct {
+	listeners   map[int]func(available bool, err error)
+	nextID      int
+	unavailable bool
+	lock        sync.Mutex
+}
+
+func (l *availabilityListeners) add(fn func(available bool, err error)) func() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.listeners == nil {
+		l.listeners = make(map[int]func(bool, error))
+	}
+	id := l.nextID
+	l.nextID++
+	l.listeners[id] = fn
+	return func() {
+		l.lock.Lock()
+		delete(l.listeners, id)
+		l.lock.Unlock()
+	}
+}
+
+// record updates the availability from the outcome of an operation or availability check. A success
+// means that the store is available; an error means that it is unavailable only if it shows that
+// Firestore could not be reached or denied access, rather than being about the request itself. The
+// listeners are called while the lock is held, so that they see the changes in order.
+func (l *availabilityListeners) record(err error) {
+	var unavailable bool
+	switch errorCode(err) {
+	case codes.OK:
+		unavailable = false
+	case codes.Unavailable, codes.DeadlineExceeded, codes.PermissionDenied, codes.Unauthenticated:
+		unavailable = true
+	default:
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if unavailable == l.unavailable {
+		return
+	}
+	l.unavailable = unavailable
+	for _, fn := range l.listeners {
+		fn(!unavailable, err)
+	}
+}
+
+func (store *firestoreDataStore) OnAvailabilityChange(fn func(available bool, err error)) func() {
+	return store.availabilityListeners.add(fn)
+}
+
+// recordAvailabilityError remembers the result of an availability check, and logs it if the kind of
+// failure is not the same as in the previous check.
+func (store *firestoreDataStore) recordAvailabilityError(err error) {
+	store.lock.Lock()
+	previous := store.availabilityErr
+	store.availabilityErr = err
+	store.lock.Unlock()
+
+	kind := availabilityErrorKind(err)
+	if kind == availabilityErrorKind(previous) {
+		return
+	}
+	switch kind {
+	case nil:
+		store.loggers.Infof("Firestore collection %q is available again", store.collection)
+	case ErrPermissionDenied:
+		store.loggers.Errorf("Firestore denied access to collection %q; check the credentials and the "+
+			"permissions of the service account: %s", store.collection, err)
+	case ErrUnreachable:
+		store.loggers.Warnf("Could not reach Firestore to check collection %q: %s", store.collection, err)
+	default:
+		store.loggers.Warnf("Firestore availability check for collection %q failed: %s", store.collection, err)
+	}
+}
+
+func (store *firestoreDataStore) AvailabilityError() error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	return store.availabilityErr
+}
+
+// applyAvailabilityGrace returns the availability that IsStoreAvailable should report. Until the store
+// has connected to Firestore successfully for the first time, a failed check is reported as available
+// if the store was created less than the availability grace period ago, since the first connection can
+// be slow (for instance, while credentials are being obtained) even when Firestore is working. See
+// [StoreBuilder.AvailabilityGracePeriod].
+func (store *firestoreDataStore) applyAvailabilityGrace(available bool, err error) bool {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if available {
+		store.everAvailable = true
+		return true
+	}
+	if store.everAvailable || store.availabilityGrace <= 0 {
+		return false
+	}
+	if elapsed := store.now().Sub(store.startTime); elapsed < store.availabilityGrace {
+		store.loggers.Debugf("Firestore availability check failed %s after startup, but reporting the store "+
+			"as available during the grace period: %s", elapsed, err)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package ldfirestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type settableClock struct{ t time.Time }
+
+func (c *settableClock) Now() time.Time { return c.t }
+
+func TestAvailabilityGracePeriod(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	errUnavailable := errors.New("unavailable")
+	makeStore := func(grace time.Duration) (*firestoreDataStore, *settableClock) {
+		clock := &settableClock{t: start}
+		return &firestoreDataStore{
+			loggers:           ldlogtest.NewMockLog().Loggers,
+			clock:             clock,
+			availabilityGrace: grace,
+			startTime:         start,
+		}, clock
+	}
+
+	t.Run("no grace period by default", func(t *testing.T) {
+		store, _ := makeStore(0)
+		assert.False(t, store.applyAvailabilityGrace(false, errUnavailable))
+	})
+
+	t.Run("optimistic until grace period ends", func(t *testing.T) {
+		store, clock := makeStore(time.Minute)
+		assert.True(t, store.applyAvailabilityGrace(false, errUnavailable))
+		clock.t = start.Add(time.Minute)
+		assert.False(t, store.applyAvailabilityGrace(false, errUnavailable))
+	})
+
+	t.Run("not optimistic after first success", func(t *testing.T) {
+		store, _ := makeStore(time.Minute)
+		assert.True(t, store.applyAvailabilityGrace(true, nil))
+		assert.False(t, store.applyAvailabilityGrace(false, errUnavailable))
+	})
+
+	t.Run("builder", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").AvailabilityGracePeriod(time.Minute)
+		assert.Equal(t, time.Minute, b.availabilityGrace)
+	})
+}
+
+func TestAvailabilityErrors(t *testing.T) {
+	t.Run("classification", func(t *testing.T) {
+		assert.NoError(t, classifyAvailabilityError(nil))
+		assert.ErrorIs(t, classifyAvailabilityError(status.Error(codes.PermissionDenied, "no")), ErrPermissionDenied)
+		assert.ErrorIs(t, classifyAvailabilityError(status.Error(codes.Unauthenticated, "no")), ErrPermissionDenied)
+		assert.ErrorIs(t, classifyAvailabilityError(status.Error(codes.Unavailable, "down")), ErrUnreachable)
+		assert.ErrorIs(t, classifyAvailabilityError(context.DeadlineExceeded), ErrUnreachable)
+
+		other := errors.New("other")
+		assert.Equal(t, other, classifyAvailabilityError(other))
+	})
+
+	t.Run("logs changes only", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := &firestoreDataStore{loggers: mockLog.Loggers, collection: "my-collection"}
+
+		unreachable := classifyAvailabilityError(status.Error(codes.Unavailable, "down"))
+		store.recordAvailabilityError(unreachable)
+		store.recordAvailabilityError(unreachable)
+		assert.Len(t, mockLog.GetOutput(ldlog.Warn), 1)
+		assert.Equal(t, unreachable, store.AvailabilityError())
+
+		denied := classifyAvailabilityError(status.Error(codes.PermissionDenied, "no"))
+		store.recordAvailabilityError(denied)
+		assert.Len(t, mockLog.GetOutput(ldlog.Error), 1)
+
+		store.recordAvailabilityError(nil)
+		assert.Len(t, mockLog.GetOutput(ldlog.Info), 1)
+		assert.NoError(t, store.AvailabilityError())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		client, err := createTestClient()
+		require.NoError(t, err)
+		_ = client.Close()
+
+		builder := DataStore("my-project", "my-collection").FirestoreClient(client)
+		ds, err := builder.Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		assert.Equal(t, DefaultAvailabilityCheckTimeout, ds.(*firestoreDataStore).availabilityTimeout)
+		_ = ds.Close()
+
+		ds, err = builder.AvailabilityCheckTimeout(time.Millisecond).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		defer func() { _ = ds.Close() }()
+		store := ds.(*firestoreDataStore)
+		assert.Equal(t, time.Millisecond, store.availabilityTimeout)
+
+		assert.False(t, store.IsStoreAvailable())
+		assert.Error(t, store.AvailabilityError())
+	})
+}
+
+func TestOnAvailabilityChange(t *testing.T) {
+	t.Run("reports changes only", func(t *testing.T) {
+		store := &firestoreDataStore{}
+		type change struct {
+			available bool
+			err       error
+		}
+		var changes []change
+		unregister := store.OnAvailabilityChange(func(available bool, err error) {
+			changes = append(changes, change{available, err})
+		})
+
+		down := status.Error(codes.Unavailable, "down")
+		denied := fmt.Errorf("%w: %w", ErrPermissionDenied, status.Error(codes.PermissionDenied, "denied"))
+		store.observeResult(nil)
+		store.observeResult(down)
+		store.observeResult(denied)
+		store.observeResult(status.Error(codes.NotFound, "not a connection problem"))
+		store.observeResult(nil)
+		store.observeResult(nil)
+		assert.Equal(t, []change{{false, down}, {true, nil}}, changes)
+
+		unregister()
+		store.observeResult(down)
+		assert.Len(t, changes, 2)
+	})
+
+	t.Run("from operations", func(t *testing.T) {
+		ds, err := DataStore("my-project", "my-collection").
+			ClientOptions(option.WithEndpoint("localhost:1"), option.WithoutAuthentication()).
+			Retry(RetryPolicy{}).
+			OperationTimeout(200 * time.Millisecond).
+			Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		defer func() { _ = ds.Close() }()
+
+		var changes []bool
+		ds.(ExtendedDataStore).OnAvailabilityChange(func(available bool, err error) {
+			changes = append(changes, available)
+			assert.Equal(t, available, err == nil)
+		})
+		_, err = ds.Get(ldstoreimpl.Features(), "flag1")
+		require.Error(t, err)
+		assert.Equal(t, []bool{false}, changes)
+	})
+}
@@ -0,0 +1,136 @@
+package ldfirestore
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+)
+
+// DefaultMembershipCacheMaxContexts is the default value for [MembershipCacheOptions.MaxContexts].
+const DefaultMembershipCacheMaxContexts = 10000
+
+// MembershipCacheOptions configures the cache that is enabled with [StoreBuilder.MembershipCache].
+type MembershipCacheOptions struct {
+	// TTL is how long a membership is cached. The cache is disabled if this is zero.
+	TTL time.Duration
+
+	// MaxContexts is the maximum number of contexts whose memberships are cached; when it is reached,
+	// the least recently used one is removed. If it is zero, DefaultMembershipCacheMaxContexts is used.
+	MaxContexts int
+}
+
+func validateMembershipCache(options MembershipCacheOptions) error {
+	if options.TTL < 0 {
+		return errors.New("membership cache TTL must not be negative")
+	}
+	if options.MaxContexts < 0 {
+		return errors.New("membership cache MaxContexts must not be negative")
+	}
+	return nil
+}
+
+// membershipCache is a read-through cache of the results of GetMembership. A nil *membershipCache is
+// a disabled cache, whose methods do nothing.
+type membershipCache struct {
+	ttl         time.Duration
+	maxContexts int
+	entries     map[string]*list.Element // values are *cachedMembership, ordered from most recently used
+	order       *list.List
+	stats       ItemCacheStats
+	lock        sync.Mutex
+}
+
+type cachedMembership struct {
+	contextHashKey string
+	membership     subsystems.BigSegmentMembership
+	found          bool
+	size           int
+	expiresAt      time.Time
+}
+
+func newMembershipCache(options MembershipCacheOptions) *membershipCache {
+	if options.TTL <= 0 {
+		return nil
+	}
+	maxContexts := options.MaxContexts
+	if maxContexts == 0 {
+		maxContexts = DefaultMembershipCacheMaxContexts
+	}
+	return &membershipCache{
+		ttl:         options.TTL,
+		maxContexts: maxContexts,
+		entries:     make(map[string]*list.Element),
+		order:       list.New(),
+	}
+}
+
+func (c *membershipCache) get(contextHashKey string, now time.Time) (*cachedMembership, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if elem, ok := c.entries[contextHashKey]; ok {
+		entry := elem.Value.(*cachedMembership)
+		if now.Before(entry.expiresAt) {
+			c.order.MoveToFront(elem)
+			c.stats.Hits++
+			return entry, true
+		}
+		c.removeElement(elem)
+	}
+	c.stats.Misses++
+	return nil, false
+}
+
+func (c *membershipCache) put(entry *cachedMembership, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry.expiresAt = now.Add(c.ttl)
+	if elem, ok := c.entries[entry.contextHashKey]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[entry.contextHashKey] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxContexts {
+		c.removeElement(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *membershipCache) removeElement(elem *list.Element) {
+	delete(c.entries, elem.Value.(*cachedMembership).contextHashKey)
+	c.order.Remove(elem)
+}
+
+func (c *membershipCache) getStats() ItemCacheStats {
+	if c == nil {
+		return ItemCacheStats{}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.stats
+}
+
+func (store *firestoreBigSegmentStoreImpl) MembershipCacheStats() ItemCacheStats {
+	return store.cache.getStats()
+}
+
+// remove discards the cached membership of a context, if any, after the store has changed it.
+func (c *membershipCache) remove(contextHashKey string) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if elem, ok := c.entries[contextHashKey]; ok {
+		c.removeElement(elem)
+	}
+}
@@ -0,0 +1,107 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMembershipCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(contextHashKey string) *cachedMembership {
+		return &cachedMembership{
+			contextHashKey: contextHashKey,
+			membership:     ldstoreimpl.NewBigSegmentMembershipFromSegmentRefs([]string{"seg1"}, nil),
+			found:          true,
+			size:           1,
+		}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		c := newMembershipCache(MembershipCacheOptions{})
+		assert.Nil(t, c)
+		c.put(entry("a"), now)
+		_, ok := c.get("a", now)
+		assert.False(t, ok)
+		assert.Equal(t, ItemCacheStats{}, c.getStats())
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		c := newMembershipCache(MembershipCacheOptions{TTL: time.Minute})
+		assert.Equal(t, DefaultMembershipCacheMaxContexts, c.maxContexts)
+		_, ok := c.get("a", now)
+		assert.False(t, ok)
+
+		c.put(entry("a"), now)
+		got, ok := c.get("a", now.Add(time.Second))
+		require.True(t, ok)
+		assert.True(t, got.found)
+		assert.Equal(t, 1, got.size)
+
+		_, ok = c.get("a", now.Add(time.Minute))
+		assert.False(t, ok)
+		assert.Equal(t, ItemCacheStats{Hits: 1, Misses: 2}, c.getStats())
+	})
+
+	t.Run("least recently used contexts are evicted", func(t *testing.T) {
+		c := newMembershipCache(MembershipCacheOptions{TTL: time.Minute, MaxContexts: 2})
+		c.put(entry("a"), now)
+		c.put(entry("b"), now)
+		_, _ = c.get("a", now)
+		c.put(entry("c"), now)
+
+		_, ok := c.get("b", now)
+		assert.False(t, ok)
+		_, ok = c.get("a", now)
+		assert.True(t, ok)
+		_, ok = c.get("c", now)
+		assert.True(t, ok)
+		assert.Equal(t, 1, c.getStats().Evictions)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		assert.NoError(t, validateMembershipCache(MembershipCacheOptions{}))
+		assert.Error(t, validateMembershipCache(MembershipCacheOptions{TTL: -1}))
+		assert.Error(t, validateMembershipCache(MembershipCacheOptions{TTL: time.Minute, MaxContexts: -1}))
+	})
+}
+
+func TestBigSegmentStoreMembershipCache(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close() // every read from Firestore will fail
+
+	var calls []BigSegmentCallInfo
+	bs, err := BigSegmentStore(testProjectID, testCollectionName).
+		FirestoreClient(client).
+		MembershipCache(MembershipCacheOptions{TTL: time.Hour}).
+		BigSegmentsHook(func(info BigSegmentCallInfo) { calls = append(calls, info) }).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = bs.Close() }()
+	store := bs.(*firestoreBigSegmentStoreImpl)
+
+	membership := ldstoreimpl.NewBigSegmentMembershipFromSegmentRefs([]string{"seg1"}, nil)
+	store.cache.put(&cachedMembership{contextHashKey: "abcdefghijk", membership: membership, found: true,
+		size: 1}, time.Now())
+
+	got, err := store.GetMembership("abcdefghijk")
+	require.NoError(t, err)
+	assert.Equal(t, membership, got)
+
+	_, err = store.GetMembership("other")
+	assert.Error(t, err)
+	_, err = store.GetMembership("other")
+	assert.Error(t, err) // errors are not cached
+
+	require.Len(t, calls, 3)
+	assert.True(t, calls[0].Cached)
+	assert.True(t, calls[0].Found)
+	assert.Equal(t, 1, calls[0].ResultSize)
+	assert.False(t, calls[1].Cached)
+	assert.Equal(t, ItemCacheStats{Hits: 1, Misses: 2}, store.MembershipCacheStats())
+}
@@ -0,0 +1,136 @@
+package ldfirestore
+
+import (
+	"fmt"
+	"strconv"
+
+	"cloud.google.com/go/firestore"
+)
+
+// bigSegmentsChunksAttr is the attribute of a membership document that gives the number of documents
+// that the context's membership is split across, including the membership document itself.
+//
+// A context that is included in or excluded from so many segments that its references would not fit
+// in one document (whose size is limited to 1 MiB) has its references split into chunks. The first
+// chunk is in the usual membership document, which also has this attribute. Chunk i, for i from 1 to
+// chunks-1, is a document in the same namespace whose key is membershipChunkKey(contextHashKey, i),
+// and which has its own included and excluded attributes. Since the chunks share the namespace of the
+// membership documents, Purge deletes them too. A membership document without this attribute is a
+// single chunk.
+const bigSegmentsChunksAttr = "chunks"
+
+// membershipChunkMaxBytes is the estimated size above which ApplyMembershipPatch splits a membership
+// into more chunks. It is below Firestore's 1 MiB document limit, to leave room for the other fields
+// and for the inaccuracy of the estimate.
+const membershipChunkMaxBytes = 900 * 1024
+
+// membershipChunk is the segment references in one chunk of a context's membership.
+type membershipChunk struct {
+	included, excluded []string
+}
+
+func (c membershipChunk) empty() bool {
+	return len(c.included) == 0 && len(c.excluded) == 0
+}
+
+// splitMembership divides a context's segment references into as few chunks as it can, such that the
+// estimated size of each chunk's references is at most membershipChunkMaxBytes. It always returns at
+// least one chunk.
+func splitMembership(included, excluded []string) []membershipChunk {
+	chunks := []membershipChunk{{}}
+	size := 0
+	add := func(ref string, isIncluded bool) {
+		refSize := len(ref) + estimateValueSize(true)
+		if size > 0 && size+refSize > membershipChunkMaxBytes {
+			chunks = append(chunks, membershipChunk{})
+			size = 0
+		}
+		size += refSize
+		last := &chunks[len(chunks)-1]
+		if isIncluded {
+			last.included = append(last.included, ref)
+		} else {
+			last.excluded = append(last.excluded, ref)
+		}
+	}
+	for _, ref := range included {
+		add(ref, true)
+	}
+	for _, ref := range excluded {
+		add(ref, false)
+	}
+	return chunks
+}
+
+// membershipChunkRef returns the document of a chunk of a context's membership; chunk 0 is the
+// membership document itself.
+func (store *firestoreBigSegmentStoreImpl) membershipChunkRef(contextHashKey string, index int) *firestore.DocumentRef {
+	key := contextHashKey
+	if index > 0 {
+		key = membershipChunkKey(contextHashKey, index)
+	}
+	return store.collectionRef(store.collection).Doc(store.makeDocID(bigSegmentsUserDataKey, key))
+}
+
+// membershipChunkKey returns the key of a chunk of a context's membership after the first one. Context
+// hash keys are base64-encoded, so they never contain the separator, and a chunk key cannot be mistaken
+// for the key of another context.
+func membershipChunkKey(contextHashKey string, index int) string {
+	return contextHashKey + "#" + strconv.Itoa(index)
+}
+
+// membershipChunkCount returns the number of chunks that a membership document says the context's
+// membership is split across.
+func membershipChunkCount(data map[string]any) (int, error) {
+	value, found := data[bigSegmentsChunksAttr]
+	if !found {
+		return 1, nil
+	}
+	count, ok := value.(int64)
+	if !ok || count < 1 {
+		return 0, fmt.Errorf("expected a positive chunk count but found %v", value)
+	}
+	return int(count), nil
+}
+
+// readMembershipChunks reads the chunks of a context's membership after the first one, and returns
+// the segment references in them. It fails if any chunk is missing, rather than returning a partial
+// membership that could include the context in a segment that it is excluded from.
+func (store *firestoreBigSegmentStoreImpl) readMembershipChunks(
+	contextHashKey string,
+	count int,
+) (included []string, excluded []string, err error) {
+	refs := make([]*firestore.DocumentRef, 0, count-1)
+	for i := 1; i < count; i++ {
+		refs = append(refs, store.membershipChunkRef(contextHashKey, i))
+	}
+
+	var docs []*firestore.DocumentSnapshot
+	err = store.retry.do(store.context, nil, func() (err error) {
+		ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+		defer cancel()
+		docs, err = store.client.GetAll(ctx, refs)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, doc := range docs {
+		if !doc.Exists() {
+			return nil, nil, fmt.Errorf("chunk %d of %d of the membership is missing", i+1, count)
+		}
+		data := doc.Data()
+		chunkIncluded, err := getStringSliceFromInterface(data, bigSegmentsIncludedAttr)
+		if err != nil {
+			return nil, nil, err
+		}
+		chunkExcluded, err := getStringSliceFromInterface(data, bigSegmentsExcludedAttr)
+		if err != nil {
+			return nil, nil, err
+		}
+		included = append(included, chunkIncluded...)
+		excluded = append(excluded, chunkExcluded...)
+	}
+	return included, excluded, nil
+}
@@ -0,0 +1,87 @@
+package ldfirestore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMembershipChunkCount(t *testing.T) {
+	count, err := membershipChunkCount(map[string]any{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = membershipChunkCount(map[string]any{bigSegmentsChunksAttr: int64(3)})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	for _, bad := range []any{int64(0), int64(-1), "3"} {
+		_, err = membershipChunkCount(map[string]any{bigSegmentsChunksAttr: bad})
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestBigSegmentStoreChunkedMembership(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	client, err := createTestClient()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	setChunk := func(key string, fields map[string]any) {
+		data := map[string]any{
+			fieldNamespace: makeTestNamespace("", bigSegmentsUserDataKey),
+			fieldKey:       key,
+		}
+		for k, v := range fields {
+			data[k] = v
+		}
+		_, err := client.Collection(testCollectionName).Doc(makeTestDocID("", bigSegmentsUserDataKey, key)).
+			Set(context.Background(), data)
+		require.NoError(t, err)
+	}
+
+	store, err := baseBigSegmentStoreBuilder().Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	t.Run("reassembles chunks", func(t *testing.T) {
+		setChunk("context1", map[string]any{
+			bigSegmentsChunksAttr:   3,
+			bigSegmentsIncludedAttr: []string{"seg1"},
+		})
+		setChunk(membershipChunkKey("context1", 1), map[string]any{bigSegmentsIncludedAttr: []string{"seg2"}})
+		setChunk(membershipChunkKey("context1", 2), map[string]any{bigSegmentsExcludedAttr: []string{"seg3"}})
+
+		membership, err := store.GetMembership("context1")
+		require.NoError(t, err)
+		assert.Equal(t, ldvalue.NewOptionalBool(true), membership.CheckMembership("seg1"))
+		assert.Equal(t, ldvalue.NewOptionalBool(true), membership.CheckMembership("seg2"))
+		assert.Equal(t, ldvalue.NewOptionalBool(false), membership.CheckMembership("seg3"))
+	})
+
+	t.Run("fails if a chunk is missing", func(t *testing.T) {
+		setChunk("context2", map[string]any{
+			bigSegmentsChunksAttr:   2,
+			bigSegmentsIncludedAttr: []string{"seg1"},
+		})
+
+		_, err := store.GetMembership("context2")
+		assert.ErrorContains(t, err, "chunk 1 of 2")
+	})
+
+	t.Run("purge deletes chunks", func(t *testing.T) {
+		_, err := store.(ExtendedBigSegmentStore).Purge(nil)
+		require.NoError(t, err)
+		docs, err := client.Collection(testCollectionName).Documents(context.Background()).GetAll()
+		require.NoError(t, err)
+		assert.Len(t, docs, 0)
+	})
+}
@@ -0,0 +1,70 @@
+package ldfirestore
+
+import "time"
+
+// BigSegmentOperation identifies the Big Segment store method described by a [BigSegmentCallInfo].
+type BigSegmentOperation string
+
+const (
+	// BigSegmentGetMembership is a call to look up the Big Segment membership of a context.
+	BigSegmentGetMembership BigSegmentOperation = "GetMembership"
+
+	// BigSegmentGetMetadata is a call to read the Big Segment store metadata.
+	BigSegmentGetMetadata BigSegmentOperation = "GetMetadata"
+
+	// BigSegmentApplyMembershipPatch is a call to change the Big Segment membership of a context.
+	BigSegmentApplyMembershipPatch BigSegmentOperation = "ApplyMembershipPatch"
+
+	// BigSegmentSetMetadata is a call to write the Big Segment store metadata.
+	BigSegmentSetMetadata BigSegmentOperation = "SetMetadata"
+)
+
+// BigSegmentCallInfo describes a single call to the Big Segment store. See
+// [StoreBuilder.BigSegmentsHook].
+type BigSegmentCallInfo struct {
+	// Operation is the method that was called.
+	Operation BigSegmentOperation
+
+	// ContextHash identifies the context for a GetMembership or ApplyMembershipPatch call. It is a
+	// short prefix of the hashed context key that the SDK provides, so it cannot be used to recover the
+	// key, but calls for the same context can still be grouped together. It is empty for GetMetadata
+	// and SetMetadata.
+	ContextHash string
+
+	// Latency is how long the call took, including the Firestore request.
+	Latency time.Duration
+
+	// Found is true if the Firestore document for the call existed.
+	Found bool
+
+	// ResultSize is the number of segment references (included plus excluded) that were returned by
+	// GetMembership. It is zero for the other operations.
+	ResultSize int
+
+	// Cached is true if the result of GetMembership came from the cache that is enabled with
+	// [StoreBuilder.MembershipCache], or the result of GetMetadata came from the snapshot listener
+	// that is enabled with [StoreBuilder.WatchMetadata], rather than from a Firestore read.
+	Cached bool
+
+	// Err is the error returned by the call, if any.
+	Err error
+}
+
+// contextHashPrefixLength is the number of characters of the hashed context key that are passed to
+// the hook.
+const contextHashPrefixLength = 8
+
+func redactContextHash(contextHashKey string) string {
+	if len(contextHashKey) > contextHashPrefixLength {
+		return contextHashKey[:contextHashPrefixLength]
+	}
+	return contextHashKey
+}
+
+func (store *firestoreBigSegmentStoreImpl) reportCall(info BigSegmentCallInfo, start time.Time) {
+	if store.hook == nil {
+		return
+	}
+	info.Latency = time.Since(start)
+	store.hook(info)
+}
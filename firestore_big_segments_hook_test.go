@@ -0,0 +1,42 @@
+package ldfirestore
+
+import (
+	"testing"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactContextHash(t *testing.T) {
+	assert.Equal(t, "abcdefgh", redactContextHash("abcdefghijklmnop"))
+	assert.Equal(t, "abc", redactContextHash("abc"))
+}
+
+func TestBigSegmentsHook(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close() // use a closed client so that every call fails without a server
+
+	var calls []BigSegmentCallInfo
+	store, err := BigSegmentStore(testProjectID, testCollectionName).
+		FirestoreClient(client).
+		BigSegmentsHook(func(info BigSegmentCallInfo) { calls = append(calls, info) }).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	_, err = store.GetMembership("abcdefghijklmnop")
+	assert.Error(t, err)
+	_, err = store.GetMetadata()
+	assert.Error(t, err)
+
+	require.Len(t, calls, 2)
+	assert.Equal(t, BigSegmentGetMembership, calls[0].Operation)
+	assert.Equal(t, "abcdefgh", calls[0].ContextHash)
+	assert.Error(t, calls[0].Err)
+	assert.False(t, calls[0].Found)
+	assert.Equal(t, BigSegmentGetMetadata, calls[1].Operation)
+	assert.Equal(t, "", calls[1].ContextHash)
+	assert.Error(t, calls[1].Err)
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"sync"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
@@ -22,119 +25,321 @@ const (
 	bigSegmentsExcludedAttr = "excluded"
 )
 
+// ExtendedBigSegmentStore is implemented by the Big Segment store that is created by
+// [BigSegmentStore]. It provides operations beyond the standard [subsystems.BigSegmentStore]
+// interface, for use by tooling rather than by the SDK itself. As with [ExtendedDataStore], use a
+// type assertion to access it.
+type ExtendedBigSegmentStore interface {
+	subsystems.BigSegmentStore
+
+	// Purge deletes the Big Segment metadata and every membership document for the store's prefix,
+	// for instance to clean up after an environment is decommissioned. Documents for other prefixes
+	// are not affected. After each batch of deletes, onProgress (if not nil) is called with the number
+	// of documents deleted so far. It returns the total number of documents deleted.
+	Purge(onProgress func(deleted int)) (int, error)
+
+	// MembershipCacheStats returns the hit, miss, and eviction counts of the cache that is enabled with
+	// [StoreBuilder.MembershipCache], counting each GetMembership call.
+	MembershipCacheStats() ItemCacheStats
+
+	// ApplyMembershipPatch changes the Big Segment membership of a context, for use by a custom
+	// synchronizer that populates the store in place of the Relay Proxy. The segment references in
+	// addIncluded and addExcluded are added to the context's included and excluded segments, and those
+	// in removeIncluded and removeExcluded are removed from them. The change is made in a transaction,
+	// so it is not affected by concurrent patches for the same context. The membership is written in
+	// the map encoding, replacing any array encoding, and a membership that is split into chunks keeps
+	// its chunks, with new references added to the first one unless they are already in another chunk.
+	// If the first chunk would become too large for a Firestore document, the membership is split into
+	// chunks again.
+	ApplyMembershipPatch(
+		contextHashKey string,
+		addIncluded, removeIncluded, addExcluded, removeExcluded []string,
+	) error
+
+	// SetMetadata records the time at which a synchronizer last brought the store up to date, which
+	// the SDK uses to decide whether Big Segment data is stale.
+	SetMetadata(lastUpToDate ldtime.UnixMillisecondTime) error
+}
+
 // Internal implementation of the BigSegmentStore interface for Firestore.
 type firestoreBigSegmentStoreImpl struct {
-	client        *firestore.Client
-	context       context.Context
-	cancelContext func()
-	collection    string
-	prefix        string
-	loggers       ldlog.Loggers
-	ownsClient    bool // true if we created the client and should close it
+	client           *firestore.Client
+	context          context.Context
+	cancelContext    func()
+	collection       string
+	parentDoc        string
+	prefix           string
+	loggers          ldlog.Loggers
+	operationTimeout time.Duration
+	hook             func(BigSegmentCallInfo)
+	telemetry        *telemetry
+	retry            RetryPolicy
+	ids              docIDScheme
+	ownsClient       bool // true if we created the client and should close it
+	sharedClient     *SharedClient
+	closeOnce        sync.Once
+	panics           panicSites
+	cache            *membershipCache
+	watch            *metadataWatch
 }
 
 func newFirestoreBigSegmentStoreImpl(
 	builder builderOptions,
 	loggers ldlog.Loggers,
 ) (*firestoreBigSegmentStoreImpl, error) {
-	if builder.collection == "" {
-		return nil, errors.New("collection name is required")
+	if errs := builder.validateBigSegmentStore(); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	parentDoc, err := normalizeParentDocument(builder.parentDocument)
+	if err != nil {
+		return nil, err
 	}
 
 	client := builder.client
-	ctx, cancelContext := context.WithCancel(context.Background())
+	ctx, cancelContext := builder.storeContext()
 	ownsClient := false
 
-	// If a client was provided, use it directly. Otherwise, create a new one.
-	// We only close clients that we create ourselves.
-	if client == nil {
-		var err error
-		if client, ctx, cancelContext, err = makeClientAndContext(builder); err != nil {
+	// If a client or shared client was provided, use it directly. Otherwise, create a new one, or in
+	// cold-start mode use the process-wide one. We only close clients that we create ourselves.
+	clientBuilder := withClientOptions(builder, loggers, "FirestoreBigSegmentStore:")
+	if client == nil && builder.sharedClient != nil {
+		if client, err = builder.sharedClient.acquire(); err != nil {
+			cancelContext()
+			return nil, err
+		}
+	} else if client == nil && builder.coldStart {
+		if client, err = getSharedClient(clientBuilder); err != nil {
+			cancelContext()
+			return nil, err
+		}
+	} else if client == nil {
+		if client, ctx, cancelContext, err = makeClientAndContext(clientBuilder); err != nil {
 			return nil, err
 		}
 		ownsClient = true
 	}
 
 	store := &firestoreBigSegmentStoreImpl{
-		client:        client,
-		context:       ctx,
-		cancelContext: cancelContext,
-		collection:    builder.collection,
-		prefix:        builder.prefix,
-		loggers:       loggers, // copied by value so we can modify it
-		ownsClient:    ownsClient,
+		client:           client,
+		context:          ctx,
+		cancelContext:    cancelContext,
+		collection:       builder.collection,
+		parentDoc:        parentDoc,
+		prefix:           builder.prefix,
+		loggers:          loggers, // copied by value so we can modify it
+		operationTimeout: builder.effectiveOperationTimeout(),
+		hook:             builder.bigSegmentsHook,
+		retry:            builder.retryPolicy,
+		ids:              builder.docIDs,
+		ownsClient:       ownsClient,
+		sharedClient:     builder.sharedClient,
+		cache:            newMembershipCache(builder.membershipCache),
 	}
 	store.loggers.SetPrefix("FirestoreBigSegmentStore:")
+	store.telemetry = newTelemetry(store.context, builder, store.loggers)
 	store.loggers.Infof(`Using Firestore collection %s`, store.collection)
+	logEmulatorMode(builder, store.loggers)
+	if builder.watchMetadata {
+		store.watch = &metadataWatch{}
+		go store.watchMetadata()
+	}
 
 	return store, nil
 }
 
 func (store *firestoreBigSegmentStoreImpl) GetMetadata() (subsystems.BigSegmentStoreMetadata, error) {
-	docID := store.makeDocID(bigSegmentsMetadataKey, bigSegmentsMetadataKey)
-	docRef := store.client.Collection(store.collection).Doc(docID)
+	start := time.Now()
+	op, err := store.telemetry.start(string(BigSegmentGetMetadata), nil, "")
+	var metadata subsystems.BigSegmentStoreMetadata
+	var found, cached bool
+	if err == nil {
+		err = store.panics.protect(store.loggers, string(BigSegmentGetMetadata), func() (err error) {
+			if metadata, found, cached = store.watch.get(); cached {
+				return nil
+			}
+			metadata, found, err = store.getMetadata()
+			return err
+		})
+		if err != nil {
+			err = newStoreError(string(BigSegmentGetMetadata), nil, "", err)
+		}
+	}
+	op.end(foundCount(found), err)
+	store.reportCall(BigSegmentCallInfo{
+		Operation: BigSegmentGetMetadata,
+		Found:     found,
+		Cached:    cached,
+		Err:       err,
+	}, start)
+	return metadata, err
+}
 
-	doc, err := docRef.Get(store.context)
+func (store *firestoreBigSegmentStoreImpl) getMetadata() (subsystems.BigSegmentStoreMetadata, bool, error) {
+	doc, err := store.getDocument(store.metadataDocRef())
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
 			// this is just a "not found" result, not a database error
-			return subsystems.BigSegmentStoreMetadata{}, nil
+			return subsystems.BigSegmentStoreMetadata{}, false, nil
 		}
-		return subsystems.BigSegmentStoreMetadata{}, err
+		return subsystems.BigSegmentStoreMetadata{}, false, err
 	}
 
 	if !doc.Exists() {
-		return subsystems.BigSegmentStoreMetadata{}, nil
+		return subsystems.BigSegmentStoreMetadata{}, false, nil
 	}
+	return metadataFromData(doc.Data()), true, nil
+}
 
-	data := doc.Data()
+// checkConnection reads the metadata document to check that Firestore can be reached, like the data
+// store's availability check.
+func (store *firestoreBigSegmentStoreImpl) checkConnection(timeout time.Duration) error {
+	ctx, cancel := withOperationTimeout(store.context, timeout)
+	defer cancel()
+
+	_, err := store.metadataDocRef().Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	return err
+}
+
+func (store *firestoreBigSegmentStoreImpl) metadataDocRef() *firestore.DocumentRef {
+	return store.collectionRef(store.collection).Doc(store.makeDocID(bigSegmentsMetadataKey, bigSegmentsMetadataKey))
+}
+
+// metadataFromData returns the metadata in the fields of the metadata document.
+func metadataFromData(data map[string]any) subsystems.BigSegmentStoreMetadata {
 	value, ok := data[bigSegmentsSyncTimeAttr].(int64)
 	if !ok || value == 0 {
-		return subsystems.BigSegmentStoreMetadata{}, nil
+		return subsystems.BigSegmentStoreMetadata{}
 	}
-
-	return subsystems.BigSegmentStoreMetadata{
-		LastUpToDate: ldtime.UnixMillisecondTime(uint64(value)),
-	}, nil
+	return subsystems.BigSegmentStoreMetadata{LastUpToDate: ldtime.UnixMillisecondTime(uint64(value))}
 }
 
 func (store *firestoreBigSegmentStoreImpl) GetMembership(
 	contextHashKey string,
 ) (subsystems.BigSegmentMembership, error) {
+	start := time.Now()
+	contextHash := redactContextHash(contextHashKey)
+	op, err := store.telemetry.start(string(BigSegmentGetMembership), nil, contextHash,
+		attrContextHash.String(contextHash))
+	var membership subsystems.BigSegmentMembership
+	var found, cached bool
+	var size int
+	if err == nil {
+		err = store.panics.protect(store.loggers, string(BigSegmentGetMembership), func() (err error) {
+			if entry, ok := store.cache.get(contextHashKey, time.Now()); ok {
+				membership, found, size, cached = entry.membership, entry.found, entry.size, true
+				return nil
+			}
+			membership, found, size, err = store.getMembership(contextHashKey)
+			if err == nil {
+				store.cache.put(&cachedMembership{
+					contextHashKey: contextHashKey,
+					membership:     membership,
+					found:          found,
+					size:           size,
+				}, time.Now())
+			}
+			return err
+		})
+		if err != nil {
+			err = newStoreError(string(BigSegmentGetMembership), nil, contextHash, err)
+		}
+	}
+	op.end(foundCount(found), err)
+	store.reportCall(BigSegmentCallInfo{
+		Operation:   BigSegmentGetMembership,
+		ContextHash: contextHash,
+		Found:       found,
+		ResultSize:  size,
+		Cached:      cached,
+		Err:         err,
+	}, start)
+	return membership, err
+}
+
+// getMembership returns the membership for a context, whether its document was found, and the
+// number of segment references in it. If the membership is split into chunks, they are all read and
+// combined.
+func (store *firestoreBigSegmentStoreImpl) getMembership(
+	contextHashKey string,
+) (subsystems.BigSegmentMembership, bool, int, error) {
 	docID := store.makeDocID(bigSegmentsUserDataKey, contextHashKey)
-	docRef := store.client.Collection(store.collection).Doc(docID)
+	docRef := store.collectionRef(store.collection).Doc(docID)
 
-	doc, err := docRef.Get(store.context)
+	doc, err := store.getDocument(docRef)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
-			return ldstoreimpl.NewBigSegmentMembershipFromSegmentRefs(nil, nil), nil
+			return ldstoreimpl.NewBigSegmentMembershipFromSegmentRefs(nil, nil), false, 0, nil
 		}
-		return nil, err
+		return nil, false, 0, err
 	}
 
 	if !doc.Exists() {
-		return ldstoreimpl.NewBigSegmentMembershipFromSegmentRefs(nil, nil), nil
+		return ldstoreimpl.NewBigSegmentMembershipFromSegmentRefs(nil, nil), false, 0, nil
 	}
 
 	data := doc.Data()
 	includedRefs, err := getStringSliceFromInterface(data, bigSegmentsIncludedAttr)
 	if err != nil {
-		return nil, err
+		return nil, true, 0, err
 	}
 	excludedRefs, err := getStringSliceFromInterface(data, bigSegmentsExcludedAttr)
 	if err != nil {
-		return nil, err
+		return nil, true, 0, err
+	}
+	chunks, err := membershipChunkCount(data)
+	if err != nil {
+		return nil, true, 0, err
 	}
+	if chunks > 1 {
+		moreIncluded, moreExcluded, err := store.readMembershipChunks(contextHashKey, chunks)
+		if err != nil {
+			return nil, true, 0, err
+		}
+		includedRefs = append(includedRefs, moreIncluded...)
+		excludedRefs = append(excludedRefs, moreExcluded...)
+	}
+
+	return ldstoreimpl.NewBigSegmentMembershipFromSegmentRefs(includedRefs, excludedRefs), true,
+		len(includedRefs) + len(excludedRefs), nil
+}
 
-	return ldstoreimpl.NewBigSegmentMembershipFromSegmentRefs(includedRefs, excludedRefs), nil
+func (store *firestoreBigSegmentStoreImpl) Purge(onProgress func(deleted int)) (int, error) {
+	query := store.collectionRef(store.collection).Where(fieldNamespace, "in", []string{
+		prefixedNamespace(store.prefix, bigSegmentsMetadataKey),
+		prefixedNamespace(store.prefix, bigSegmentsUserDataKey),
+	})
+	deleted, err := deleteByQuery(store.context, store.client, query, onProgress)
+	if err != nil {
+		return deleted, newStoreError("Purge", nil, "", err)
+	}
+	store.loggers.Infof("Purged %d Big Segment document(s) from collection %q", deleted, store.collection)
+	return deleted, nil
 }
 
+// getStringSliceFromInterface returns the segment references in an included or excluded attribute.
+// The attribute is either an array of references, or a map whose keys are the references and whose
+// values are true. Other values in a map are ignored, so that a syncer can remove a reference by
+// setting it to false as well as by deleting it.
 func getStringSliceFromInterface(data map[string]any, key string) ([]string, error) {
 	value, found := data[key]
 	if !found {
 		return nil, nil // attribute is optional
 	}
 
+	if m, ok := value.(map[string]any); ok {
+		result := make([]string, 0, len(m))
+		for ref, v := range m {
+			if v == true {
+				result = append(result, ref)
+			}
+		}
+		slices.Sort(result)
+		return result, nil
+	}
+
 	if arr, ok := value.([]any); ok {
 		result := make([]string, 0, len(arr))
 		for _, v := range arr {
@@ -147,24 +352,43 @@ func getStringSliceFromInterface(data map[string]any, key string) ([]string, err
 		return result, nil
 	}
 
-	return nil, errors.New("expected string array")
+	return nil, errors.New("expected string array or map")
 }
 
+// Close stops any pending operations and releases the client. Calling it more than once has no
+// further effect.
 func (store *firestoreBigSegmentStoreImpl) Close() error {
-	store.cancelContext() // stops any pending operations
-	// Only close the client if we created it. If a client was provided to us,
-	// it's the caller's responsibility to close it.
-	if store.ownsClient {
-		return store.client.Close()
-	}
-	return nil
+	var err error
+	store.closeOnce.Do(func() {
+		store.cancelContext() // stops any pending operations
+		// Only close the client if we created it. If a client was provided to us,
+		// it's the caller's responsibility to close it.
+		if store.ownsClient {
+			err = store.client.Close()
+		} else if store.sharedClient != nil {
+			err = store.sharedClient.release()
+		}
+	})
+	return err
 }
 
 func (store *firestoreBigSegmentStoreImpl) makeDocID(namespace, key string) string {
 	// Document ID format: {prefix}:{namespace}:{key}
-	fullNamespace := namespace
-	if store.prefix != "" {
-		fullNamespace = store.prefix + ":" + namespace
-	}
-	return fullNamespace + ":" + key
+	return store.ids.join(prefixedNamespace(store.prefix, namespace), key)
+}
+
+// getDocument reads a document, retrying transient errors according to the retry policy.
+func (store *firestoreBigSegmentStoreImpl) getDocument(docRef *firestore.DocumentRef) (*firestore.DocumentSnapshot, error) {
+	var doc *firestore.DocumentSnapshot
+	err := store.retry.do(store.context, nil, func() (err error) {
+		ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+		defer cancel()
+		doc, err = docRef.Get(ctx)
+		return err
+	})
+	return doc, err
+}
+
+func (store *firestoreBigSegmentStoreImpl) collectionRef(name string) *firestore.CollectionRef {
+	return collectionRef(store.client, resolveParentDocument(store.parentDoc, store.prefix), name)
 }
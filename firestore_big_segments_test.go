@@ -2,10 +2,13 @@ package ldfirestore
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
 	"github.com/launchdarkly/go-server-sdk/v7/testhelpers/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBigSegmentStore(t *testing.T) {
@@ -90,3 +93,73 @@ func makeTestNamespace(prefix, namespace string) string {
 	}
 	return prefix + ":" + namespace
 }
+
+func TestBigSegmentStorePurge(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	client, err := createTestClient()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	for _, prefix := range []string{"old", "current"} {
+		for _, ref := range [][2]string{
+			{bigSegmentsMetadataKey, bigSegmentsMetadataKey},
+			{bigSegmentsUserDataKey, "user1"},
+			{bigSegmentsUserDataKey, "user2"},
+		} {
+			_, err := client.Collection(testCollectionName).Doc(makeTestDocID(prefix, ref[0], ref[1])).
+				Set(context.Background(), map[string]any{
+					fieldNamespace: makeTestNamespace(prefix, ref[0]),
+					fieldKey:       ref[1],
+				})
+			require.NoError(t, err)
+		}
+	}
+
+	store, err := baseBigSegmentStoreBuilder().Prefix("old").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	deleted, err := store.(ExtendedBigSegmentStore).Purge(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+
+	docs, err := client.Collection(testCollectionName).Documents(context.Background()).GetAll()
+	require.NoError(t, err)
+	assert.Len(t, docs, 3)
+	for _, doc := range docs {
+		assert.True(t, strings.HasPrefix(doc.Ref.ID, "current:"), doc.Ref.ID)
+	}
+}
+
+func TestGetStringSliceFromInterface(t *testing.T) {
+	t.Run("array", func(t *testing.T) {
+		refs, err := getStringSliceFromInterface(map[string]any{"included": []any{"a", "b"}}, "included")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, refs)
+	})
+
+	t.Run("map", func(t *testing.T) {
+		refs, err := getStringSliceFromInterface(map[string]any{
+			"included": map[string]any{"b": true, "a": true, "removed": false},
+		}, "included")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, refs)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		refs, err := getStringSliceFromInterface(map[string]any{}, "included")
+		require.NoError(t, err)
+		assert.Nil(t, refs)
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		_, err := getStringSliceFromInterface(map[string]any{"included": "a"}, "included")
+		assert.Error(t, err)
+		_, err = getStringSliceFromInterface(map[string]any{"included": []any{1}}, "included")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,85 @@
+package ldfirestore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+)
+
+// metadataWatch holds the Big Segment metadata from the latest snapshot of its document, for a store
+// with [StoreBuilder.WatchMetadata] enabled. A nil *metadataWatch is never live.
+type metadataWatch struct {
+	lock     sync.Mutex
+	live     bool
+	metadata subsystems.BigSegmentStoreMetadata
+	found    bool
+}
+
+// get returns the latest metadata and whether its document exists, or false if the listener is not
+// running, in which case the metadata should be read from Firestore instead.
+func (w *metadataWatch) get() (subsystems.BigSegmentStoreMetadata, bool, bool) {
+	if w == nil {
+		return subsystems.BigSegmentStoreMetadata{}, false, false
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.metadata, w.found, w.live
+}
+
+func (w *metadataWatch) set(metadata subsystems.BigSegmentStoreMetadata, found bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.metadata, w.found, w.live = metadata, found, true
+}
+
+func (w *metadataWatch) stop() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.live = false
+}
+
+// watchMetadata listens for changes to the metadata document until the store is closed. If the
+// listener fails, it is restarted with exponential backoff, as in watchKind.
+func (store *firestoreBigSegmentStoreImpl) watchMetadata() {
+	backoff := watchInitialBackoff
+	for {
+		received, err := store.listenToMetadata()
+		store.watch.stop()
+		if store.context.Err() != nil {
+			return
+		}
+		if received {
+			backoff = watchInitialBackoff
+		}
+		store.loggers.Warnf("Snapshot listener for Big Segment metadata in collection %q failed; "+
+			"restarting in %s: %s", store.collection, backoff, err)
+		select {
+		case <-store.context.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, watchMaxBackoff)
+	}
+}
+
+// listenToMetadata runs a snapshot listener on the metadata document until it fails, and returns the
+// error along with whether any snapshot was received.
+func (store *firestoreBigSegmentStoreImpl) listenToMetadata() (bool, error) {
+	iter := store.metadataDocRef().Snapshots(store.context)
+	defer iter.Stop()
+
+	received := false
+	for {
+		doc, err := iter.Next()
+		if err != nil {
+			return received, err
+		}
+		received = true
+		if !doc.Exists() {
+			store.watch.set(subsystems.BigSegmentStoreMetadata{}, false)
+			continue
+		}
+		store.watch.set(metadataFromData(doc.Data()), true)
+	}
+}
@@ -0,0 +1,96 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldtime"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+func TestMetadataWatch(t *testing.T) {
+	t.Run("nil watch is never live", func(t *testing.T) {
+		var w *metadataWatch
+		_, _, live := w.get()
+		assert.False(t, live)
+	})
+
+	t.Run("holds the latest snapshot while live", func(t *testing.T) {
+		w := &metadataWatch{}
+		_, _, live := w.get()
+		assert.False(t, live)
+
+		w.set(subsystems.BigSegmentStoreMetadata{LastUpToDate: 1000}, true)
+		metadata, found, live := w.get()
+		assert.True(t, live)
+		assert.True(t, found)
+		assert.Equal(t, ldtime.UnixMillisecondTime(1000), metadata.LastUpToDate)
+
+		w.stop()
+		_, _, live = w.get()
+		assert.False(t, live)
+	})
+
+	t.Run("decodes metadata", func(t *testing.T) {
+		assert.Equal(t, ldtime.UnixMillisecondTime(1000),
+			metadataFromData(map[string]any{bigSegmentsSyncTimeAttr: int64(1000)}).LastUpToDate)
+		assert.Equal(t, subsystems.BigSegmentStoreMetadata{}, metadataFromData(map[string]any{}))
+	})
+}
+
+func TestBigSegmentStoreWatchMetadataFallsBackToReads(t *testing.T) {
+	built, err := BigSegmentStore(testProjectID, testCollectionName).
+		ClientOptions(option.WithEndpoint("localhost:1"), option.WithoutAuthentication()).
+		Retry(RetryPolicy{}).
+		OperationTimeout(200 * time.Millisecond).
+		WatchMetadata(true).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = built.Close() }()
+
+	var calls []BigSegmentCallInfo
+	store := built.(*firestoreBigSegmentStoreImpl)
+	store.hook = func(info BigSegmentCallInfo) { calls = append(calls, info) }
+
+	_, err = store.GetMetadata()
+	assert.Error(t, err)
+	require.Len(t, calls, 1)
+	assert.False(t, calls[0].Cached)
+}
+
+func TestBigSegmentStoreWatchMetadata(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	var calls []BigSegmentCallInfo
+	built, err := baseBigSegmentStoreBuilder().
+		WatchMetadata(true).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = built.Close() }()
+	store := built.(*firestoreBigSegmentStoreImpl)
+	store.hook = func(info BigSegmentCallInfo) { calls = append(calls, info) }
+
+	require.NoError(t, store.SetMetadata(ldtime.UnixMillisecondTime(1234)))
+	require.Eventually(t, func() bool {
+		metadata, found, live := store.watch.get()
+		return live && found && metadata.LastUpToDate == 1234
+	}, 5*time.Second, 10*time.Millisecond)
+
+	metadata, err := store.GetMetadata()
+	require.NoError(t, err)
+	assert.Equal(t, ldtime.UnixMillisecondTime(1234), metadata.LastUpToDate)
+	require.NotEmpty(t, calls)
+	assert.True(t, calls[len(calls)-1].Cached)
+
+	require.NoError(t, store.SetMetadata(ldtime.UnixMillisecondTime(5678)))
+	assert.Eventually(t, func() bool {
+		metadata, err := store.GetMetadata()
+		return err == nil && metadata.LastUpToDate == 5678
+	}, 5*time.Second, 10*time.Millisecond)
+}
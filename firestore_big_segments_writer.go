@@ -0,0 +1,321 @@
+package ldfirestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-sdk-common/v3/ldtime"
+)
+
+// membershipPatch is the change that ApplyMembershipPatch makes to the membership of a context.
+type membershipPatch struct {
+	addIncluded, removeIncluded, addExcluded, removeExcluded []string
+}
+
+func (store *firestoreBigSegmentStoreImpl) ApplyMembershipPatch(
+	contextHashKey string,
+	addIncluded, removeIncluded, addExcluded, removeExcluded []string,
+) error {
+	start := time.Now()
+	contextHash := redactContextHash(contextHashKey)
+	op, err := store.telemetry.start(string(BigSegmentApplyMembershipPatch), nil, contextHash,
+		attrContextHash.String(contextHash))
+	var found bool
+	if err == nil {
+		err = store.panics.protect(store.loggers, string(BigSegmentApplyMembershipPatch), func() (err error) {
+			if contextHashKey == "" {
+				return errors.New("context hash key is required")
+			}
+			found, err = store.applyMembershipPatch(contextHashKey, membershipPatch{
+				addIncluded:    addIncluded,
+				removeIncluded: removeIncluded,
+				addExcluded:    addExcluded,
+				removeExcluded: removeExcluded,
+			})
+			return err
+		})
+		// Even a failed patch may have been committed, so the cached membership can't be trusted
+		store.cache.remove(contextHashKey)
+		if err != nil {
+			err = newStoreError(string(BigSegmentApplyMembershipPatch), nil, contextHash, err)
+		}
+	}
+	op.end(foundCount(found), err)
+	store.reportCall(BigSegmentCallInfo{
+		Operation:   BigSegmentApplyMembershipPatch,
+		ContextHash: contextHash,
+		Found:       found,
+		Err:         err,
+	}, start)
+	return err
+}
+
+// applyMembershipPatch applies a patch in a transaction, and returns whether the context's membership
+// document existed beforehand.
+func (store *firestoreBigSegmentStoreImpl) applyMembershipPatch(
+	contextHashKey string,
+	patch membershipPatch,
+) (bool, error) {
+	mainRef := store.membershipChunkRef(contextHashKey, 0)
+	var found bool
+
+	err := store.retry.do(store.context, nil, func() error {
+		ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+		defer cancel()
+		return store.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+			docs, err := tx.GetAll([]*firestore.DocumentRef{mainRef})
+			if err != nil {
+				return err
+			}
+			found = docs[0].Exists()
+			chunks := 1
+			if found {
+				if chunks, err = membershipChunkCount(docs[0].Data()); err != nil {
+					return err
+				}
+			}
+			if chunks > 1 {
+				refs := make([]*firestore.DocumentRef, 0, chunks-1)
+				for i := 1; i < chunks; i++ {
+					refs = append(refs, store.membershipChunkRef(contextHashKey, i))
+				}
+				more, err := tx.GetAll(refs)
+				if err != nil {
+					return err
+				}
+				docs = append(docs, more...)
+			}
+
+			// All of the reads in a transaction must come before its writes
+			current := make([]membershipChunk, len(docs))
+			rewrite := make([]bool, len(docs))
+			for i, doc := range docs {
+				if current[i], rewrite[i], err = readPatchedChunk(doc, i, chunks); err != nil {
+					return err
+				}
+			}
+			return store.writeMembershipPatch(tx, docs, contextHashKey, current, rewrite, patch)
+		})
+	})
+	return found, err
+}
+
+// readPatchedChunk returns the references in a chunk of a membership that is being patched, and
+// whether the chunk must be rewritten because it still uses the array encoding.
+func readPatchedChunk(doc *firestore.DocumentSnapshot, index, chunks int) (membershipChunk, bool, error) {
+	var chunk membershipChunk
+	if !doc.Exists() {
+		if index > 0 {
+			return chunk, false, fmt.Errorf("chunk %d of %d of the membership is missing", index, chunks)
+		}
+		return chunk, false, nil
+	}
+	data := doc.Data()
+	var err error
+	if chunk.included, err = getStringSliceFromInterface(data, bigSegmentsIncludedAttr); err != nil {
+		return chunk, false, err
+	}
+	if chunk.excluded, err = getStringSliceFromInterface(data, bigSegmentsExcludedAttr); err != nil {
+		return chunk, false, err
+	}
+	_, includedArray := data[bigSegmentsIncludedAttr].([]any)
+	_, excludedArray := data[bigSegmentsExcludedAttr].([]any)
+	return chunk, includedArray || excludedArray, nil
+}
+
+// writeMembershipPatch writes the chunks of a membership within a transaction, after applying a patch
+// to them. Only the chunks that change, or still use the array encoding, are written. If the first
+// chunk, which gains the added references, would become too large, the whole membership is split into
+// chunks again. A membership that becomes empty, and is not split into chunks, is deleted.
+func (store *firestoreBigSegmentStoreImpl) writeMembershipPatch(
+	tx *firestore.Transaction,
+	docs []*firestore.DocumentSnapshot,
+	contextHashKey string,
+	current []membershipChunk,
+	rewrite []bool,
+	patch membershipPatch,
+) error {
+	patched, changed := patchMembershipChunks(current, patch)
+	if estimateDocSize(store.membershipChunkFields(contextHashKey, patched[0], 0, len(patched))) >
+		membershipChunkMaxBytes {
+		return store.resplitMembership(tx, contextHashKey, patched)
+	}
+
+	for i, doc := range docs {
+		if !rewrite[i] && !changed[i] {
+			continue
+		}
+		if len(docs) == 1 && patched[0].empty() {
+			if doc.Exists() {
+				if err := tx.Delete(doc.Ref); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := tx.Set(doc.Ref, store.membershipChunkFields(contextHashKey, patched[i], i, len(docs))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchMembershipChunks applies a patch to the chunks of a membership, and returns the patched chunks
+// and whether each one changed. Each chunk loses the removed references, and the first chunk gains the
+// added ones, except for those that are already in any of the chunks.
+func patchMembershipChunks(chunks []membershipChunk, patch membershipPatch) ([]membershipChunk, []bool) {
+	existingIncluded := make(map[string]bool)
+	existingExcluded := make(map[string]bool)
+	for _, chunk := range chunks {
+		for _, ref := range chunk.included {
+			existingIncluded[ref] = true
+		}
+		for _, ref := range chunk.excluded {
+			existingExcluded[ref] = true
+		}
+	}
+	newRefs := func(refs []string, existing map[string]bool) []string {
+		var result []string
+		for _, ref := range refs {
+			if !existing[ref] {
+				result = append(result, ref)
+			}
+		}
+		return result
+	}
+
+	patched := make([]membershipChunk, len(chunks))
+	changed := make([]bool, len(chunks))
+	for i, chunk := range chunks {
+		var addIncluded, addExcluded []string
+		if i == 0 {
+			addIncluded = newRefs(patch.addIncluded, existingIncluded)
+			addExcluded = newRefs(patch.addExcluded, existingExcluded)
+		}
+		var includedChanged, excludedChanged bool
+		patched[i].included, includedChanged = patchSegmentRefs(chunk.included, patch.removeIncluded, addIncluded)
+		patched[i].excluded, excludedChanged = patchSegmentRefs(chunk.excluded, patch.removeExcluded, addExcluded)
+		changed[i] = includedChanged || excludedChanged
+	}
+	return patched, changed
+}
+
+// resplitMembership replaces the chunks of a membership with a new set of chunks that holds the same
+// references, within a transaction. Chunks beyond the new number of chunks are deleted.
+func (store *firestoreBigSegmentStoreImpl) resplitMembership(
+	tx *firestore.Transaction,
+	contextHashKey string,
+	chunks []membershipChunk,
+) error {
+	var included, excluded []string
+	for _, chunk := range chunks {
+		included = append(included, chunk.included...)
+		excluded = append(excluded, chunk.excluded...)
+	}
+	split := splitMembership(included, excluded)
+	for i, chunk := range split {
+		fields := store.membershipChunkFields(contextHashKey, chunk, i, len(split))
+		if err := tx.Set(store.membershipChunkRef(contextHashKey, i), fields); err != nil {
+			return err
+		}
+	}
+	for i := len(split); i < len(chunks); i++ {
+		if err := tx.Delete(store.membershipChunkRef(contextHashKey, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// membershipChunkFields returns the fields of the document for a chunk of a membership.
+func (store *firestoreBigSegmentStoreImpl) membershipChunkFields(
+	contextHashKey string,
+	chunk membershipChunk,
+	index, chunks int,
+) map[string]any {
+	key := contextHashKey
+	if index > 0 {
+		key = membershipChunkKey(contextHashKey, index)
+	}
+	fields := map[string]any{
+		fieldNamespace: prefixedNamespace(store.prefix, bigSegmentsUserDataKey),
+		fieldKey:       key,
+	}
+	if len(chunk.included) > 0 {
+		fields[bigSegmentsIncludedAttr] = segmentRefMap(chunk.included)
+	}
+	if len(chunk.excluded) > 0 {
+		fields[bigSegmentsExcludedAttr] = segmentRefMap(chunk.excluded)
+	}
+	if index == 0 && chunks > 1 {
+		fields[bigSegmentsChunksAttr] = chunks
+	}
+	return fields
+}
+
+// patchSegmentRefs removes and then adds segment references, and returns the result and whether it
+// differs from the original references.
+func patchSegmentRefs(refs, remove, add []string) ([]string, bool) {
+	removed := make(map[string]bool, len(remove))
+	for _, ref := range remove {
+		removed[ref] = true
+	}
+	seen := make(map[string]bool, len(refs)+len(add))
+	result := make([]string, 0, len(refs)+len(add))
+	changed := false
+	for _, ref := range refs {
+		if removed[ref] {
+			changed = true
+		} else if !seen[ref] {
+			seen[ref] = true
+			result = append(result, ref)
+		}
+	}
+	for _, ref := range add {
+		if !seen[ref] && !removed[ref] {
+			seen[ref] = true
+			result = append(result, ref)
+			changed = true
+		}
+	}
+	return result, changed
+}
+
+// segmentRefMap returns the map encoding of segment references, which getStringSliceFromInterface
+// reads.
+func segmentRefMap(refs []string) map[string]any {
+	result := make(map[string]any, len(refs))
+	for _, ref := range refs {
+		result[ref] = true
+	}
+	return result
+}
+
+func (store *firestoreBigSegmentStoreImpl) SetMetadata(lastUpToDate ldtime.UnixMillisecondTime) error {
+	start := time.Now()
+	op, err := store.telemetry.start(string(BigSegmentSetMetadata), nil, "")
+	if err == nil {
+		err = store.panics.protect(store.loggers, string(BigSegmentSetMetadata), func() error {
+			docRef := store.metadataDocRef()
+			return store.retry.do(store.context, nil, func() error {
+				ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+				defer cancel()
+				_, err := docRef.Set(ctx, map[string]any{
+					fieldNamespace:          prefixedNamespace(store.prefix, bigSegmentsMetadataKey),
+					fieldKey:                bigSegmentsMetadataKey,
+					bigSegmentsSyncTimeAttr: int64(lastUpToDate),
+				})
+				return err
+			})
+		})
+		if err != nil {
+			err = newStoreError(string(BigSegmentSetMetadata), nil, "", err)
+		}
+	}
+	op.end(0, err)
+	store.reportCall(BigSegmentCallInfo{Operation: BigSegmentSetMetadata, Err: err}, start)
+	return err
+}
@@ -0,0 +1,183 @@
+package ldfirestore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldtime"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchSegmentRefs(t *testing.T) {
+	refs, changed := patchSegmentRefs([]string{"a", "b"}, []string{"b"}, []string{"c", "a"})
+	assert.Equal(t, []string{"a", "c"}, refs)
+	assert.True(t, changed)
+
+	refs, changed = patchSegmentRefs([]string{"a"}, []string{"x"}, []string{"a"})
+	assert.Equal(t, []string{"a"}, refs)
+	assert.False(t, changed)
+
+	refs, changed = patchSegmentRefs(nil, []string{"a"}, []string{"a"})
+	assert.Empty(t, refs)
+	assert.False(t, changed)
+}
+
+func TestPatchMembershipChunks(t *testing.T) {
+	chunks := []membershipChunk{
+		{included: []string{"seg1"}},
+		{included: []string{"seg2"}, excluded: []string{"seg3"}},
+	}
+	patched, changed := patchMembershipChunks(chunks, membershipPatch{
+		addIncluded:    []string{"seg2", "seg4"},
+		removeIncluded: []string{"seg1"},
+		addExcluded:    []string{"seg3"},
+	})
+	assert.Equal(t, []membershipChunk{
+		{included: []string{"seg4"}, excluded: []string{}},
+		{included: []string{"seg2"}, excluded: []string{"seg3"}},
+	}, patched)
+	assert.Equal(t, []bool{true, false}, changed)
+}
+
+func TestSplitMembership(t *testing.T) {
+	assert.Equal(t, []membershipChunk{{}}, splitMembership(nil, nil))
+	assert.Equal(t, []membershipChunk{{included: []string{"a"}, excluded: []string{"b"}}},
+		splitMembership([]string{"a"}, []string{"b"}))
+
+	ref := strings.Repeat("x", 1000)
+	var included []string
+	for i := 0; i < 2000; i++ {
+		included = append(included, fmt.Sprintf("%s%d", ref, i))
+	}
+	chunks := splitMembership(included, []string{"excluded"})
+	require.Len(t, chunks, 3)
+	var all []string
+	for _, chunk := range chunks {
+		size := 0
+		for _, ref := range append(chunk.included, chunk.excluded...) {
+			size += len(ref) + estimateValueSize(true)
+		}
+		assert.LessOrEqual(t, size, membershipChunkMaxBytes)
+		all = append(all, chunk.included...)
+	}
+	assert.Equal(t, included, all)
+	assert.Equal(t, []string{"excluded"}, chunks[2].excluded)
+}
+
+func TestBigSegmentStoreWriter(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	client, err := createTestClient()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	built, err := baseBigSegmentStoreBuilder().
+		MembershipCache(MembershipCacheOptions{TTL: time.Hour}).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = built.Close() }()
+	store := built.(ExtendedBigSegmentStore)
+
+	getDoc := func(key string) map[string]any {
+		doc, err := client.Collection(testCollectionName).Doc(makeTestDocID("", bigSegmentsUserDataKey, key)).
+			Get(context.Background())
+		if err != nil {
+			return nil
+		}
+		return doc.Data()
+	}
+
+	t.Run("adds and removes references", func(t *testing.T) {
+		require.NoError(t, store.ApplyMembershipPatch("context1", []string{"seg1", "seg2"}, nil, []string{"seg3"}, nil))
+		membership, err := store.GetMembership("context1")
+		require.NoError(t, err)
+		assert.Equal(t, ldvalue.NewOptionalBool(true), membership.CheckMembership("seg2"))
+
+		require.NoError(t, store.ApplyMembershipPatch("context1", nil, []string{"seg2"}, nil, nil))
+		membership, err = store.GetMembership("context1")
+		require.NoError(t, err)
+		assert.Equal(t, ldvalue.NewOptionalBool(true), membership.CheckMembership("seg1"))
+		assert.Equal(t, ldvalue.OptionalBool{}, membership.CheckMembership("seg2"))
+		assert.Equal(t, ldvalue.NewOptionalBool(false), membership.CheckMembership("seg3"))
+
+		data := getDoc("context1")
+		assert.Equal(t, map[string]any{"seg1": true}, data[bigSegmentsIncludedAttr])
+	})
+
+	t.Run("converts the array encoding", func(t *testing.T) {
+		_, err := client.Collection(testCollectionName).Doc(makeTestDocID("", bigSegmentsUserDataKey, "context2")).
+			Set(context.Background(), map[string]any{
+				fieldNamespace:          makeTestNamespace("", bigSegmentsUserDataKey),
+				fieldKey:                "context2",
+				bigSegmentsIncludedAttr: []string{"seg1"},
+			})
+		require.NoError(t, err)
+
+		require.NoError(t, store.ApplyMembershipPatch("context2", nil, nil, []string{"seg2"}, nil))
+		data := getDoc("context2")
+		assert.Equal(t, map[string]any{"seg1": true}, data[bigSegmentsIncludedAttr])
+		assert.Equal(t, map[string]any{"seg2": true}, data[bigSegmentsExcludedAttr])
+	})
+
+	t.Run("removes references from every chunk", func(t *testing.T) {
+		for i, fields := range []map[string]any{
+			{bigSegmentsChunksAttr: 2, bigSegmentsIncludedAttr: map[string]any{"seg1": true}},
+			{bigSegmentsIncludedAttr: map[string]any{"seg2": true, "seg3": true}},
+		} {
+			key := "context3"
+			if i > 0 {
+				key = membershipChunkKey(key, i)
+			}
+			fields[fieldNamespace] = makeTestNamespace("", bigSegmentsUserDataKey)
+			fields[fieldKey] = key
+			_, err := client.Collection(testCollectionName).Doc(makeTestDocID("", bigSegmentsUserDataKey, key)).
+				Set(context.Background(), fields)
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, store.ApplyMembershipPatch("context3", []string{"seg4"}, []string{"seg2"}, nil, nil))
+		assert.Equal(t, map[string]any{"seg1": true, "seg4": true}, getDoc("context3")[bigSegmentsIncludedAttr])
+		assert.Equal(t, map[string]any{"seg3": true},
+			getDoc(membershipChunkKey("context3", 1))[bigSegmentsIncludedAttr])
+	})
+
+	t.Run("splits a membership that becomes too large", func(t *testing.T) {
+		var refs []string
+		for i := 0; i < 1000; i++ {
+			refs = append(refs, fmt.Sprintf("%s%d", strings.Repeat("s", 1000), i))
+		}
+		require.NoError(t, store.ApplyMembershipPatch("context5", refs[:500], nil, nil, nil))
+		assert.Nil(t, getDoc("context5")[bigSegmentsChunksAttr])
+		require.NoError(t, store.ApplyMembershipPatch("context5", refs[500:], nil, nil, nil))
+		assert.Equal(t, int64(2), getDoc("context5")[bigSegmentsChunksAttr])
+
+		membership, err := store.GetMembership("context5")
+		require.NoError(t, err)
+		for _, ref := range refs {
+			assert.Equal(t, ldvalue.NewOptionalBool(true), membership.CheckMembership(ref))
+		}
+	})
+
+	t.Run("deletes an empty membership", func(t *testing.T) {
+		require.NoError(t, store.ApplyMembershipPatch("context4", []string{"seg1"}, nil, nil, nil))
+		require.NotNil(t, getDoc("context4"))
+		require.NoError(t, store.ApplyMembershipPatch("context4", nil, []string{"seg1"}, nil, nil))
+		assert.Nil(t, getDoc("context4"))
+	})
+
+	t.Run("sets metadata", func(t *testing.T) {
+		require.NoError(t, store.SetMetadata(ldtime.UnixMillisecondTime(1234)))
+		metadata, err := store.GetMetadata()
+		require.NoError(t, err)
+		assert.Equal(t, ldtime.UnixMillisecondTime(1234), metadata.LastUpToDate)
+	})
+}
@@ -1,9 +1,15 @@
 package ldfirestore
 
 import (
+	"context"
+	"time"
+
 	"cloud.google.com/go/firestore"
 	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
 	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/api/option"
 )
 
@@ -39,11 +45,81 @@ type StoreBuilder[T any] struct {
 }
 
 type builderOptions struct {
-	client         *firestore.Client
-	projectID      string
-	collection     string
-	prefix         string
-	clientOptions  []option.ClientOption
+	client                *firestore.Client
+	sharedClient          *SharedClient
+	projectID             string
+	databaseID            string
+	collection            string
+	parentDocument        string
+	prefix                string
+	clientOptions         []option.ClientOption
+	credentialsFile       string
+	credentialsJSON       []byte
+	kindLimits            map[string]kindSizeLimit
+	oversizedPolicy       OversizedItemPolicy
+	overflowStore         OverflowStore
+	kindRoutes            map[string]kindRoute
+	readRepair            bool
+	initRetry             InitRetryOptions
+	initFlushInterval     int
+	maxConcurrentOps      int
+	schedulingPolicy      SchedulingPolicy
+	coldStart             bool
+	operationTimeout      time.Duration
+	initTimeout           time.Duration
+	payloadFilter         string
+	readOnlyOnNewerSchema bool
+	readOnly              bool
+	preserveExisting      bool
+	connPoolSize          int
+	debugCategories       LogCategory
+	subcollections        bool
+	collectionGroup       bool
+	preflight             bool
+	labels                map[string]string
+	compression           PayloadCompression
+	itemBytes             bool
+	structured            bool
+	codec                 PayloadCodec
+	checksum              ChecksumAlgorithm
+	expireAfter           time.Duration
+	getAllConcurrency     int
+	getAllPageSize        int
+	atomicInit            bool
+	preconditionUpserts   bool
+	differentialInit      bool
+	initWriteRate         int
+	initConcurrency       int
+	onChange              func(ItemChange)
+	itemCache             ItemCacheOptions
+	snapshotFallback      SnapshotFallbackOptions
+	outageBuffer          OutageBufferOptions
+	consistentGetAll      bool
+	tracerProvider        trace.TracerProvider
+	meterProvider         metric.MeterProvider
+	operationHook         OperationHook
+	auditTrail            AuditTrailOptions
+	clientRecovery        ClientRecoveryOptions
+	namespaceSummaries    bool
+	namespaceManifests    bool
+	excludeDeleted        bool
+	bigSegmentsHook       func(BigSegmentCallInfo)
+	membershipCache       MembershipCacheOptions
+	watchMetadata         bool
+	ignoreEmulator        bool
+	privateEndpoint       *PrivateEndpointOptions
+	versionAnomalies      bool
+	sizeWarningFraction   float64
+	sizeWarningHook       func(ItemSizeWarning)
+	availabilityGrace     time.Duration
+	availabilityTimeout   time.Duration
+	retryPolicy           RetryPolicy
+	baseContext           context.Context
+	docIDs                docIDScheme
+	writerInstanceID      string
+	writerLeaseDuration   time.Duration
+	clock                 Clock
+	rpcLogSampleRate      float64
 }
 
 // DataStore returns a configurable builder for a Firestore-backed data store.
@@ -74,8 +150,9 @@ type builderOptions struct {
 func DataStore(projectID, collection string) *StoreBuilder[subsystems.PersistentDataStore] {
 	return &StoreBuilder[subsystems.PersistentDataStore]{
 		builderOptions: builderOptions{
-			projectID:  projectID,
-			collection: collection,
+			projectID:   projectID,
+			collection:  collection,
+			retryPolicy: defaultRetryPolicy(),
 		},
 		factory: createPersistentDataStore,
 	}
@@ -106,8 +183,9 @@ func DataStore(projectID, collection string) *StoreBuilder[subsystems.Persistent
 func BigSegmentStore(projectID, collection string) *StoreBuilder[subsystems.BigSegmentStore] {
 	return &StoreBuilder[subsystems.BigSegmentStore]{
 		builderOptions: builderOptions{
-			projectID:  projectID,
-			collection: collection,
+			projectID:   projectID,
+			collection:  collection,
+			retryPolicy: defaultRetryPolicy(),
 		},
 		factory: createBigSegmentStore,
 	}
@@ -135,6 +213,75 @@ func (b *StoreBuilder[T]) FirestoreClient(client *firestore.Client) *StoreBuilde
 	return b
 }
 
+// SharedClient specifies a [SharedClient] to use, so that the main data store and the Big Segment
+// store, or several data stores, can share one Firestore client. Each store keeps the client open
+// until the store is closed. Any configurations specified with ClientOptions, DatabaseID, or other
+// options that affect how the client is created are ignored. If FirestoreClient is also specified,
+// it takes precedence.
+func (b *StoreBuilder[T]) SharedClient(client *SharedClient) *StoreBuilder[T] {
+	b.sharedClient = client
+	return b
+}
+
+// Context specifies a context that the store's operations are derived from, instead of
+// context.Background(). When this context is canceled, any operations that are in progress are
+// canceled, and later operations fail immediately, so a server that shuts down gracefully can stop all
+// Firestore activity with the same context that it uses for everything else. Canceling the context
+// does not release the store's client; call Close for that.
+//
+// The default is nil, meaning that the store's operations are only canceled by Close.
+func (b *StoreBuilder[T]) Context(ctx context.Context) *StoreBuilder[T] {
+	b.baseContext = ctx
+	return b
+}
+
+// DatabaseID specifies the ID of a named Firestore database to use, instead of the project's default
+// database. The option has no effect if you provide your own client with FirestoreClient; in that
+// case, create the client with firestore.NewClientWithDatabase instead.
+//
+//	ldfirestore.DataStore("my-project", "launchdarkly").DatabaseID("flags-db")
+//
+// The default is an empty string, meaning the "(default)" database.
+func (b *StoreBuilder[T]) DatabaseID(databaseID string) *StoreBuilder[T] {
+	b.databaseID = databaseID
+	return b
+}
+
+// DocumentIDFormat specifies how document IDs are built from the store's prefix, the namespace of a
+// data kind, and an item's key. By default, they are joined with colons and not escaped, so a prefix
+// or key that contains a colon can produce the same ID as a different item. To rule that out, use
+// [DocumentIDEscapingPercent]:
+//
+//	ldfirestore.DataStore("my-project", "launchdarkly").
+//		DocumentIDFormat(":", ldfirestore.DocumentIDEscapingPercent)
+//
+// An empty separator means ":". The separator cannot contain "/", and with percent escaping, it cannot
+// contain "%" or a hexadecimal digit.
+//
+// Changing this for a store that already has data means that the SDK no longer finds the existing
+// documents when it reads a single item. Use [ExtendedDataStore.MigrateDocumentIDs] to move them.
+// For a Big Segment store, the simplest way is to purge the data (see [ExtendedBigSegmentStore]) and
+// let it be synchronized again.
+func (b *StoreBuilder[T]) DocumentIDFormat(separator string, escaping DocumentIDEscaping) *StoreBuilder[T] {
+	b.docIDs.separator = separator
+	b.docIDs.escaping = escaping
+	return b
+}
+
+// HashedDocumentIDs specifies whether the ID of each document is the SHA-256 hash of the ID that it
+// would otherwise have (see [StoreBuilder.DocumentIDFormat]), rather than that ID itself. Firestore
+// document IDs cannot be longer than 1500 bytes or contain "/", which can rule out some flag keys or
+// prefixes; hashed IDs are always 64 hexadecimal characters. The key of each item is still stored in
+// the document, so reading all items of a kind is unaffected.
+//
+// Changing this for a store that already has data means that the SDK no longer finds the existing
+// documents when it reads a single item. Use [ExtendedDataStore.MigrateDocumentIDs] to move them.
+// The default is false. This option only affects the main data store.
+func (b *StoreBuilder[T]) HashedDocumentIDs(enabled bool) *StoreBuilder[T] {
+	b.docIDs.hashed = enabled
+	return b
+}
+
 // ClientOptions specifies custom parameters for the firestore.NewClient client constructor. This can be used
 // to set properties such as credentials programmatically, rather than relying on the defaults from the environment.
 func (b *StoreBuilder[T]) ClientOptions(options ...option.ClientOption) *StoreBuilder[T] {
@@ -142,6 +289,990 @@ func (b *StoreBuilder[T]) ClientOptions(options ...option.ClientOption) *StoreBu
 	return b
 }
 
+// ConnectionPoolSize specifies the number of gRPC connections that the Firestore client opens. Each
+// connection supports a limited number of concurrent requests, so under a high rate of reads, such as
+// in an application that uses the SDK in daemon mode with a short cache TTL or none, a single
+// connection can become a bottleneck. Requests are spread over the connections in the pool.
+//
+//	ldfirestore.DataStore("my-project", "launchdarkly").ConnectionPoolSize(4)
+//
+// The size must be between 1 and [MaxConnectionPoolSize]; otherwise, building the store fails. The
+// option has no effect if you provide your own client with FirestoreClient. The default is zero,
+// meaning the Firestore client's own default, which is currently a single connection.
+func (b *StoreBuilder[T]) ConnectionPoolSize(size int) *StoreBuilder[T] {
+	b.connPoolSize = size
+	return b
+}
+
+// CredentialsFile specifies the path of a service account key file to authenticate with, instead of
+// the Application Default Credentials from the environment. This is a shortcut for passing
+// option.WithAuthCredentialsFile to [StoreBuilder.ClientOptions].
+//
+//	ldfirestore.DataStore("my-project", "launchdarkly").CredentialsFile("/etc/secrets/sa-key.json")
+//
+// Only one of CredentialsFile and CredentialsJSON can be specified, and neither can be used together
+// with [StoreBuilder.FirestoreClient]; otherwise, building the store fails.
+func (b *StoreBuilder[T]) CredentialsFile(path string) *StoreBuilder[T] {
+	b.credentialsFile = path
+	return b
+}
+
+// CredentialsJSON specifies the contents of a service account key to authenticate with, instead of
+// the Application Default Credentials from the environment. This is useful if the key comes from a
+// secret manager rather than a file. It is a shortcut for passing option.WithAuthCredentialsJSON to
+// [StoreBuilder.ClientOptions].
+//
+// Only one of CredentialsFile and CredentialsJSON can be specified, and neither can be used together
+// with [StoreBuilder.FirestoreClient]; otherwise, building the store fails.
+func (b *StoreBuilder[T]) CredentialsJSON(json []byte) *StoreBuilder[T] {
+	b.credentialsJSON = json
+	return b
+}
+
+// OversizedItems specifies what to do with an item that is too large to store in a Firestore
+// document, for every data kind that does not have its own policy from [StoreBuilder.KindSizeLimit].
+// The default is [OversizedItemDrop], which only logs an error, so a flag that is too large is
+// missing from the store; use [OversizedItemError] to make Init and Upsert fail instead, or
+// [OversizedItemOverflow] with [StoreBuilder.OverflowStore] to store the item elsewhere. This option
+// only affects the main data store.
+func (b *StoreBuilder[T]) OversizedItems(policy OversizedItemPolicy) *StoreBuilder[T] {
+	b.oversizedPolicy = policy
+	return b
+}
+
+// OverflowStore specifies where to save items that are too large to store in a Firestore document,
+// for the [OversizedItemOverflow] policy. Building the store fails if that policy is used without an
+// OverflowStore. The data source created by [DataSource] also needs it, to read such items.
+func (b *StoreBuilder[T]) OverflowStore(overflow OverflowStore) *StoreBuilder[T] {
+	b.overflowStore = overflow
+	return b
+}
+
+// KindSizeLimit specifies the maximum document size, in bytes, for items of the given data kind, and
+// what to do with an item that exceeds it. This option only affects the main data store.
+//
+// By default, every kind uses a conservative limit of about 900 KB (Firestore's actual limit is
+// 1 MiB) and the policy from [StoreBuilder.OversizedItems]. A maxBytes value of zero or less keeps the default
+// limit while changing only the policy. For instance, to make Init and Upsert fail rather than
+// silently dropping a segment:
+//
+//	ldfirestore.DataStore("my-project", "launchdarkly").
+//		KindSizeLimit(ldstoreimpl.Segments(), 0, ldfirestore.OversizedItemError)
+func (b *StoreBuilder[T]) KindSizeLimit(
+	kind ldstoretypes.DataKind,
+	maxBytes int,
+	policy OversizedItemPolicy,
+) *StoreBuilder[T] {
+	if b.kindLimits == nil {
+		b.kindLimits = make(map[string]kindSizeLimit)
+	}
+	b.kindLimits[kind.GetName()] = kindSizeLimit{maxBytes: maxBytes, policy: policy}
+	return b
+}
+
+// ParentDocument specifies the path of a document under which the store's collection is created as a
+// subcollection, instead of being a top-level collection. This allows LaunchDarkly data to be nested
+// inside an existing Firestore hierarchy, where it is governed by the security rules for that part of
+// the hierarchy. The path must have an even number of segments, such as "apps/myapp". Collections
+// specified with [StoreBuilder.KindRoute] are also created under this document.
+//
+//	ldfirestore.DataStore("my-project", "launchdarkly").ParentDocument("apps/myapp")
+//
+// The path can be a template containing the placeholder "{prefix}", which is replaced with the
+// store's prefix (or, for a kind with its own prefix from KindRoute, with that prefix). This allows
+// multi-tenant deployments to keep each tenant's data under the tenant's own document, such as
+// "tenants/{prefix}" with a prefix for each tenant. A template requires a non-empty prefix. See also
+// [StoreBuilder.CollectionGroupQueries].
+//
+// The default is an empty string, meaning that the collection is at the top level of the database.
+func (b *StoreBuilder[T]) ParentDocument(path string) *StoreBuilder[T] {
+	b.parentDocument = path
+	return b
+}
+
+// ItemSizeWarning configures the store to warn when an item's document reaches the given fraction of
+// the size limit for its data kind (see [StoreBuilder.KindSizeLimit]), so that flags or segments that
+// are growing toward the limit can be noticed before they are dropped. For instance, 0.8 warns about
+// any item that is at least 80% of the limit. The warning is logged, and hook (if not nil) is called
+// with the details. Each item is only warned about once, unless it shrinks below the threshold and
+// then grows past it again.
+//
+// Regardless of this option, the store keeps a histogram of the document sizes that it writes; see
+// [ExtendedDataStore.ItemSizeHistogram]. This option only affects the main data store. The default
+// is zero, meaning that there are no warnings.
+func (b *StoreBuilder[T]) ItemSizeWarning(fraction float64, hook func(ItemSizeWarning)) *StoreBuilder[T] {
+	b.sizeWarningFraction = fraction
+	b.sizeWarningHook = hook
+	return b
+}
+
+// CollectionGroupQueries specifies whether the store uses Firestore collection group queries to find
+// all the items of a data kind, in GetAll and when Init looks for obsolete items. A collection group
+// query finds documents in every collection with the same name, regardless of their parent document,
+// so this is useful when the data is nested under per-tenant parent documents with a
+// [StoreBuilder.ParentDocument] template: the items are still identified by their namespace, which
+// includes the tenant's prefix. Reading and writing single items is not affected.
+//
+// Collection group queries require a collection group index on the namespace field, which Firestore
+// does not create automatically; the error from the first query includes a link for creating it.
+// Since Init deletes obsolete items that it finds anywhere in the collection group, every tenant must
+// have a different prefix. The default is false. This option only affects the main data store.
+func (b *StoreBuilder[T]) CollectionGroupQueries(enabled bool) *StoreBuilder[T] {
+	b.collectionGroup = enabled
+	return b
+}
+
+// SubcollectionPerKind specifies whether each data kind is stored in its own subcollection, instead of
+// all items being stored directly in the store's collection. With this layout, the items of each kind
+// are in {collection}/{namespace}/items/{key}, where the namespace is the kind's name with the prefix,
+// such as "launchdarkly/features/items/my-flag". This makes it easy to write security rules for, or
+// attribute the cost of, each kind separately. The document that records whether the store has been
+// initialized is still stored directly in the collection.
+//
+// The two layouts are not compatible: a store with this option does not see data that was written
+// without it, and vice versa. The ldfirestore-admin tool only supports the default layout. The
+// default is false. This option only affects the main data store.
+func (b *StoreBuilder[T]) SubcollectionPerKind(enabled bool) *StoreBuilder[T] {
+	b.subcollections = enabled
+	return b
+}
+
+// KindRoute specifies a different collection and/or key prefix to use for items of the given data
+// kind, instead of the ones that were specified for the store as a whole. An empty string for either
+// parameter means the store's own setting is used for that kind. The collection must already exist
+// in Firestore. This option only affects the main data store.
+//
+// The data store handles any kind of data that the SDK asks it to store, not only flags and
+// segments. Together with [StoreBuilder.KindSizeLimit], this option allows each kind to be configured
+// separately, including kinds that may be added in future versions of the SDK. The special document
+// that records whether the store has been initialized always uses the store's own collection and
+// prefix.
+func (b *StoreBuilder[T]) KindRoute(kind ldstoretypes.DataKind, collection, prefix string) *StoreBuilder[T] {
+	if b.kindRoutes == nil {
+		b.kindRoutes = make(map[string]kindRoute)
+	}
+	b.kindRoutes[kind.GetName()] = kindRoute{collection: collection, prefix: prefix}
+	return b
+}
+
+// DocumentLabels specifies extra fields, such as cost-attribution labels, that are added to every
+// document that Init and Upsert write, including the document that records whether the store has
+// been initialized. The store ignores these fields when reading. A label cannot have the same name as
+// one of the fields that the store uses, such as "key" or "version"; otherwise, building the store
+// fails.
+//
+//	ldfirestore.DataStore("my-project", "launchdarkly").
+//		DocumentLabels(map[string]string{"team": "platform", "cost-center": "1234"})
+//
+// Labels count toward the document size limit (see [StoreBuilder.KindSizeLimit]). This option only
+// affects the main data store.
+func (b *StoreBuilder[T]) DocumentLabels(labels map[string]string) *StoreBuilder[T] {
+	b.labels = copyLabels(labels)
+	return b
+}
+
+// Compression specifies whether the serialized flag or segment in each document that Init and Upsert
+// write is compressed. Large flags with many targeting rules typically compress to between a tenth
+// and a fifth of their size, which reduces storage and network costs and allows much larger items to
+// fit within the document size limit (see [StoreBuilder.KindSizeLimit]).
+//
+// Each compressed document has an "encoding" field that records how it was compressed, and documents
+// are always read according to that field, so existing documents remain readable when this option is
+// added or changed, and instances with different settings can share a collection. Compressed items
+// cannot be read by versions of this library that do not support compression.
+//
+// The default is [CompressionNone]. This option only affects the main data store.
+func (b *StoreBuilder[T]) Compression(compression PayloadCompression) *StoreBuilder[T] {
+	b.compression = compression
+	return b
+}
+
+// StoreItemsAsBytes specifies whether the serialized flag or segment in each document that Init and
+// Upsert write is stored as a Firestore bytes value rather than a string. Firestore does not index
+// bytes values longer than 1500 bytes, so this avoids the cost of updating an index entry for every
+// large item that is written, which has no use since the store never queries by item content.
+//
+// Documents written with either setting can be read regardless of this option, so it can be changed
+// without rewriting existing data. However, items stored as bytes cannot be read by versions of this
+// library that do not support this option. Items compressed with [StoreBuilder.Compression] are
+// always stored as bytes.
+//
+// The default is false. This option only affects the main data store.
+func (b *StoreBuilder[T]) StoreItemsAsBytes(enabled bool) *StoreBuilder[T] {
+	b.itemBytes = enabled
+	return b
+}
+
+// StructuredItems specifies whether the serialized flag or segment in each document that Init and
+// Upsert write is stored as a Firestore map, rather than as a JSON string. This makes the properties
+// of each item visible in the Firestore console, and allows them to be used in queries such as
+// item.on == true. The store itself still reads each item as a whole.
+//
+// Items that cannot be represented exactly as Firestore values, such as a flag with a variation whose
+// value is an array that contains another array, are stored as strings instead. Firestore indexes
+// every property of a map by default, so large items can make writes slower and more expensive, or
+// exceed Firestore's limit on index entries per document; consider adding a single-field index
+// exemption for the "item" field. Maps also take more space than the equivalent JSON.
+//
+// Documents written with either setting can be read regardless of this option, but items stored as
+// maps cannot be read by versions of this library that do not support this option. This option
+// cannot be combined with [StoreBuilder.Compression] or [StoreBuilder.StoreItemsAsBytes]. The
+// default is false. This option only affects the main data store.
+func (b *StoreBuilder[T]) StructuredItems(enabled bool) *StoreBuilder[T] {
+	b.structured = enabled
+	return b
+}
+
+// PayloadCodec specifies a custom encoding, such as encryption, for the serialized flag or segment in
+// each document that Init and Upsert write. The encoded item is stored as bytes, and the codec's
+// format name is stored in the document's "encoding" field.
+//
+// The store can read documents that were written without the codec, or with one of the built-in
+// encodings, so the codec can be added to an existing collection without rewriting it. Documents
+// written with the codec can only be read by stores that are configured with the same codec; for
+// other stores, including the ldfirestore-admin tool, they are treated as if they did not exist.
+// This option cannot be combined with [StoreBuilder.Compression] or [StoreBuilder.StructuredItems];
+// a codec that compresses can use any compression library. This option only affects the main data
+// store.
+func (b *StoreBuilder[T]) PayloadCodec(codec PayloadCodec) *StoreBuilder[T] {
+	b.codec = codec
+	return b
+}
+
+// Checksum specifies that a checksum of the serialized flag or segment is written in each document that
+// Init and Upsert write. When the store reads a document that has a checksum, it verifies the item
+// against it, regardless of this option; if they do not match, because the document was written
+// partially or edited by hand, the store logs an error and treats the item as if it did not exist,
+// rather than passing it to the SDK.
+//
+// The default is [ChecksumNone]. This option only affects the main data store.
+func (b *StoreBuilder[T]) Checksum(algorithm ChecksumAlgorithm) *StoreBuilder[T] {
+	b.checksum = algorithm
+	return b
+}
+
+// ExpireAfter specifies that every document that the data store writes has an "expireAt" field, set to
+// the time of the write plus the given duration. If a Firestore TTL policy is configured for this
+// field, Firestore deletes the documents some time after they expire, so that the data for short-lived
+// environments, such as test or preview environments, is removed without a manual purge.
+//
+// Documents are only rewritten when Init or Upsert writes them, so the data for an environment whose
+// flags rarely change may expire while it is still in use. To prevent this, call
+// [ExtendedDataStore.TouchAll] periodically, which also extends the expiration time, or choose a
+// duration longer than the lifetime of the environment.
+//
+// The default is zero, which means that no expiration time is written. This option only affects the
+// main data store.
+func (b *StoreBuilder[T]) ExpireAfter(d time.Duration) *StoreBuilder[T] {
+	b.expireAfter = d
+	return b
+}
+
+// PayloadFilter specifies the payload filter key that the SDK is configured with, if any. This must be
+// the same value that is passed to the PayloadFilter method of the SDK's data source builder.
+//
+// The data store records this value whenever it is initialized. If a store configured with a
+// different value (or none) finds that value, it logs an error and reports that it is not initialized,
+// rather than silently serving a different set of flags than the application expects. This is
+// especially useful when one application writes the data and others only read it.
+//
+// This option only affects the main data store.
+func (b *StoreBuilder[T]) PayloadFilter(filterKey string) *StoreBuilder[T] {
+	b.payloadFilter = filterKey
+	return b
+}
+
+// PreserveExistingItems specifies whether Init leaves in place documents that are not in the data it
+// is given. Normally, Init deletes every document in the namespace of each data kind that is not in
+// the new data, so that flags and segments that were deleted in LaunchDarkly do not linger. With this
+// option, Init only writes the items it was given and never deletes anything, which is safer when the
+// collection is shared with other data or contains items that were added by other means. It also
+// makes Init faster, since the existing documents do not need to be read first.
+//
+// The downside is that items that were deleted in LaunchDarkly while the SDK was not connected stay in
+// the store until they are removed in some other way, for instance with [ExtendedDataStore.Delete].
+// With [StoreBuilder.NamespaceSummaries], the item counts only include the items written by Init.
+//
+// The default is false. This option only affects the main data store.
+func (b *StoreBuilder[T]) PreserveExistingItems(enabled bool) *StoreBuilder[T] {
+	b.preserveExisting = enabled
+	return b
+}
+
+// ReadOnly makes the data store read-only, for applications that use the SDK in daemon mode, where
+// another process such as the Relay Proxy writes the data. Init, Upsert, and the administrative
+// operations of [ExtendedDataStore] return [ErrReadOnly] without contacting Firestore, and the store
+// never writes anything else, such as the lease for [StoreBuilder.WriterElection]. This guarantees
+// that the application's service account only needs permission to read from Firestore.
+//
+// In daemon mode, the SDK should also be configured with ldcomponents.ExternalUpdatesOnly(), so that
+// it does not try to write to the store:
+//
+//	config.DataSource = ldcomponents.ExternalUpdatesOnly()
+//	config.DataStore = ldcomponents.PersistentDataStore(
+//		ldfirestore.DataStore("my-project", "launchdarkly").ReadOnly(true))
+//
+// The default is false. This option only affects the main data store; the Big Segment store is always
+// read-only.
+func (b *StoreBuilder[T]) ReadOnly(enabled bool) *StoreBuilder[T] {
+	b.readOnly = enabled
+	return b
+}
+
+// ReadOnlyOnNewerSchema specifies what the data store does if it reads a document that was written by
+// a newer version of this package with a newer document format. The store always logs a warning the
+// first time this happens. If this option is true, it also stops writing: Init, Upsert, and the
+// administrative operations of [ExtendedDataStore] return [ErrReadOnly] from then on, so that an
+// older application cannot downgrade or corrupt data written by newer ones.
+//
+// The default is false. This option only affects the main data store.
+func (b *StoreBuilder[T]) ReadOnlyOnNewerSchema(enabled bool) *StoreBuilder[T] {
+	b.readOnlyOnNewerSchema = enabled
+	return b
+}
+
+// WriterElection enables writer election, for deployments where many application instances share the
+// same collection and would otherwise all write the same data. The instances compete for a lease
+// that is stored in a document in the collection, and only the instance holding the lease performs
+// Init, Upsert, and the Delete and Touch operations of [ExtendedDataStore]. This reduces write costs
+// and transaction contention, at the expense of a delay of up to leaseDuration before another instance
+// takes over if the writer stops without closing its store.
+//
+// On the other instances, Init and Upsert skip the write but report success, as if it had been made:
+// Init returns nil, and Upsert returns true (in [UpsertResult], Updated is true and PreviousVersion is
+// -1, since the stored item was not read). The SDK then caches the data it passed to the store, just
+// as it would after a write, rather than rereading an item that the writer may not have updated yet.
+// Delete and Touch, which the SDK does not use, skip the write and return false.
+//
+// The lease is renewed every third of leaseDuration. The instanceID identifies this instance in the
+// lease document and in log messages; if it is empty, a random ID is used.
+//
+// Writes are not fenced by the lease: an instance decides whether it is the writer from the lease
+// expiry it last saw and its own clock, and does not check the lease document when it writes. So if
+// the writer's clock is skewed, or its process is paused for longer than the rest of its lease, it
+// can keep writing for a while after another instance has taken over, and both write at once. Upserts
+// are still applied in version order, so this costs extra writes rather than losing updates, but an
+// Init from the old writer can replace newer data until the next Init.
+//
+// The default is that writer election is disabled. This option only affects the main data store.
+func (b *StoreBuilder[T]) WriterElection(instanceID string, leaseDuration time.Duration) *StoreBuilder[T] {
+	b.writerInstanceID = instanceID
+	b.writerLeaseDuration = leaseDuration
+	return b
+}
+
+// Clock specifies the source of the current time for timestamps that the data store writes or
+// compares, such as the updatedAt field written by [ExtendedDataStore.Touch] and the expiry times
+// of writer leases. This can be used to control time in tests, or to correct for an environment whose
+// system clock is known to be skewed. If it is nil, the system clock is used, which is the default.
+// If the clock also implements [TimerClock], it controls the delays between background retries of
+// Init writes as well.
+//
+// This option only affects the main data store.
+func (b *StoreBuilder[T]) Clock(clock Clock) *StoreBuilder[T] {
+	b.clock = clock
+	return b
+}
+
+// ReadRepair specifies whether the data store should try to recover when a stored document cannot be
+// decoded. If enabled, the store re-reads the document directly from Firestore and then tries more
+// lenient decodings (for instance, accepting a version that was stored as a string) before treating
+// the item as invalid. Each attempt is logged at WARN level, and each failure at ERROR level. The
+// attempts and their outcomes are also counted in [Stats.ReadRepairs], for alerting on the rate of
+// corruption.
+//
+// The default is false. This option only affects the main data store.
+func (b *StoreBuilder[T]) ReadRepair(enabled bool) *StoreBuilder[T] {
+	b.readRepair = enabled
+	return b
+}
+
+// InitRetry enables background retrying of document writes that fail with a transient error during
+// Init. Instead of failing the whole Init, the store retries just the failed writes with exponential
+// backoff, and Init returns successfully. Unless [InitRetryOptions.MarkInitializedEarly] is set, the
+// store is not marked as initialized until every retried write has succeeded; if any of them fails
+// with an error that is not transient, or still fails after the last attempt, it stays uninitialized.
+// When the retries are over, [ExtendedDataStore.LastInitReport] is updated: the writes that succeeded
+// are counted, and the ones that did not remain in Failures with Retrying set to false. A later Init
+// cancels any retries that are still pending.
+//
+// By default, retrying is disabled and any failed write causes Init to return an [InitError]. This
+// option only affects the main data store.
+func (b *StoreBuilder[T]) InitRetry(options InitRetryOptions) *StoreBuilder[T] {
+	b.initRetry = options
+	return b
+}
+
+// AtomicInit makes Init write its changes in a series of commits that are each applied entirely or
+// not at all, in a fixed order: first every item, then the deletions of obsolete items, and finally
+// the document that marks the store as initialized. If a commit fails, even after any retries (see
+// [StoreBuilder.Retry]), Init stops, so obsolete items are not deleted until every new item has been
+// written, and the store is not marked as initialized with an incomplete data set.
+//
+// Init as a whole is not atomic, and nothing is rolled back. Firestore limits each commit to 500
+// documents, so a large data set is written in several commits, and if one of them fails, the items
+// in the commits before it stay written: only the failed commit and the ones after it are withheld.
+// The returned error says so, and wraps an [InitError] (see [errors.As]) that lists the operations
+// that were not applied; those that were not attempted have the error [ErrInitNotAttempted].
+//
+// By default, Init writes all documents concurrently, which is faster for large data sets but can
+// leave any combination of them unwritten if some writes fail. Each commit contains up to 500
+// documents, so this option also makes Init slower for large data sets. It cannot be combined with
+// [StoreBuilder.InitRetry], and [StoreBuilder.InitFlushInterval] has no effect. This option only
+// affects the main data store.
+func (b *StoreBuilder[T]) AtomicInit(enabled bool) *StoreBuilder[T] {
+	b.atomicInit = enabled
+	return b
+}
+
+// PreconditionUpserts makes the store update items without a transaction where possible. Normally
+// each update reads the existing document in a transaction to check its version, and then writes it.
+// With this option, the store remembers when it last wrote each document, and the next update of the
+// same item is written with a precondition that the document has not changed since then, which saves
+// the read and avoids transaction contention. If the store has not written the document yet, it reads
+// it first without a transaction. If the precondition fails, because something else has changed the
+// document, or if the outcome is otherwise uncertain, the store falls back to a transaction, so the
+// result is the same either way.
+//
+// This is most effective when a single SDK instance makes repeated updates to the same items. It
+// cannot be combined with [StoreBuilder.NamespaceSummaries] or [StoreBuilder.NamespaceManifests],
+// which must be updated in the same transaction. This option only affects the main data store. The default is false.
+func (b *StoreBuilder[T]) PreconditionUpserts(enabled bool) *StoreBuilder[T] {
+	b.preconditionUpserts = enabled
+	return b
+}
+
+// DifferentialInit makes Init skip items that have not changed. Before writing, Init reads the
+// version of every existing item, and only writes the items whose version differs from the stored
+// one, which greatly reduces the number of writes when the SDK reconnects and receives the same data
+// again. The reads are of the version fields and of the fields that depend on how items are stored,
+// and are done even if obsolete items are not deleted (see [StoreBuilder.PreserveExistingItems] and
+// [StoreBuilder.ColdStartOptimized]).
+//
+// An item is still rewritten if its document was stored differently from how it would be stored now:
+// if it was written with an older schema version, or with different [StoreBuilder.Compression],
+// [StoreBuilder.PayloadCodec], [StoreBuilder.StructuredItems], [StoreBuilder.Checksum], or
+// [StoreBuilder.DocumentLabels] settings, or if it is missing any field that the store writes, such
+// as the deleted field. Items that are too large to store as they are are always rewritten. With
+// [StoreBuilder.NamespaceManifests], the manifest records how the items were stored, and if that
+// differs, Init queries the items instead. This option cannot be combined with
+// [StoreBuilder.ExpireAfter], and only affects the main data store. The default is false.
+func (b *StoreBuilder[T]) DifferentialInit(enabled bool) *StoreBuilder[T] {
+	b.differentialInit = enabled
+	return b
+}
+
+// InitFlushInterval causes Init to flush its writes to Firestore after every n operations and wait
+// for them to complete before continuing. After each flush, the store logs its progress at INFO level
+// and any failures in that group of operations at WARN level, so that problems in a very large Init
+// can be located. The default is zero, meaning that all of the writes are flushed together at the end.
+//
+// Flushing more often makes Init slower, since it limits how many writes are in flight at once. This
+// option only affects the main data store.
+func (b *StoreBuilder[T]) InitFlushInterval(n int) *StoreBuilder[T] {
+	b.initFlushInterval = n
+	return b
+}
+
+// InitMaxWritesPerSecond limits how fast Init sends document writes to Firestore, so that a large
+// Init does not use up a tight write quota or compete with other clients of the same database. The
+// BulkWriter that Init uses already throttles itself, starting at 500 requests per second and
+// ramping up from there, as Firestore recommends; this sets a lower limit on top of that. To also
+// control how many writes are in flight at once, see [StoreBuilder.InitFlushInterval].
+//
+// The default is zero, meaning that only the BulkWriter's own throttling applies. This option has no
+// effect with [StoreBuilder.AtomicInit], and only affects the main data store.
+func (b *StoreBuilder[T]) InitMaxWritesPerSecond(n int) *StoreBuilder[T] {
+	b.initWriteRate = n
+	return b
+}
+
+// InitConcurrency makes Init divide its writes into n ranges and write them with n BulkWriters at the
+// same time, to initialize a large data set faster. Each BulkWriter sends batches of writes in
+// parallel and ramps up its own rate over time, so with more of them, more writes are in flight at
+// once from the start. On a database with a tight write quota, use [StoreBuilder.InitMaxWritesPerSecond]
+// to limit the total rate of all of them, or [StoreBuilder.InitFlushInterval] to limit how many
+// writes each of them has in flight.
+//
+// By default, Init uses a single BulkWriter. This option has no effect with [StoreBuilder.AtomicInit],
+// and only affects the main data store.
+func (b *StoreBuilder[T]) InitConcurrency(n int) *StoreBuilder[T] {
+	b.initConcurrency = n
+	return b
+}
+
+// WatchChanges makes the store listen for changes to the flags and segments in Firestore, and call
+// fn for each item that is added, changed, or removed by any writer, usually within a second or so.
+// This is meant for daemon mode, where another process writes the data: the SDK only rereads an item
+// when its cache entry expires, so with a long CacheSeconds it can take that long to see a change,
+// and fn can be used to react sooner, for instance by clearing another cache.
+//
+// The SDK itself has no way of being told about changes by a data store, so this does not affect
+// the SDK's own cache. The store keeps one snapshot listener open per data kind until it is closed;
+// if a listener fails, it is restarted, and all items are then reported as changed, since changes
+// may have been missed. fn is called on the listener's goroutine, so it should not block. This option
+// only affects the main data store.
+func (b *StoreBuilder[T]) WatchChanges(fn func(ItemChange)) *StoreBuilder[T] {
+	b.onChange = fn
+	return b
+}
+
+// ItemCache enables a cache inside the store for the results of Get and GetAll, for applications
+// that read the store directly or cannot use the SDK's own caching of persistent store data. Cached
+// results are used until they are older than [ItemCacheOptions.TTL]. Writes made through this store
+// remove the items they change from the cache; changes made by other writers are only seen when the
+// cached results expire, unless [ItemCacheOptions.InvalidateOnChange] is set. Use
+// [ExtendedDataStore.InvalidateItemCache] and [ExtendedDataStore.ClearItemCache] to remove entries
+// explicitly, and [ExtendedDataStore.ItemCacheStats] to see how well the cache is working.
+//
+// If the SDK's cache is enabled (see ldcomponents.PersistentDataStore), this cache only adds another
+// delay before changes are seen, so it should only be used in place of that cache. By default there
+// is no cache. This option only affects the main data store.
+func (b *StoreBuilder[T]) ItemCache(options ItemCacheOptions) *StoreBuilder[T] {
+	b.itemCache = options
+	return b
+}
+
+// SnapshotFallback makes the store save every flag and segment to a local file periodically, and
+// serve reads from the last saved data when it cannot read from Firestore, so that an application
+// using the SDK in daemon mode can keep evaluating flags during a Firestore outage. Reads are served
+// from the file until the next snapshot is saved successfully; a warning is logged when this starts.
+//
+// The file is also read when the store is created, so that an application that starts during an
+// outage can still use the data from its last run. It is ignored if it was saved from a different
+// collection. Each snapshot reads every item from Firestore, so the interval should not be too short
+// for the size of the data set. By default no snapshot is saved. This option only affects the main
+// data store.
+func (b *StoreBuilder[T]) SnapshotFallback(options SnapshotFallbackOptions) *StoreBuilder[T] {
+	b.snapshotFallback = options
+	return b
+}
+
+// OutageBuffer makes the data store hold updates in memory when they cannot be written because
+// Firestore is unavailable, and write them when it is available again, so that a brief outage does
+// not leave Firestore behind the data that the SDK has already received. A buffered update is
+// reported to the SDK as successful, and reads of the item return it until it has been written.
+// Updates are written with the usual version check, so one that has been superseded in Firestore in
+// the meantime is discarded.
+//
+// Buffered updates are lost if the process exits or the store is closed before they are written, and
+// they are discarded by the next Init, whose data replaces them. Once MaxItems items are buffered,
+// further updates fail as they would without this option. By default updates are not buffered. This
+// option only affects the main data store.
+func (b *StoreBuilder[T]) OutageBuffer(options OutageBufferOptions) *StoreBuilder[T] {
+	b.outageBuffer = options
+	return b
+}
+
+// AuditTrail makes the data store add a record to a separate collection for every Upsert and Init,
+// for change-tracking requirements on the flag data that is stored in Firestore. The record of an
+// Upsert has the actor, the time, the item's namespace and key, and its old and new versions; it is
+// added in the same transaction as the item, so there is a record if and only if the item was
+// written. Since an Init may write thousands of items, its record has the numbers of items written,
+// deleted, unchanged, and failed, as in [InitReport], and the error if Init failed.
+//
+// Records are only ever added, so the audit collection grows without limit unless something else,
+// such as a Firestore TTL policy on the timestamp field, removes old records. This cannot be combined
+// with [StoreBuilder.PreconditionUpserts]. By default there is no audit trail. This option only
+// affects the main data store.
+func (b *StoreBuilder[T]) AuditTrail(options AuditTrailOptions) *StoreBuilder[T] {
+	b.auditTrail = options
+	return b
+}
+
+// ClientRecovery configures how the data store recovers from a Firestore client whose connection has
+// stopped working, as can happen after a network change or when its credentials can no longer be
+// refreshed. When enough operations and availability checks in a row have failed with connection
+// errors, the store creates a new client with the same settings and uses it from then on; the old
+// client is closed a minute later, so that operations already using it can finish. A warning is
+// logged when the client is recreated, and an informational message when Firestore responds again.
+//
+// This is enabled by default with [DefaultClientRecoveryThreshold] and
+// [DefaultClientRecoveryInterval]; set FailureThreshold to a negative number to disable it. It only
+// applies to a client that the store creates itself, not to one set with
+// [StoreBuilder.FirestoreClient] or [StoreBuilder.SharedClient], or to the process-wide client used
+// in [StoreBuilder.ColdStartOptimized] mode. This option only affects the main data store.
+func (b *StoreBuilder[T]) ClientRecovery(options ClientRecoveryOptions) *StoreBuilder[T] {
+	b.clientRecovery = options
+	return b
+}
+
+// MaxConcurrentOperations limits how many Firestore operations the data store performs at once, and
+// specifies which waiting operation goes next when the limit is reached. Reads are Get, GetAll, and
+// IsInitialized; writes are Init, Upsert, and the administrative operations of [ExtendedDataStore].
+// An entire Init counts as a single operation.
+//
+// For instance, to keep evaluations from being delayed by a large Init:
+//
+//	ldfirestore.DataStore("my-project", "launchdarkly").
+//		MaxConcurrentOperations(50, ldfirestore.SchedulingReadsFirst)
+//
+// The default is zero, meaning there is no limit. This option only affects the main data store.
+func (b *StoreBuilder[T]) MaxConcurrentOperations(n int, policy SchedulingPolicy) *StoreBuilder[T] {
+	b.maxConcurrentOps = n
+	b.schedulingPolicy = policy
+	return b
+}
+
+// ParallelGetAll makes GetAll divide the documents of a data kind into up to n ranges, using a
+// Firestore partition query, and read the ranges concurrently. This can make the SDK start faster
+// when there are tens of thousands of flags or segments. Firestore may use fewer ranges than requested
+// if there are not enough documents to make it worthwhile.
+//
+// Firestore can only partition a collection group query, which finds every collection in the database
+// with the same ID, so this option only has an effect if [StoreBuilder.CollectionGroupQueries] is also
+// enabled. Otherwise each GetAll uses a single query, rather than reading and discarding the documents
+// of other collections with the same ID. With [StoreBuilder.ConsistentGetAll], the ranges are read one
+// at a time in the same transaction, which keeps the result consistent but gives up the concurrency.
+//
+// The partition queries need a single-field index on the namespace field with collection group
+// scope, and if [StoreBuilder.ExcludeDeletedFromGetAll] is enabled, a composite index on the namespace
+// and deleted fields with collection group scope. Firestore does not create these automatically, and
+// the emulator does not require them. Without them, the queries fail with FailedPrecondition (the
+// error includes a link for creating the index); the store then logs a warning and reads with a
+// single query for the rest of its life.
+//
+// The default is zero, meaning that each GetAll uses a single query. This option only affects the main
+// data store.
+func (b *StoreBuilder[T]) ParallelGetAll(n int) *StoreBuilder[T] {
+	b.getAllConcurrency = n
+	return b
+}
+
+// GetAllPageSize makes GetAll read the documents of a data kind with a separate query for each n
+// documents, rather than with a single query. Each page is retried on its own if it fails with a
+// transient error (see [StoreBuilder.Retry]), so a failure late in reading a very large data set does
+// not make GetAll start again from the beginning, and the Firestore client only holds one page of
+// documents at a time. To process items without holding all of them in memory at once, use
+// [ExtendedDataStore.ForEachItem] instead.
+//
+// The default is zero, meaning that GetAll uses a single query. This option cannot be combined with
+// [StoreBuilder.ParallelGetAll]. This option only affects the main data store.
+func (b *StoreBuilder[T]) GetAllPageSize(n int) *StoreBuilder[T] {
+	b.getAllPageSize = n
+	return b
+}
+
+// ConsistentGetAll makes GetAll read the documents of a data kind in a read-only Firestore
+// transaction, so that the result reflects the collection at a single point in time. Without this,
+// when GetAll uses more than one query (see [StoreBuilder.GetAllPageSize] and
+// [StoreBuilder.ParallelGetAll]), an Init or Upsert made by another process while the queries are
+// running can leave some items from before the change and some from after it in the same result.
+//
+// A read-only transaction does not lock documents or delay writers. When this is enabled, each page
+// is no longer retried on its own; instead the whole GetAll is retried, and OperationTimeout applies
+// to the whole GetAll rather than to each page. This does not make Init itself atomic, since Init
+// writes documents in many separate commits, so a GetAll may still see an Init that is only partly
+// written; see [StoreBuilder.AtomicInit] to limit what such a result can contain. The default is false.
+// This option only affects the main data store.
+func (b *StoreBuilder[T]) ConsistentGetAll(enabled bool) *StoreBuilder[T] {
+	b.consistentGetAll = enabled
+	return b
+}
+
+// Retry configures the store to retry operations that fail with a transient Firestore error, such as
+// Unavailable, Aborted, DeadlineExceeded, or ResourceExhausted, with jittered exponential backoff,
+// instead of returning the error immediately, so that a momentary Firestore problem does not make
+// the SDK consider the store unavailable. Errors that would happen again, such as PermissionDenied or
+// InvalidArgument, are returned without retrying. It applies to Get, GetAll, Upsert, IsInitialized,
+// the reads and writes done by Init, the administrative operations of [ExtendedDataStore], and Big
+// Segment reads, but not to IsStoreAvailable, whose result should reflect the current state. If
+// OperationTimeout is set, it applies to each attempt separately.
+//
+//	ldfirestore.DataStore("my-project", "launchdarkly").
+//		Retry(ldfirestore.RetryPolicy{MaxAttempts: 5, MaxBackoff: 10 * time.Second})
+//
+// This is separate from [StoreBuilder.InitRetry], which retries failed Init writes in the background
+// after Init has returned. The default is [DefaultRetryMaxAttempts] attempts with a jitter of
+// [DefaultRetryJitter]; use a RetryPolicy with a MaxAttempts of 1 to disable retrying.
+func (b *StoreBuilder[T]) Retry(policy RetryPolicy) *StoreBuilder[T] {
+	b.retryPolicy = policy
+	return b
+}
+
+// PreflightCheck specifies whether Build verifies that the store can be used before returning it. The
+// check reads from the collection, runs the queries that GetAll uses, and (unless the store is
+// read-only) writes and deletes a document. If any of these fails, Build returns an error that says
+// what to fix, such as a missing IAM role or a missing index, instead of the SDK reporting a gRPC error
+// later on. The SDK only logs errors from Build, so if the application should not start without a
+// working store, call Build yourself and pass the result to the SDK.
+//
+// The default is false. This option only affects the main data store.
+func (b *StoreBuilder[T]) PreflightCheck(enabled bool) *StoreBuilder[T] {
+	b.preflight = enabled
+	return b
+}
+
+// OperationTimeout specifies a time limit for each individual Firestore operation performed by the
+// store, other than Init and [ExtendedDataStore.TouchAll]. The default is zero, meaning there is no
+// time limit other than what the Firestore client itself applies.
+func (b *StoreBuilder[T]) OperationTimeout(timeout time.Duration) *StoreBuilder[T] {
+	b.operationTimeout = timeout
+	return b
+}
+
+// InitTimeout specifies a time limit for each call to Init as a whole, including reading the existing
+// items, writing every item in batches, and retrying failed writes. Since Init can take much longer
+// than a single read or write of an item, this is separate from [StoreBuilder.OperationTimeout]. If
+// the time runs out, Init returns an error and the store is not marked as initialized; writes that
+// [StoreBuilder.InitRetry] continues in the background afterward are not limited by it. The default
+// is zero, meaning there is no time limit. This option only affects the main data store.
+func (b *StoreBuilder[T]) InitTimeout(timeout time.Duration) *StoreBuilder[T] {
+	b.initTimeout = timeout
+	return b
+}
+
+// AvailabilityGracePeriod specifies how long after the store is created it should report itself as
+// available even if it cannot reach Firestore, as long as it has not yet connected successfully. This
+// keeps the SDK from reporting a data store outage at startup when Firestore is working but the first
+// connection is slow, for instance because credentials are still being obtained. Once the store has
+// connected successfully, or the grace period has passed, availability is reported normally.
+//
+// The default is zero, meaning that there is no grace period. This option only affects the main data
+// store.
+func (b *StoreBuilder[T]) AvailabilityGracePeriod(gracePeriod time.Duration) *StoreBuilder[T] {
+	b.availabilityGrace = gracePeriod
+	return b
+}
+
+// AvailabilityCheckTimeout specifies how long the check that the SDK uses to find out whether the
+// store is available can take before the store is reported as unavailable. The check is a read of a
+// single document, so it should be fast; a short timeout keeps it from stalling the SDK's data store
+// status updates during a network outage. [ExtendedDataStore.AvailabilityError] tells whether a failed
+// check was because Firestore could not be reached or because it denied access.
+//
+// If this is zero or negative, DefaultAvailabilityCheckTimeout is used. It is not affected by
+// [StoreBuilder.OperationTimeout]. This option only affects the main data store.
+func (b *StoreBuilder[T]) AvailabilityCheckTimeout(timeout time.Duration) *StoreBuilder[T] {
+	b.availabilityTimeout = timeout
+	return b
+}
+
+// ColdStartOptimized configures the store to add as little startup latency as possible, for use in
+// serverless environments such as Cloud Functions or Cloud Run. In this mode:
+//
+//   - Unless a client was specified with FirestoreClient, the store uses a Firestore client that is
+//     shared by every store in the process that has the same project ID, and that is never closed.
+//     The client is created with the ClientOptions of the first store to need it.
+//   - Init does not read the existing document IDs in the collection first. This means that documents
+//     for flags or segments that no longer exist are not removed.
+//   - If OperationTimeout was not specified, it defaults to [DefaultColdStartOperationTimeout].
+//
+// The default is false.
+func (b *StoreBuilder[T]) ColdStartOptimized(enabled bool) *StoreBuilder[T] {
+	b.coldStart = enabled
+	return b
+}
+
+// NamespaceSummaries configures the store to maintain a small summary document for each data kind,
+// containing the number of items, the highest item version, and the time of the last change. The
+// summary is updated in the same transaction as each write, so dashboards and health checks can read
+// one document instead of querying every item of that kind. Use
+// [ExtendedDataStore.NamespaceSummary] to read it.
+//
+// This makes each update slightly slower, and updates to items of the same kind contend for the same
+// summary document, so it is not recommended if there are very frequent updates. This option only
+// affects the main data store. The default is false.
+func (b *StoreBuilder[T]) NamespaceSummaries(enabled bool) *StoreBuilder[T] {
+	b.namespaceSummaries = enabled
+	return b
+}
+
+// NamespaceManifests configures the store to maintain a manifest document for each data kind, listing
+// the key and version of every item. The manifest is updated in the same transaction as each write.
+// Init reads the manifests instead of querying every existing item to find the ones to delete (and,
+// with [StoreBuilder.DifferentialInit], the ones that have not changed), and tools can read one
+// document with [ExtendedDataStore.NamespaceManifest] to compare the stored data with another data
+// set. If some of Init's writes fail, the manifests are deleted, and the next Init queries the items
+// and writes new ones.
+//
+// Every process that writes to the collection must enable this option, or the manifests will not
+// match the items. A Firestore document is limited to 1 MiB, which allows for tens of thousands of
+// items of each kind, depending on the length of the keys. As with [StoreBuilder.NamespaceSummaries],
+// updates to items of the same kind contend for the same document, and this cannot be combined with
+// [StoreBuilder.PreconditionUpserts]. This option only affects the main data store. The default is
+// false.
+func (b *StoreBuilder[T]) NamespaceManifests(enabled bool) *StoreBuilder[T] {
+	b.namespaceManifests = enabled
+	return b
+}
+
+// ExcludeDeletedFromGetAll configures the store so that GetAll does not return placeholders for
+// deleted items. The placeholders are filtered out by the Firestore query, using the "deleted" field
+// that the store writes to every document, so they are not transferred at all. Get still returns
+// them, and they are still used for version checks when items are updated.
+//
+// This reduces the amount of data read by consumers that only care about items that currently exist.
+// Documents written by versions of this library that did not store the "deleted" field are also
+// excluded from GetAll until they are rewritten, which happens the next time the store is
+// initialized, or when the item is next updated. With [StoreBuilder.DifferentialInit], Init still
+// rewrites these documents even if their version has not changed, since they are missing the field.
+// This option only affects the main data store. The default is false.
+func (b *StoreBuilder[T]) ExcludeDeletedFromGetAll(enabled bool) *StoreBuilder[T] {
+	b.excludeDeleted = enabled
+	return b
+}
+
+// TracerProvider makes the store create an OpenTelemetry span for each Get, GetAll, Upsert, Init, and
+// IsInitialized call, and for each Big Segment read, so that the time spent in Firestore shows up in
+// traces. Each span records the operation, the collection, the data kind and key where there is one,
+// the number of documents read or written, and the error if the operation failed. The SDK does not
+// pass a context to the store, so a span's parent is the span in the context given to
+// [StoreBuilder.Context], if any; otherwise each operation is the root of its own trace.
+//
+// By default, no spans are created. The Firestore client library can also create spans for its own
+// requests; see its documentation.
+func (b *StoreBuilder[T]) TracerProvider(provider trace.TracerProvider) *StoreBuilder[T] {
+	b.tracerProvider = provider
+	return b
+}
+
+// MeterProvider makes the store record OpenTelemetry metrics for the same operations that
+// [StoreBuilder.TracerProvider] creates spans for:
+//
+//   - ldfirestore.operation.duration: a histogram of the duration of each operation, in seconds
+//   - ldfirestore.operations: a counter of operations
+//   - ldfirestore.documents: a counter of the documents read or written by the operations
+//
+// Each has the attributes db.operation.name (such as "Get" or "GetMembership"), db.collection.name,
+// ldfirestore.kind for operations on a data kind, and ldfirestore.result, which is "success" or
+// "error". Item keys are not recorded, to keep the number of distinct attribute sets small. By
+// default, no metrics are recorded.
+func (b *StoreBuilder[T]) MeterProvider(provider metric.MeterProvider) *StoreBuilder[T] {
+	b.meterProvider = provider
+	return b
+}
+
+// OperationHook specifies a hook that is called before and after each Get, GetAll, Upsert, Init,
+// and IsInitialized call to the data store, and each GetMembership and GetMetadata call to the Big
+// Segment store. This can be used for custom metrics or logging without depending on OpenTelemetry
+// (see [StoreBuilder.TracerProvider] and [StoreBuilder.MeterProvider]), or to make operations fail
+// in tests by returning an error from [OperationHook.BeforeOperation].
+//
+// The hook is called synchronously on the goroutine that called the store, so it should return
+// quickly. By default there is no hook.
+func (b *StoreBuilder[T]) OperationHook(hook OperationHook) *StoreBuilder[T] {
+	b.operationHook = hook
+	return b
+}
+
+// BigSegmentsHook specifies a function that is called after every call to the Big Segment store, with
+// information about the call such as its latency and the size of the result. This can be used to
+// measure the cost of Big Segment evaluations in each service.
+//
+// The SDK caches Big Segment memberships, so calls only reach the store (and the hook) when the
+// SDK's cache does not have the result. The hook is called synchronously on the evaluating goroutine,
+// so it should return quickly. This option only affects the Big Segment store.
+func (b *StoreBuilder[T]) BigSegmentsHook(hook func(BigSegmentCallInfo)) *StoreBuilder[T] {
+	b.bigSegmentsHook = hook
+	return b
+}
+
+// MembershipCache makes the Big Segment store keep the results of GetMembership in memory for a
+// while, so that contexts that are evaluated often do not each cause a Firestore read whenever the
+// SDK's own cache of that context's membership is missing. A cached membership is used until its TTL
+// has passed, even if the membership changes in Firestore in the meantime, so the TTL should be no
+// longer than the staleness that is acceptable. Errors are not cached.
+//
+// By default there is no cache. Use [ExtendedBigSegmentStore.MembershipCacheStats] to see how well
+// the cache is working. This option only affects the Big Segment store.
+func (b *StoreBuilder[T]) MembershipCache(options MembershipCacheOptions) *StoreBuilder[T] {
+	b.membershipCache = options
+	return b
+}
+
+// WatchMetadata makes the Big Segment store keep a snapshot listener open on the Big Segment metadata
+// document, and answer GetMetadata from the latest snapshot instead of reading the document each time
+// the SDK polls it. This saves a read per poll, and since the listener sees a synchronizer's update
+// within a second or so, the SDK notices that the data has become stale or up to date again at its
+// next poll, however that poll is timed.
+//
+// While the listener is not running, for instance after it fails and before it is restarted, GetMetadata
+// reads the document as usual. The default is false. This option only affects the Big Segment store.
+func (b *StoreBuilder[T]) WatchMetadata(enabled bool) *StoreBuilder[T] {
+	b.watchMetadata = enabled
+	return b
+}
+
+// EmulatorDetection controls whether the store adjusts its behavior when the FIRESTORE_EMULATOR_HOST
+// environment variable is set, so that local development works without special configuration. This is
+// enabled by default. When the emulator is detected, the store logs that it is in emulator mode, and:
+//
+//   - The Firestore client connects to the emulator without authentication. The Firestore client
+//     library does this whenever the variable is set, regardless of this option.
+//   - If OperationTimeout was not specified, it defaults to [DefaultEmulatorOperationTimeout], so
+//     that a stopped emulator causes errors instead of long waits.
+//   - If InitRetry is enabled without an initial delay, failed writes are retried after a much
+//     shorter delay than usual, since the emulator has no quotas to respect.
+//
+// None of this applies if you provide your own client with FirestoreClient.
+func (b *StoreBuilder[T]) EmulatorDetection(enabled bool) *StoreBuilder[T] {
+	b.ignoreEmulator = !enabled
+	return b
+}
+
+// PrivateEndpoint configures the store to connect to Firestore through a private endpoint, such as a
+// regional endpoint or a Private Service Connect endpoint, optionally using mutual TLS. This is
+// equivalent to passing the corresponding options to ClientOptions, but the endpoint is checked when
+// the store is built, and Unavailable errors from the endpoint include its address to make
+// connectivity problems easier to diagnose.
+//
+//	ldfirestore.DataStore("my-project", "launchdarkly").
+//		PrivateEndpoint(ldfirestore.PrivateEndpointOptions{Endpoint: "firestore-myendpoint.p.googleapis.com:443"})
+//
+// The option has no effect if you provide your own client with FirestoreClient, or if the
+// FIRESTORE_EMULATOR_HOST environment variable is set.
+func (b *StoreBuilder[T]) PrivateEndpoint(options PrivateEndpointOptions) *StoreBuilder[T] {
+	b.privateEndpoint = &options
+	return b
+}
+
+// VersionAnomalyDetection configures the store to remember the highest version of each item that it
+// has read or written, and to log a warning when a later read returns an older version. With a single
+// writer that should never happen, so a warning usually means that more than one process is writing
+// to the same collection, for instance because two SDK instances with different data are configured
+// with the same collection and prefix. Use [ExtendedDataStore.VersionAnomalies] to get the number of
+// anomalies that were detected.
+//
+// The remembered versions are cleared whenever the store is initialized. This option only affects the
+// main data store. The default is false.
+func (b *StoreBuilder[T]) VersionAnomalyDetection(enabled bool) *StoreBuilder[T] {
+	b.versionAnomalies = enabled
+	return b
+}
+
+// DebugLogging enables debug logging for only the given categories of data store activity, such as
+// [LogWrites], so that one part of the store's behavior can be traced in production without the
+// volume of debug messages from everything else. Messages in these categories are logged at the Debug
+// level even if the SDK's minimum log level is higher, while debug messages in other categories are
+// not logged. Debug messages that do not belong to any category still follow the SDK's log level.
+//
+//	ldfirestore.DataStore("my-project", "launchdarkly").
+//		DebugLogging(ldfirestore.LogWrites, ldfirestore.LogTransactions)
+//
+// By default, all debug messages follow the SDK's log level. This option only affects the main data
+// store.
+func (b *StoreBuilder[T]) DebugLogging(categories ...LogCategory) *StoreBuilder[T] {
+	b.debugCategories = 0
+	for _, c := range categories {
+		b.debugCategories |= c
+	}
+	return b
+}
+
+// RPCLogging enables logging of a random sample of the raw Firestore RPCs issued by the store. For each
+// sampled call, the store logs the gRPC method, the document or collection path, the latency, and the
+// status code at INFO level. The sample rate is a fraction between 0 and 1; for instance, 0.01 logs
+// about one call in a hundred, and 1 logs every call.
+//
+// This is intended for debugging; logging many calls can affect performance. The option has no effect
+// if you provide your own client with FirestoreClient. The default is zero, meaning that no calls are
+// logged.
+func (b *StoreBuilder[T]) RPCLogging(sampleRate float64) *StoreBuilder[T] {
+	b.rpcLogSampleRate = sampleRate
+	return b
+}
+
 // Build is called internally by the SDK.
 func (b *StoreBuilder[T]) Build(context subsystems.ClientContext) (T, error) {
 	return b.factory(b, context)
@@ -1,13 +1,18 @@
 package ldfirestore
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
 	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
 )
 
 func TestDataStoreBuilder(t *testing.T) {
@@ -45,6 +50,234 @@ func TestDataStoreBuilder(t *testing.T) {
 		assert.Len(t, b.clientOptions, 2)
 	})
 
+	t.Run("KindSizeLimit", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").
+			KindSizeLimit(ldstoreimpl.Features(), 500000, OversizedItemTruncateRules).
+			KindSizeLimit(ldstoreimpl.Segments(), 0, OversizedItemError)
+		assert.Equal(t, map[string]kindSizeLimit{
+			"features": {maxBytes: 500000, policy: OversizedItemTruncateRules},
+			"segments": {maxBytes: 0, policy: OversizedItemError},
+		}, b.kindLimits)
+	})
+
+	t.Run("OversizedItems", func(t *testing.T) {
+		overflow := &testOverflowStore{}
+		b := DataStore("my-project", "my-collection").OversizedItems(OversizedItemOverflow).OverflowStore(overflow)
+		assert.Equal(t, OversizedItemOverflow, b.oversizedPolicy)
+		assert.Equal(t, overflow, b.overflowStore)
+
+		ds, err := DataStore("my-project", "my-collection").OversizedItems(OversizedItemOverflow).
+			Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, ds)
+	})
+
+	t.Run("ReadRepair", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection")
+		assert.False(t, b.readRepair)
+
+		b.ReadRepair(true)
+		assert.True(t, b.readRepair)
+	})
+
+	t.Run("InitRetry", func(t *testing.T) {
+		options := InitRetryOptions{MaxAttempts: 3, InitialDelay: time.Millisecond, MarkInitializedEarly: true}
+		b := DataStore("my-project", "my-collection").InitRetry(options)
+		assert.Equal(t, options, b.initRetry)
+	})
+
+	t.Run("InitFlushInterval", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection")
+		assert.Equal(t, 0, b.initFlushInterval)
+
+		b.InitFlushInterval(500)
+		assert.Equal(t, 500, b.initFlushInterval)
+	})
+
+	t.Run("MaxConcurrentOperations", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").MaxConcurrentOperations(10, SchedulingReadsFirst)
+		assert.Equal(t, 10, b.maxConcurrentOps)
+		assert.Equal(t, SchedulingReadsFirst, b.schedulingPolicy)
+	})
+
+	t.Run("KindRoute", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").KindRoute(ldstoreimpl.Segments(), "segments-collection", "")
+		assert.Equal(t, map[string]kindRoute{"segments": {collection: "segments-collection"}}, b.kindRoutes)
+	})
+
+	t.Run("PayloadFilter", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").PayloadFilter("mobile")
+		assert.Equal(t, "mobile", b.payloadFilter)
+	})
+
+	t.Run("ReadOnlyOnNewerSchema", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").ReadOnlyOnNewerSchema(true)
+		assert.True(t, b.readOnlyOnNewerSchema)
+	})
+
+	t.Run("WriterElection", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").WriterElection("instance1", time.Minute)
+		assert.Equal(t, "instance1", b.writerInstanceID)
+		assert.Equal(t, time.Minute, b.writerLeaseDuration)
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		b := DataStore("my-project", "my-collection").Context(ctx)
+		assert.Equal(t, ctx, b.baseContext)
+
+		store, err := b.ClientOptions(makeTestOptions()...).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		defer func() { _ = store.Close() }()
+		storeCtx := store.(*firestoreDataStore).context
+		assert.NoError(t, storeCtx.Err())
+		cancel()
+		assert.Equal(t, context.Canceled, storeCtx.Err())
+	})
+
+	t.Run("DatabaseID", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").DatabaseID("flags-db")
+		assert.Equal(t, "flags-db", b.databaseID)
+
+		store, err := b.ClientOptions(makeTestOptions()...).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		defer func() { _ = store.Close() }()
+		assert.Contains(t, store.(*firestoreDataStore).collectionRef("c").Path, "/databases/flags-db/")
+	})
+
+	t.Run("ParentDocument", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").ParentDocument("apps/myapp")
+		assert.Equal(t, "apps/myapp", b.parentDocument)
+	})
+
+	t.Run("NamespaceSummaries", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").NamespaceSummaries(true)
+		assert.True(t, b.namespaceSummaries)
+	})
+
+	t.Run("ExcludeDeletedFromGetAll", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").ExcludeDeletedFromGetAll(true)
+		assert.True(t, b.excludeDeleted)
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		assert.Equal(t, RetryPolicy{MaxAttempts: DefaultRetryMaxAttempts, Jitter: DefaultRetryJitter},
+			DataStore("my-project", "my-collection").retryPolicy)
+
+		policy := RetryPolicy{MaxAttempts: 4, Jitter: 0.2}
+		b := DataStore("my-project", "my-collection").Retry(policy)
+		assert.Equal(t, policy, b.retryPolicy)
+	})
+
+	t.Run("PreserveExistingItems", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").PreserveExistingItems(true)
+		assert.True(t, b.preserveExisting)
+
+		store, err := b.ClientOptions(makeTestOptions()...).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		defer func() { _ = store.Close() }()
+		assert.True(t, store.(*firestoreDataStore).skipExistingScan)
+	})
+
+	t.Run("CollectionGroupQueries", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").CollectionGroupQueries(true)
+		assert.True(t, b.collectionGroup)
+	})
+
+	t.Run("Compression", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection")
+		assert.Equal(t, CompressionNone, b.compression)
+
+		b.Compression(CompressionZstd)
+		assert.Equal(t, CompressionZstd, b.compression)
+	})
+
+	t.Run("StoreItemsAsBytes", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").StoreItemsAsBytes(true)
+		assert.True(t, b.itemBytes)
+	})
+
+	t.Run("StructuredItems", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").StructuredItems(true)
+		assert.True(t, b.structured)
+	})
+
+	t.Run("PayloadCodec", func(t *testing.T) {
+		codec := reverseCodec{format: "reverse"}
+		b := DataStore("my-project", "my-collection").PayloadCodec(codec)
+		assert.Equal(t, codec, b.codec)
+	})
+
+	t.Run("Checksum", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").Checksum(ChecksumCRC32C)
+		assert.Equal(t, ChecksumCRC32C, b.checksum)
+	})
+
+	t.Run("ExpireAfter", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").ExpireAfter(time.Hour)
+		assert.Equal(t, time.Hour, b.expireAfter)
+
+		ds, err := DataStore("my-project", "my-collection").ExpireAfter(-time.Hour).
+			Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, ds)
+	})
+
+	t.Run("ParallelGetAll", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").ParallelGetAll(8)
+		assert.Equal(t, 8, b.getAllConcurrency)
+	})
+
+	t.Run("GetAllPageSize", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").GetAllPageSize(100)
+		assert.Equal(t, 100, b.getAllPageSize)
+
+		ds, err := b.ParallelGetAll(4).Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, ds)
+	})
+
+	t.Run("ConsistentGetAll", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").ConsistentGetAll(true)
+		assert.True(t, b.consistentGetAll)
+	})
+
+	t.Run("InitTimeout", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").InitTimeout(time.Nanosecond)
+		assert.Equal(t, time.Nanosecond, b.initTimeout)
+
+		store, err := b.ClientOptions(option.WithEndpoint("localhost:1"), option.WithoutAuthentication()).
+			OperationTimeout(time.Minute).
+			Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		defer func() { _ = store.Close() }()
+		err = store.Init(makeAtomicInitTestData(1))
+		assert.Equal(t, codes.DeadlineExceeded, errorCode(err))
+	})
+
+	t.Run("AtomicInit", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").AtomicInit(true)
+		assert.True(t, b.atomicInit)
+
+		ds, err := b.InitRetry(InitRetryOptions{MaxAttempts: 3}).Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, ds)
+	})
+
+	t.Run("error for invalid parent document", func(t *testing.T) {
+		ds, err := DataStore("my-project", "my-collection").ParentDocument("apps").
+			Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, ds)
+		assert.Contains(t, err.Error(), "refers to a collection")
+
+		bs, err := BigSegmentStore("my-project", "my-collection").ParentDocument("apps//x").
+			Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, bs)
+		assert.Contains(t, err.Error(), "empty segment")
+	})
+
 	t.Run("error for empty project ID", func(t *testing.T) {
 		ds, err := DataStore("", "my-collection").Build(subsystems.BasicClientContext{})
 		assert.Error(t, err)
@@ -0,0 +1,104 @@
+package ldfirestore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+// ChecksumAlgorithm specifies how the checksum of each stored item is computed. See
+// [StoreBuilder.Checksum].
+type ChecksumAlgorithm int
+
+const (
+	// ChecksumNone means that no checksum is written. This is the default.
+	ChecksumNone ChecksumAlgorithm = iota
+
+	// ChecksumCRC32C means that a CRC-32C checksum is written, which detects accidental corruption
+	// at very little cost.
+	ChecksumCRC32C
+
+	// ChecksumSHA256 means that a SHA-256 hash is written.
+	ChecksumSHA256
+)
+
+// fieldChecksum holds the checksum of a document's serialized item, as "<algorithm>:<hex digest>",
+// so that documents can be verified regardless of how the store that reads them is configured.
+const fieldChecksum = "checksum"
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+var errChecksumMismatch = errors.New("the item does not match its checksum")
+
+func (a ChecksumAlgorithm) String() string {
+	switch a {
+	case ChecksumNone:
+		return "none"
+	case ChecksumCRC32C:
+		return "crc32c"
+	case ChecksumSHA256:
+		return "sha256"
+	default:
+		return fmt.Sprintf("ChecksumAlgorithm(%d)", int(a))
+	}
+}
+
+func validateChecksum(a ChecksumAlgorithm) error {
+	switch a {
+	case ChecksumNone, ChecksumCRC32C, ChecksumSHA256:
+		return nil
+	default:
+		return fmt.Errorf("unknown checksum algorithm %s", a)
+	}
+}
+
+func (a ChecksumAlgorithm) sum(itemJSON []byte) string {
+	switch a {
+	case ChecksumCRC32C:
+		return fmt.Sprintf("%s:%08x", a, crc32.Checksum(itemJSON, crc32cTable))
+	case ChecksumSHA256:
+		digest := sha256.Sum256(itemJSON)
+		return a.String() + ":" + hex.EncodeToString(digest[:])
+	default:
+		return ""
+	}
+}
+
+// setChecksum adds the checksum of a serialized item to the fields of a document that is being
+// written, if the store is configured to do so.
+func (store *firestoreDataStore) setChecksum(data map[string]any, itemJSON []byte) {
+	if store.checksum == ChecksumNone {
+		delete(data, fieldChecksum)
+		return
+	}
+	if props, ok := data[fieldItem].(map[string]any); ok {
+		// An item stored as a map is read back as the JSON that the map marshals to, which can differ
+		// from the original JSON in the order of properties.
+		itemJSON, _ = json.Marshal(props)
+	}
+	data[fieldChecksum] = store.checksum.sum(itemJSON)
+}
+
+// verifyChecksum checks a serialized item that was read from a document against the document's
+// checksum, if it has one. Checksums written with an algorithm that this version does not know are
+// ignored.
+func verifyChecksum(data map[string]any, itemJSON []byte) error {
+	checksum, _ := data[fieldChecksum].(string)
+	if checksum == "" {
+		return nil
+	}
+	name, _, _ := strings.Cut(checksum, ":")
+	for _, a := range []ChecksumAlgorithm{ChecksumCRC32C, ChecksumSHA256} {
+		if a.String() == name {
+			if a.sum(itemJSON) != checksum {
+				return errChecksumMismatch
+			}
+			return nil
+		}
+	}
+	return nil
+}
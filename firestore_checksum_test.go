@@ -0,0 +1,93 @@
+package ldfirestore
+
+import (
+	"testing"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksum(t *testing.T) {
+	itemJSON := []byte(`{"key":"flag1","version":1,"on":true}`)
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: itemJSON}
+
+	for _, algorithm := range []ChecksumAlgorithm{ChecksumCRC32C, ChecksumSHA256} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			store := &firestoreDataStore{checksum: algorithm}
+			data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", item)
+			require.NoError(t, err)
+			assert.Contains(t, data[fieldChecksum], algorithm.String()+":")
+
+			decoded, err := store.itemPayload(data)
+			require.NoError(t, err)
+			assert.Equal(t, itemJSON, decoded)
+
+			data[fieldItem] = `{"key":"flag1","version":1,"on":false}`
+			_, err = store.itemPayload(data)
+			assert.ErrorIs(t, err, errChecksumMismatch)
+		})
+	}
+
+	t.Run("no checksum by default", func(t *testing.T) {
+		store := &firestoreDataStore{}
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", item)
+		require.NoError(t, err)
+		assert.NotContains(t, data, fieldChecksum)
+	})
+
+	t.Run("checksum is verified regardless of configuration", func(t *testing.T) {
+		data, err := (&firestoreDataStore{checksum: ChecksumSHA256}).encodeItem(ldstoreimpl.Features(), "flag1", item)
+		require.NoError(t, err)
+		data[fieldItem] = `{}`
+		_, err = (&firestoreDataStore{}).itemPayload(data)
+		assert.ErrorIs(t, err, errChecksumMismatch)
+	})
+
+	t.Run("checksum of compressed or structured item", func(t *testing.T) {
+		for _, store := range []*firestoreDataStore{
+			{checksum: ChecksumCRC32C, compression: CompressionZstd},
+			{checksum: ChecksumCRC32C, structured: true},
+		} {
+			data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", item)
+			require.NoError(t, err)
+			decoded, err := store.itemPayload(data)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(itemJSON), string(decoded))
+		}
+	})
+
+	t.Run("truncated item has a new checksum", func(t *testing.T) {
+		store := &firestoreDataStore{checksum: ChecksumCRC32C}
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", item)
+		require.NoError(t, err)
+		truncated, ok := store.truncateFlagRules(data)
+		require.True(t, ok)
+		_, err = store.itemPayload(truncated)
+		assert.NoError(t, err)
+	})
+
+	t.Run("unknown algorithm is ignored", func(t *testing.T) {
+		assert.NoError(t, verifyChecksum(map[string]any{fieldChecksum: "md5:abc"}, itemJSON))
+	})
+
+	t.Run("corrupted item is not returned by read repair", func(t *testing.T) {
+		store := &firestoreDataStore{checksum: ChecksumCRC32C, loggers: ldlogtest.NewMockLog().Loggers}
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", item)
+		require.NoError(t, err)
+		data[fieldVersion] = int64(1)
+		data[fieldItem] = `{"key":"flag1","version":1,"on":false}`
+		_, _, ok := store.decodeLenient(ldstoreimpl.Features(), "flag1", data)
+		assert.False(t, ok)
+	})
+
+	t.Run("error for unknown algorithm", func(t *testing.T) {
+		ds, err := DataStore("my-project", "my-collection").Checksum(ChecksumAlgorithm(9)).
+			Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, ds)
+	})
+}
@@ -0,0 +1,145 @@
+package ldfirestore
+
+import (
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// DefaultClientRecoveryThreshold is the default value for [ClientRecoveryOptions.FailureThreshold].
+	DefaultClientRecoveryThreshold = 5
+
+	// DefaultClientRecoveryInterval is the default value for [ClientRecoveryOptions.MinInterval].
+	DefaultClientRecoveryInterval = time.Minute
+
+	// oldClientCloseDelay is how long a replaced client is kept open, so that operations that were
+	// already using it can finish.
+	oldClientCloseDelay = time.Minute
+)
+
+// ClientRecoveryOptions configures how the data store recreates its Firestore client when the
+// connection stops working. See [StoreBuilder.ClientRecovery].
+type ClientRecoveryOptions struct {
+	// FailureThreshold is the number of consecutive operations and availability checks that must fail
+	// with a connection error (Unavailable, DeadlineExceeded, or Unauthenticated) before the client is
+	// recreated. If it is zero, DefaultClientRecoveryThreshold is used. If it is negative, the client
+	// is never recreated.
+	FailureThreshold int
+
+	// MinInterval is the shortest time between two recreations of the client. If it is zero or less,
+	// DefaultClientRecoveryInterval is used.
+	MinInterval time.Duration
+
+	// OnRecreate, if not nil, is called after the client has been recreated, with the error that the
+	// last of the failed operations returned.
+	OnRecreate func(err error)
+}
+
+// clientRecovery keeps track of connection errors, to decide when to recreate the client.
+type clientRecovery struct {
+	options      ClientRecoveryOptions
+	builder      builderOptions // the options that the client was created with
+	failures     int
+	lastRecreate time.Time
+	recreated    bool // true if the client was recreated and no operation has succeeded since then
+	lock         sync.Mutex
+}
+
+// newClientRecovery returns nil if client recovery is disabled.
+func newClientRecovery(options ClientRecoveryOptions, builder builderOptions) *clientRecovery {
+	if options.FailureThreshold < 0 {
+		return nil
+	}
+	if options.FailureThreshold == 0 {
+		options.FailureThreshold = DefaultClientRecoveryThreshold
+	}
+	if options.MinInterval <= 0 {
+		options.MinInterval = DefaultClientRecoveryInterval
+	}
+	return &clientRecovery{options: options, builder: builder}
+}
+
+// isConnectionError returns true for errors that may mean that the client's connection to Firestore
+// is no longer working, as opposed to errors that Firestore returned for the request itself.
+func isConnectionError(err error) bool {
+	switch errorCode(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Unauthenticated:
+		return true
+	default:
+		return false
+	}
+}
+
+// firestoreClient returns the client that the store currently uses.
+func (store *firestoreDataStore) firestoreClient() *firestore.Client {
+	store.clientLock.RLock()
+	defer store.clientLock.RUnlock()
+	return store.client
+}
+
+// observeResult is called with the outcome of every operation and availability check.
+func (store *firestoreDataStore) observeResult(err error) {
+	store.availabilityListeners.record(err)
+	if store.recovery != nil {
+		store.observeClientResult(err)
+	}
+}
+
+// observeClientResult recreates the client if too many operations and availability checks in a row
+// have failed with connection errors.
+func (store *firestoreDataStore) observeClientResult(err error) {
+	r := store.recovery
+	r.lock.Lock()
+	if err == nil || !isConnectionError(err) {
+		// Any response from Firestore, even an error, means the connection works
+		if r.recreated {
+			store.loggers.Infof("Firestore is responding again since the client was recreated")
+			r.recreated = false
+		}
+		r.failures = 0
+		r.lock.Unlock()
+		return
+	}
+	r.failures++
+	now := store.now()
+	if r.failures < r.options.FailureThreshold ||
+		(!r.lastRecreate.IsZero() && now.Sub(r.lastRecreate) < r.options.MinInterval) {
+		r.lock.Unlock()
+		return
+	}
+	failures := r.failures
+	r.failures = 0
+	r.lastRecreate = now
+	r.lock.Unlock()
+
+	store.recreateClient(failures, err)
+}
+
+// recreateClient replaces the store's client with a new one. The old client is closed after a delay.
+func (store *firestoreDataStore) recreateClient(failures int, lastErr error) {
+	r := store.recovery
+	client, err := newClient(store.context, r.builder)
+	if err != nil {
+		store.loggers.Errorf("Failed to recreate the Firestore client after %d consecutive connection "+
+			"errors: %s", failures, err)
+		return
+	}
+
+	store.clientLock.Lock()
+	old := store.client
+	store.client = client
+	store.clientLock.Unlock()
+	time.AfterFunc(oldClientCloseDelay, func() { _ = old.Close() })
+
+	r.lock.Lock()
+	r.recreated = true
+	r.lock.Unlock()
+	store.loggers.Warnf("Recreated the Firestore client after %d consecutive connection errors: %s",
+		failures, lastErr)
+	if r.options.OnRecreate != nil {
+		r.options.OnRecreate(lastErr)
+	}
+}
@@ -0,0 +1,106 @@
+package ldfirestore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewClientRecovery(t *testing.T) {
+	r := newClientRecovery(ClientRecoveryOptions{}, builderOptions{})
+	require.NotNil(t, r)
+	assert.Equal(t, DefaultClientRecoveryThreshold, r.options.FailureThreshold)
+	assert.Equal(t, DefaultClientRecoveryInterval, r.options.MinInterval)
+
+	assert.Nil(t, newClientRecovery(ClientRecoveryOptions{FailureThreshold: -1}, builderOptions{}))
+}
+
+func TestIsConnectionError(t *testing.T) {
+	assert.True(t, isConnectionError(status.Error(codes.Unavailable, "down")))
+	assert.True(t, isConnectionError(status.Error(codes.Unauthenticated, "expired")))
+	assert.True(t, isConnectionError(newStoreError("Get", nil, "", status.Error(codes.DeadlineExceeded, "slow"))))
+	assert.False(t, isConnectionError(status.Error(codes.NotFound, "missing")))
+	assert.False(t, isConnectionError(errors.New("other")))
+	assert.False(t, isConnectionError(nil))
+}
+
+func TestClientRecovery(t *testing.T) {
+	unreachable := []option.ClientOption{option.WithEndpoint("localhost:1"), option.WithoutAuthentication()}
+
+	build := func(t *testing.T, recovery ClientRecoveryOptions) *firestoreDataStore {
+		ds, err := DataStore("my-project", "my-collection").
+			ClientOptions(unreachable...).
+			Retry(RetryPolicy{}).
+			OperationTimeout(time.Second).
+			ClientRecovery(recovery).
+			Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = ds.Close() })
+		return ds.(*firestoreDataStore)
+	}
+
+	t.Run("recreates the client after consecutive connection errors", func(t *testing.T) {
+		var recreatedWith []error
+		store := build(t, ClientRecoveryOptions{
+			FailureThreshold: 2,
+			OnRecreate:       func(err error) { recreatedWith = append(recreatedWith, err) },
+		})
+		original := store.firestoreClient()
+
+		_, err := store.Get(ldstoreimpl.Features(), "flag1")
+		require.Error(t, err)
+		require.True(t, isConnectionError(err), "unexpected error: %s", err)
+		assert.Same(t, original, store.firestoreClient())
+		assert.Empty(t, recreatedWith)
+
+		_, _ = store.Get(ldstoreimpl.Features(), "flag1")
+		assert.NotSame(t, original, store.firestoreClient())
+		require.Len(t, recreatedWith, 1)
+		assert.True(t, isConnectionError(recreatedWith[0]))
+
+		// not again until MinInterval has passed
+		recreated := store.firestoreClient()
+		_, _ = store.Get(ldstoreimpl.Features(), "flag1")
+		_, _ = store.Get(ldstoreimpl.Features(), "flag1")
+		assert.Same(t, recreated, store.firestoreClient())
+		assert.Len(t, recreatedWith, 1)
+	})
+
+	t.Run("other errors reset the count", func(t *testing.T) {
+		store := build(t, ClientRecoveryOptions{FailureThreshold: 2})
+		original := store.firestoreClient()
+
+		store.observeClientResult(status.Error(codes.Unavailable, "down"))
+		store.observeClientResult(status.Error(codes.NotFound, "missing"))
+		store.observeClientResult(status.Error(codes.Unavailable, "down"))
+		assert.Same(t, original, store.firestoreClient())
+
+		store.observeClientResult(status.Error(codes.Unavailable, "down"))
+		assert.NotSame(t, original, store.firestoreClient())
+	})
+
+	t.Run("can be disabled", func(t *testing.T) {
+		store := build(t, ClientRecoveryOptions{FailureThreshold: -1})
+		assert.Nil(t, store.recovery)
+	})
+
+	t.Run("does not apply to a client that was provided", func(t *testing.T) {
+		client, err := createTestClient()
+		require.NoError(t, err)
+		defer func() { _ = client.Close() }()
+
+		ds, err := DataStore("my-project", "my-collection").FirestoreClient(client).
+			Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		defer func() { _ = ds.Close() }()
+		assert.Nil(t, ds.(*firestoreDataStore).recovery)
+	})
+}
@@ -0,0 +1,40 @@
+package ldfirestore
+
+import (
+	"time"
+)
+
+// Clock is a source of the current time. The data store uses it for every timestamp that it writes or
+// compares, such as the updatedAt field and writer lease expiry times. See [StoreBuilder.Clock].
+//
+// A Clock can also implement [TimerClock], to control how long the store waits between retries.
+type Clock interface {
+	Now() time.Time
+}
+
+// TimerClock is a [Clock] that also controls delays. If the clock passed to [StoreBuilder.Clock]
+// implements it, the store waits for the channel returned by After instead of a real timer before
+// each background retry of Init writes (see [StoreBuilder.InitRetry]).
+type TimerClock interface {
+	Clock
+
+	// After returns a channel that receives the current time once d has passed, like time.After.
+	After(d time.Duration) <-chan time.Time
+}
+
+// now returns the current time according to the store's clock.
+func (store *firestoreDataStore) now() time.Time {
+	if store.clock == nil {
+		return time.Now()
+	}
+	return store.clock.Now()
+}
+
+// after returns a channel that receives a value once d has passed, according to the store's clock if
+// it is a TimerClock.
+func (store *firestoreDataStore) after(d time.Duration) <-chan time.Time {
+	if clock, ok := store.clock.(TimerClock); ok {
+		return clock.After(d)
+	}
+	return time.After(d)
+}
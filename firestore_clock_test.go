@@ -0,0 +1,32 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+func TestClock(t *testing.T) {
+	t.Run("defaults to system clock", func(t *testing.T) {
+		store := &firestoreDataStore{}
+		before := time.Now()
+		assert.False(t, store.now().Before(before))
+	})
+
+	t.Run("uses configured clock", func(t *testing.T) {
+		fixed := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		store := &firestoreDataStore{clock: fixedClock(fixed)}
+		assert.Equal(t, fixed, store.now())
+	})
+
+	t.Run("builder", func(t *testing.T) {
+		clock := fixedClock(time.Now())
+		b := DataStore("my-project", "my-collection").Clock(clock)
+		assert.Equal(t, clock, b.clock)
+	})
+}
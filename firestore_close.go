@@ -0,0 +1,41 @@
+package ldfirestore
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrStoreClosed is the error returned by data store operations that are started after the store
+// has been closed. See [ExtendedDataStore.CloseWithContext].
+var ErrStoreClosed = errors.New("the data store has been closed")
+
+func (store *firestoreDataStore) CloseWithContext(ctx context.Context) error {
+	if !store.beginClose() {
+		return nil
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		store.inFlight.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		store.loggers.Warnf("Canceling Firestore operations that were still in progress when the store was closed: %s",
+			ctx.Err())
+	}
+	return store.closeNow()
+}
+
+// beginClose makes new operations fail with ErrStoreClosed. It returns false if the store was
+// already closed.
+func (store *firestoreDataStore) beginClose() bool {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.closed {
+		return false
+	}
+	store.closed = true
+	return true
+}
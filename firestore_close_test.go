@@ -0,0 +1,82 @@
+package ldfirestore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClose(t *testing.T) {
+	build := func(t *testing.T) *firestoreDataStore {
+		ds, err := DataStore(testProjectID, testCollectionName).ClientOptions(makeTestOptions()...).
+			Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		return ds.(*firestoreDataStore)
+	}
+
+	t.Run("can be called more than once", func(t *testing.T) {
+		store := build(t)
+		require.NoError(t, store.Close())
+		require.NoError(t, store.Close())
+		require.NoError(t, store.CloseWithContext(context.Background()))
+	})
+
+	t.Run("operations fail after closing", func(t *testing.T) {
+		store := build(t)
+		require.NoError(t, store.Close())
+		_, err := store.Get(ldstoreimpl.Features(), "flag1")
+		assert.True(t, errors.Is(err, ErrStoreClosed), "unexpected error: %s", err)
+	})
+
+	t.Run("waits for operations in progress", func(t *testing.T) {
+		store := build(t)
+		done, err := store.beginOperation(false)
+		require.NoError(t, err)
+
+		closed := make(chan error)
+		go func() { closed <- store.CloseWithContext(context.Background()) }()
+		select {
+		case <-closed:
+			require.Fail(t, "CloseWithContext returned before the operation was done")
+		case <-time.After(50 * time.Millisecond):
+		}
+		assert.NoError(t, store.context.Err())
+		_, err = store.beginOperation(true)
+		assert.Equal(t, ErrStoreClosed, err)
+
+		done()
+		select {
+		case err := <-closed:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			require.Fail(t, "timed out waiting for CloseWithContext")
+		}
+		assert.Error(t, store.context.Err())
+	})
+
+	t.Run("cancels operations when the context ends", func(t *testing.T) {
+		store := build(t)
+		done, err := store.beginOperation(false)
+		require.NoError(t, err)
+		defer done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		require.NoError(t, store.CloseWithContext(ctx))
+		assert.Error(t, store.context.Err())
+	})
+}
+
+func TestBigSegmentStoreCloseMoreThanOnce(t *testing.T) {
+	store, err := BigSegmentStore(testProjectID, testCollectionName).ClientOptions(makeTestOptions()...).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+	require.NoError(t, store.Close())
+}
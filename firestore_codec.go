@@ -0,0 +1,150 @@
+package ldfirestore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// PayloadCodec transforms the serialized flag or segment in each document, for instance to compress
+// or encrypt it. See [StoreBuilder.PayloadCodec].
+//
+// Implementations must be safe for concurrent use.
+type PayloadCodec interface {
+	// Format returns a name that identifies the encoding. It is stored in each document that the
+	// codec encodes, so that the document can be decoded by the same codec later. The name must not
+	// change once data has been written with it, and must not be one of the names used for built-in
+	// encodings: "gzip", "zstd", or "map".
+	Format() string
+
+	// Encode transforms a serialized item before it is written. If it returns an error, the write
+	// fails.
+	Encode(item []byte) ([]byte, error)
+
+	// Decode reverses Encode. If it returns an error, the document is treated as if it did not
+	// exist, and a warning is logged.
+	Decode(data []byte) ([]byte, error)
+}
+
+// fieldEncoding records how the item field of a document was encoded. It is absent for documents
+// whose item is a plain JSON string or bytes.
+const fieldEncoding = "encoding"
+
+var builtInEncodings = map[string]bool{encodingGzip: true, encodingZstd: true, encodingMap: true}
+
+func (b builderOptions) validateCodec() error {
+	if b.codec == nil {
+		return nil
+	}
+	format := b.codec.Format()
+	if format == "" {
+		return errors.New("PayloadCodec format must not be empty")
+	}
+	if builtInEncodings[format] {
+		return fmt.Errorf("PayloadCodec format %q is reserved for a built-in encoding", format)
+	}
+	if b.compression != CompressionNone || b.structured {
+		return errors.New("PayloadCodec cannot be combined with Compression or StructuredItems")
+	}
+	return nil
+}
+
+// payloadCodec returns the codec used for writing items, or nil if items are not encoded.
+func (store *firestoreDataStore) payloadCodec() PayloadCodec {
+	if store.codec != nil {
+		return store.codec
+	}
+	return store.compression.codec()
+}
+
+// setItemPayload stores a serialized item in the fields of a document that is being written,
+// encoding it if the store is configured to do so. Encoded items are always stored as bytes; other
+// items are stored as strings unless the store is configured to store them as bytes or maps.
+func (store *firestoreDataStore) setItemPayload(data map[string]any, itemJSON []byte) error {
+	if err := store.encodeItemPayload(data, itemJSON); err != nil {
+		return err
+	}
+	store.setChecksum(data, itemJSON)
+	return nil
+}
+
+func (store *firestoreDataStore) encodeItemPayload(data map[string]any, itemJSON []byte) error {
+	if store.structured {
+		if props, ok := structuredItem(itemJSON); ok {
+			data[fieldItem] = props
+			data[fieldEncoding] = encodingMap
+			return nil
+		}
+	}
+	if codec := store.payloadCodec(); codec != nil {
+		encoded, err := codec.Encode(itemJSON)
+		if err != nil {
+			return fmt.Errorf("failed to encode item with %q codec: %w", codec.Format(), err)
+		}
+		data[fieldItem] = encoded
+		data[fieldEncoding] = codec.Format()
+		return nil
+	}
+	if store.itemBytes {
+		data[fieldItem] = itemJSON
+	} else {
+		data[fieldItem] = string(itemJSON)
+	}
+	delete(data, fieldEncoding)
+	return nil
+}
+
+// itemPayload returns the serialized item from the fields of a document, decoding it if necessary.
+// Documents written with any of the built-in encodings can be read, regardless of how the store is
+// configured, so that the setting can be changed without rewriting existing data. Documents written
+// with a custom codec can only be read if the store is configured with that codec. If the document has
+// a checksum that does not match the item, it returns an error.
+func (store *firestoreDataStore) itemPayload(data map[string]any) ([]byte, error) {
+	itemJSON, err := store.decodeItemPayload(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyChecksum(data, itemJSON); err != nil {
+		return nil, err
+	}
+	return itemJSON, nil
+}
+
+// DecodeItem returns the serialized flag or segment in the fields of a document written by the data
+// store, decoding it as the store would when reading it. It is for tools, such as ldfirestore-admin,
+// that read the collection directly. It returns an error for an item that was encoded with a custom
+// [PayloadCodec] or stored in an [OverflowStore], since neither is available to it, and for an item
+// whose checksum does not match.
+func DecodeItem(fields map[string]any) ([]byte, error) {
+	return (&firestoreDataStore{}).itemPayload(fields)
+}
+
+func (store *firestoreDataStore) decodeItemPayload(data map[string]any) ([]byte, error) {
+	if overflowed, _ := data[fieldOverflow].(bool); overflowed {
+		return store.overflowPayload(data)
+	}
+
+	var raw []byte
+	switch v := data[fieldItem].(type) {
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	case map[string]any:
+		return json.Marshal(v)
+	}
+
+	encoding, _ := data[fieldEncoding].(string)
+	switch {
+	case encoding == "":
+		return raw, nil
+	case store.codec != nil && encoding == store.codec.Format():
+		return store.codec.Decode(raw)
+	case encoding == encodingGzip:
+		return gzipCodec{}.Decode(raw)
+	case encoding == encodingZstd:
+		return zstdCodec{}.Decode(raw)
+	default:
+		return nil, fmt.Errorf("unknown item encoding %q", encoding)
+	}
+}
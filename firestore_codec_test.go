@@ -0,0 +1,120 @@
+package ldfirestore
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reverseCodec is a trivial codec that reverses the bytes of each item.
+type reverseCodec struct {
+	format    string
+	encodeErr error
+}
+
+func (c reverseCodec) Format() string { return c.format }
+
+func (c reverseCodec) Encode(item []byte) ([]byte, error) {
+	if c.encodeErr != nil {
+		return nil, c.encodeErr
+	}
+	return reversed(item), nil
+}
+
+func (c reverseCodec) Decode(data []byte) ([]byte, error) {
+	return reversed(data), nil
+}
+
+func reversed(data []byte) []byte {
+	out := bytes.Clone(data)
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+func TestPayloadCodec(t *testing.T) {
+	itemJSON := []byte(`{"key":"flag1","version":1}`)
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: itemJSON}
+
+	t.Run("items are encoded and decoded with the codec", func(t *testing.T) {
+		store := &firestoreDataStore{codec: reverseCodec{format: "reverse"}}
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", item)
+		require.NoError(t, err)
+		assert.Equal(t, "reverse", data[fieldEncoding])
+		assert.Equal(t, reversed(itemJSON), data[fieldItem])
+
+		decoded, err := store.itemPayload(data)
+		require.NoError(t, err)
+		assert.Equal(t, itemJSON, decoded)
+	})
+
+	t.Run("documents with other encodings can still be read", func(t *testing.T) {
+		gzipStore := &firestoreDataStore{compression: CompressionGzip}
+		data, err := gzipStore.encodeItem(ldstoreimpl.Features(), "flag1", item)
+		require.NoError(t, err)
+
+		store := &firestoreDataStore{codec: reverseCodec{format: "reverse"}}
+		decoded, err := store.itemPayload(data)
+		require.NoError(t, err)
+		assert.Equal(t, itemJSON, decoded)
+
+		decoded, err = store.itemPayload(map[string]any{fieldItem: string(itemJSON)})
+		require.NoError(t, err)
+		assert.Equal(t, itemJSON, decoded)
+	})
+
+	t.Run("documents written with the codec cannot be read without it", func(t *testing.T) {
+		store := &firestoreDataStore{codec: reverseCodec{format: "reverse"}}
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", item)
+		require.NoError(t, err)
+
+		_, err = (&firestoreDataStore{}).itemPayload(data)
+		assert.Error(t, err)
+	})
+
+	t.Run("documents can be decoded without a store", func(t *testing.T) {
+		zstdStore := &firestoreDataStore{compression: CompressionZstd}
+		data, err := zstdStore.encodeItem(ldstoreimpl.Features(), "flag1", item)
+		require.NoError(t, err)
+		decoded, err := DecodeItem(data)
+		require.NoError(t, err)
+		assert.Equal(t, itemJSON, decoded)
+
+		store := &firestoreDataStore{codec: reverseCodec{format: "reverse"}}
+		data, err = store.encodeItem(ldstoreimpl.Features(), "flag1", item)
+		require.NoError(t, err)
+		_, err = DecodeItem(data)
+		assert.Error(t, err)
+	})
+
+	t.Run("encoding error fails the write", func(t *testing.T) {
+		encodeErr := errors.New("no key")
+		store := &firestoreDataStore{
+			codec:   reverseCodec{format: "reverse", encodeErr: encodeErr},
+			loggers: ldlogtest.NewMockLog().Loggers,
+		}
+		_, err := store.encodeItem(ldstoreimpl.Features(), "flag1", item)
+		assert.ErrorIs(t, err, encodeErr)
+	})
+
+	t.Run("error for invalid codec configuration", func(t *testing.T) {
+		for _, b := range []*StoreBuilder[subsystems.PersistentDataStore]{
+			DataStore("my-project", "my-collection").PayloadCodec(reverseCodec{}),
+			DataStore("my-project", "my-collection").PayloadCodec(reverseCodec{format: encodingZstd}),
+			DataStore("my-project", "my-collection").PayloadCodec(reverseCodec{format: "reverse"}).
+				Compression(CompressionGzip),
+		} {
+			ds, err := b.Build(subsystems.BasicClientContext{})
+			assert.Error(t, err)
+			assert.Nil(t, ds)
+		}
+	})
+}
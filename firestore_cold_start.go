@@ -0,0 +1,56 @@
+package ldfirestore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// DefaultColdStartOperationTimeout is the operation timeout that is used in cold-start mode if no
+// other timeout was specified. See [StoreBuilder.ColdStartOptimized].
+const DefaultColdStartOperationTimeout = 2 * time.Second
+
+var (
+	sharedClients     = make(map[string]*firestore.Client)
+	sharedClientsLock sync.Mutex
+)
+
+// getSharedClient returns the process-wide client for the builder's project and database, creating
+// it if necessary. Shared clients are never closed, so that they can be reused by later store
+// instances (for instance, in later invocations of a serverless function that reuse the same process).
+func getSharedClient(builder builderOptions) (*firestore.Client, error) {
+	sharedClientsLock.Lock()
+	defer sharedClientsLock.Unlock()
+
+	clientKey := builder.projectID + "/" + builder.databaseID
+	if client, ok := sharedClients[clientKey]; ok {
+		return client, nil
+	}
+
+	if builder.projectID == "" {
+		return nil, errors.New("project ID is required")
+	}
+	client, err := newClient(context.Background(), builder)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedClients[clientKey] = client
+	return client, nil
+}
+
+func (b builderOptions) effectiveOperationTimeout() time.Duration {
+	switch {
+	case b.operationTimeout != 0:
+		return b.operationTimeout
+	case b.coldStart:
+		return DefaultColdStartOperationTimeout
+	case b.emulatorHost() != "":
+		return DefaultEmulatorOperationTimeout
+	default:
+		return 0
+	}
+}
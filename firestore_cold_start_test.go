@@ -0,0 +1,43 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColdStartOptimized(t *testing.T) {
+	t.Run("stores share a client", func(t *testing.T) {
+		ds, err := DataStore("cold-start-project", testCollectionName).ClientOptions(makeTestOptions()...).
+			ColdStartOptimized(true).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		bs, err := BigSegmentStore("cold-start-project", testCollectionName).ClientOptions(makeTestOptions()...).
+			ColdStartOptimized(true).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+
+		dsImpl := ds.(*firestoreDataStore)
+		bsImpl := bs.(*firestoreBigSegmentStoreImpl)
+		assert.Same(t, dsImpl.client, bsImpl.client)
+		assert.False(t, dsImpl.ownsClient)
+		assert.False(t, bsImpl.ownsClient)
+		assert.True(t, dsImpl.skipExistingScan)
+
+		require.NoError(t, ds.Close())
+		require.NoError(t, bs.Close())
+	})
+
+	t.Run("operation timeout", func(t *testing.T) {
+		t.Setenv(emulatorHostEnvVar, "") // the emulator would change the default timeout
+		b := DataStore("my-project", "my-collection")
+		assert.Equal(t, time.Duration(0), b.effectiveOperationTimeout())
+
+		b.ColdStartOptimized(true)
+		assert.Equal(t, DefaultColdStartOperationTimeout, b.effectiveOperationTimeout())
+
+		b.OperationTimeout(time.Second * 5)
+		assert.Equal(t, time.Second*5, b.effectiveOperationTimeout())
+	})
+}
@@ -0,0 +1,116 @@
+package ldfirestore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// PayloadCompression specifies how the serialized item in each document is compressed. See
+// [StoreBuilder.Compression].
+type PayloadCompression int
+
+const (
+	// CompressionNone means that items are stored as JSON strings. This is the default.
+	CompressionNone PayloadCompression = iota
+
+	// CompressionGzip means that items are compressed with gzip.
+	CompressionGzip
+
+	// CompressionZstd means that items are compressed with Zstandard, which is usually faster than
+	// gzip and compresses at least as well.
+	CompressionZstd
+)
+
+const (
+	encodingGzip = "gzip"
+	encodingZstd = "zstd"
+)
+
+// The zstd encoder and decoder are safe for concurrent use with EncodeAll and DecodeAll, so a single
+// instance of each is shared by all stores. Creating them can only fail if invalid options are given.
+var (
+	zstdEncoder = sync.OnceValue(func() *zstd.Encoder {
+		enc, _ := zstd.NewWriter(nil)
+		return enc
+	})
+	zstdDecoder = sync.OnceValue(func() *zstd.Decoder {
+		dec, _ := zstd.NewReader(nil)
+		return dec
+	})
+)
+
+func (c PayloadCompression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return encodingGzip
+	case CompressionZstd:
+		return encodingZstd
+	default:
+		return fmt.Sprintf("PayloadCompression(%d)", int(c))
+	}
+}
+
+func validateCompression(c PayloadCompression) error {
+	switch c {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+		return nil
+	default:
+		return fmt.Errorf("unknown payload compression %s", c)
+	}
+}
+
+// codec returns the PayloadCodec that implements the compression, or nil for CompressionNone.
+func (c PayloadCompression) codec() PayloadCodec {
+	switch c {
+	case CompressionGzip:
+		return gzipCodec{}
+	case CompressionZstd:
+		return zstdCodec{}
+	default:
+		return nil
+	}
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Format() string { return encodingGzip }
+
+func (gzipCodec) Encode(item []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(item); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decode(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	return io.ReadAll(r)
+}
+
+type zstdCodec struct{}
+
+func (zstdCodec) Format() string { return encodingZstd }
+
+func (zstdCodec) Encode(item []byte) ([]byte, error) {
+	return zstdEncoder().EncodeAll(item, nil), nil
+}
+
+func (zstdCodec) Decode(data []byte) ([]byte, error) {
+	return zstdDecoder().DecodeAll(data, nil)
+}
@@ -0,0 +1,101 @@
+package ldfirestore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	itemJSON := []byte(`{"key":"flag1","version":1,"rules":[` +
+		strings.Repeat(`{"id":"rule","clauses":[{"attribute":"key","op":"in","values":["a","b","c"]}]},`, 100) +
+		`{"id":"last"}]}`)
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: itemJSON}
+
+	for _, compression := range []PayloadCompression{CompressionNone, CompressionGzip, CompressionZstd} {
+		t.Run(compression.String(), func(t *testing.T) {
+			store := &firestoreDataStore{compression: compression, loggers: ldlogtest.NewMockLog().Loggers}
+			data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", item)
+			require.NoError(t, err)
+
+			if compression == CompressionNone {
+				assert.Equal(t, string(itemJSON), data[fieldItem])
+				assert.NotContains(t, data, fieldEncoding)
+			} else {
+				assert.Equal(t, compression.String(), data[fieldEncoding])
+				compressed, ok := data[fieldItem].([]byte)
+				require.True(t, ok)
+				assert.Less(t, len(compressed), len(itemJSON)/5)
+			}
+
+			decoded, err := store.itemPayload(data)
+			require.NoError(t, err)
+			assert.Equal(t, itemJSON, decoded)
+		})
+	}
+
+	t.Run("uncompressed items stored as bytes", func(t *testing.T) {
+		store := &firestoreDataStore{itemBytes: true}
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", item)
+		require.NoError(t, err)
+		assert.Equal(t, itemJSON, data[fieldItem])
+		assert.NotContains(t, data, fieldEncoding)
+
+		decoded, err := store.itemPayload(data)
+		require.NoError(t, err)
+		assert.Equal(t, itemJSON, decoded)
+	})
+
+	t.Run("documents are read according to their own encoding", func(t *testing.T) {
+		gzipStore := &firestoreDataStore{compression: CompressionGzip}
+		data, err := gzipStore.encodeItem(ldstoreimpl.Features(), "flag1", item)
+		require.NoError(t, err)
+		data[fieldVersion] = int64(1) // as it would be read back from Firestore
+
+		zstdStore := &firestoreDataStore{compression: CompressionZstd, loggers: ldlogtest.NewMockLog().Loggers}
+		key, decoded, ok := zstdStore.decodeLenient(ldstoreimpl.Features(), "flag1", data)
+		require.True(t, ok)
+		assert.Equal(t, "flag1", key)
+		assert.Equal(t, itemJSON, decoded.SerializedItem)
+
+		decoded2, err := zstdStore.itemPayload(map[string]any{fieldItem: string(itemJSON)})
+		require.NoError(t, err)
+		assert.Equal(t, itemJSON, decoded2)
+	})
+
+	t.Run("error for unknown or corrupt encoding", func(t *testing.T) {
+		store := &firestoreDataStore{}
+		_, err := store.itemPayload(map[string]any{fieldItem: []byte("x"), fieldEncoding: "brotli"})
+		assert.Error(t, err)
+		_, err = store.itemPayload(map[string]any{fieldItem: []byte("not gzip"), fieldEncoding: encodingGzip})
+		assert.Error(t, err)
+		_, err = store.itemPayload(map[string]any{fieldItem: []byte("not zstd"), fieldEncoding: encodingZstd})
+		assert.Error(t, err)
+	})
+
+	t.Run("truncated rules are compressed", func(t *testing.T) {
+		store := &firestoreDataStore{compression: CompressionZstd}
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", item)
+		require.NoError(t, err)
+		truncated, ok := store.truncateFlagRules(data)
+		require.True(t, ok)
+		assert.Equal(t, encodingZstd, truncated[fieldEncoding])
+		decoded, err := store.itemPayload(truncated)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"key":"flag1","version":1,"rules":[]}`, string(decoded))
+	})
+
+	t.Run("error for unknown compression", func(t *testing.T) {
+		ds, err := DataStore("my-project", "my-collection").Compression(PayloadCompression(99)).
+			Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, ds)
+		assert.Contains(t, err.Error(), "unknown payload compression")
+	})
+}
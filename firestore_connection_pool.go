@@ -0,0 +1,29 @@
+package ldfirestore
+
+import (
+	"fmt"
+	"slices"
+
+	"google.golang.org/api/option"
+)
+
+// MaxConnectionPoolSize is the largest value allowed for [StoreBuilder.ConnectionPoolSize].
+const MaxConnectionPoolSize = 64
+
+func validateConnectionPoolSize(size int) error {
+	if size < 0 || size > MaxConnectionPoolSize {
+		return fmt.Errorf("connection pool size must be between 1 and %d, but was %d", MaxConnectionPoolSize, size)
+	}
+	return nil
+}
+
+// withConnectionPool returns a copy of the builder options whose client options set the size of the
+// gRPC connection pool, if one was configured.
+func withConnectionPool(builder builderOptions) builderOptions {
+	if builder.connPoolSize <= 0 {
+		return builder
+	}
+	builder.clientOptions = slices.Concat(builder.clientOptions,
+		[]option.ClientOption{option.WithGRPCConnectionPool(builder.connPoolSize)})
+	return builder
+}
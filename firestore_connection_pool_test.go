@@ -0,0 +1,44 @@
+package ldfirestore
+
+import (
+	"testing"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionPoolSize(t *testing.T) {
+	t.Run("builder options", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection")
+		assert.Len(t, withConnectionPool(b.builderOptions).clientOptions, 0)
+
+		b.ConnectionPoolSize(4)
+		assert.Equal(t, 4, b.connPoolSize)
+		assert.Len(t, withConnectionPool(b.builderOptions).clientOptions, 1)
+		assert.Len(t, b.clientOptions, 0)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		assert.NoError(t, validateConnectionPoolSize(0))
+		assert.NoError(t, validateConnectionPoolSize(1))
+		assert.NoError(t, validateConnectionPoolSize(MaxConnectionPoolSize))
+		assert.Error(t, validateConnectionPoolSize(-1))
+		assert.Error(t, validateConnectionPoolSize(MaxConnectionPoolSize+1))
+	})
+
+	t.Run("store uses pool", func(t *testing.T) {
+		store, err := DataStore("my-project", "my-collection").ClientOptions(makeTestOptions()...).
+			ConnectionPoolSize(2).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		assert.NoError(t, store.Close())
+	})
+
+	t.Run("error for invalid size", func(t *testing.T) {
+		bs, err := BigSegmentStore("my-project", "my-collection").ConnectionPoolSize(1000).
+			Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, bs)
+		assert.Contains(t, err.Error(), "connection pool size")
+	})
+}
@@ -0,0 +1,86 @@
+package ldfirestore
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+// queryDocumentsFunc runs a query, either on its own or as part of a transaction.
+type queryDocumentsFunc func(query firestore.Query) *firestore.DocumentIterator
+
+// queryDocuments returns a queryDocumentsFunc that runs each query on its own.
+func queryDocuments(ctx context.Context) queryDocumentsFunc {
+	return func(query firestore.Query) *firestore.DocumentIterator {
+		return query.Documents(ctx)
+	}
+}
+
+// getAllConsistent implements GetAll when the store is configured with ConsistentGetAll. Every query
+// that GetAll makes, whether it reads all of the documents at once, a page at a time, or in
+// partitions, runs in one read-only transaction, so they all see the collection as it was at the same
+// point in time.
+func (store *firestoreDataStore) getAllConsistent(
+	kind ldstoretypes.DataKind,
+) ([]ldstoretypes.KeyedSerializedItemDescriptor, error) {
+	var results []ldstoretypes.KeyedSerializedItemDescriptor
+	err := store.retry.do(store.context, store.onRetry, func() error {
+		ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+		defer cancel()
+		return store.runTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+			documents := func(query firestore.Query) *firestore.DocumentIterator {
+				return tx.Documents(query)
+			}
+			var err error
+			switch {
+			case store.getAllPageSize > 0:
+				results, err = store.readPages(documents, kind, store.getAllPageSize)
+			case store.usePartitionedGetAll():
+				// The partitions still run in the same transaction, but one at a time, since a
+				// transaction must not be used concurrently
+				results, err = store.getAllPartitioned(ctx, kind, documents, true)
+			default:
+				results, err = store.readPartition(documents, kind, store.getAllQuery(kind))
+			}
+			return err
+		}, firestore.ReadOnly)
+	})
+	if err != nil {
+		return nil, newStoreError("GetAll", kind, "", err)
+	}
+
+	if log, ok := store.debugLog(LogReads); ok {
+		log.Debugf("Read %d item(s) in a read-only transaction (namespace=%s)", len(results),
+			store.namespaceForKind(kind))
+	}
+	return results, nil
+}
+
+// readPages reads the items of a data kind with a separate query for each page of pageSize
+// documents. Unlike forEachItemPage, it does not retry each page on its own, since all of the pages
+// must be read with the same documents function.
+func (store *firestoreDataStore) readPages(
+	documents queryDocumentsFunc,
+	kind ldstoretypes.DataKind,
+	pageSize int,
+) ([]ldstoretypes.KeyedSerializedItemDescriptor, error) {
+	var results []ldstoretypes.KeyedSerializedItemDescriptor
+	pageQuery := store.getAllQuery(kind).OrderBy(firestore.DocumentID, firestore.Asc).Limit(pageSize)
+	for {
+		docs, err := documents(pageQuery).GetAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			if key, item, ok := store.decodeDocumentWithRepair(kind, doc); ok {
+				store.observeVersion(kind, key, item.Version)
+				results = append(results, ldstoretypes.KeyedSerializedItemDescriptor{Key: key, Item: item})
+			}
+		}
+		if len(docs) < pageSize {
+			return results, nil
+		}
+		pageQuery = pageQuery.StartAfter(docs[len(docs)-1])
+	}
+}
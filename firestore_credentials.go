@@ -0,0 +1,48 @@
+package ldfirestore
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+
+	"google.golang.org/api/option"
+)
+
+// validateCredentials checks that at most one source of credentials was configured with
+// CredentialsFile, CredentialsJSON, FirestoreClient, or SharedClient.
+func (b builderOptions) validateCredentials() error {
+	sources := 0
+	if b.credentialsFile != "" {
+		sources++
+	}
+	if b.credentialsJSON != nil {
+		sources++
+		if !json.Valid(b.credentialsJSON) {
+			return errors.New("credentials JSON is not valid JSON")
+		}
+	}
+	if sources > 1 {
+		return errors.New("only one of CredentialsFile and CredentialsJSON can be specified")
+	}
+	if sources > 0 && (b.client != nil || b.sharedClient != nil) {
+		return errors.New("credentials cannot be specified together with FirestoreClient or SharedClient, " +
+			"since the provided client already has its own credentials")
+	}
+	return nil
+}
+
+// withCredentials returns a copy of the builder options whose client options include the service
+// account credentials from CredentialsFile or CredentialsJSON, if either was specified.
+func withCredentials(builder builderOptions) builderOptions {
+	var credOption option.ClientOption
+	switch {
+	case builder.credentialsFile != "":
+		credOption = option.WithAuthCredentialsFile(option.ServiceAccount, builder.credentialsFile)
+	case builder.credentialsJSON != nil:
+		credOption = option.WithAuthCredentialsJSON(option.ServiceAccount, builder.credentialsJSON)
+	default:
+		return builder
+	}
+	builder.clientOptions = slices.Concat(builder.clientOptions, []option.ClientOption{credOption})
+	return builder
+}
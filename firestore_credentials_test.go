@@ -0,0 +1,56 @@
+package ldfirestore
+
+import (
+	"testing"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentials(t *testing.T) {
+	t.Run("builder options", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection")
+		assert.Len(t, withCredentials(b.builderOptions).clientOptions, 0)
+
+		b.CredentialsFile("/path/to/key.json")
+		assert.Equal(t, "/path/to/key.json", b.credentialsFile)
+		assert.NoError(t, b.validateCredentials())
+		assert.Len(t, withCredentials(b.builderOptions).clientOptions, 1)
+		assert.Len(t, b.clientOptions, 0)
+
+		b = DataStore("my-project", "my-collection").CredentialsJSON([]byte(`{"type":"service_account"}`))
+		assert.NoError(t, b.validateCredentials())
+		assert.Len(t, withCredentials(b.builderOptions).clientOptions, 1)
+	})
+
+	t.Run("error for more than one credential source", func(t *testing.T) {
+		ds, err := DataStore("my-project", "my-collection").
+			CredentialsFile("/path/to/key.json").
+			CredentialsJSON([]byte(`{}`)).
+			Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, ds)
+		assert.Contains(t, err.Error(), "only one of")
+
+		client, err := createTestClient()
+		require.NoError(t, err)
+		defer func() { _ = client.Close() }()
+		bs, err := BigSegmentStore("my-project", "my-collection").
+			FirestoreClient(client).
+			CredentialsFile("/path/to/key.json").
+			Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, bs)
+		assert.Contains(t, err.Error(), "FirestoreClient")
+	})
+
+	t.Run("error for invalid JSON", func(t *testing.T) {
+		ds, err := DataStore("my-project", "my-collection").
+			CredentialsJSON([]byte("not json")).
+			Build(subsystems.BasicClientContext{})
+		require.Error(t, err)
+		assert.Nil(t, ds)
+		assert.Contains(t, err.Error(), "not valid JSON")
+	})
+}
@@ -0,0 +1,319 @@
+package ldfirestore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"github.com/launchdarkly/go-server-sdk/v7/interfaces"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+// DataSource returns a configurable builder for a data source that reads flag data from a Firestore
+// collection that is written by another process, such as the Relay Proxy or an SDK using
+// [DataStore], and receives changes from Firestore as they happen.
+//
+// This is an alternative to daemon mode, where the SDK reads from the data store on demand and
+// caches what it reads: instead, the SDK keeps all of the data in memory, as it does when it connects
+// to LaunchDarkly, and the data source keeps that up to date with a snapshot listener for each data
+// kind. Only the options of the builder that apply to reading data are used; the data source never
+// writes to Firestore. Until the collection has been initialized by the process that writes it, the
+// data source reports that it is still initializing, and does not give any data to the SDK.
+//
+//	config.DataSource = ldfirestore.DataSource("my-project", "launchdarkly")
+//	config.Events = ldcomponents.NoEvents() // if events should not be sent to LaunchDarkly
+func DataSource(projectID, collection string) *StoreBuilder[subsystems.DataSource] {
+	return &StoreBuilder[subsystems.DataSource]{
+		builderOptions: builderOptions{
+			projectID:   projectID,
+			collection:  collection,
+			retryPolicy: defaultRetryPolicy(),
+		},
+		factory: createDataSource,
+	}
+}
+
+func createDataSource(
+	builder *StoreBuilder[subsystems.DataSource],
+	clientContext subsystems.ClientContext,
+) (subsystems.DataSource, error) {
+	return newFirestoreDataSourceImpl(builder.builderOptions, clientContext.GetDataSourceUpdateSink(),
+		clientContext.GetLogging().Loggers)
+}
+
+// firestoreDataSource delivers the data in a Firestore collection to the SDK. It keeps its own copy of
+// the data, since the SDK can only be given a full data set for all data kinds at once: when one of
+// the snapshot listeners is restarted, its first snapshot replaces the items of its kind, and then the
+// full data set is given to the SDK again, so that items removed in the meantime are not left behind.
+// Nothing is given to the SDK until the inited document exists, since an empty collection that has
+// never been written would otherwise look like a valid, empty data set.
+type firestoreDataSource struct {
+	store            *firestoreDataStore
+	sink             subsystems.DataSourceUpdateSink
+	loggers          ldlog.Loggers
+	kinds            []ldstoretypes.DataKind
+	data             map[string]map[string]ldstoretypes.ItemDescriptor // by kind name, then key
+	collectionInited bool                                              // true once the inited document exists
+	initialized      bool
+	reportedNotInit  bool
+	readyOnce        sync.Once
+	lock             sync.Mutex
+}
+
+func newFirestoreDataSourceImpl(
+	builder builderOptions,
+	sink subsystems.DataSourceUpdateSink,
+	loggers ldlog.Loggers,
+) (*firestoreDataSource, error) {
+	storeOptions := builder
+	storeOptions.readOnly = true
+	storeOptions.writerLeaseDuration = 0
+	storeOptions.onChange = nil
+	storeOptions.itemCache = ItemCacheOptions{}
+	storeOptions.snapshotFallback = SnapshotFallbackOptions{}
+	storeOptions.outageBuffer = OutageBufferOptions{}
+	store, err := newFirestoreDataStoreImpl(storeOptions, loggers)
+	if err != nil {
+		return nil, err
+	}
+	return &firestoreDataSource{
+		store:   store,
+		sink:    sink,
+		loggers: store.loggers,
+		kinds:   ldstoreimpl.AllKinds(),
+		data:    make(map[string]map[string]ldstoretypes.ItemDescriptor),
+	}, nil
+}
+
+func (ds *firestoreDataSource) IsInitialized() bool {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	return ds.initialized
+}
+
+func (ds *firestoreDataSource) Start(closeWhenReady chan<- struct{}) {
+	go ds.watch("the inited document", func(ctx context.Context) (bool, error) {
+		return ds.listenToInited(ctx, closeWhenReady)
+	})
+	for _, kind := range ds.kinds {
+		go ds.watch(kind.GetName(), func(ctx context.Context) (bool, error) {
+			return ds.listen(ctx, kind, closeWhenReady)
+		})
+	}
+}
+
+func (ds *firestoreDataSource) Close() error {
+	return ds.store.Close()
+}
+
+// watch keeps a snapshot listener open until the data source is closed, or until listen returns
+// without an error, restarting it with exponential backoff if it fails.
+func (ds *firestoreDataSource) watch(what string, listen func(context.Context) (bool, error)) {
+	ctx := ds.store.context
+	backoff := watchInitialBackoff
+	for {
+		received, err := listen(ctx)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		if received {
+			backoff = watchInitialBackoff
+		}
+		ds.loggers.Warnf("Snapshot listener for %s in collection %q failed; restarting in %s: %s",
+			what, ds.store.collection, backoff, err)
+		ds.sink.UpdateStatus(interfaces.DataSourceStateInterrupted, interfaces.DataSourceErrorInfo{
+			Kind:    interfaces.DataSourceErrorKindNetworkError,
+			Message: err.Error(),
+			Time:    ds.store.now(),
+		})
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, watchMaxBackoff)
+	}
+}
+
+// listen runs a snapshot listener until it fails, and returns the error along with whether any
+// snapshot was received. The first snapshot contains every item of the kind; later ones contain
+// changes.
+func (ds *firestoreDataSource) listen(
+	ctx context.Context,
+	kind ldstoretypes.DataKind,
+	closeWhenReady chan<- struct{},
+) (bool, error) {
+	iter := ds.store.queryForKind(kind).Snapshots(ctx)
+	defer iter.Stop()
+
+	received := false
+	for {
+		snap, err := iter.Next()
+		if err != nil {
+			return received, err
+		}
+		if !received {
+			docs, err := snap.Documents.GetAll()
+			if err != nil {
+				return false, err
+			}
+			items := make(map[string]ldstoretypes.ItemDescriptor, len(docs))
+			for _, doc := range docs {
+				if key, item, ok := ds.decode(kind, doc); ok {
+					items[key] = item
+				}
+			}
+			ds.replaceKind(kind, items, closeWhenReady)
+			received = true
+			continue
+		}
+		for _, change := range snap.Changes {
+			ds.applyChange(kind, change)
+		}
+	}
+}
+
+// listenToInited runs a snapshot listener on the inited document until the document exists, in which
+// case it returns a nil error, or until the listener fails. Until then, the data source reports that
+// it is still initializing. Like the data store, it does not check the document again once it has
+// been found.
+func (ds *firestoreDataSource) listenToInited(ctx context.Context, closeWhenReady chan<- struct{}) (bool, error) {
+	iter := ds.store.collectionRef(ds.store.collection).Doc(ds.store.initedDocID()).Snapshots(ctx)
+	defer iter.Stop()
+
+	received := false
+	for {
+		doc, err := iter.Next()
+		if err != nil {
+			return received, err
+		}
+		received = true
+		if doc.Exists() && ds.store.checkInitedDoc(doc.Data()) {
+			ds.setCollectionInitialized(closeWhenReady)
+			return true, nil
+		}
+		ds.reportNotInitialized()
+	}
+}
+
+// reportNotInitialized tells the SDK that the data source is still initializing because the
+// collection has not been initialized.
+func (ds *firestoreDataSource) reportNotInitialized() {
+	ds.lock.Lock()
+	alreadyReported := ds.reportedNotInit
+	ds.reportedNotInit = true
+	ds.lock.Unlock()
+	if !alreadyReported {
+		ds.loggers.Warnf("Firestore collection %q has not been initialized; waiting for it to be initialized "+
+			"before using its data", ds.store.collection)
+	}
+	ds.sink.UpdateStatus(interfaces.DataSourceStateInitializing, interfaces.DataSourceErrorInfo{
+		Kind:    interfaces.DataSourceErrorKindUnknown,
+		Message: fmt.Sprintf("Firestore collection %q has not been initialized", ds.store.collection),
+		Time:    ds.store.now(),
+	})
+}
+
+// setCollectionInitialized records that the inited document exists, and gives the data to the SDK if
+// every kind has already been received.
+func (ds *firestoreDataSource) setCollectionInitialized(closeWhenReady chan<- struct{}) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	if ds.collectionInited {
+		return
+	}
+	ds.collectionInited = true
+	ds.initSDK(closeWhenReady)
+}
+
+// replaceKind stores the full set of items for a data kind. Once every kind has been received, and
+// the inited document exists, the full data set is given to the SDK; after that, this only happens
+// again if a listener was restarted.
+func (ds *firestoreDataSource) replaceKind(
+	kind ldstoretypes.DataKind,
+	items map[string]ldstoretypes.ItemDescriptor,
+	closeWhenReady chan<- struct{},
+) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	ds.data[kind.GetName()] = items
+	ds.initSDK(closeWhenReady)
+}
+
+// initSDK gives the full data set to the SDK, if every kind has been received and the collection has
+// been initialized. It is called with the lock held.
+func (ds *firestoreDataSource) initSDK(closeWhenReady chan<- struct{}) {
+	if !ds.collectionInited || len(ds.data) < len(ds.kinds) {
+		return
+	}
+
+	allData := make([]ldstoretypes.Collection, 0, len(ds.kinds))
+	for _, k := range ds.kinds {
+		coll := ldstoretypes.Collection{Kind: k}
+		for key, item := range ds.data[k.GetName()] {
+			coll.Items = append(coll.Items, ldstoretypes.KeyedItemDescriptor{Key: key, Item: item})
+		}
+		allData = append(allData, coll)
+	}
+	if !ds.sink.Init(allData) {
+		ds.loggers.Warn("The SDK could not store the flag data received from Firestore")
+		return
+	}
+	ds.sink.UpdateStatus(interfaces.DataSourceStateValid, interfaces.DataSourceErrorInfo{})
+	if !ds.initialized {
+		ds.initialized = true
+		ds.loggers.Infof("Received flag data from Firestore collection %q", ds.store.collection)
+	}
+	ds.readyOnce.Do(func() { close(closeWhenReady) })
+}
+
+// applyChange gives a changed item to the SDK. A document that was removed, rather than replaced with
+// a deleted-item placeholder, is treated as a deletion with the next higher version.
+func (ds *firestoreDataSource) applyChange(kind ldstoretypes.DataKind, change firestore.DocumentChange) {
+	var key string
+	var item ldstoretypes.ItemDescriptor
+	if change.Kind == firestore.DocumentRemoved {
+		data := change.Doc.Data()
+		if key, _ = data[fieldKey].(string); key == "" {
+			return
+		}
+		version, _ := data[fieldVersion].(int64)
+		item = ldstoretypes.ItemDescriptor{Version: int(version) + 1}
+	} else {
+		var ok bool
+		if key, item, ok = ds.decode(kind, change.Doc); !ok {
+			return
+		}
+	}
+
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	if items := ds.data[kind.GetName()]; items != nil {
+		items[key] = item
+	}
+	if ds.initialized {
+		ds.sink.Upsert(kind, key, item)
+	}
+}
+
+// decode reads an item from a document, logging a warning if it is invalid.
+func (ds *firestoreDataSource) decode(
+	kind ldstoretypes.DataKind,
+	doc *firestore.DocumentSnapshot,
+) (string, ldstoretypes.ItemDescriptor, bool) {
+	key, serialized, ok := ds.store.decodeDocument(doc)
+	if !ok {
+		return "", ldstoretypes.ItemDescriptor{}, false
+	}
+	item, err := kind.Deserialize(serialized.SerializedItem)
+	if err != nil {
+		ds.loggers.Warnf("Could not parse %s key %q from Firestore document %q: %s", kind, key, doc.Ref.ID, err)
+		return "", ldstoretypes.ItemDescriptor{}, false
+	}
+	return key, item, true
+}
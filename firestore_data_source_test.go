@@ -0,0 +1,180 @@
+package ldfirestore
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-server-sdk/v7/interfaces"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockUpdateSink struct {
+	inits    [][]ldstoretypes.Collection
+	upserts  []ldstoretypes.KeyedItemDescriptor
+	statuses []interfaces.DataSourceState
+	lock     sync.Mutex
+}
+
+func (s *mockUpdateSink) Init(allData []ldstoretypes.Collection) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.inits = append(s.inits, allData)
+	return true
+}
+
+func (s *mockUpdateSink) Upsert(_ ldstoretypes.DataKind, key string, item ldstoretypes.ItemDescriptor) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.upserts = append(s.upserts, ldstoretypes.KeyedItemDescriptor{Key: key, Item: item})
+	return true
+}
+
+func (s *mockUpdateSink) UpdateStatus(state interfaces.DataSourceState, _ interfaces.DataSourceErrorInfo) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.statuses = append(s.statuses, state)
+}
+
+func (s *mockUpdateSink) GetDataStoreStatusProvider() interfaces.DataStoreStatusProvider { return nil }
+
+func (s *mockUpdateSink) upsertCount() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.upserts)
+}
+
+func TestDataSourceInitializesWhenAllKindsReceived(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	sink := &mockUpdateSink{}
+	ds, err := newFirestoreDataSourceImpl(DataSource("my-project", "my-collection").FirestoreClient(client).
+		builderOptions, sink, ldlogtest.NewMockLog().Loggers)
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+	assert.True(t, ds.store.readOnly)
+
+	ready := make(chan struct{})
+	flag := ldstoretypes.ItemDescriptor{Version: 1}
+	ds.replaceKind(ldstoreimpl.Features(), map[string]ldstoretypes.ItemDescriptor{"flag1": flag}, ready)
+	assert.False(t, ds.IsInitialized())
+	assert.Len(t, sink.inits, 0)
+
+	ds.replaceKind(ldstoreimpl.Segments(), map[string]ldstoretypes.ItemDescriptor{}, ready)
+	assert.False(t, ds.IsInitialized()) // until the inited document is found
+	assert.Len(t, sink.inits, 0)
+
+	ds.setCollectionInitialized(ready)
+	assert.True(t, ds.IsInitialized())
+	require.Len(t, sink.inits, 1)
+	assert.Equal(t, []ldstoretypes.Collection{
+		{Kind: ldstoreimpl.Features(), Items: []ldstoretypes.KeyedItemDescriptor{{Key: "flag1", Item: flag}}},
+		{Kind: ldstoreimpl.Segments()},
+	}, sink.inits[0])
+	assert.Equal(t, []interfaces.DataSourceState{interfaces.DataSourceStateValid}, sink.statuses)
+	select {
+	case <-ready:
+	default:
+		assert.Fail(t, "ready channel was not closed")
+	}
+
+	// A restarted listener replaces the items of its kind, and the SDK gets the full data set again
+	ds.replaceKind(ldstoreimpl.Features(), map[string]ldstoretypes.ItemDescriptor{}, ready)
+	require.Len(t, sink.inits, 2)
+	assert.Len(t, sink.inits[1][0].Items, 0)
+}
+
+func TestDataSourceWaitsForCollectionToBeInitialized(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	sink := &mockUpdateSink{}
+	client, err := createTestClient()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+	ds, err := newFirestoreDataSourceImpl(DataSource(testProjectID, testCollectionName).FirestoreClient(client).
+		builderOptions, sink, ldlogtest.NewMockLog().Loggers)
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+
+	ready := make(chan struct{})
+	ds.Start(ready)
+	require.Eventually(t, func() bool {
+		sink.lock.Lock()
+		defer sink.lock.Unlock()
+		return len(sink.statuses) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.False(t, ds.IsInitialized())
+	sink.lock.Lock()
+	assert.Equal(t, interfaces.DataSourceStateInitializing, sink.statuses[0])
+	assert.Len(t, sink.inits, 0)
+	sink.lock.Unlock()
+
+	writer, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = writer.Close() }()
+	require.NoError(t, writer.Init(nil))
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for data source to initialize")
+	}
+	assert.True(t, ds.IsInitialized())
+}
+
+func TestDataSource(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	writer, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = writer.Close() }()
+	flagJSON := func(key string, version int) []byte {
+		return []byte(fmt.Sprintf(`{"key":%q,"version":%d}`, key, version))
+	}
+	require.NoError(t, writer.Init([]ldstoretypes.SerializedCollection{{
+		Kind: ldstoreimpl.Features(),
+		Items: []ldstoretypes.KeyedSerializedItemDescriptor{
+			{Key: "flag1", Item: ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: flagJSON("flag1", 1)}},
+		},
+	}}))
+
+	sink := &mockUpdateSink{}
+	client, err := createTestClient()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+	ds, err := newFirestoreDataSourceImpl(DataSource(testProjectID, testCollectionName).FirestoreClient(client).
+		builderOptions, sink, ldlogtest.NewMockLog().Loggers)
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+
+	ready := make(chan struct{})
+	ds.Start(ready)
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for data source to initialize")
+	}
+	require.True(t, ds.IsInitialized())
+	require.Len(t, sink.inits, 1)
+	require.Len(t, sink.inits[0][0].Items, 1)
+	assert.Equal(t, "flag1", sink.inits[0][0].Items[0].Key)
+
+	_, err = writer.Upsert(ldstoreimpl.Features(), "flag1",
+		ldstoretypes.SerializedItemDescriptor{Version: 2, SerializedItem: flagJSON("flag1", 2)})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return sink.upsertCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, sink.upserts[0].Item.Version)
+}
@@ -0,0 +1,125 @@
+package ldfirestore
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"google.golang.org/api/iterator"
+)
+
+// DebugDocument describes one document in the output of [ExtendedDataStore.DebugDump].
+type DebugDocument struct {
+	// Path is the full path of the document, including its collection and any parent document.
+	Path string
+
+	// ID is the document ID.
+	ID string
+
+	// Namespace is the document's namespace field: the data kind of an item, or the kind of metadata
+	// such as "$inited", along with the prefix.
+	Namespace string
+
+	// Key is the document's key field.
+	Key string
+
+	// Version is the version of the item, or -1 if the document is not an item.
+	Version int
+
+	// Deleted is true if the item is a deletion marker.
+	Deleted bool
+
+	// SchemaVersion is the version of the document format that the document was written in.
+	SchemaVersion int
+
+	// Size is a rough estimate of the stored size of the document, in bytes.
+	Size int
+
+	// UpdateTime is when the document was last changed.
+	UpdateTime time.Time
+}
+
+// debugDumpCollections returns the collections that may hold documents for a prefix: the store's
+// collection, and the collections of flags and segments if they are elsewhere because of KindRoute
+// or SubcollectionPerKind.
+func (store *firestoreDataStore) debugDumpCollections(prefix string) []*firestore.CollectionRef {
+	colls := []*firestore.CollectionRef{
+		collectionRef(store.firestoreClient(), resolveParentDocument(store.parentDoc, prefix), store.collection),
+	}
+	for _, kind := range ldstoreimpl.AllKinds() {
+		coll := store.collectionForKind(kind)
+		if !slices.ContainsFunc(colls, func(c *firestore.CollectionRef) bool { return c.Path == coll.Path }) {
+			colls = append(colls, coll)
+		}
+	}
+	return colls
+}
+
+// hasNamespacePrefix returns true if a namespace belongs to the given prefix. Without a prefix,
+// namespaces have no ":" separator.
+func hasNamespacePrefix(namespace, prefix string) bool {
+	if prefix == "" {
+		return !strings.Contains(namespace, ":")
+	}
+	return strings.HasPrefix(namespace, prefix+":")
+}
+
+func (store *firestoreDataStore) DebugDump(ctx context.Context, prefix string) ([]DebugDocument, error) {
+	done, err := store.beginOperation(true)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var results []DebugDocument
+	for _, coll := range store.debugDumpCollections(prefix) {
+		query := coll.Query
+		if prefix != "" {
+			// ";" is the character after ":", so this is every namespace that starts with the prefix
+			query = query.Where(fieldNamespace, ">=", prefix+":").Where(fieldNamespace, "<", prefix+";")
+		}
+		reads := 0
+		iter := query.Documents(ctx)
+		for {
+			doc, err := iter.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				iter.Stop()
+				return nil, newStoreError("DebugDump", nil, "", err)
+			}
+			reads++
+			data := doc.Data()
+			namespace, ok := data[fieldNamespace].(string)
+			if !ok || !hasNamespacePrefix(namespace, prefix) {
+				continue // not a document of this package, or one for another prefix
+			}
+			key, _ := data[fieldKey].(string)
+			deleted, _ := data[fieldDeleted].(bool)
+			version := -1
+			if v, ok := data[fieldVersion].(int64); ok {
+				version = int(v)
+			}
+			results = append(results, DebugDocument{
+				Path:          doc.Ref.Path,
+				ID:            doc.Ref.ID,
+				Namespace:     namespace,
+				Key:           key,
+				Version:       version,
+				Deleted:       deleted,
+				SchemaVersion: documentSchemaVersion(data),
+				Size:          estimateDocSize(data),
+				UpdateTime:    doc.UpdateTime,
+			})
+		}
+		iter.Stop()
+		store.countUsage("DebugDump", DocumentOperations{Reads: queryReads(reads)})
+	}
+
+	slices.SortFunc(results, func(a, b DebugDocument) int { return strings.Compare(a.Path, b.Path) })
+	return results, nil
+}
@@ -0,0 +1,62 @@
+package ldfirestore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasNamespacePrefix(t *testing.T) {
+	assert.True(t, hasNamespacePrefix("features", ""))
+	assert.True(t, hasNamespacePrefix("$inited", ""))
+	assert.False(t, hasNamespacePrefix("p:features", ""))
+	assert.True(t, hasNamespacePrefix("p:features", "p"))
+	assert.False(t, hasNamespacePrefix("pq:features", "p"))
+	assert.False(t, hasNamespacePrefix("features", "p"))
+}
+
+func TestDebugDump(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := baseDataStoreBuilder().Prefix("p").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	other, err := baseDataStoreBuilder().Prefix("q").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = other.Close() }()
+
+	flag := ldstoretypes.SerializedItemDescriptor{Version: 3, SerializedItem: []byte(`{"key":"flag1"}`)}
+	for _, s := range []subsystems.PersistentDataStore{store, other} {
+		require.NoError(t, s.Init([]ldstoretypes.SerializedCollection{
+			{Kind: ldstoreimpl.Features(), Items: []ldstoretypes.KeyedSerializedItemDescriptor{
+				{Key: "flag1", Item: flag},
+			}},
+		}))
+	}
+
+	docs, err := store.(ExtendedDataStore).DebugDump(context.Background(), "p")
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	byNamespace := map[string]DebugDocument{}
+	for _, doc := range docs {
+		byNamespace[doc.Namespace] = doc
+	}
+
+	item := byNamespace["p:features"]
+	assert.Equal(t, "flag1", item.Key)
+	assert.Equal(t, 3, item.Version)
+	assert.Equal(t, currentSchemaVersion, item.SchemaVersion)
+	assert.Greater(t, item.Size, 0)
+	assert.False(t, item.UpdateTime.IsZero())
+
+	inited := byNamespace["p:$inited"]
+	assert.Equal(t, -1, inited.Version)
+}
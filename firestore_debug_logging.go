@@ -0,0 +1,34 @@
+package ldfirestore
+
+import (
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+)
+
+// LogCategory is a category of debug log messages from the data store. See [StoreBuilder.DebugLogging].
+type LogCategory int
+
+const (
+	// LogReads is for messages about reading items, such as items that were not found.
+	LogReads LogCategory = 1 << iota
+
+	// LogWrites is for messages about writing or deleting single items, including updates that were
+	// rejected because a newer version was already stored.
+	LogWrites
+
+	// LogTransactions is for messages about the attempts made by Firestore transactions, which are
+	// retried automatically if they conflict with another update.
+	LogTransactions
+
+	// LogBulkOperations is for messages about operations on many documents, such as Init.
+	LogBulkOperations
+)
+
+// debugLog returns the loggers to use for a debug message in the given category, and whether the
+// message should be logged at all. If categories were specified with DebugLogging, only messages in
+// those categories are logged, regardless of the SDK's log level.
+func (store *firestoreDataStore) debugLog(category LogCategory) (ldlog.Loggers, bool) {
+	if store.debugCategories == 0 {
+		return store.loggers, store.loggers.IsDebugEnabled()
+	}
+	return store.debugLoggers, store.debugCategories&category != 0
+}
@@ -0,0 +1,58 @@
+package ldfirestore
+
+import (
+	"testing"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugLogging(t *testing.T) {
+	buildStore := func(t *testing.T, minLevel ldlog.LogLevel, categories ...LogCategory) (*firestoreDataStore, *ldlogtest.MockLog) {
+		mockLog := ldlogtest.NewMockLog()
+		mockLog.Loggers.SetMinLevel(minLevel)
+		clientContext := subsystems.BasicClientContext{
+			Logging: subsystems.LoggingConfiguration{Loggers: mockLog.Loggers},
+		}
+		store, err := DataStore("my-project", "my-collection").ClientOptions(makeTestOptions()...).
+			DebugLogging(categories...).Build(clientContext)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = store.Close() })
+		return store.(*firestoreDataStore), mockLog
+	}
+
+	t.Run("builder combines categories", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").DebugLogging(LogReads, LogBulkOperations)
+		assert.Equal(t, LogReads|LogBulkOperations, b.debugCategories)
+	})
+
+	t.Run("default follows SDK log level", func(t *testing.T) {
+		store, _ := buildStore(t, ldlog.Info)
+		_, ok := store.debugLog(LogWrites)
+		assert.False(t, ok)
+
+		store, _ = buildStore(t, ldlog.Debug)
+		_, ok = store.debugLog(LogWrites)
+		assert.True(t, ok)
+	})
+
+	t.Run("selected categories are logged regardless of SDK log level", func(t *testing.T) {
+		store, mockLog := buildStore(t, ldlog.Info, LogWrites)
+		log, ok := store.debugLog(LogWrites)
+		require.True(t, ok)
+		log.Debugf("write message")
+		mockLog.AssertMessageMatch(t, true, ldlog.Debug, "ldfirestore: write message")
+
+		_, ok = store.debugLog(LogReads)
+		assert.False(t, ok)
+	})
+
+	t.Run("other categories are not logged at Debug level", func(t *testing.T) {
+		store, _ := buildStore(t, ldlog.Debug, LogWrites)
+		_, ok := store.debugLog(LogReads)
+		assert.False(t, ok)
+	})
+}
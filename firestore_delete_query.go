@@ -0,0 +1,124 @@
+package ldfirestore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	// deletePageSize is the number of documents that are read and deleted at a time by deleteByQuery.
+	deletePageSize = 500
+
+	// deleteMaxAttempts is the number of times deleteByQuery tries to delete each document.
+	deleteMaxAttempts = 3
+
+	// deleteRetryDelay is the delay before retrying failed deletes, unless Firestore suggests one.
+	deleteRetryDelay = time.Second
+)
+
+// forEachDocRefPage runs a keys-only version of the query, a page at a time, and calls fn with the
+// references in each page. Paging keeps memory use bounded, and lets the caller delete the documents
+// in a page without affecting the next one.
+func forEachDocRefPage(
+	ctx context.Context,
+	query firestore.Query,
+	pageSize int,
+	fn func(refs []*firestore.DocumentRef) error,
+) error {
+	// Select no fields, just get document IDs
+	return forEachDocPage(ctx, query, pageSize, nil, func(docs []*firestore.DocumentSnapshot) error {
+		refs := make([]*firestore.DocumentRef, 0, len(docs))
+		for _, doc := range docs {
+			refs = append(refs, doc.Ref)
+		}
+		return fn(refs)
+	})
+}
+
+// forEachDocPage runs the query, reading only the given fields, a page at a time, and calls fn with
+// the documents in each page.
+func forEachDocPage(
+	ctx context.Context,
+	query firestore.Query,
+	pageSize int,
+	fields []string,
+	fn func(docs []*firestore.DocumentSnapshot) error,
+) error {
+	pageQuery := query.Select(fields...).OrderBy(firestore.DocumentID, firestore.Asc).Limit(pageSize)
+	for {
+		docs, err := pageQuery.Documents(ctx).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+		if err := fn(docs); err != nil {
+			return err
+		}
+		if len(docs) < pageSize {
+			return nil
+		}
+		pageQuery = pageQuery.StartAfter(docs[len(docs)-1])
+	}
+}
+
+// deleteByQuery deletes every document matched by the query, a page at a time. Deletes that fail
+// with a transient error are retried. After each page, onProgress (if not nil) is called with the
+// total number of documents deleted so far. It returns the number of documents that were deleted,
+// and an error if any document could not be deleted.
+func deleteByQuery(
+	ctx context.Context,
+	client *firestore.Client,
+	query firestore.Query,
+	onProgress func(deleted int),
+) (int, error) {
+	deleted := 0
+	err := forEachDocRefPage(ctx, query, deletePageSize, func(refs []*firestore.DocumentRef) error {
+		pending := make([]firestoreOperation, 0, len(refs))
+		for _, ref := range refs {
+			pending = append(pending, deleteOperation{ref: ref})
+		}
+
+		for attempt := 1; ; attempt++ {
+			failures, err := batchWriteOperations(ctx, client, pending)
+			if err != nil {
+				return err
+			}
+			deleted += len(pending) - len(failures)
+			if len(failures) == 0 {
+				break
+			}
+
+			var retryAfter time.Duration
+			retryable := make([]firestoreOperation, 0, len(failures))
+			for _, f := range failures {
+				if !isTransientError(f.err) || attempt >= deleteMaxAttempts {
+					return fmt.Errorf("failed to delete document %q: %w", f.op.docRef().ID, f.err)
+				}
+				retryable = append(retryable, f.op)
+				if delay, ok := serverRetryDelay(f.err); ok {
+					retryAfter = max(retryAfter, delay)
+				}
+			}
+			if retryAfter == 0 {
+				retryAfter = deleteRetryDelay * time.Duration(attempt)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryAfter):
+			}
+			pending = retryable
+		}
+
+		if onProgress != nil {
+			onProgress(deleted)
+		}
+		return nil
+	})
+	return deleted, err
+}
@@ -0,0 +1,114 @@
+package ldfirestore
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+func (b builderOptions) validateDifferentialInit() error {
+	if b.differentialInit && b.expireAfter > 0 {
+		return errors.New("DifferentialInit cannot be combined with ExpireAfter, since unchanged items would expire")
+	}
+	return nil
+}
+
+// existingItem is what Init knows about an existing item document when DifferentialInit is enabled.
+type existingItem struct {
+	version int
+	fields  map[string]any // the stored fields that depend on the configuration; nil if read from a manifest
+}
+
+// formatFields returns the names of the document fields, other than the version, whose values depend
+// on how the store is configured. An item is only skipped if these fields match what encodeItem
+// would write now.
+func (store *firestoreDataStore) formatFields() []string {
+	fields := []string{fieldSchemaVersion, fieldEncoding, fieldDeleted, fieldLastUpdated, fieldChecksum,
+		fieldOverflow, fieldDegraded}
+	for name := range store.labels {
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// readExistingVersions is like readExistingDocRefs, but also returns the version and the
+// configuration-dependent fields of each existing item, so that Init can skip items that have not
+// changed.
+func (store *firestoreDataStore) readExistingVersions(
+	ctx context.Context,
+	newData []ldstoretypes.SerializedCollection,
+) (map[string]*firestore.DocumentRef, map[string]existingItem, error) {
+	docRefs := make(map[string]*firestore.DocumentRef)
+	existing := make(map[string]existingItem)
+	selected := append([]string{fieldVersion}, store.formatFields()...)
+
+	for _, coll := range newData {
+		query := store.queryForKind(coll.Kind)
+		err := forEachDocPage(ctx, query, deletePageSize, selected,
+			func(docs []*firestore.DocumentSnapshot) error {
+				for _, doc := range docs {
+					docRefs[doc.Ref.Path] = doc.Ref
+					data := doc.Data()
+					if version, ok := data[fieldVersion].(int64); ok {
+						existing[doc.Ref.Path] = existingItem{version: int(version), fields: data}
+					}
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return docRefs, existing, nil
+}
+
+// isUnchanged returns true if Init can skip writing an item, because the existing document already
+// has the same version, and was stored the same way as the encoded document in data would be. A
+// document that is missing any of the fields that the current configuration writes is rewritten, and
+// so is an item that is too large to store as it is, since the size limits may now treat it
+// differently.
+func (store *firestoreDataStore) isUnchanged(
+	existing map[string]existingItem,
+	kind ldstoretypes.DataKind,
+	docRef *firestore.DocumentRef,
+	item ldstoretypes.SerializedItemDescriptor,
+	data map[string]any,
+) bool {
+	old, ok := existing[docRef.Path]
+	if !ok || old.version != item.Version || estimateDocSize(data) > store.sizeLimitForKind(kind).maxBytes {
+		return false
+	}
+	if old.fields == nil {
+		// The manifest was written with the same configuration; see readManifestVersions
+		return true
+	}
+	for _, name := range store.formatFields() {
+		if !sameFieldValue(old.fields, data, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameFieldValue returns true if a stored document has the same value for a field as a document that
+// is about to be written. The lastUpdated field only has to exist, since it is set by the server.
+func sameFieldValue(stored, written map[string]any, name string) bool {
+	newValue, inNew := written[name]
+	oldValue, inOld := stored[name]
+	switch {
+	case !inNew:
+		return !inOld
+	case !inOld:
+		return false
+	case name == fieldLastUpdated:
+		return true
+	}
+	if v, ok := newValue.(int); ok {
+		old, ok := oldValue.(int64)
+		return ok && old == int64(v)
+	}
+	return oldValue == newValue
+}
@@ -0,0 +1,180 @@
+package ldfirestore
+
+import (
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDifferentialInitBuilder(t *testing.T) {
+	b := DataStore("my-project", "my-collection").DifferentialInit(true)
+	assert.True(t, b.differentialInit)
+
+	ds, err := b.ExpireAfter(time.Hour).Build(subsystems.BasicClientContext{})
+	assert.Error(t, err)
+	assert.Nil(t, ds)
+	assert.Contains(t, err.Error(), "cannot be combined with ExpireAfter")
+}
+
+func TestIsUnchanged(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	type dataStoreBuilder = StoreBuilder[subsystems.PersistentDataStore]
+	makeStore := func(configure func(*dataStoreBuilder)) *firestoreDataStore {
+		b := DataStore("my-project", "my-collection").FirestoreClient(client)
+		configure(b)
+		ds, err := b.Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = ds.Close() })
+		return ds.(*firestoreDataStore)
+	}
+	item := ldstoretypes.SerializedItemDescriptor{Version: 2, SerializedItem: []byte(`{"key":"flag1","version":2}`)}
+	kind := ldstoreimpl.Features()
+
+	// stored returns the fields that readExistingVersions would read from a document written by store
+	stored := func(store *firestoreDataStore, item ldstoretypes.SerializedItemDescriptor) map[string]existingItem {
+		data, err := store.encodeItem(kind, "flag1", item)
+		require.NoError(t, err)
+		fields := make(map[string]any)
+		for _, name := range store.formatFields() {
+			if value, ok := data[name]; ok {
+				if v, ok := value.(int); ok {
+					value = int64(v)
+				}
+				fields[name] = value
+			}
+		}
+		fields[fieldLastUpdated] = time.Now()
+		return map[string]existingItem{store.docRef(kind, "flag1").Path: {version: item.Version, fields: fields}}
+	}
+	unchanged := func(store *firestoreDataStore, existing map[string]existingItem,
+		item ldstoretypes.SerializedItemDescriptor) bool {
+		data, err := store.encodeItem(kind, "flag1", item)
+		require.NoError(t, err)
+		return store.isUnchanged(existing, kind, store.docRef(kind, "flag1"), item, data)
+	}
+
+	plain := makeStore(func(*dataStoreBuilder) {})
+	existing := stored(plain, item)
+	assert.True(t, unchanged(plain, existing, item))
+	assert.False(t, unchanged(plain, existing, ldstoretypes.SerializedItemDescriptor{Version: 3}))
+	assert.False(t, unchanged(plain, nil, item))
+	assert.False(t, plain.isUnchanged(existing, kind, plain.docRef(kind, "flag2"), item, nil))
+
+	t.Run("configuration changes", func(t *testing.T) {
+		for name, configure := range map[string]func(*dataStoreBuilder){
+			"compression":      func(b *dataStoreBuilder) { b.Compression(CompressionGzip) },
+			"structured items": func(b *dataStoreBuilder) { b.StructuredItems(true) },
+			"checksum":         func(b *dataStoreBuilder) { b.Checksum(ChecksumCRC32C) },
+			"labels": func(b *dataStoreBuilder) {
+				b.DocumentLabels(map[string]string{"env": "prod"})
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				store := makeStore(configure)
+				assert.False(t, unchanged(store, existing, item))
+				assert.True(t, unchanged(store, stored(store, item), item))
+			})
+		}
+	})
+
+	t.Run("missing or different fields", func(t *testing.T) {
+		path := plain.docRef(kind, "flag1").Path
+		for _, name := range []string{fieldSchemaVersion, fieldDeleted, fieldLastUpdated} {
+			fields := maps.Clone(existing[path].fields)
+			delete(fields, name)
+			assert.False(t, unchanged(plain, map[string]existingItem{path: {version: 2, fields: fields}}, item), name)
+		}
+		fields := maps.Clone(existing[path].fields)
+		fields[fieldSchemaVersion] = int64(currentSchemaVersion - 1)
+		assert.False(t, unchanged(plain, map[string]existingItem{path: {version: 2, fields: fields}}, item))
+		fields = maps.Clone(existing[path].fields)
+		fields[fieldDeleted] = true
+		assert.False(t, unchanged(plain, map[string]existingItem{path: {version: 2, fields: fields}}, item))
+	})
+
+	t.Run("versions from a manifest", func(t *testing.T) {
+		manifest := map[string]existingItem{plain.docRef(kind, "flag1").Path: {version: 2}}
+		assert.True(t, unchanged(plain, manifest, item))
+		compressed := makeStore(func(b *dataStoreBuilder) { b.Compression(CompressionGzip) })
+		assert.NotEqual(t, plain.itemFormat(), compressed.itemFormat())
+	})
+}
+
+func TestDifferentialInit(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := baseDataStoreBuilder().DifferentialInit(true).Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	ext := store.(ExtendedDataStore)
+
+	makeData := func(versions map[string]int) []ldstoretypes.SerializedCollection {
+		var items []ldstoretypes.KeyedSerializedItemDescriptor
+		for key, version := range versions {
+			items = append(items, ldstoretypes.KeyedSerializedItemDescriptor{
+				Key: key,
+				Item: ldstoretypes.SerializedItemDescriptor{
+					Version:        version,
+					SerializedItem: []byte(`{"key":"` + key + `"}`),
+				},
+			})
+		}
+		return []ldstoretypes.SerializedCollection{{Kind: ldstoreimpl.Features(), Items: items}}
+	}
+
+	require.NoError(t, store.Init(makeData(map[string]int{"flag1": 1, "flag2": 1, "flag3": 1})))
+	assert.Equal(t, InitReport{ItemsWritten: 3}, ext.LastInitReport())
+
+	require.NoError(t, store.Init(makeData(map[string]int{"flag1": 1, "flag2": 2})))
+	assert.Equal(t, InitReport{ItemsWritten: 1, ItemsDeleted: 1, ItemsUnchanged: 1}, ext.LastInitReport())
+
+	items, err := store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	for _, item := range items {
+		expected := map[string]int{"flag1": 1, "flag2": 2}[item.Key]
+		assert.Equal(t, expected, item.Item.Version)
+	}
+	assert.True(t, store.IsInitialized())
+}
+
+func TestDifferentialInitRewritesItemsStoredDifferently(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)}
+	data := []ldstoretypes.SerializedCollection{{
+		Kind:  ldstoreimpl.Features(),
+		Items: []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "flag1", Item: item}},
+	}}
+	for _, manifests := range []bool{false, true} {
+		require.NoError(t, clearTestData(""))
+		builder := baseDataStoreBuilder().DifferentialInit(true).NamespaceManifests(manifests)
+		store, err := builder.Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		require.NoError(t, store.Init(data))
+		_ = store.Close()
+
+		compressed, err := builder.Compression(CompressionGzip).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		require.NoError(t, compressed.Init(data))
+		assert.Equal(t, InitReport{ItemsWritten: 1}, compressed.(ExtendedDataStore).LastInitReport())
+		require.NoError(t, compressed.Init(data))
+		assert.Equal(t, InitReport{ItemsUnchanged: 1}, compressed.(ExtendedDataStore).LastInitReport())
+		_ = compressed.Close()
+	}
+}
@@ -0,0 +1,205 @@
+package ldfirestore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DocumentIDEscaping specifies how the parts of a document ID are escaped. See
+// [StoreBuilder.DocumentIDFormat].
+type DocumentIDEscaping int
+
+const (
+	// DocumentIDEscapingNone means that the prefix, namespace, and key are used in document IDs as
+	// they are. If any of them contains the separator, two different items can have the same document
+	// ID. This is the default, for compatibility with existing data.
+	DocumentIDEscapingNone DocumentIDEscaping = iota
+
+	// DocumentIDEscapingPercent means that any character of the prefix, namespace, or key that is a
+	// "%", a "/", or one of the characters of the separator is percent-encoded, as in a URL. This
+	// guarantees that different items always have different document IDs.
+	DocumentIDEscapingPercent
+)
+
+const defaultDocIDSeparator = ":"
+
+// docIDScheme determines how document IDs are built from their parts. The zero value is the
+// original format, which joins the parts with colons and does no escaping.
+type docIDScheme struct {
+	separator string
+	escaping  DocumentIDEscaping
+	hashed    bool
+}
+
+func (s docIDScheme) sep() string {
+	if s.separator == "" {
+		return defaultDocIDSeparator
+	}
+	return s.separator
+}
+
+// validate checks that the scheme can be used in Firestore document IDs, and that with escaping,
+// the escaped parts can never contain the separator.
+func (s docIDScheme) validate() error {
+	if strings.Contains(s.separator, "/") {
+		return fmt.Errorf("document ID separator %q must not contain \"/\"", s.separator)
+	}
+	switch s.escaping {
+	case DocumentIDEscapingNone:
+	case DocumentIDEscapingPercent:
+		if strings.ContainsFunc(s.separator, func(r rune) bool {
+			return r == '%' || ('0' <= r && r <= '9') || ('A' <= r && r <= 'F')
+		}) {
+			return fmt.Errorf("document ID separator %q cannot be used with percent escaping, "+
+				"since it contains \"%%\" or a hexadecimal digit", s.separator)
+		}
+	default:
+		return fmt.Errorf("unknown document ID escaping %d", s.escaping)
+	}
+	return nil
+}
+
+// escape encodes a single part of a document ID.
+func (s docIDScheme) escape(part string) string {
+	if s.escaping != DocumentIDEscapingPercent {
+		return part
+	}
+	sep := s.sep()
+	var b strings.Builder
+	for i := 0; i < len(part); i++ {
+		c := part[i]
+		if c == '%' || c == '/' || strings.IndexByte(sep, c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// unescape reverses escape. If the input was not produced by escape, it is returned unchanged.
+func (s docIDScheme) unescape(part string) string {
+	if s.escaping != DocumentIDEscapingPercent {
+		return part
+	}
+	if decoded, err := url.PathUnescape(part); err == nil {
+		return decoded
+	}
+	return part
+}
+
+// join builds a document ID from its parts, escaping each of them.
+func (s docIDScheme) join(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = s.escape(part)
+	}
+	return strings.Join(escaped, s.sep())
+}
+
+// makeID builds the ID of a data store document in the format {prefix}:{namespace}:{key}, or
+// {namespace}:{key} if there is no prefix, or the hash of that if IDs are hashed.
+func (s docIDScheme) makeID(prefix, namespace, key string) string {
+	if prefix == "" {
+		return s.finish(s.join(namespace, key))
+	}
+	return s.finish(s.join(prefix, namespace, key))
+}
+
+// finish returns the ID of a data store document given its unhashed ID. If IDs are hashed, this is
+// the hex-encoded SHA-256 hash of the unhashed ID, which is always 64 characters long and never
+// contains "/", however long the key is or whatever characters it contains.
+func (s docIDScheme) finish(id string) string {
+	if !s.hashed {
+		return id
+	}
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+func (store *firestoreDataStore) MigrateDocumentIDs(kind ldstoretypes.DataKind) (int, error) {
+	if err := store.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	done, err := store.beginOperation(false)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	iter := store.queryForKind(kind).Documents(store.context)
+	defer iter.Stop()
+
+	var creates []firestoreOperation
+	var oldRefs []*firestore.DocumentRef
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s documents: %w", kind, err)
+		}
+		data := doc.Data()
+		key, _ := data[fieldKey].(string)
+		if key == "" {
+			store.loggers.Warnf("Not migrating document %q, which has no key", doc.Ref.ID)
+			continue
+		}
+		newRef := store.docRef(kind, key)
+		if newRef.Path == doc.Ref.Path {
+			continue
+		}
+		creates = append(creates, createOperation{ref: newRef, data: data})
+		oldRefs = append(oldRefs, doc.Ref)
+	}
+
+	failures, err := batchWriteOperations(store.context, store.firestoreClient(), creates)
+	if err != nil {
+		return 0, fmt.Errorf("failed to migrate %s documents: %w", kind, err)
+	}
+	failed := make(map[int]error, len(failures))
+	for _, f := range failures {
+		if status.Code(f.err) != codes.AlreadyExists {
+			failed[f.index] = f.err
+		}
+	}
+
+	// Only delete the old documents that are known to have been copied
+	var deletes []firestoreOperation
+	for i, ref := range oldRefs {
+		if _, ok := failed[i]; !ok {
+			deletes = append(deletes, deleteOperation{ref: ref})
+		}
+	}
+	deleteFailures, err := batchWriteOperations(store.context, store.firestoreClient(), deletes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to migrate %s documents: %w", kind, err)
+	}
+	moved := len(deletes) - len(deleteFailures)
+	if len(failed) > 0 || len(deleteFailures) > 0 {
+		var firstErr error
+		for _, f := range failures {
+			if _, ok := failed[f.index]; ok {
+				firstErr = f.err
+				break
+			}
+		}
+		if firstErr == nil {
+			firstErr = deleteFailures[0].err
+		}
+		return moved, fmt.Errorf("failed to migrate %d %s document(s): %w",
+			len(failed)+len(deleteFailures), kind, firstErr)
+	}
+	return moved, nil
+}
@@ -0,0 +1,137 @@
+package ldfirestore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocIDScheme(t *testing.T) {
+	t.Run("default format", func(t *testing.T) {
+		var ids docIDScheme
+		assert.Equal(t, "p:p:features:flag1", ids.makeID("p", "p:features", "flag1"))
+		assert.Equal(t, "features:flag1", ids.makeID("", "features", "flag1"))
+		assert.Equal(t, ids.makeID("a:b", "c", "d"), ids.makeID("a", "b:c", "d"))
+	})
+
+	t.Run("custom separator", func(t *testing.T) {
+		ids := docIDScheme{separator: "|"}
+		assert.Equal(t, "p|p:features|flag1", ids.makeID("p", "p:features", "flag1"))
+	})
+
+	t.Run("percent escaping", func(t *testing.T) {
+		ids := docIDScheme{escaping: DocumentIDEscapingPercent}
+		assert.Equal(t, "p:p%3Afeatures:a%25b%2Fc", ids.makeID("p", "p:features", "a%b/c"))
+		assert.NotEqual(t, ids.makeID("a:b", "c", "d"), ids.makeID("a", "b:c", "d"))
+		assert.Equal(t, "a%b/c:d", ids.unescape(ids.escape("a%b/c:d")))
+		assert.Equal(t, "bad%zz", ids.unescape("bad%zz"))
+
+		multi := docIDScheme{separator: "::", escaping: DocumentIDEscapingPercent}
+		assert.NotEqual(t, multi.join("a:", "b"), multi.join("a", ":b"))
+	})
+
+	t.Run("hashed", func(t *testing.T) {
+		ids := docIDScheme{hashed: true}
+		id := ids.makeID("p", "p:features", strings.Repeat("a/", 1000))
+		assert.Len(t, id, 64)
+		assert.NotContains(t, id, "/")
+		assert.Equal(t, id, ids.makeID("p", "p:features", strings.Repeat("a/", 1000)))
+		assert.NotEqual(t, id, ids.makeID("p", "p:features", "a/"))
+		assert.Equal(t, ids.finish("p:p:features:flag1"), ids.makeID("p", "p:features", "flag1"))
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		assert.NoError(t, docIDScheme{}.validate())
+		assert.NoError(t, docIDScheme{separator: "~", escaping: DocumentIDEscapingPercent}.validate())
+		assert.Error(t, docIDScheme{separator: "/"}.validate())
+		assert.Error(t, docIDScheme{separator: "%", escaping: DocumentIDEscapingPercent}.validate())
+		assert.Error(t, docIDScheme{separator: "-A-", escaping: DocumentIDEscapingPercent}.validate())
+		assert.Error(t, docIDScheme{escaping: DocumentIDEscaping(99)}.validate())
+	})
+
+	t.Run("builder", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").DocumentIDFormat("|", DocumentIDEscapingPercent)
+		assert.Equal(t, docIDScheme{separator: "|", escaping: DocumentIDEscapingPercent}, b.docIDs)
+
+		ds, err := DataStore("my-project", "my-collection").DocumentIDFormat("a/b", DocumentIDEscapingNone).
+			Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, ds)
+
+		b = DataStore("my-project", "my-collection").HashedDocumentIDs(true).DocumentIDFormat("|", DocumentIDEscapingNone)
+		assert.Equal(t, docIDScheme{separator: "|", hashed: true}, b.docIDs)
+	})
+
+	t.Run("read repair recovers escaped key", func(t *testing.T) {
+		store := &firestoreDataStore{
+			prefix:  "p",
+			ids:     docIDScheme{escaping: DocumentIDEscapingPercent},
+			loggers: ldlogtest.NewMockLog().Loggers,
+		}
+		docID := store.makeDocID(ldstoreimpl.Features(), "a:b")
+		key, _, ok := store.decodeLenient(ldstoreimpl.Features(), docID, map[string]any{
+			fieldVersion: int64(1), fieldItem: `{"key":"a:b"}`,
+		})
+		assert.True(t, ok)
+		assert.Equal(t, "a:b", key)
+	})
+
+	t.Run("hashed ID is used for reads and writes", func(t *testing.T) {
+		for _, subcollections := range []bool{false, true} {
+			store := &firestoreDataStore{
+				prefix:         "p",
+				ids:            docIDScheme{hashed: true},
+				subcollections: subcollections,
+				loggers:        ldlogtest.NewMockLog().Loggers,
+			}
+			docID := store.makeDocID(ldstoreimpl.Features(), "flag1")
+			assert.Len(t, docID, 64)
+
+			_, _, ok := store.decodeLenient(ldstoreimpl.Features(), docID, map[string]any{
+				fieldVersion: int64(1), fieldItem: `{"key":"flag1"}`,
+			})
+			assert.False(t, ok) // the key cannot be recovered from the ID
+		}
+	})
+}
+
+func TestMigrateDocumentIDs(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	oldStore, err := baseDataStoreBuilder().Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = oldStore.Close() }()
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1","version":1}`)}
+	_, err = oldStore.Upsert(ldstoreimpl.Features(), "flag1", item)
+	require.NoError(t, err)
+
+	newStore, err := baseDataStoreBuilder().DocumentIDFormat("|", DocumentIDEscapingPercent).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = newStore.Close() }()
+
+	result, err := newStore.Get(ldstoreimpl.Features(), "flag1")
+	require.NoError(t, err)
+	assert.Equal(t, -1, result.Version)
+
+	moved, err := newStore.(ExtendedDataStore).MigrateDocumentIDs(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, 1, moved)
+
+	result, err = newStore.Get(ldstoreimpl.Features(), "flag1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Version)
+
+	moved, err = newStore.(ExtendedDataStore).MigrateDocumentIDs(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, 0, moved)
+}
@@ -0,0 +1,41 @@
+package ldfirestore
+
+import (
+	"os"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+)
+
+const emulatorHostEnvVar = "FIRESTORE_EMULATOR_HOST"
+
+// DefaultEmulatorOperationTimeout is the operation timeout that is used when the store is connected
+// to the Firestore emulator, if no other timeout was specified. See [StoreBuilder.EmulatorDetection].
+const DefaultEmulatorOperationTimeout = 5 * time.Second
+
+// emulatorInitRetryDelay is the default delay before retrying failed Init writes when the store is
+// connected to the emulator, which has no quotas and does not need to be backed off from gently.
+const emulatorInitRetryDelay = 100 * time.Millisecond
+
+// emulatorHost returns the address of the Firestore emulator that the store will connect to, or an
+// empty string if it is not using the emulator or emulator detection was disabled.
+func (b builderOptions) emulatorHost() string {
+	if b.ignoreEmulator || b.client != nil {
+		return ""
+	}
+	return os.Getenv(emulatorHostEnvVar)
+}
+
+func (b builderOptions) effectiveInitRetry() InitRetryOptions {
+	retry := b.initRetry
+	if retry.InitialDelay <= 0 && b.emulatorHost() != "" {
+		retry.InitialDelay = emulatorInitRetryDelay
+	}
+	return retry
+}
+
+func logEmulatorMode(builder builderOptions, loggers ldlog.Loggers) {
+	if host := builder.emulatorHost(); host != "" {
+		loggers.Infof("Using the Firestore emulator at %s; authentication is disabled and timeouts are shortened", host)
+	}
+}
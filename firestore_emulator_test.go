@@ -0,0 +1,40 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmulatorDetection(t *testing.T) {
+	t.Run("adjusts defaults when emulator is set", func(t *testing.T) {
+		t.Setenv(emulatorHostEnvVar, "localhost:8080")
+		b := DataStore("my-project", "my-collection").InitRetry(InitRetryOptions{MaxAttempts: 3})
+		assert.Equal(t, "localhost:8080", b.emulatorHost())
+		assert.Equal(t, DefaultEmulatorOperationTimeout, b.effectiveOperationTimeout())
+		assert.Equal(t, emulatorInitRetryDelay, b.effectiveInitRetry().InitialDelay)
+	})
+
+	t.Run("explicit settings take precedence", func(t *testing.T) {
+		t.Setenv(emulatorHostEnvVar, "localhost:8080")
+		b := DataStore("my-project", "my-collection").OperationTimeout(time.Minute).
+			InitRetry(InitRetryOptions{MaxAttempts: 3, InitialDelay: time.Second})
+		assert.Equal(t, time.Minute, b.effectiveOperationTimeout())
+		assert.Equal(t, time.Second, b.effectiveInitRetry().InitialDelay)
+	})
+
+	t.Run("can be disabled", func(t *testing.T) {
+		t.Setenv(emulatorHostEnvVar, "localhost:8080")
+		b := DataStore("my-project", "my-collection").EmulatorDetection(false)
+		assert.Equal(t, "", b.emulatorHost())
+		assert.Equal(t, time.Duration(0), b.effectiveOperationTimeout())
+	})
+
+	t.Run("not active without environment variable", func(t *testing.T) {
+		t.Setenv(emulatorHostEnvVar, "")
+		b := DataStore("my-project", "my-collection")
+		assert.Equal(t, "", b.emulatorHost())
+		assert.Equal(t, time.Duration(0), b.effectiveInitRetry().InitialDelay)
+	})
+}
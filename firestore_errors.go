@@ -0,0 +1,104 @@
+package ldfirestore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StoreError is the error returned by a store operation that failed because of an error from
+// Firestore, or because a document that was read could not be decoded. Use [errors.As] to get it
+// from an error returned by the store, or one of [IsNotFound], [IsTransient], and
+// [IsPermissionDenied] to check what kind of failure it was.
+//
+// Errors that do not come from Firestore, such as [ErrReadOnly] or an error returned by an
+// [OperationHook], are not wrapped in a StoreError. Neither are the errors of individual writes
+// in Init, which are reported by [InitError].
+type StoreError struct {
+	// Op is the name of the store method that failed, such as "Get", "Upsert", or "Init".
+	Op string
+
+	// Kind is the data kind that the operation was for, or nil if it was not for a single data kind.
+	Kind ldstoretypes.DataKind
+
+	// Key is the key of the item that the operation was for, or empty if it was not for one item.
+	Key string
+
+	// Code is the gRPC status code of the error from Firestore. It is codes.DeadlineExceeded or
+	// codes.Canceled if the operation's context expired or was canceled, and codes.Unknown if the
+	// error did not come from Firestore, as for a document that could not be decoded.
+	Code codes.Code
+
+	// Err is the underlying error.
+	Err error
+}
+
+// errInvalidData is the underlying error of a StoreError for an item whose document could not be
+// decoded.
+var errInvalidData = errors.New("invalid data")
+
+// newStoreError wraps an error from Firestore in a StoreError.
+func newStoreError(op string, kind ldstoretypes.DataKind, key string, err error) *StoreError {
+	return &StoreError{Op: op, Kind: kind, Key: key, Code: errorCode(err), Err: err}
+}
+
+// Error describes the operation and the underlying error.
+func (e *StoreError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Op)
+	if e.Kind != nil {
+		fmt.Fprintf(&b, " %s", e.Kind)
+	}
+	if e.Key != "" {
+		fmt.Fprintf(&b, " key %q", e.Key)
+	}
+	fmt.Fprintf(&b, " failed: %s", e.Err)
+	return b.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *StoreError) Unwrap() error {
+	return e.Err
+}
+
+// errorCode returns the gRPC status code of an error, treating a context that expired or was
+// canceled the same as the corresponding status codes.
+func errorCode(err error) codes.Code {
+	var storeErr *StoreError
+	if errors.As(err, &storeErr) {
+		return storeErr.Code
+	}
+	if code := status.Code(err); code != codes.Unknown {
+		return code
+	}
+	return status.FromContextError(err).Code()
+}
+
+// IsNotFound returns true if err is, or wraps, an error for a document that did not exist. The
+// methods that read items do not return this, since they report a missing item as not found.
+func IsNotFound(err error) bool {
+	return err != nil && errorCode(err) == codes.NotFound
+}
+
+// IsTransient returns true if err is, or wraps, an error for a temporary condition in Firestore or
+// the network, such that trying the same operation again later could succeed.
+func IsTransient(err error) bool {
+	return err != nil && isTransientCode(errorCode(err))
+}
+
+// IsPermissionDenied returns true if err is, or wraps, an error for Firestore rejecting the store's
+// credentials, or the permissions of its service account.
+func IsPermissionDenied(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch errorCode(err) {
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return true
+	}
+	return errors.Is(err, ErrPermissionDenied)
+}
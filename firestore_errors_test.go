@@ -0,0 +1,81 @@
+package ldfirestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStoreError(t *testing.T) {
+	t.Run("message", func(t *testing.T) {
+		err := newStoreError("Get", ldstoreimpl.Features(), "flag1", status.Error(codes.Unavailable, "down"))
+		assert.Equal(t, `Get features key "flag1" failed: rpc error: code = Unavailable desc = down`, err.Error())
+		assert.Equal(t, codes.Unavailable, err.Code)
+
+		err = newStoreError("Init", nil, "", errors.New("bad"))
+		assert.Equal(t, "Init failed: bad", err.Error())
+		assert.Equal(t, codes.Unknown, err.Code)
+	})
+
+	t.Run("code of wrapped error", func(t *testing.T) {
+		err := newStoreError("Init", nil, "", fmt.Errorf("could not write: %w", status.Error(codes.Aborted, "")))
+		assert.Equal(t, codes.Aborted, err.Code)
+
+		err = newStoreError("GetAll", ldstoreimpl.Segments(), "", context.DeadlineExceeded)
+		assert.Equal(t, codes.DeadlineExceeded, err.Code)
+	})
+
+	t.Run("helpers", func(t *testing.T) {
+		wrap := func(code codes.Code) error {
+			err := newStoreError("Upsert", ldstoreimpl.Features(), "flag1", status.Error(code, ""))
+			return fmt.Errorf("outer: %w", err)
+		}
+		assert.True(t, IsNotFound(wrap(codes.NotFound)))
+		assert.False(t, IsNotFound(wrap(codes.Unavailable)))
+		assert.True(t, IsTransient(wrap(codes.Unavailable)))
+		assert.True(t, IsTransient(wrap(codes.ResourceExhausted)))
+		assert.False(t, IsTransient(wrap(codes.InvalidArgument)))
+		assert.True(t, IsPermissionDenied(wrap(codes.PermissionDenied)))
+		assert.True(t, IsPermissionDenied(wrap(codes.Unauthenticated)))
+		assert.False(t, IsPermissionDenied(wrap(codes.NotFound)))
+
+		// the helpers also work for errors that are not wrapped in a StoreError
+		assert.True(t, IsTransient(status.Error(codes.Unavailable, "")))
+		assert.True(t, IsPermissionDenied(fmt.Errorf("%w: denied", ErrPermissionDenied)))
+
+		assert.False(t, IsNotFound(nil))
+		assert.False(t, IsTransient(nil))
+		assert.False(t, IsPermissionDenied(nil))
+	})
+
+	t.Run("returned by store", func(t *testing.T) {
+		client, err := createTestClient()
+		require.NoError(t, err)
+		_ = client.Close() // every operation will fail
+
+		ds, err := DataStore("my-project", "my-collection").FirestoreClient(client).
+			Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		defer func() { _ = ds.Close() }()
+
+		_, err = ds.Get(ldstoreimpl.Features(), "flag1")
+		var storeErr *StoreError
+		require.ErrorAs(t, err, &storeErr)
+		assert.Equal(t, "Get", storeErr.Op)
+		assert.Equal(t, ldstoreimpl.Features(), storeErr.Kind)
+		assert.Equal(t, "flag1", storeErr.Key)
+
+		_, err = ds.GetAll(ldstoreimpl.Segments())
+		require.ErrorAs(t, err, &storeErr)
+		assert.Equal(t, "GetAll", storeErr.Op)
+		assert.Equal(t, ldstoreimpl.Segments(), storeErr.Kind)
+	})
+}
@@ -0,0 +1,49 @@
+package ldfirestore
+
+import (
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+func (store *firestoreDataStore) GetAllVersions(kind ldstoretypes.DataKind) (map[string]int, error) {
+	done, err := store.beginOperation(true)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	store.countRead()
+
+	// Only the key and version fields are returned by Firestore, so the items are neither
+	// transferred nor decoded.
+	var versions map[string]int
+	err = store.retry.do(store.context, store.onRetry, func() error {
+		ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+		defer cancel()
+
+		versions = make(map[string]int)
+		return forEachDocPage(ctx, store.getAllQuery(kind), defaultItemPageSize,
+			[]string{fieldKey, fieldVersion, fieldSchemaVersion},
+			func(docs []*firestore.DocumentSnapshot) error {
+				for _, doc := range docs {
+					data := doc.Data()
+					key, _ := data[fieldKey].(string)
+					version, ok := data[fieldVersion].(int64)
+					if key == "" || !ok {
+						continue
+					}
+					store.observeSchemaVersion(data)
+					versions[key] = int(version)
+				}
+				return nil
+			})
+	})
+	if err != nil {
+		return nil, newStoreError("GetAllVersions", kind, "", err)
+	}
+	store.countUsage("GetAllVersions", DocumentOperations{Reads: queryReads(len(versions))})
+
+	if log, ok := store.debugLog(LogReads); ok {
+		log.Debugf("Read the versions of %d item(s) (namespace=%s)", len(versions), store.namespaceForKind(kind))
+	}
+	return versions, nil
+}
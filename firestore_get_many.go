@@ -0,0 +1,65 @@
+package ldfirestore
+
+import (
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+// getManyBatchSize is the maximum number of documents that GetMany reads in one request.
+const getManyBatchSize = 100
+
+func (store *firestoreDataStore) GetMany(
+	kind ldstoretypes.DataKind,
+	keys []string,
+) ([]ldstoretypes.SerializedItemDescriptor, error) {
+	results := make([]ldstoretypes.SerializedItemDescriptor, len(keys))
+	if len(keys) == 0 {
+		return results, nil
+	}
+
+	done, err := store.beginOperation(true)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	store.countRead()
+
+	for start := 0; start < len(keys); start += getManyBatchSize {
+		end := min(start+getManyBatchSize, len(keys))
+		refs := make([]*firestore.DocumentRef, 0, end-start)
+		for _, key := range keys[start:end] {
+			refs = append(refs, store.docRef(kind, key))
+		}
+
+		var docs []*firestore.DocumentSnapshot
+		err = store.retry.do(store.context, store.onRetry, func() (err error) {
+			ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+			defer cancel()
+			docs, err = store.firestoreClient().GetAll(ctx, refs)
+			return err
+		})
+		if err != nil {
+			return nil, newStoreError("GetMany", kind, "", err)
+		}
+		store.countUsage("GetMany", DocumentOperations{Reads: len(refs)})
+
+		for i, doc := range docs {
+			key := keys[start+i]
+			if !doc.Exists() {
+				results[start+i] = ldstoretypes.SerializedItemDescriptor{}.NotFound()
+				continue
+			}
+			_, item, ok := store.decodeDocumentWithRepair(kind, doc)
+			if !ok {
+				return nil, newStoreError("GetMany", kind, key, errInvalidData)
+			}
+			store.observeVersion(kind, key, item.Version)
+			results[start+i] = item
+		}
+	}
+
+	if log, ok := store.debugLog(LogReads); ok {
+		log.Debugf("Read %d item(s) by key (namespace=%s)", len(keys), store.namespaceForKind(kind))
+	}
+	return results, nil
+}
@@ -0,0 +1,82 @@
+package ldfirestore
+
+import (
+	"sync"
+	"time"
+)
+
+// HealthStatus describes the recent health of the store's use of Firestore in more detail than
+// IsStoreAvailable, for monitoring and for deciding whether the store is degraded. See
+// [ExtendedDataStore.Health].
+type HealthStatus struct {
+	// LastError is the error from the most recent operation or availability check that failed, or nil
+	// if none has failed. It is not cleared when later operations succeed; see ConsecutiveFailures.
+	LastError error
+
+	// LastErrorTime is when LastError happened, or the zero time if nothing has failed.
+	LastErrorTime time.Time
+
+	// ConsecutiveFailures is the number of operations and availability checks that have failed since
+	// the most recent one that succeeded. A store is degraded while this is greater than zero.
+	ConsecutiveFailures int
+
+	// LastSuccessTime is when the most recent successful operation or availability check finished, or
+	// the zero time if none has succeeded.
+	LastSuccessTime time.Time
+}
+
+// Degraded returns true if the most recent operation or availability check failed.
+func (h HealthStatus) Degraded() bool {
+	return h.ConsecutiveFailures > 0
+}
+
+// healthTracker keeps the HealthStatus of a store. The zero value is ready to use.
+type healthTracker struct {
+	status HealthStatus
+	lock   sync.Mutex
+}
+
+func (h *healthTracker) record(err error, now time.Time) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if err == nil {
+		h.status.ConsecutiveFailures = 0
+		h.status.LastSuccessTime = now
+		return
+	}
+	h.status.ConsecutiveFailures++
+	h.status.LastError = err
+	h.status.LastErrorTime = now
+}
+
+func (h *healthTracker) get() HealthStatus {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.status
+}
+
+// recordHealth records the outcome of an operation or availability check in the HealthStatus.
+func (t *telemetry) recordHealth(err error) {
+	if t != nil {
+		t.health.record(err, t.now())
+		if t.observe != nil {
+			t.observe(err)
+		}
+	}
+}
+
+// now returns the current time according to the data store's clock.
+func (t *telemetry) now() time.Time {
+	if t.clock == nil {
+		return time.Now()
+	}
+	return t.clock.Now()
+}
+
+func (store *firestoreDataStore) Health() HealthStatus {
+	if store.telemetry == nil {
+		return HealthStatus{}
+	}
+	return store.telemetry.health.get()
+}
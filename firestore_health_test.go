@@ -0,0 +1,73 @@
+package ldfirestore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthTracker(t *testing.T) {
+	var h healthTracker
+	assert.Equal(t, HealthStatus{}, h.get())
+	assert.False(t, h.get().Degraded())
+
+	t1 := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	t2, t3 := t1.Add(time.Second), t1.Add(2*time.Second)
+	err := errors.New("bad")
+
+	h.record(nil, t1)
+	h.record(err, t2)
+	h.record(err, t3)
+	assert.Equal(t, HealthStatus{LastError: err, LastErrorTime: t3, ConsecutiveFailures: 2, LastSuccessTime: t1},
+		h.get())
+	assert.True(t, h.get().Degraded())
+
+	h.record(nil, t3)
+	assert.Equal(t, HealthStatus{LastError: err, LastErrorTime: t3, LastSuccessTime: t3}, h.get())
+	assert.False(t, h.get().Degraded())
+}
+
+func TestHealth(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close() // every operation will fail
+
+	ds, err := DataStore("my-project", "my-collection").FirestoreClient(client).Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+	ext := ds.(ExtendedDataStore)
+	assert.Equal(t, HealthStatus{}, ext.Health())
+
+	_, getErr := ds.Get(ldstoreimpl.Features(), "flag1")
+	require.Error(t, getErr)
+	assert.False(t, ds.IsStoreAvailable())
+
+	health := ext.Health()
+	assert.Equal(t, 2, health.ConsecutiveFailures)
+	assert.Error(t, health.LastError)
+	assert.NotEqual(t, getErr, health.LastError) // it is the error from the availability check
+	assert.False(t, health.LastErrorTime.IsZero())
+	assert.True(t, health.LastSuccessTime.IsZero())
+}
+
+func TestHealthUsesClock(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close() // every operation will fail
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	ds, err := DataStore("my-project", "my-collection").FirestoreClient(client).
+		Clock(fixedClock(now)).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+
+	_, err = ds.Get(ldstoreimpl.Features(), "flag1")
+	require.Error(t, err)
+	assert.Equal(t, now, ds.(ExtendedDataStore).Health().LastErrorTime)
+}
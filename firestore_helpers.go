@@ -3,22 +3,25 @@ package ldfirestore
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"golang.org/x/time/rate"
 )
 
 // makeClientAndContext creates a new Firestore client and context.
 // This function should only be called when builder.client is nil.
 // The caller is responsible for closing the returned client.
 func makeClientAndContext(builder builderOptions) (*firestore.Client, context.Context, context.CancelFunc, error) {
-	ctx, cancelFunc := context.WithCancel(context.Background())
+	ctx, cancelFunc := builder.storeContext()
 
 	if builder.projectID == "" {
 		cancelFunc()
 		return nil, nil, nil, fmt.Errorf("project ID is required")
 	}
 
-	client, err := firestore.NewClient(ctx, builder.projectID, builder.clientOptions...)
+	client, err := newClient(ctx, builder)
 	if err != nil {
 		cancelFunc()
 		return nil, nil, nil, err
@@ -27,32 +30,130 @@ func makeClientAndContext(builder builderOptions) (*firestore.Client, context.Co
 	return client, ctx, cancelFunc, nil
 }
 
+// withClientOptions returns a copy of the builder options whose client options include everything
+// that was configured with other builder options, such as credentials and a private endpoint.
+func withClientOptions(builder builderOptions, loggers ldlog.Loggers, logPrefix string) builderOptions {
+	return withRPCLogging(withConnectionPool(withPrivateEndpoint(withCredentials(builder))), loggers, logPrefix)
+}
+
+// storeContext returns a new cancelable context for a store, derived from the context that was
+// specified with StoreBuilder.Context if any.
+func (b builderOptions) storeContext() (context.Context, context.CancelFunc) {
+	if b.baseContext != nil {
+		return context.WithCancel(b.baseContext)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// newClient creates a Firestore client for the builder's project and database.
+func newClient(ctx context.Context, builder builderOptions) (*firestore.Client, error) {
+	if builder.databaseID != "" {
+		return firestore.NewClientWithDatabase(ctx, builder.projectID, builder.databaseID, builder.clientOptions...)
+	}
+	return firestore.NewClient(ctx, builder.projectID, builder.clientOptions...)
+}
+
+// withOperationTimeout returns a context for a single store operation, which is canceled after the
+// configured operation timeout if there is one.
+func withOperationTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
 // batchWriteOperations executes a list of operations using Firestore's BulkWriter.
 // BulkWriter automatically handles batching (up to 20 writes per batch) and sends
 // operations in parallel for better performance.
+//
+// An error is returned only if the operations could not be submitted at all. Operations
+// that were submitted but failed individually are returned as a list of failures.
 func batchWriteOperations(
 	ctx context.Context,
 	client *firestore.Client,
 	operations []firestoreOperation,
-) error {
+) ([]operationFailure, error) {
+	return batchWriteOperationsWithFlush(ctx, client, operations, 0, nil, nil)
+}
+
+// batchWriteOperationsWithFlush is like batchWriteOperations, but if flushEvery is greater than
+// zero, it flushes the BulkWriter after every flushEvery operations and waits for them to complete
+// before enqueueing more. After each flush, onFlush (if not nil) is called with the index of the
+// first operation that was just flushed, the number of operations completed so far, and the
+// failures among the operations that were just flushed. If limiter is not nil, it waits for the
+// limiter before enqueueing each operation.
+func batchWriteOperationsWithFlush(
+	ctx context.Context,
+	client *firestore.Client,
+	operations []firestoreOperation,
+	flushEvery int,
+	onFlush func(chunkStart, completed int, chunkFailures []operationFailure),
+	limiter *rate.Limiter,
+) ([]operationFailure, error) {
 	bulkWriter := client.BulkWriter(ctx)
 
+	var failures []operationFailure
+	jobs := make([]*firestore.BulkWriterJob, 0, len(operations))
+	collectResults := func(from int) []operationFailure {
+		// Every job up to this point has completed, so Results will not block
+		var chunkFailures []operationFailure
+		for i := from; i < len(jobs); i++ {
+			if _, err := jobs[i].Results(); err != nil {
+				chunkFailures = append(chunkFailures, operationFailure{index: i, op: operations[i], err: err})
+			}
+		}
+		return chunkFailures
+	}
+
 	// Enqueue all operations
+	chunkStart := 0
 	for _, op := range operations {
-		if err := op.apply(bulkWriter); err != nil {
-			return fmt.Errorf("failed to enqueue operation: %w", err)
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				bulkWriter.End()
+				return nil, err
+			}
+		}
+		job, err := op.apply(bulkWriter)
+		if err != nil {
+			bulkWriter.End()
+			return nil, fmt.Errorf("failed to enqueue operation: %w", err)
+		}
+		jobs = append(jobs, job)
+
+		if flushEvery > 0 && len(jobs)-chunkStart >= flushEvery && len(jobs) < len(operations) {
+			bulkWriter.Flush()
+			chunkFailures := collectResults(chunkStart)
+			failures = append(failures, chunkFailures...)
+			if onFlush != nil {
+				onFlush(chunkStart, len(jobs), chunkFailures)
+			}
+			chunkStart = len(jobs)
 		}
 	}
 
-	// Flush all operations and close the BulkWriter
+	// Flush all remaining operations and close the BulkWriter
 	bulkWriter.End()
+	chunkFailures := collectResults(chunkStart)
+	failures = append(failures, chunkFailures...)
+	if onFlush != nil && flushEvery > 0 {
+		onFlush(chunkStart, len(jobs), chunkFailures)
+	}
+
+	return failures, nil
+}
 
-	return nil
+// operationFailure is an operation that was submitted to the BulkWriter but did not succeed
+type operationFailure struct {
+	index int
+	op    firestoreOperation
+	err   error
 }
 
-// firestoreOperation represents a BulkWriter operation (set or delete)
+// firestoreOperation represents a BulkWriter operation (set, update, or delete)
 type firestoreOperation interface {
-	apply(bulkWriter *firestore.BulkWriter) error
+	apply(bulkWriter *firestore.BulkWriter) (*firestore.BulkWriterJob, error)
+	docRef() *firestore.DocumentRef
 }
 
 // setOperation represents a set operation
@@ -61,17 +162,44 @@ type setOperation struct {
 	data map[string]any
 }
 
-func (op setOperation) apply(bulkWriter *firestore.BulkWriter) error {
-	_, err := bulkWriter.Set(op.ref, op.data)
-	return err
+func (op setOperation) apply(bulkWriter *firestore.BulkWriter) (*firestore.BulkWriterJob, error) {
+	return bulkWriter.Set(op.ref, op.data)
+}
+
+func (op setOperation) docRef() *firestore.DocumentRef { return op.ref }
+
+// createOperation represents an operation that creates a document, and fails if it already exists
+type createOperation struct {
+	ref  *firestore.DocumentRef
+	data map[string]any
 }
 
+func (op createOperation) apply(bulkWriter *firestore.BulkWriter) (*firestore.BulkWriterJob, error) {
+	return bulkWriter.Create(op.ref, op.data)
+}
+
+func (op createOperation) docRef() *firestore.DocumentRef { return op.ref }
+
 // deleteOperation represents a delete operation
 type deleteOperation struct {
 	ref *firestore.DocumentRef
 }
 
-func (op deleteOperation) apply(bulkWriter *firestore.BulkWriter) error {
-	_, err := bulkWriter.Delete(op.ref)
-	return err
+func (op deleteOperation) apply(bulkWriter *firestore.BulkWriter) (*firestore.BulkWriterJob, error) {
+	return bulkWriter.Delete(op.ref)
 }
+
+func (op deleteOperation) docRef() *firestore.DocumentRef { return op.ref }
+
+// updateOperation represents an update of specific fields in an existing document
+type updateOperation struct {
+	ref      *firestore.DocumentRef
+	updates  []firestore.Update
+	preconds []firestore.Precondition
+}
+
+func (op updateOperation) apply(bulkWriter *firestore.BulkWriter) (*firestore.BulkWriterJob, error) {
+	return bulkWriter.Update(op.ref, op.updates, op.preconds...)
+}
+
+func (op updateOperation) docRef() *firestore.DocumentRef { return op.ref }
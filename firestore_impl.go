@@ -27,6 +27,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
@@ -42,6 +44,12 @@ const (
 	fieldKey       = "key"
 	fieldVersion   = "version"
 	fieldItem      = "item"
+	fieldUpdatedAt = "updatedAt"
+	fieldDeleted   = "deleted"
+
+	// fieldLastUpdated is set by Firestore to the time when the item was last written by Init or
+	// Upsert. Unlike updatedAt, it is not changed by Touch.
+	fieldLastUpdated = "lastUpdated"
 
 	// We won't try to store items whose total size exceeds this. Firestore's actual limit
 	// is 1 MiB, but we use a conservative limit to account for field overhead and indexing.
@@ -50,35 +58,132 @@ const (
 
 // Internal type for our Firestore implementation of the PersistentDataStore interface.
 type firestoreDataStore struct {
-	client         *firestore.Client
-	context        context.Context
-	cancelContext  func()
-	collection     string
-	prefix         string
-	loggers        ldlog.Loggers
-	testUpdateHook func() // Used only by unit tests
-	ownsClient     bool   // true if we created the client and should close it
+	client                 *firestore.Client // use firestoreClient(), since it may be recreated
+	clientLock             sync.RWMutex
+	recovery               *clientRecovery // nil unless the store owns a client that may be recreated
+	context                context.Context
+	cancelContext          func()
+	collection             string
+	parentDoc              string
+	prefix                 string
+	kindLimits             map[string]kindSizeLimit
+	oversizedPolicy        OversizedItemPolicy
+	overflow               OverflowStore
+	kindRoutes             map[string]kindRoute
+	readRepair             bool
+	initRetry              InitRetryOptions
+	initFlushInterval      int
+	cancelInitRetries      func()
+	lastInitReport         InitReport
+	throttleStats          ThrottleStats
+	readStats              ReadStats
+	transactions           int
+	versionConflicts       int
+	readRepairs            ReadRepairStats
+	usage                  map[string]DocumentOperations
+	auditCollection        string
+	auditActor             string
+	scheduler              *operationScheduler
+	operationTimeout       time.Duration
+	initTimeout            time.Duration
+	skipExistingScan       bool
+	payloadFilter          string
+	reportedFilterMismatch string
+	readOnlyOnNewerSchema  bool
+	namespaceSummaries     bool
+	namespaceManifests     bool
+	excludeDeleted         bool
+	versions               *versionTracker
+	keyLocks               keyLocks
+	itemSizes              itemSizeStats
+	sizeWarningFraction    float64
+	sizeWarningHook        func(ItemSizeWarning)
+	availabilityGrace      time.Duration
+	availabilityTimeout    time.Duration
+	availabilityErr        error
+	startTime              time.Time
+	everAvailable          bool
+	availabilityListeners  availabilityListeners
+	retry                  RetryPolicy
+	ids                    docIDScheme
+	newerSchemaReported    bool
+	readOnly               bool
+	election               *writerElection
+	clock                  Clock
+	filterMismatchReported bool
+	initedCached           bool
+	lock                   sync.Mutex
+	closed                 bool           // true once Close has been called; protected by lock
+	inFlight               sync.WaitGroup // operations that have started; see beginOperation
+	loggers                ldlog.Loggers
+	testUpdateHook         func() // Used only by unit tests
+	ownsClient             bool   // true if we created the client and should close it
+	sharedClient           *SharedClient
+	debugCategories        LogCategory
+	subcollections         bool
+	collectionGroup        bool
+	labels                 map[string]string
+	compression            PayloadCompression
+	itemBytes              bool
+	structured             bool
+	codec                  PayloadCodec
+	checksum               ChecksumAlgorithm
+	expireAfter            time.Duration
+	getAllConcurrency      int
+	partitionQueriesFailed bool // set if a partitioned GetAll has failed for lack of an index
+	getAllPageSize         int
+	atomicInit             bool
+	preconditionUpserts    bool
+	differentialInit       bool
+	initWriteRate          int
+	initConcurrency        int
+	onChange               func(ItemChange)
+	cache                  *itemCache
+	invalidateOnChange     bool
+	fallback               *snapshotFallback
+	outage                 *outageBuffer
+	consistentGetAll       bool
+	telemetry              *telemetry
+	knownDocs              knownDocuments
+	debugLoggers           ldlog.Loggers // a copy of loggers with Debug enabled, for DebugLogging
+	panics                 panicSites
 }
 
 func newFirestoreDataStoreImpl(builder builderOptions, loggers ldlog.Loggers) (*firestoreDataStore, error) {
-	if builder.collection == "" {
-		return nil, errors.New("collection name is required")
+	if errs := builder.validate(); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	parentDoc, err := normalizeParentDocument(builder.parentDocument)
+	if err != nil {
+		return nil, err
 	}
 
 	var client *firestore.Client
 	var ctx context.Context
 	var cancelContext func()
 	var ownsClient bool
-	var err error
 
-	// If a client was provided, use it directly. Otherwise, create a new one.
-	// We only close clients that we create ourselves.
+	// If a client or shared client was provided, use it directly. Otherwise, create a new one, or in
+	// cold-start mode use the process-wide one. We only close clients that we create ourselves.
+	clientBuilder := withClientOptions(builder, loggers, "ldfirestore:")
 	if builder.client != nil {
 		client = builder.client
-		ctx, cancelContext = context.WithCancel(context.Background())
+		ctx, cancelContext = builder.storeContext()
+		ownsClient = false
+	} else if builder.sharedClient != nil {
+		if client, err = builder.sharedClient.acquire(); err != nil {
+			return nil, err
+		}
+		ctx, cancelContext = builder.storeContext()
+		ownsClient = false
+	} else if builder.coldStart {
+		if client, err = getSharedClient(clientBuilder); err != nil {
+			return nil, err
+		}
+		ctx, cancelContext = builder.storeContext()
 		ownsClient = false
 	} else {
-		client, ctx, cancelContext, err = makeClientAndContext(builder)
+		client, ctx, cancelContext, err = makeClientAndContext(clientBuilder)
 		if err != nil {
 			return nil, err
 		}
@@ -86,38 +191,239 @@ func newFirestoreDataStoreImpl(builder builderOptions, loggers ldlog.Loggers) (*
 	}
 
 	store := &firestoreDataStore{
-		client:        client,
-		context:       ctx,
-		cancelContext: cancelContext,
-		collection:    builder.collection,
-		prefix:        builder.prefix,
-		loggers:       loggers, // copied by value so we can modify it
-		ownsClient:    ownsClient,
+		client:                client,
+		context:               ctx,
+		cancelContext:         cancelContext,
+		collection:            builder.collection,
+		parentDoc:             parentDoc,
+		prefix:                builder.prefix,
+		kindLimits:            builder.kindLimits,
+		oversizedPolicy:       builder.oversizedPolicy,
+		overflow:              builder.overflowStore,
+		kindRoutes:            builder.kindRoutes,
+		readRepair:            builder.readRepair,
+		initRetry:             builder.effectiveInitRetry(),
+		initFlushInterval:     builder.initFlushInterval,
+		scheduler:             newOperationScheduler(builder.maxConcurrentOps, builder.schedulingPolicy),
+		operationTimeout:      builder.effectiveOperationTimeout(),
+		initTimeout:           builder.initTimeout,
+		skipExistingScan:      builder.coldStart || builder.preserveExisting,
+		payloadFilter:         builder.payloadFilter,
+		readOnlyOnNewerSchema: builder.readOnlyOnNewerSchema,
+		readOnly:              builder.readOnly,
+		namespaceSummaries:    builder.namespaceSummaries,
+		namespaceManifests:    builder.namespaceManifests,
+		excludeDeleted:        builder.excludeDeleted,
+		sizeWarningFraction:   builder.sizeWarningFraction,
+		sizeWarningHook:       builder.sizeWarningHook,
+		availabilityGrace:     builder.availabilityGrace,
+		availabilityTimeout:   DefaultAvailabilityCheckTimeout,
+		retry:                 builder.retryPolicy,
+		ids:                   builder.docIDs,
+		clock:                 builder.clock,
+		loggers:               loggers, // copied by value so we can modify it
+		ownsClient:            ownsClient,
+		sharedClient:          builder.sharedClient,
+		debugCategories:       builder.debugCategories,
+		subcollections:        builder.subcollections,
+		collectionGroup:       builder.collectionGroup,
+		labels:                builder.labels,
+		compression:           builder.compression,
+		itemBytes:             builder.itemBytes,
+		structured:            builder.structured,
+		codec:                 builder.codec,
+		checksum:              builder.checksum,
+		expireAfter:           builder.expireAfter,
+		getAllConcurrency:     builder.getAllConcurrency,
+		getAllPageSize:        builder.getAllPageSize,
+		atomicInit:            builder.atomicInit,
+		preconditionUpserts:   builder.preconditionUpserts,
+		differentialInit:      builder.differentialInit,
+		initWriteRate:         builder.initWriteRate,
+		initConcurrency:       builder.initConcurrency,
+		onChange:              builder.onChange,
+		cache:                 newItemCache(builder.itemCache),
+		invalidateOnChange:    builder.itemCache.TTL > 0 && builder.itemCache.InvalidateOnChange,
+		fallback:              newSnapshotFallback(builder.snapshotFallback),
+		outage:                newOutageBuffer(builder.outageBuffer),
+		consistentGetAll:      builder.consistentGetAll,
+		auditCollection:       builder.auditTrail.Collection,
+		auditActor:            builder.auditTrail.Actor,
+	}
+	store.startTime = store.now()
+	if builder.availabilityTimeout > 0 {
+		store.availabilityTimeout = builder.availabilityTimeout
+	}
+	if builder.versionAnomalies {
+		store.versions = newVersionTracker()
 	}
 	store.loggers.SetPrefix("ldfirestore:")
+	store.telemetry = newTelemetry(store.context, builder, store.loggers)
+	if ownsClient {
+		store.recovery = newClientRecovery(builder.clientRecovery, clientBuilder)
+	}
+	store.telemetry.observe = store.observeResult
+	store.telemetry.clock = store.clock
+	store.debugLoggers = store.loggers
+	store.debugLoggers.SetMinLevel(ldlog.Debug)
 	store.loggers.Infof(`Using Firestore collection %s`, store.collection)
+	logEmulatorMode(builder, store.loggers)
+	if builder.readOnly {
+		store.loggers.Info("Data store is read-only")
+	}
+
+	if builder.preflight {
+		if err := store.preflight(); err != nil {
+			_ = store.Close()
+			return nil, err
+		}
+	}
+
+	if builder.writerLeaseDuration > 0 && !builder.readOnly {
+		instanceID := builder.writerInstanceID
+		if instanceID == "" {
+			instanceID = newInstanceID()
+		}
+		store.election = &writerElection{instanceID: instanceID, leaseDuration: builder.writerLeaseDuration}
+		go store.runLeaseRenewal()
+	}
+	if store.auditCollection != "" && store.auditActor == "" {
+		instanceID := ""
+		if store.election != nil {
+			instanceID = store.election.instanceID
+		}
+		store.auditActor = defaultAuditActor(instanceID)
+	}
+	if store.onChange != nil || store.invalidateOnChange {
+		store.startWatching()
+	}
+	if store.fallback != nil {
+		go store.runSnapshotFallback()
+	}
+	if store.outage != nil {
+		go store.runOutageReplay()
+	}
 
 	return store, nil
 }
 
 func (store *firestoreDataStore) Init(allData []ldstoretypes.SerializedCollection) error {
-	// Start by reading the existing document IDs; we will later delete any of these that weren't in allData.
-	unusedOldIDs, err := store.readExistingDocIDs(allData)
+	op, err := store.telemetry.start("Init", nil, "")
+	if err == nil {
+		err = store.panics.protect(store.loggers, "Init", func() error { return store.initData(allData) })
+	}
+	op.end(countItems(allData), err)
+	return err
+}
+
+func (store *firestoreDataStore) initData(allData []ldstoretypes.SerializedCollection) (err error) {
+	if err := store.checkWritable(); err != nil {
+		return err
+	}
+	if !store.isWriter() {
+		// Reporting success is safe, since the SDK caches the data that it passed to Init; see WriterElection
+		store.loggers.Debug("Not initializing the store, since this instance does not hold the writer lease")
+		return nil
+	}
+	if store.auditCollection != "" {
+		defer func() { store.writeInitAuditRecord(err) }()
+	}
+
+	done, err := store.beginOperation(false)
 	if err != nil {
-		return fmt.Errorf("failed to get existing items prior to Init: %w", err)
+		return err
+	}
+	defer done()
+
+	ctx, cancel := withOperationTimeout(store.context, store.initTimeout)
+	defer cancel()
+	// Any writes still being retried from an earlier Init are obsolete now, as are buffered updates.
+	// The retries are stopped first, so that they can't update the report of this Init.
+	store.stopInitRetries()
+	store.setLastInitReport(InitReport{})
+	store.outage.clear()
+	if store.versions != nil {
+		store.versions.reset()
+	}
+	store.knownDocs.reset()
+	defer store.cache.clear()
+
+	// Start by reading the existing document IDs; we will later delete any of these that weren't in allData.
+	// In cold-start mode, or if existing items are preserved, this scan is skipped, so obsolete documents
+	// are left in place.
+	// With DifferentialInit, the scan also reads the version of each item, and is done even if
+	// obsolete documents are not going to be deleted.
+	unusedOldDocs := make(map[string]*firestore.DocumentRef)
+	var existingVersions map[string]existingItem
+	if !store.skipExistingScan || store.differentialInit {
+		var fromManifests bool
+		err = store.retry.do(ctx, store.onRetry, func() (err error) {
+			if store.namespaceManifests {
+				unusedOldDocs, existingVersions, fromManifests, err = store.readManifestVersions(ctx, allData)
+				if err != nil || fromManifests {
+					if !store.differentialInit {
+						existingVersions = nil
+					}
+					return err
+				}
+			}
+			if store.differentialInit {
+				unusedOldDocs, existingVersions, err = store.readExistingVersions(ctx, allData)
+			} else {
+				unusedOldDocs, err = store.readExistingDocRefs(ctx, allData)
+			}
+			return err
+		})
+		if err != nil {
+			return newStoreError("Init", nil, "", fmt.Errorf("could not get existing items: %w", err))
+		}
+		if fromManifests {
+			store.countUsage("Init", DocumentOperations{Reads: len(allData)})
+		} else {
+			store.countUsage("Init", DocumentOperations{Reads: max(len(unusedOldDocs), len(allData))})
+		}
+		if store.skipExistingScan {
+			unusedOldDocs = make(map[string]*firestore.DocumentRef)
+		}
 	}
 
 	operations := make([]firestoreOperation, 0)
-	numItems := 0
+	items := make(map[string]initItemRef)
+	var report InitReport
 
 	// Insert or update every provided item
+	metadataPaths := map[string]bool{store.initedOperation().ref.Path: true}
 	for _, coll := range allData {
+		summary := NamespaceSummary{UpdatedAt: store.now()}
+		manifest := make(map[string]int, len(coll.Items))
 		for _, item := range coll.Items {
-			docID := store.makeDocID(coll.Kind, item.Key)
-			docRef := store.client.Collection(store.collection).Doc(docID)
+			docRef := store.docRef(coll.Kind, item.Key)
+			data, err := store.encodeItem(coll.Kind, item.Key, item.Item)
+			if err != nil {
+				return err
+			}
+			if store.isUnchanged(existingVersions, coll.Kind, docRef, item.Item, data) {
+				report.ItemsUnchanged++
+				delete(unusedOldDocs, docRef.Path)
+				summary.ItemCount++
+				summary.MaxVersion = max(summary.MaxVersion, item.Item.Version)
+				manifest[item.Key] = item.Item.Version
+				continue
+			}
 
-			data := store.encodeItem(coll.Kind, item.Key, item.Item)
-			if !store.checkSizeLimit(data) {
+			encoded := data
+			data, ok, err := store.applySizeLimit(coll.Kind, data)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				report.ItemsSkipped++
+				report.Skipped = append(report.Skipped, InitSkippedItem{
+					Namespace: store.namespaceForKind(coll.Kind),
+					Key:       item.Key,
+					Size:      estimateDocSize(encoded),
+					MaxSize:   store.sizeLimitForKind(coll.Kind).maxBytes,
+				})
 				continue
 			}
 
@@ -125,108 +431,389 @@ func (store *firestoreDataStore) Init(allData []ldstoretypes.SerializedCollectio
 				ref:  docRef,
 				data: data,
 			})
-			unusedOldIDs[docID] = false
-			numItems++
+			items[docRef.Path] = initItemRef{namespace: store.namespaceForKind(coll.Kind), key: item.Key}
+			delete(unusedOldDocs, docRef.Path)
+			summary.ItemCount++
+			summary.MaxVersion = max(summary.MaxVersion, item.Item.Version)
+			manifest[item.Key] = item.Item.Version
+		}
+		if store.namespaceSummaries {
+			op := store.summaryOperation(coll.Kind, summary)
+			operations = append(operations, op)
+			metadataPaths[op.ref.Path] = true
+		}
+		if store.namespaceManifests {
+			op := store.manifestOperation(coll.Kind, manifest)
+			operations = append(operations, op)
+			metadataPaths[op.ref.Path] = true
 		}
 	}
 
 	// Now delete any previously existing items whose keys were not in the current data
-	initedKey := store.initedDocID()
-	for docID, shouldDelete := range unusedOldIDs {
-		if shouldDelete && docID != initedKey {
-			docRef := store.client.Collection(store.collection).Doc(docID)
+	for path, docRef := range unusedOldDocs {
+		if !metadataPaths[path] {
 			operations = append(operations, deleteOperation{ref: docRef})
 		}
 	}
 
-	// Now set the special key that we check in IsInitialized()
-	initedDocRef := store.client.Collection(store.collection).Doc(initedKey)
-	operations = append(operations, setOperation{
-		ref: initedDocRef,
-		data: map[string]any{
-			fieldNamespace: store.initedKey(),
-			fieldKey:       store.initedKey(),
-		},
-	})
+	if store.atomicInit {
+		err := store.initAtomically(ctx, operations, items, metadataPaths, report)
+		if err != nil && store.namespaceManifests {
+			store.discardManifests(allData)
+		}
+		return err
+	}
+
+	// Now set the special key that we check in IsInitialized(). If failed writes are going to be
+	// retried, this is deferred until we know whether they need to be.
+	retrying := store.initRetry.MaxAttempts > 0
+	if !retrying {
+		operations = append(operations, store.initedOperation())
+	}
+
+	var onFlush func(int, int, []operationFailure)
+	if store.initFlushInterval > 0 {
+		onFlush = func(chunkStart, completed int, chunkFailures []operationFailure) {
+			store.loggers.Infof("Init progress for collection %q: %d of %d operation(s) completed",
+				store.collection, completed, len(operations))
+			if len(chunkFailures) > 0 {
+				store.loggers.Warnf("%d operation(s) failed between operations %d and %d of Init (first failure: %q: %s)",
+					len(chunkFailures), chunkStart+1, completed,
+					chunkFailures[0].op.docRef().ID, chunkFailures[0].err)
+			}
+		}
+	}
+	failures, err := batchWriteOperationsConcurrently(ctx, store.firestoreClient(), operations,
+		store.initConcurrency, store.initFlushInterval, onFlush, newWriteLimiter(store.initWriteRate))
+	if err != nil {
+		return newStoreError("Init", nil, "",
+			fmt.Errorf("could not write %d item(s) in batches: %w", len(operations), err))
+	}
+	failures = store.retryFailedWrites(ctx, failures)
+	if len(failures) > 0 && store.namespaceManifests {
+		store.discardManifests(allData)
+	}
+	if log, ok := store.debugLog(LogBulkOperations); ok {
+		log.Debugf("Init wrote %d operation(s) to collection %q (%d failed)", len(operations), store.collection,
+			len(failures))
+	}
+
+	retryAfter := store.recordThrottles(failures)
+	failed := make(map[int]bool, len(failures))
+	var pending []firestoreOperation
+	numFatal := 0
+	for _, f := range failures {
+		failed[f.index] = true
+	}
+	store.countUsage("Init", operationsUsage(operations, failed))
+	for _, f := range failures {
+		failure := initFailure(f.op, items, f.err)
+		if retrying && isTransientError(f.err) {
+			failure.Retrying = true
+			pending = append(pending, f.op)
+		} else {
+			numFatal++
+		}
+		report.Failures = append(report.Failures, failure)
+	}
+	for i, op := range operations {
+		if failed[i] || metadataPaths[op.docRef().Path] {
+			continue
+		}
+		if _, isDelete := op.(deleteOperation); isDelete {
+			report.ItemsDeleted++
+		} else {
+			report.ItemsWritten++
+		}
+	}
+	store.setLastInitReport(report)
+
+	if retrying && numFatal == 0 {
+		markLater := len(pending) > 0 && !store.initRetry.MarkInitializedEarly
+		if !markLater {
+			if err := store.writeInitedDoc(store.context); err != nil {
+				return newStoreError("Init", nil, "", fmt.Errorf("could not mark collection as initialized: %w", err))
+			}
+			store.countUsage("Init", DocumentOperations{Writes: 1})
+		}
+		if len(pending) > 0 {
+			store.loggers.Warnf("%d write(s) failed while initializing collection %q and will be retried",
+				len(pending), store.collection)
+			store.startInitRetries(pending, markLater, retryAfter)
+			return nil
+		}
+	} else if len(pending) > 0 {
+		store.startInitRetries(pending, false, retryAfter)
+	}
 
-	if err := batchWriteOperations(store.context, store.client, operations); err != nil {
-		return fmt.Errorf("failed to write %d item(s) in batches: %w", len(operations), err)
+	if numFatal > 0 {
+		store.loggers.Errorf("Failed to write %d of %d operation(s) while initializing collection %q",
+			len(report.Failures), len(operations), store.collection)
+		return &InitError{Report: report}
 	}
 
-	store.loggers.Infof("Initialized collection %q with %d item(s)", store.collection, numItems)
+	store.loggers.Infof("Initialized collection %q with %d item(s)", store.collection, report.ItemsWritten)
 
 	return nil
 }
 
+// initedOperation returns the operation that sets the special document we check in IsInitialized().
+func (store *firestoreDataStore) initedOperation() setOperation {
+	data := store.initedMetadata()
+	data[fieldNamespace] = store.initedKey()
+	data[fieldKey] = store.initedKey()
+	store.addLabels(data)
+	store.addExpiry(data)
+	return setOperation{
+		ref:  store.collectionRef(store.collection).Doc(store.initedDocID()),
+		data: data,
+	}
+}
+
+func (store *firestoreDataStore) writeInitedDoc(ctx context.Context) error {
+	op := store.initedOperation()
+	_, err := op.ref.Set(ctx, op.data)
+	return err
+}
+
 func (store *firestoreDataStore) IsInitialized() bool {
-	docRef := store.client.Collection(store.collection).Doc(store.initedDocID())
-	_, err := docRef.Get(store.context)
-	return err == nil
+	op, err := store.telemetry.start("IsInitialized", nil, "")
+	inited := false
+	if err == nil {
+		err = store.panics.protect(store.loggers, "IsInitialized", func() error {
+			inited = store.isInitialized()
+			return nil
+		})
+	}
+	op.setAttributes(attrInitialized.Bool(inited))
+	op.end(-1, err)
+	return inited
+}
+
+func (store *firestoreDataStore) isInitialized() bool {
+	done, err := store.beginOperation(true)
+	if err != nil {
+		return false
+	}
+	defer done()
+
+	// Once the inited document exists, it is never removed by the store, so it is only read until
+	// it has been found once. See RecheckInitialized.
+	store.lock.Lock()
+	inited := store.initedCached
+	if inited {
+		store.readStats.InitializedCacheHits++
+	}
+	store.lock.Unlock()
+	if inited {
+		return true
+	}
+	store.countRead()
+
+	doc, err := store.getDocument(store.collectionRef(store.collection).Doc(store.initedDocID()))
+	if err == nil || status.Code(err) == codes.NotFound {
+		store.countUsage("IsInitialized", DocumentOperations{Reads: 1})
+	}
+	if err != nil {
+		// A missing inited document means that the collection really is not initialized, so the
+		// snapshot is only used if Firestore could not be reached. Without this, the SDK would not
+		// evaluate flags from the snapshot before it has connected.
+		if status.Code(err) == codes.NotFound || !(isTransientError(err) || isConnectionError(err)) {
+			return false
+		}
+		return store.fallbackSnapshot(err) != nil
+	}
+	if !store.checkInitedDoc(doc.Data()) {
+		return false
+	}
+	store.lock.Lock()
+	store.initedCached = true
+	store.lock.Unlock()
+	return true
+}
+
+func (store *firestoreDataStore) RecheckInitialized() bool {
+	store.lock.Lock()
+	store.initedCached = false
+	store.lock.Unlock()
+	return store.IsInitialized()
 }
 
 func (store *firestoreDataStore) GetAll(
 	kind ldstoretypes.DataKind,
+) (items []ldstoretypes.KeyedSerializedItemDescriptor, err error) {
+	op, err := store.telemetry.start("GetAll", kind, "")
+	defer func() { op.end(len(items), err) }()
+	defer store.panics.recover(store.loggers, "GetAll", &err)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := store.namespaceForKind(kind)
+	if cached, ok := store.cache.getAll(namespace, store.now()); ok {
+		return store.withBufferedItems(kind, cached), nil
+	}
+	items, err = store.getAllItems(kind)
+	if err == nil {
+		store.countUsage("GetAll", DocumentOperations{Reads: queryReads(len(items))})
+		store.cache.putAll(namespace, items, store.now())
+	} else if fallbackItems, ok := store.fallbackGetAll(kind, err); ok {
+		return store.withBufferedItems(kind, fallbackItems), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return store.withBufferedItems(kind, items), nil
+}
+
+// getAllItems reads every item of a data kind from Firestore, without using the item cache.
+func (store *firestoreDataStore) getAllItems(
+	kind ldstoretypes.DataKind,
 ) ([]ldstoretypes.KeyedSerializedItemDescriptor, error) {
+	done, err := store.beginOperation(true)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	store.countRead()
+
 	namespace := store.namespaceForKind(kind)
-	query := store.client.Collection(store.collection).Where(fieldNamespace, "==", namespace)
+	query := store.getAllQuery(kind)
 
-	iter := query.Documents(store.context)
-	defer iter.Stop()
+	if store.consistentGetAll {
+		return store.getAllConsistent(kind)
+	}
+	if store.getAllPageSize > 0 {
+		return store.getAllPaged(kind)
+	}
 
 	var results []ldstoretypes.KeyedSerializedItemDescriptor
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to iterate documents: %w", err)
+	err = store.retry.do(store.context, store.onRetry, func() error {
+		ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+		defer cancel()
+
+		if store.usePartitionedGetAll() {
+			partitioned, err := store.getAllPartitioned(ctx, kind, queryDocuments(ctx), false)
+			if status.Code(err) != codes.FailedPrecondition {
+				results = partitioned
+				return err
+			}
+			store.disablePartitionedGetAll(err)
 		}
 
-		key, serializedItemDesc, ok := store.decodeDocument(doc)
-		if ok {
-			results = append(results, ldstoretypes.KeyedSerializedItemDescriptor{
-				Key:  key,
-				Item: serializedItemDesc,
-			})
+		iter := query.Documents(ctx)
+		defer iter.Stop()
+
+		results = nil
+		for {
+			doc, err := iter.Next()
+			if err == iterator.Done {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			key, serializedItemDesc, ok := store.decodeDocumentWithRepair(kind, doc)
+			if ok {
+				store.observeVersion(kind, key, serializedItemDesc.Version)
+				results = append(results, ldstoretypes.KeyedSerializedItemDescriptor{
+					Key:  key,
+					Item: serializedItemDesc,
+				})
+			}
 		}
+	})
+	if err != nil {
+		return nil, newStoreError("GetAll", kind, "", err)
 	}
 
+	if log, ok := store.debugLog(LogReads); ok {
+		log.Debugf("Read %d item(s) (namespace=%s)", len(results), namespace)
+	}
 	return results, nil
 }
 
 func (store *firestoreDataStore) Get(
 	kind ldstoretypes.DataKind,
 	key string,
+) (item ldstoretypes.SerializedItemDescriptor, err error) {
+	op, err := store.telemetry.start("Get", kind, key)
+	defer func() { op.end(foundCount(err == nil && item.Version >= 0), err) }()
+	defer store.panics.recover(store.loggers, "Get", &err)
+	if err != nil {
+		return ldstoretypes.SerializedItemDescriptor{}.NotFound(), err
+	}
+	// A buffered update is only used if it is newer than the stored item, which another instance may
+	// have updated since, or if the stored item can't be read
+	buffered, isBuffered := store.bufferedGet(kind, key)
+
+	namespace := store.namespaceForKind(kind)
+	if cached, ok := store.cache.get(namespace, key, store.now()); ok {
+		if isBuffered && buffered.Version > cached.Version {
+			return buffered, nil
+		}
+		return cached, nil
+	}
+	item, err = store.getItem(kind, key)
+	if err == nil {
+		store.cache.put(namespace, key, item, store.now())
+	} else if fallbackItem, ok := store.fallbackGet(kind, key, err); ok {
+		item, err = fallbackItem, nil
+	}
+	if isBuffered && (err != nil || buffered.Version > item.Version) {
+		return buffered, nil
+	}
+	return item, err
+}
+
+// getItem reads an item from Firestore, without using the item cache.
+func (store *firestoreDataStore) getItem(
+	kind ldstoretypes.DataKind,
+	key string,
 ) (ldstoretypes.SerializedItemDescriptor, error) {
-	docID := store.makeDocID(kind, key)
-	docRef := store.client.Collection(store.collection).Doc(docID)
+	done, err := store.beginOperation(true)
+	if err != nil {
+		return ldstoretypes.SerializedItemDescriptor{}.NotFound(), err
+	}
+	defer done()
+	store.countRead()
+
+	docRef := store.docRef(kind, key)
 
-	doc, err := docRef.Get(store.context)
+	var doc *firestore.DocumentSnapshot
+	err = store.retry.do(store.context, store.onRetry, func() (err error) {
+		ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+		defer cancel()
+		doc, err = docRef.Get(ctx)
+		return err
+	})
+	if err == nil || status.Code(err) == codes.NotFound {
+		store.countUsage("Get", DocumentOperations{Reads: 1})
+	}
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
-			if store.loggers.IsDebugEnabled() {
-				store.loggers.Debugf("Item not found (key=%s)", key)
+			if log, ok := store.debugLog(LogReads); ok {
+				log.Debugf("Item not found (key=%s)", key)
 			}
 			return ldstoretypes.SerializedItemDescriptor{}.NotFound(), nil
 		}
-		return ldstoretypes.SerializedItemDescriptor{}.NotFound(),
-			fmt.Errorf("failed to get %s key %s: %w", kind, key, err)
+		return ldstoretypes.SerializedItemDescriptor{}.NotFound(), newStoreError("Get", kind, key, err)
 	}
 
 	if !doc.Exists() {
-		if store.loggers.IsDebugEnabled() {
-			store.loggers.Debugf("Item not found (key=%s)", key)
+		if log, ok := store.debugLog(LogReads); ok {
+			log.Debugf("Item not found (key=%s)", key)
 		}
 		return ldstoretypes.SerializedItemDescriptor{}.NotFound(), nil
 	}
 
-	if _, serializedItemDesc, ok := store.decodeDocument(doc); ok {
+	if _, serializedItemDesc, ok := store.decodeDocumentWithRepair(kind, doc); ok {
+		store.observeVersion(kind, key, serializedItemDesc.Version)
 		return serializedItemDesc, nil
 	}
 
-	return ldstoretypes.SerializedItemDescriptor{}.NotFound(),
-		fmt.Errorf("invalid data for %s key %s", kind, key)
+	return ldstoretypes.SerializedItemDescriptor{}.NotFound(), newStoreError("Get", kind, key, errInvalidData)
 }
 
 func (store *firestoreDataStore) Upsert(
@@ -234,151 +821,373 @@ func (store *firestoreDataStore) Upsert(
 	key string,
 	newItem ldstoretypes.SerializedItemDescriptor,
 ) (bool, error) {
-	data := store.encodeItem(kind, key, newItem)
-	if !store.checkSizeLimit(data) {
-		return false, nil
+	result, err := store.UpsertWithResult(kind, key, newItem)
+	return result.Updated, err
+}
+
+func (store *firestoreDataStore) UpsertWithResult(
+	kind ldstoretypes.DataKind,
+	key string,
+	newItem ldstoretypes.SerializedItemDescriptor,
+) (UpsertResult, error) {
+	op, err := store.telemetry.start("Upsert", kind, key)
+	result := UpsertResult{PreviousVersion: -1}
+	if err == nil {
+		err = store.panics.protect(store.loggers, "Upsert", func() (err error) {
+			result, err = store.upsertWithResult(kind, key, newItem)
+			return err
+		})
+	}
+	if result.Conflict {
+		store.countVersionConflict()
+	}
+	op.end(foundCount(result.Updated), err)
+	if err != nil && store.bufferUpsert(kind, key, newItem, err) {
+		return UpsertResult{Updated: true, PreviousVersion: -1, Buffered: true}, nil
+	}
+	return result, err
+}
+
+func (store *firestoreDataStore) upsertWithResult(
+	kind ldstoretypes.DataKind,
+	key string,
+	newItem ldstoretypes.SerializedItemDescriptor,
+) (UpsertResult, error) {
+	result := UpsertResult{PreviousVersion: -1}
+	if err := store.checkWritable(); err != nil {
+		return result, err
+	}
+	if !store.isWriter() {
+		// Report the skipped write as applied. If it were reported as a version conflict, the SDK
+		// would reread the item and cache whatever the writer had stored so far; see WriterElection
+		result.Updated = true
+		return result, nil
 	}
 
+	data, err := store.encodeItem(kind, key, newItem)
+	if err != nil {
+		return result, err
+	}
+	data, ok, err := store.applySizeLimit(kind, data)
+	if err != nil {
+		return result, err
+	}
+	if !ok {
+		return result, nil
+	}
+
+	// Wait for any other update of the same item in this process, rather than contending with it in
+	// Firestore. This is done before taking an operation slot, so waiting doesn't use one up.
+	docRef := store.docRef(kind, key)
+	release := store.keyLocks.acquire(docRef.Path)
+	defer release()
+
+	done, err := store.beginOperation(false)
+	if err != nil {
+		return result, err
+	}
+	defer done()
+
 	if store.testUpdateHook != nil {
 		store.testUpdateHook()
 	}
 
-	docID := store.makeDocID(kind, key)
-	docRef := store.client.Collection(store.collection).Doc(docID)
+	handled := false
+	if store.preconditionUpserts {
+		handled, err = store.upsertWithPreconditions(kind, key, docRef, data, newItem.Version, &result)
+	}
 
-	// Use a transaction to ensure version checking
-	err := store.client.RunTransaction(store.context, func(ctx context.Context, tx *firestore.Transaction) error {
-		doc, err := tx.Get(docRef)
+	if !handled {
+		// Use a transaction to ensure version checking
+		attempts := 0
+		err = store.retry.do(store.context, store.onRetry, func() error {
+			opCtx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+			defer cancel()
+			return store.runTransaction(opCtx, func(ctx context.Context, tx *firestore.Transaction) error {
+				attempts++
+				if log, ok := store.debugLog(LogTransactions); ok {
+					log.Debugf("Transaction attempt %d to update item (namespace=%s key=%s)", attempts, kind, key)
+				}
+				doc, err := tx.Get(docRef)
+				store.countUsage("Upsert", DocumentOperations{Reads: 1})
 
-		var oldVersion int
-		if err == nil {
-			if doc.Exists() {
-				if v, ok := doc.Data()[fieldVersion].(int64); ok {
-					oldVersion = int(v)
+				var oldVersion int
+				if err == nil {
+					if doc.Exists() {
+						if store.observeSchemaVersion(doc.Data()) && store.readOnlyOnNewerSchema {
+							return ErrReadOnly
+						}
+						if v, ok := doc.Data()[fieldVersion].(int64); ok {
+							oldVersion = int(v)
+						}
+					}
+				} else if status.Code(err) == codes.NotFound {
+					oldVersion = -1
+				} else {
+					// Any error other than NotFound is a real error
+					return err
 				}
-			}
-		} else if status.Code(err) == codes.NotFound {
-			oldVersion = -1
-		} else {
-			// Any error other than NotFound is a real error
-			return err
-		}
+				// The transaction function may run more than once; the last attempt is the one that counts.
+				result.PreviousVersion = oldVersion
 
-		if oldVersion >= newItem.Version {
-			if store.loggers.IsDebugEnabled() {
-				store.loggers.Debugf("Not updating item due to version check (namespace=%s key=%s version=%d, existing=%d)",
-					kind, key, newItem.Version, oldVersion)
-			}
-			return errVersionCheckFailed
-		}
+				if oldVersion >= newItem.Version {
+					if log, ok := store.debugLog(LogWrites); ok {
+						log.Debugf("Not updating item due to version check (namespace=%s key=%s version=%d, existing=%d)",
+							kind, key, newItem.Version, oldVersion)
+					}
+					return errVersionCheckFailed
+				}
 
-		return tx.Set(docRef, data)
-	})
+				if store.namespaceSummaries {
+					countDelta := 0
+					if oldVersion < 0 {
+						countDelta = 1
+					}
+					if err := store.updateSummaryInTransaction(tx, kind, countDelta, newItem.Version); err != nil {
+						return err
+					}
+				}
+				if store.namespaceManifests {
+					if err := store.updateManifestInTransaction(tx, kind, key, newItem.Version); err != nil {
+						return err
+					}
+				}
+				if store.auditCollection != "" {
+					if err := store.addUpsertAuditRecord(tx, kind, key, oldVersion, newItem.Version); err != nil {
+						return err
+					}
+				}
+				return tx.Set(docRef, data)
+			})
+		})
+	}
+
+	// Whether or not the item was written, the cached one may be out of date
+	store.cache.invalidate(store.namespaceForKind(kind), key)
 
 	if err == errVersionCheckFailed {
-		return false, nil
+		result.Conflict = true
+		return result, nil
 	}
 	if err != nil {
-		return false, fmt.Errorf("failed to upsert %s key %s: %w", kind, key, err)
+		store.recordThrottle(err)
+		return UpsertResult{PreviousVersion: -1}, newStoreError("Upsert", kind, key, err)
 	}
 
-	return true, nil
+	result.Updated = true
+	if !handled {
+		writes := 1 + store.metadataWrites()
+		if store.auditCollection != "" {
+			writes++
+		}
+		store.countUsage("Upsert", DocumentOperations{Writes: writes})
+	}
+	if log, ok := store.debugLog(LogWrites); ok {
+		log.Debugf("Updated item (namespace=%s key=%s version=%d, previous=%d)",
+			kind, key, newItem.Version, result.PreviousVersion)
+	}
+	store.observeVersion(kind, key, newItem.Version)
+	return result, nil
 }
 
 var errVersionCheckFailed = errors.New("version check failed")
 
 func (store *firestoreDataStore) IsStoreAvailable() bool {
-	// Test the connection by trying to get the inited document
-	docRef := store.client.Collection(store.collection).Doc(store.initedDocID())
-	_, err := docRef.Get(store.context)
+	defer store.panics.recover(store.loggers, "IsStoreAvailable", nil)
+	err := classifyAvailabilityError(store.checkConnection(store.availabilityTimeout))
+	store.recordAvailabilityError(err)
+	store.telemetry.recordHealth(err)
+	return store.applyAvailabilityGrace(err == nil, err)
+}
+
+// checkConnection tests the connection by trying to get the inited document, giving up after the
+// timeout.
+func (store *firestoreDataStore) checkConnection(timeout time.Duration) error {
+	ctx, cancel := withOperationTimeout(store.context, timeout)
+	defer cancel()
+
+	docRef := store.collectionRef(store.collection).Doc(store.initedDocID())
+	_, err := docRef.Get(ctx)
 	// Both "found" and "not found" are acceptable - we just want to know the connection works
-	return err == nil
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	return err
 }
 
 func (store *firestoreDataStore) Close() error {
+	if !store.beginClose() {
+		return nil
+	}
+	return store.closeNow()
+}
+
+// closeNow cancels any operations that are still in progress and releases the client.
+func (store *firestoreDataStore) closeNow() error {
+	if store.election != nil {
+		store.releaseLease()
+	}
 	store.cancelContext() // stops any pending operations
 	// Only close the client if we created it. If a client was provided to us,
 	// it's the caller's responsibility to close it.
 	if store.ownsClient {
-		return store.client.Close()
+		return store.firestoreClient().Close()
+	}
+	if shared := store.sharedClient; shared != nil {
+		store.sharedClient = nil // so that closing the store twice does not release the client twice
+		return shared.release()
 	}
 	return nil
 }
 
-func (store *firestoreDataStore) prefixedNamespace(baseNamespace string) string {
-	if store.prefix == "" {
+// kindRoute is the collection and prefix configuration for a single data kind.
+type kindRoute struct {
+	collection string
+	prefix     string
+}
+
+// kindPrefix returns the key prefix for a data kind, which may have been overridden with KindRoute.
+func (store *firestoreDataStore) kindPrefix(kind ldstoretypes.DataKind) string {
+	if route, ok := store.kindRoutes[kind.GetName()]; ok && route.prefix != "" {
+		return route.prefix
+	}
+	return store.prefix
+}
+
+// collectionForKind returns the collection for a data kind, which may have been overridden with KindRoute.
+// With SubcollectionPerKind, this is the kind's own subcollection within that collection.
+func (store *firestoreDataStore) collectionForKind(kind ldstoretypes.DataKind) *firestore.CollectionRef {
+	parentDoc := resolveParentDocument(store.parentDoc, store.kindPrefix(kind))
+	coll := collectionRef(store.firestoreClient(), parentDoc, store.collectionNameForKind(kind))
+	if store.subcollections {
+		return kindSubcollection(coll, store.ids.escape(store.namespaceForKind(kind)))
+	}
+	return coll
+}
+
+// collectionNameForKind returns the name of the collection for a data kind, which may have been
+// overridden with KindRoute.
+func (store *firestoreDataStore) collectionNameForKind(kind ldstoretypes.DataKind) string {
+	if route, ok := store.kindRoutes[kind.GetName()]; ok && route.collection != "" {
+		return route.collection
+	}
+	return store.collection
+}
+
+// queryForKind returns a query for the documents of a data kind. With CollectionGroupQueries, this
+// is a collection group query, which finds the documents under any parent document.
+func (store *firestoreDataStore) queryForKind(kind ldstoretypes.DataKind) firestore.Query {
+	namespace := store.namespaceForKind(kind)
+	if !store.collectionGroup {
+		return store.collectionForKind(kind).Where(fieldNamespace, "==", namespace)
+	}
+	groupID := store.collectionNameForKind(kind)
+	if store.subcollections {
+		groupID = itemsSubcollection
+	}
+	return store.firestoreClient().CollectionGroup(groupID).Where(fieldNamespace, "==", namespace)
+}
+
+// getAllQuery returns the query that GetAll uses for a data kind.
+func (store *firestoreDataStore) getAllQuery(kind ldstoretypes.DataKind) firestore.Query {
+	query := store.queryForKind(kind)
+	if store.excludeDeleted {
+		query = query.Where(fieldDeleted, "==", false)
+	}
+	return query
+}
+
+func (store *firestoreDataStore) collectionRef(name string) *firestore.CollectionRef {
+	return collectionRef(store.firestoreClient(), resolveParentDocument(store.parentDoc, store.prefix), name)
+}
+
+func (store *firestoreDataStore) docRef(kind ldstoretypes.DataKind, key string) *firestore.DocumentRef {
+	return store.collectionForKind(kind).Doc(store.makeDocID(kind, key))
+}
+
+func prefixedNamespace(prefix, baseNamespace string) string {
+	if prefix == "" {
 		return baseNamespace
 	}
-	return store.prefix + ":" + baseNamespace
+	return prefix + ":" + baseNamespace
 }
 
 func (store *firestoreDataStore) namespaceForKind(kind ldstoretypes.DataKind) string {
-	return store.prefixedNamespace(kind.GetName())
+	return prefixedNamespace(store.kindPrefix(kind), kind.GetName())
 }
 
 func (store *firestoreDataStore) initedKey() string {
-	return store.prefixedNamespace("$inited")
+	return prefixedNamespace(store.prefix, "$inited")
 }
 
 func (store *firestoreDataStore) initedDocID() string {
-	return store.makeDocIDFromParts(store.initedKey(), store.initedKey())
+	return store.ids.makeID(store.prefix, store.initedKey(), store.initedKey())
 }
 
 func (store *firestoreDataStore) makeDocID(kind ldstoretypes.DataKind, key string) string {
-	return store.makeDocIDFromParts(store.namespaceForKind(kind), key)
-}
-
-func (store *firestoreDataStore) makeDocIDFromParts(namespace, key string) string {
-	// Document ID format: {prefix}:{namespace}:{key}
-	// Colons are allowed in Firestore document IDs
-	if store.prefix == "" {
-		return namespace + ":" + key
+	if store.subcollections {
+		// The subcollection already identifies the prefix and namespace
+		return store.ids.finish(store.ids.escape(key))
 	}
-	return store.prefix + ":" + namespace + ":" + key
+	return store.ids.makeID(store.kindPrefix(kind), store.namespaceForKind(kind), key)
 }
 
-func (store *firestoreDataStore) readExistingDocIDs(
+// readExistingDocRefs returns the existing documents for every data kind in newData, keyed by path.
+func (store *firestoreDataStore) readExistingDocRefs(
+	ctx context.Context,
 	newData []ldstoretypes.SerializedCollection,
-) (map[string]bool, error) {
-	docIDs := make(map[string]bool)
+) (map[string]*firestore.DocumentRef, error) {
+	docRefs := make(map[string]*firestore.DocumentRef)
 
 	for _, coll := range newData {
-		namespace := store.namespaceForKind(coll.Kind)
-		query := store.client.Collection(store.collection).
-			Where(fieldNamespace, "==", namespace).
-			Select() // Select no fields, just get document IDs
-
-		iter := query.Documents(store.context)
-		for {
-			doc, err := iter.Next()
-			if err == iterator.Done {
-				break
+		query := store.queryForKind(coll.Kind)
+		err := forEachDocRefPage(ctx, query, deletePageSize, func(refs []*firestore.DocumentRef) error {
+			for _, ref := range refs {
+				docRefs[ref.Path] = ref
 			}
-			if err != nil {
-				iter.Stop()
-				return nil, err
-			}
-			docIDs[doc.Ref.ID] = true
+			return nil
+		})
+		if err != nil {
+			return nil, err
 		}
-		iter.Stop()
 	}
 
-	return docIDs, nil
+	return docRefs, nil
 }
 
 func (store *firestoreDataStore) decodeDocument(
 	doc *firestore.DocumentSnapshot,
 ) (string, ldstoretypes.SerializedItemDescriptor, bool) {
 	data := doc.Data()
+	store.observeSchemaVersion(data)
+	if _, err := upgradeDocument(data, schemaMigrations, currentSchemaVersion); err != nil {
+		store.loggers.Warnf("Could not read Firestore document %q: %s", doc.Ref.ID, err)
+		return "", ldstoretypes.SerializedItemDescriptor{}, false
+	}
 
 	key, _ := data[fieldKey].(string)
-	version, _ := data[fieldVersion].(int64)
-	itemJSON, _ := data[fieldItem].(string)
+	version, ok := data[fieldVersion].(int64)
+	if !ok {
+		// A version that is missing or has the wrong type must not be read as zero, since it would then
+		// lose every version comparison; read repair may be able to recover it
+		store.loggers.Warnf("Firestore document %q has no valid version (found %v)", doc.Ref.ID, data[fieldVersion])
+		return "", ldstoretypes.SerializedItemDescriptor{}, false
+	}
+	itemJSON, err := store.itemPayload(data)
+	if errors.Is(err, errChecksumMismatch) {
+		store.loggers.Errorf("The item in Firestore document %q may be corrupted and will be treated as missing: %s",
+			doc.Ref.ID, err)
+		return "", ldstoretypes.SerializedItemDescriptor{}, false
+	}
+	if err != nil {
+		store.loggers.Warnf("Could not decode the item in Firestore document %q: %s", doc.Ref.ID, err)
+		return "", ldstoretypes.SerializedItemDescriptor{}, false
+	}
 
 	if key != "" {
 		return key, ldstoretypes.SerializedItemDescriptor{
 			Version:        int(version),
-			SerializedItem: []byte(itemJSON),
+			SerializedItem: itemJSON,
 		}, true
 	}
 
@@ -389,32 +1198,17 @@ func (store *firestoreDataStore) encodeItem(
 	kind ldstoretypes.DataKind,
 	key string,
 	item ldstoretypes.SerializedItemDescriptor,
-) map[string]any {
-	return map[string]any{
-		fieldNamespace: store.namespaceForKind(kind),
-		fieldKey:       key,
-		fieldVersion:   item.Version,
-		fieldItem:      string(item.SerializedItem),
-	}
-}
-
-func (store *firestoreDataStore) checkSizeLimit(data map[string]any) bool {
-	// Rough estimate of document size
-	size := 0
-	for key, value := range data {
-		size += len(key)
-		if str, ok := value.(string); ok {
-			size += len(str)
-		} else {
-			size += 8 // rough estimate for numeric values
-		}
+) (map[string]any, error) {
+	data := store.addExpiry(store.addLabels(map[string]any{
+		fieldNamespace:     store.namespaceForKind(kind),
+		fieldKey:           key,
+		fieldVersion:       item.Version,
+		fieldDeleted:       item.Deleted,
+		fieldSchemaVersion: currentSchemaVersion,
+		fieldLastUpdated:   firestore.ServerTimestamp,
+	}))
+	if err := store.setItemPayload(data, item.SerializedItem); err != nil {
+		return nil, err
 	}
-
-	if size <= firestoreMaxDocSize {
-		return true
-	}
-
-	store.loggers.Errorf("The item %q in namespace %q was too large to store in Firestore and was dropped",
-		data[fieldKey], data[fieldNamespace])
-	return false
+	return data, nil
 }
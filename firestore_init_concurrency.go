@@ -0,0 +1,80 @@
+package ldfirestore
+
+import (
+	"context"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	"golang.org/x/time/rate"
+)
+
+// batchWriteOperationsConcurrently is like batchWriteOperationsWithFlush, but divides the operations
+// into up to concurrency ranges of about the same size, and writes each range with its own BulkWriter
+// at the same time. The indexes of the failures, and the arguments of onFlush, refer to the full list
+// of operations; onFlush may be called from any of the writers, but not from more than one at once.
+// The limiter, if any, is shared, so it limits the total rate of all of the writers.
+func batchWriteOperationsConcurrently(
+	ctx context.Context,
+	client *firestore.Client,
+	operations []firestoreOperation,
+	concurrency int,
+	flushEvery int,
+	onFlush func(chunkStart, completed int, chunkFailures []operationFailure),
+	limiter *rate.Limiter,
+) ([]operationFailure, error) {
+	if concurrency <= 1 || len(operations) < 2 {
+		return batchWriteOperationsWithFlush(ctx, client, operations, flushEvery, onFlush, limiter)
+	}
+
+	rangeSize := (len(operations) + concurrency - 1) / concurrency
+	var (
+		failures  []operationFailure
+		firstErr  error
+		completed int
+		lock      sync.Mutex
+		wg        sync.WaitGroup
+	)
+	for start := 0; start < len(operations); start += rangeSize {
+		end := min(start+rangeSize, len(operations))
+		offset := start
+		rangeCompleted := 0
+		var rangeOnFlush func(int, int, []operationFailure)
+		if onFlush != nil {
+			rangeOnFlush = func(chunkStart, done int, chunkFailures []operationFailure) {
+				lock.Lock()
+				defer lock.Unlock()
+				completed += done - rangeCompleted
+				rangeCompleted = done
+				onFlush(offset+chunkStart, completed, offsetFailures(chunkFailures, offset))
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rangeFailures, err := batchWriteOperationsWithFlush(ctx, client, operations[offset:end], flushEvery,
+				rangeOnFlush, limiter)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			failures = append(failures, offsetFailures(rangeFailures, offset)...)
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return failures, nil
+}
+
+// offsetFailures returns a copy of the failures with offset added to each index.
+func offsetFailures(failures []operationFailure, offset int) []operationFailure {
+	result := make([]operationFailure, 0, len(failures))
+	for _, f := range failures {
+		f.index += offset
+		result = append(result, f)
+	}
+	return result
+}
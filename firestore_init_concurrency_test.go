@@ -0,0 +1,55 @@
+package ldfirestore
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffsetFailures(t *testing.T) {
+	err := errors.New("failed")
+	failures := []operationFailure{{index: 0, err: err}, {index: 3, err: err}}
+	assert.Equal(t, []operationFailure{{index: 10, err: err}, {index: 13, err: err}}, offsetFailures(failures, 10))
+	assert.Equal(t, 0, failures[0].index) // the original is unchanged
+	assert.Len(t, offsetFailures(nil, 10), 0)
+
+	b := DataStore("my-project", "my-collection").InitConcurrency(4)
+	assert.Equal(t, 4, b.initConcurrency)
+}
+
+func TestInitConcurrency(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := baseDataStoreBuilder().InitConcurrency(4).InitFlushInterval(5).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	var items []ldstoretypes.KeyedSerializedItemDescriptor
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("flag%02d", i)
+		items = append(items, ldstoretypes.KeyedSerializedItemDescriptor{
+			Key:  key,
+			Item: ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"` + key + `"}`)},
+		})
+	}
+	require.NoError(t, store.Init([]ldstoretypes.SerializedCollection{
+		{Kind: ldstoreimpl.Features(), Items: items},
+		{Kind: ldstoreimpl.Segments()},
+	}))
+	assert.Equal(t, InitReport{ItemsWritten: 50}, store.(ExtendedDataStore).LastInitReport())
+	assert.True(t, store.IsInitialized())
+
+	all, err := store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Len(t, all, 50)
+}
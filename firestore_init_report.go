@@ -0,0 +1,151 @@
+package ldfirestore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InitOperation identifies the kind of write that Init attempted for a document.
+type InitOperation string
+
+const (
+	// InitOperationWrite is the creation or replacement of an item's document.
+	InitOperationWrite InitOperation = "write"
+
+	// InitOperationDelete is the removal of a document for an item that is no longer in the data set.
+	InitOperationDelete InitOperation = "delete"
+)
+
+// InitReport describes the outcome of an Init call on the data store. See
+// [ExtendedDataStore.LastInitReport] and [InitError].
+type InitReport struct {
+	// ItemsWritten is the number of items that were successfully written.
+	ItemsWritten int
+
+	// ItemsDeleted is the number of obsolete documents that were successfully removed.
+	ItemsDeleted int
+
+	// ItemsSkipped is the number of items that were not written because they were too large. They are
+	// listed in Skipped.
+	ItemsSkipped int
+
+	// ItemsUnchanged is the number of items that were not written because the stored item already
+	// had the same version. This is only done with [StoreBuilder.DifferentialInit].
+	ItemsUnchanged int
+
+	// Skipped lists every item that was not written because it was too large.
+	Skipped []InitSkippedItem
+
+	// Failures lists every document operation that failed.
+	Failures []InitFailure
+}
+
+// Complete returns true if every item in the data set is stored: no item was skipped, and no
+// operation failed, including any that are still being retried.
+func (r InitReport) Complete() bool {
+	return len(r.Skipped) == 0 && len(r.Failures) == 0
+}
+
+// InitSkippedItem describes an item that Init did not write because it was larger than the size
+// limit for its data kind. See [StoreBuilder.KindSizeLimit].
+type InitSkippedItem struct {
+	// Namespace and Key identify the item.
+	Namespace string
+	Key       string
+
+	// Size is the estimated size of the item's document in bytes.
+	Size int
+
+	// MaxSize is the size limit that applied to the item.
+	MaxSize int
+}
+
+// InitFailure describes a single document operation that failed during Init.
+type InitFailure struct {
+	// Operation is the kind of write that was attempted.
+	Operation InitOperation
+
+	// DocumentID is the Firestore document ID.
+	DocumentID string
+
+	// DocumentPath is the full path of the document. Unlike the ID, it distinguishes documents of
+	// different data kinds that are in different collections, such as with
+	// [StoreBuilder.SubcollectionPerKind].
+	DocumentPath string
+
+	// Namespace and Key identify the item, if it was known. They are empty for deletions of
+	// obsolete documents, since those are found by document ID only.
+	Namespace string
+	Key       string
+
+	// Err is the error returned by Firestore.
+	Err error
+
+	// Retrying is true if the operation failed with a transient error and is being retried in the
+	// background. See [StoreBuilder.InitRetry].
+	Retrying bool
+}
+
+// InitError is the error returned by Init if any of its document operations failed, other than
+// ones that are being retried. Operations that did not fail have still been applied, so the store
+// may contain a mix of old and new data. With [StoreBuilder.AtomicInit], the failures also include
+// the operations that were not attempted.
+type InitError struct {
+	Report InitReport
+}
+
+// maxInitErrorItems is the number of failures that are described individually in the message of an
+// InitError. The rest are only counted; all of them are available in the report.
+const maxInitErrorItems = 10
+
+// Error returns a summary of the failures, naming the items that could not be written or deleted.
+func (e *InitError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d operation(s) failed during Init: ", len(e.Report.Failures))
+	for i, f := range e.Report.Failures {
+		if i == maxInitErrorItems {
+			fmt.Fprintf(&b, "; and %d more", len(e.Report.Failures)-i)
+			break
+		}
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "%s %q: %s", f.Operation, f.describeTarget(), f.Err)
+	}
+	return b.String()
+}
+
+// describeTarget returns the namespace and key of the item if they are known, or else the document ID.
+func (f InitFailure) describeTarget() string {
+	if f.Key != "" {
+		return f.Namespace + ":" + f.Key
+	}
+	return f.DocumentID
+}
+
+// Unwrap returns the errors of all the failed operations.
+func (e *InitError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Report.Failures))
+	for _, f := range e.Report.Failures {
+		errs = append(errs, f.Err)
+	}
+	return errs
+}
+
+// initItemRef records which item an Init operation was for, so that failures can be reported.
+type initItemRef struct {
+	namespace string
+	key       string
+}
+
+func (store *firestoreDataStore) LastInitReport() InitReport {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	return store.lastInitReport
+}
+
+func (store *firestoreDataStore) setLastInitReport(report InitReport) {
+	store.lock.Lock()
+	store.lastInitReport = report
+	store.lock.Unlock()
+}
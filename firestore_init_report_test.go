@@ -0,0 +1,112 @@
+package ldfirestore
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitError(t *testing.T) {
+	err1 := errors.New("first")
+	err2 := errors.New("second")
+	err := &InitError{Report: InitReport{
+		ItemsWritten: 3,
+		Failures: []InitFailure{
+			{Operation: InitOperationWrite, DocumentID: "features:flag1", Namespace: "features", Key: "flag1", Err: err1},
+			{Operation: InitOperationDelete, DocumentID: "features:flag2", Err: err2},
+		},
+	}}
+
+	assert.Equal(t, `2 operation(s) failed during Init: write "features:flag1": first; delete "features:flag2": second`,
+		err.Error())
+	assert.True(t, errors.Is(err, err1))
+	assert.True(t, errors.Is(err, err2))
+
+	var initErr *InitError
+	require.True(t, errors.As(error(err), &initErr))
+	assert.Len(t, initErr.Report.Failures, 2)
+
+	t.Run("long lists are truncated", func(t *testing.T) {
+		var failures []InitFailure
+		for i := 0; i < maxInitErrorItems+5; i++ {
+			failures = append(failures, InitFailure{Operation: InitOperationWrite, DocumentID: "x", Err: err1})
+		}
+		msg := (&InitError{Report: InitReport{Failures: failures}}).Error()
+		assert.Equal(t, maxInitErrorItems, strings.Count(msg, `write "x"`))
+		assert.True(t, strings.HasSuffix(msg, "; and 5 more"))
+	})
+}
+
+func TestLastInitReport(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	ext := store.(ExtendedDataStore)
+
+	assert.Equal(t, InitReport{}, ext.LastInitReport())
+
+	makeData := func(keys ...string) []ldstoretypes.SerializedCollection {
+		var items []ldstoretypes.KeyedSerializedItemDescriptor
+		for _, key := range keys {
+			items = append(items, ldstoretypes.KeyedSerializedItemDescriptor{
+				Key:  key,
+				Item: ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"` + key + `"}`)},
+			})
+		}
+		return []ldstoretypes.SerializedCollection{{Kind: ldstoreimpl.Features(), Items: items}}
+	}
+
+	require.NoError(t, store.Init(makeData("flag1", "flag2")))
+	assert.Equal(t, InitReport{ItemsWritten: 2}, ext.LastInitReport())
+
+	require.NoError(t, store.Init(makeData("flag1")))
+	assert.Equal(t, InitReport{ItemsWritten: 1, ItemsDeleted: 1}, ext.LastInitReport())
+}
+
+func TestInitReportComplete(t *testing.T) {
+	assert.True(t, InitReport{ItemsWritten: 2, ItemsUnchanged: 1}.Complete())
+	assert.False(t, InitReport{ItemsSkipped: 1, Skipped: []InitSkippedItem{{Key: "flag1"}}}.Complete())
+	assert.False(t, InitReport{Failures: []InitFailure{{DocumentID: "x", Retrying: true}}}.Complete())
+}
+
+func TestInitReportListsSkippedItems(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close() // every write will fail
+
+	store, err := DataStore("my-project", "my-collection").FirestoreClient(client).
+		PreserveExistingItems(true).
+		KindSizeLimit(ldstoreimpl.Features(), 10, OversizedItemDrop).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	item := ldstoretypes.KeyedSerializedItemDescriptor{
+		Key:  "flag1",
+		Item: ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)},
+	}
+	data := []ldstoretypes.SerializedCollection{
+		{Kind: ldstoreimpl.Features(), Items: []ldstoretypes.KeyedSerializedItemDescriptor{item}},
+	}
+	require.Error(t, store.Init(data))
+
+	report := store.(ExtendedDataStore).LastInitReport()
+	assert.False(t, report.Complete())
+	assert.Equal(t, 1, report.ItemsSkipped)
+	require.Len(t, report.Skipped, 1)
+	assert.Equal(t, "features", report.Skipped[0].Namespace)
+	assert.Equal(t, "flag1", report.Skipped[0].Key)
+	assert.Equal(t, 10, report.Skipped[0].MaxSize)
+	assert.Greater(t, report.Skipped[0].Size, 10)
+}
@@ -0,0 +1,203 @@
+package ldfirestore
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// InitRetryOptions configures background retrying of document writes that fail during Init. See
+// [StoreBuilder.InitRetry].
+type InitRetryOptions struct {
+	// MaxAttempts is the number of retry attempts to make for the failed writes, not counting the
+	// original attempt. If it is zero or less, retrying is disabled.
+	MaxAttempts int
+
+	// InitialDelay is the delay before the first retry. Each later retry waits twice as long as the
+	// previous one, up to MaxDelay. If it is zero or less, DefaultInitRetryDelay is used.
+	InitialDelay time.Duration
+
+	// MaxDelay is the longest delay between retries. If it is zero or less, there is no maximum.
+	MaxDelay time.Duration
+
+	// MarkInitializedEarly causes the store to report itself as initialized as soon as Init returns,
+	// even if some writes are still waiting to be retried. By default, the store is only marked as
+	// initialized once all of the retries have succeeded.
+	MarkInitializedEarly bool
+}
+
+// DefaultInitRetryDelay is the default value for [InitRetryOptions.InitialDelay].
+const DefaultInitRetryDelay = time.Second
+
+// isTransientError returns true for errors that indicate a temporary condition in Firestore or the
+// network, where trying the same operation again later could succeed. This includes an attempt that
+// ran out of time, but not one that was canceled. Errors such as PermissionDenied or InvalidArgument
+// are never transient, since trying again would fail the same way.
+func isTransientError(err error) bool {
+	return isTransientCode(errorCode(err))
+}
+
+// isTransientCode returns true for the gRPC status codes of transient errors.
+func isTransientCode(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted, codes.ResourceExhausted, codes.Internal:
+		return true
+	default:
+		return false
+	}
+}
+
+// startInitRetries begins retrying the given operations in the background, replacing any retries
+// that were still pending from an earlier Init. If markInitialized is true, the inited document is
+// written once every operation has succeeded. If retryAfter is greater than zero, it is used instead
+// of the configured delay before the first retry.
+func (store *firestoreDataStore) startInitRetries(
+	pending []firestoreOperation,
+	markInitialized bool,
+	retryAfter time.Duration,
+) {
+	ctx, cancel := context.WithCancel(store.context)
+	store.lock.Lock()
+	if store.cancelInitRetries != nil {
+		store.cancelInitRetries()
+	}
+	store.cancelInitRetries = cancel
+	store.lock.Unlock()
+
+	go store.runInitRetries(ctx, pending, markInitialized, retryAfter)
+}
+
+// stopInitRetries cancels any retries that are pending from an earlier Init, since a new Init
+// supersedes them.
+func (store *firestoreDataStore) stopInitRetries() {
+	store.lock.Lock()
+	if store.cancelInitRetries != nil {
+		store.cancelInitRetries()
+		store.cancelInitRetries = nil
+	}
+	store.lock.Unlock()
+}
+
+// runInitRetries retries the pending operations until they all succeed, they have been retried
+// MaxAttempts times, or ctx is canceled. An operation that fails with an error that is not transient is
+// abandoned. The inited document is only written if markInitialized is true and no operation was left
+// unwritten, and the outcome is recorded in the report of the Init.
+func (store *firestoreDataStore) runInitRetries(
+	ctx context.Context,
+	pending []firestoreOperation,
+	markInitialized bool,
+	retryAfter time.Duration,
+) {
+	delay := store.initRetry.InitialDelay
+	if delay <= 0 {
+		delay = DefaultInitRetryDelay
+	}
+
+	var abandoned []operationFailure
+	lastErrors := make(map[string]error, len(pending))
+	for attempt := 1; attempt <= store.initRetry.MaxAttempts && len(pending) > 0; attempt++ {
+		// A delay suggested by Firestore for a quota error takes precedence over our own backoff
+		wait := delay
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-store.after(wait):
+		}
+
+		failures, err := batchWriteOperations(ctx, store.firestoreClient(), pending)
+		if ctx.Err() != nil {
+			return
+		}
+		retryAfter = 0
+		if err != nil {
+			store.loggers.Warnf("Retry %d of Init writes failed: %s", attempt, err)
+			retryAfter = store.recordThrottle(err)
+			for _, op := range pending {
+				lastErrors[op.docRef().Path] = err
+			}
+		} else {
+			retryAfter = store.recordThrottles(failures)
+			remaining := make([]firestoreOperation, 0, len(failures))
+			for _, f := range failures {
+				lastErrors[f.op.docRef().Path] = f.err
+				if isTransientError(f.err) {
+					remaining = append(remaining, f.op)
+				} else {
+					store.loggers.Errorf("Giving up on writing document %q during Init: %s", f.op.docRef().Path, f.err)
+					abandoned = append(abandoned, f)
+				}
+			}
+			pending = remaining
+		}
+
+		delay *= 2
+		if store.initRetry.MaxDelay > 0 && delay > store.initRetry.MaxDelay {
+			delay = store.initRetry.MaxDelay
+		}
+	}
+
+	unwritten := make(map[string]error, len(pending)+len(abandoned))
+	for _, op := range pending {
+		unwritten[op.docRef().Path] = lastErrors[op.docRef().Path]
+	}
+	for _, f := range abandoned {
+		unwritten[f.op.docRef().Path] = f.err
+	}
+	if !store.resolveInitRetries(ctx, unwritten) {
+		return // superseded by a later Init
+	}
+
+	if len(unwritten) > 0 {
+		store.loggers.Errorf("%d document write(s) from Init could not be completed after %d retries; "+
+			"the data in collection %q is incomplete", len(unwritten), store.initRetry.MaxAttempts, store.collection)
+		if markInitialized {
+			store.loggers.Errorf("Not marking collection %q as initialized, since it is incomplete", store.collection)
+		}
+		return
+	}
+
+	store.loggers.Infof("All retried Init writes for collection %q succeeded", store.collection)
+	if markInitialized {
+		if err := store.writeInitedDoc(ctx); err != nil {
+			store.loggers.Errorf("Failed to mark collection %q as initialized: %s", store.collection, err)
+		}
+	}
+}
+
+// resolveInitRetries updates the report of the Init whose writes were being retried, once the
+// retries are over. The failures that were being retried are removed from the report and counted as
+// written or deleted, except for those in unwritten, which is keyed by document path, and which
+// remain as failures that are no longer being retried, with their last errors. It returns false, without changing the report, if ctx has
+// been canceled because a later Init has started.
+func (store *firestoreDataStore) resolveInitRetries(ctx context.Context, unwritten map[string]error) bool {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if ctx.Err() != nil {
+		return false
+	}
+	report := &store.lastInitReport
+	failures := make([]InitFailure, 0, len(report.Failures))
+	for _, f := range report.Failures {
+		if f.Retrying {
+			if err, ok := unwritten[f.DocumentPath]; ok {
+				f.Retrying = false
+				if err != nil {
+					f.Err = err
+				}
+			} else if f.Operation == InitOperationDelete {
+				report.ItemsDeleted++
+				continue
+			} else {
+				report.ItemsWritten++
+				continue
+			}
+		}
+		failures = append(failures, f)
+	}
+	report.Failures = failures
+	return true
+}
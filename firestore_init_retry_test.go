@@ -0,0 +1,136 @@
+package ldfirestore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsTransientError(t *testing.T) {
+	for _, code := range []codes.Code{
+		codes.Unavailable, codes.DeadlineExceeded, codes.Aborted, codes.ResourceExhausted, codes.Internal,
+	} {
+		assert.True(t, isTransientError(status.Error(code, "x")), code.String())
+	}
+	for _, code := range []codes.Code{
+		codes.InvalidArgument, codes.NotFound, codes.PermissionDenied, codes.FailedPrecondition,
+	} {
+		assert.False(t, isTransientError(status.Error(code, "x")), code.String())
+	}
+	assert.False(t, isTransientError(errors.New("not a gRPC error")))
+}
+
+// fakeTimerClock is a TimerClock whose timers fire immediately, and which records the delays.
+type fakeTimerClock struct {
+	delays []time.Duration
+}
+
+func (c *fakeTimerClock) Now() time.Time { return time.Now() }
+
+func (c *fakeTimerClock) After(d time.Duration) <-chan time.Time {
+	c.delays = append(c.delays, d)
+	ch := make(chan time.Time, 1)
+	ch <- time.Now()
+	return ch
+}
+
+func TestInitRetriesDoNotMarkIncompleteDataInitialized(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close() // every write will fail with an error that is not transient
+
+	clock := &fakeTimerClock{}
+	built, err := DataStore("my-project", "my-collection").FirestoreClient(client).
+		InitRetry(InitRetryOptions{MaxAttempts: 3, InitialDelay: time.Hour}).
+		Clock(clock).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = built.Close() }()
+	store := built.(*firestoreDataStore)
+	mockLog := ldlogtest.NewMockLog()
+	store.loggers = mockLog.Loggers
+
+	ref := store.docRef(ldstoreimpl.Features(), "flag1")
+	ops := []firestoreOperation{setOperation{ref: ref, data: map[string]any{fieldKey: "flag1"}}}
+	store.setLastInitReport(InitReport{
+		ItemsWritten: 1,
+		Failures: []InitFailure{{
+			Operation:    InitOperationWrite,
+			DocumentID:   ref.ID,
+			DocumentPath: ref.Path,
+			Key:          "flag1",
+			Err:          status.Error(codes.Unavailable, "down"),
+			Retrying:     true,
+		}},
+	})
+
+	store.runInitRetries(context.Background(), ops, true, 0)
+
+	assert.Equal(t, []time.Duration{time.Hour}, clock.delays)
+	report := store.LastInitReport()
+	assert.Equal(t, 1, report.ItemsWritten)
+	require.Len(t, report.Failures, 1)
+	assert.False(t, report.Failures[0].Retrying)
+	assert.Equal(t, codes.Canceled, status.Code(report.Failures[0].Err))
+	assert.False(t, report.Complete())
+	assert.True(t, mockLog.HasMessageMatch(ldlog.Error, "Not marking collection"))
+	assert.False(t, mockLog.HasMessageMatch(ldlog.Info, "succeeded"))
+}
+
+func TestResolveInitRetriesDistinguishesDocumentsWithTheSameID(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+	store := &firestoreDataStore{}
+
+	// As with SubcollectionPerKind, where items of different kinds can have the same document ID
+	flagRef := client.Collection("my-collection/features/items").Doc("key1")
+	segmentRef := client.Collection("my-collection/segments/items").Doc("key1")
+	failure := func(ref *firestore.DocumentRef) InitFailure {
+		return InitFailure{Operation: InitOperationWrite, DocumentID: ref.ID, DocumentPath: ref.Path,
+			Err: status.Error(codes.Unavailable, "down"), Retrying: true}
+	}
+	store.setLastInitReport(InitReport{Failures: []InitFailure{failure(flagRef), failure(segmentRef)}})
+
+	lastErr := status.Error(codes.PermissionDenied, "denied")
+	require.True(t, store.resolveInitRetries(context.Background(), map[string]error{segmentRef.Path: lastErr}))
+
+	report := store.LastInitReport()
+	assert.Equal(t, 1, report.ItemsWritten)
+	require.Len(t, report.Failures, 1)
+	assert.Equal(t, segmentRef.Path, report.Failures[0].DocumentPath)
+	assert.Equal(t, lastErr, report.Failures[0].Err)
+	assert.False(t, report.Failures[0].Retrying)
+}
+
+func TestInitRetriesUseServerRetryDelay(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close()
+
+	clock := &fakeTimerClock{}
+	built, err := DataStore("my-project", "my-collection").FirestoreClient(client).
+		InitRetry(InitRetryOptions{MaxAttempts: 3, InitialDelay: time.Hour}).
+		Clock(clock).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = built.Close() }()
+	store := built.(*firestoreDataStore)
+	store.loggers = ldlogtest.NewMockLog().Loggers
+
+	ref := store.docRef(ldstoreimpl.Features(), "flag1")
+	store.runInitRetries(context.Background(), []firestoreOperation{setOperation{ref: ref, data: map[string]any{}}},
+		false, 7*time.Millisecond)
+	assert.Equal(t, []time.Duration{7 * time.Millisecond}, clock.delays)
+}
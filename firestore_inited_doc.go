@@ -0,0 +1,53 @@
+package ldfirestore
+
+import (
+	ldclient "github.com/launchdarkly/go-server-sdk/v7"
+)
+
+const (
+	// fieldPayloadFilter records the payload filter key of the SDK that last initialized the store.
+	fieldPayloadFilter = "payloadFilter"
+
+	// fieldSDKVersion and fieldIntegrationVersion record the versions of the Go SDK and of this
+	// package that last initialized the store, so that operators can find outdated writers.
+	fieldSDKVersion         = "sdkVersion"
+	fieldIntegrationVersion = "integrationVersion"
+)
+
+// initedMetadata returns the fields, other than namespace and key, that are written to the inited
+// document to describe the writer.
+func (store *firestoreDataStore) initedMetadata() map[string]any {
+	metadata := map[string]any{
+		fieldSDKVersion:         ldclient.Version,
+		fieldIntegrationVersion: Version,
+		fieldSchemaVersion:      currentSchemaVersion,
+	}
+	if store.payloadFilter != "" {
+		metadata[fieldPayloadFilter] = store.payloadFilter
+	}
+	return metadata
+}
+
+// checkInitedDoc verifies that the inited document was written by an SDK that is compatible with
+// this one. It returns false, after logging an error, if the store should not be treated as
+// initialized.
+func (store *firestoreDataStore) checkInitedDoc(data map[string]any) bool {
+	store.observeSchemaVersion(data)
+
+	storedFilter, _ := data[fieldPayloadFilter].(string)
+	if storedFilter == store.payloadFilter {
+		return true
+	}
+
+	store.lock.Lock()
+	alreadyReported := store.filterMismatchReported && store.reportedFilterMismatch == storedFilter
+	store.filterMismatchReported = true
+	store.reportedFilterMismatch = storedFilter
+	store.lock.Unlock()
+	if !alreadyReported {
+		store.loggers.Errorf("The data in collection %q was written with payload filter %q, but this store is "+
+			"configured with payload filter %q; the store will be treated as uninitialized until it is "+
+			"initialized with matching data", store.collection, storedFilter, store.payloadFilter)
+	}
+	return false
+}
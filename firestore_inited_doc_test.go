@@ -0,0 +1,75 @@
+package ldfirestore
+
+import (
+	"testing"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	ldclient "github.com/launchdarkly/go-server-sdk/v7"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitedDoc(t *testing.T) {
+	t.Run("metadata", func(t *testing.T) {
+		store := &firestoreDataStore{}
+		assert.Equal(t, map[string]any{
+			fieldSDKVersion:         ldclient.Version,
+			fieldIntegrationVersion: Version,
+			fieldSchemaVersion:      currentSchemaVersion,
+		}, store.initedMetadata())
+
+		store.payloadFilter = "mobile"
+		assert.Equal(t, "mobile", store.initedMetadata()[fieldPayloadFilter])
+	})
+
+	t.Run("matching filter", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := &firestoreDataStore{payloadFilter: "mobile", loggers: mockLog.Loggers}
+		assert.True(t, store.checkInitedDoc(map[string]any{fieldPayloadFilter: "mobile"}))
+		assert.Len(t, mockLog.GetOutput(ldlog.Error), 0)
+
+		store.payloadFilter = ""
+		assert.True(t, store.checkInitedDoc(map[string]any{}))
+	})
+
+	t.Run("mismatched filter", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := &firestoreDataStore{payloadFilter: "mobile", loggers: mockLog.Loggers}
+		assert.False(t, store.checkInitedDoc(map[string]any{fieldPayloadFilter: "web"}))
+		assert.False(t, store.checkInitedDoc(map[string]any{fieldPayloadFilter: "web"}))
+		assert.Len(t, mockLog.GetOutput(ldlog.Error), 1) // only reported once
+		mockLog.AssertMessageMatch(t, true, ldlog.Error, `written with payload filter "web"`)
+
+		assert.False(t, store.checkInitedDoc(map[string]any{}))
+		assert.Len(t, mockLog.GetOutput(ldlog.Error), 2)
+	})
+
+	t.Run("data written without a filter", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := &firestoreDataStore{payloadFilter: "mobile", loggers: mockLog.Loggers}
+		assert.False(t, store.checkInitedDoc(map[string]any{}))
+		assert.Len(t, mockLog.GetOutput(ldlog.Error), 1)
+	})
+}
+
+func TestIsInitializedCache(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close() // every read will fail
+
+	ds, err := DataStore("my-project", "my-collection").FirestoreClient(client).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+	store := ds.(*firestoreDataStore)
+
+	assert.False(t, store.IsInitialized())
+
+	store.initedCached = true // as if the inited document had been read
+	assert.True(t, store.IsInitialized())
+
+	assert.False(t, store.RecheckInitialized())
+	assert.False(t, store.IsInitialized())
+}
@@ -0,0 +1,222 @@
+package ldfirestore
+
+import (
+	"container/list"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+// DefaultItemCacheMaxItems is the default value for [ItemCacheOptions.MaxItems].
+const DefaultItemCacheMaxItems = 10000
+
+// ItemCacheOptions configures the cache that is enabled with [StoreBuilder.ItemCache].
+type ItemCacheOptions struct {
+	// TTL is how long a result is cached. The cache is disabled if this is zero.
+	TTL time.Duration
+
+	// MaxItems is the maximum number of results of Get that are cached; when it is reached, the least
+	// recently used one is removed. The result of GetAll for each data kind is cached separately and
+	// does not count towards this. If it is zero, DefaultItemCacheMaxItems is used.
+	MaxItems int
+
+	// InvalidateOnChange makes the store listen for changes in Firestore, as with
+	// [StoreBuilder.WatchChanges], and remove changed items from the cache, so that they are reread
+	// right away instead of when they expire.
+	InvalidateOnChange bool
+}
+
+// ItemCacheStats describes the use of the cache that is enabled with [StoreBuilder.ItemCache].
+type ItemCacheStats struct {
+	// Hits is the number of reads that were served from the cache.
+	Hits int
+
+	// Misses is the number of reads that were not in the cache, or had expired.
+	Misses int
+
+	// Evictions is the number of results of Get that were removed to stay within
+	// [ItemCacheOptions.MaxItems].
+	Evictions int
+}
+
+func validateItemCache(options ItemCacheOptions) error {
+	if options.TTL < 0 {
+		return errors.New("item cache TTL must not be negative")
+	}
+	if options.MaxItems < 0 {
+		return errors.New("item cache MaxItems must not be negative")
+	}
+	return nil
+}
+
+// itemCache is a read-through cache of the results of Get and GetAll. A nil *itemCache is a disabled
+// cache, whose methods do nothing.
+type itemCache struct {
+	ttl      time.Duration
+	maxItems int
+	items    map[string]*list.Element // values are *cachedItem, ordered from most recently used
+	order    *list.List
+	all      map[string]cachedAll // by namespace
+	stats    ItemCacheStats
+	lock     sync.Mutex
+}
+
+type cachedItem struct {
+	id        string
+	namespace string
+	item      ldstoretypes.SerializedItemDescriptor
+	expiresAt time.Time
+}
+
+type cachedAll struct {
+	items     []ldstoretypes.KeyedSerializedItemDescriptor
+	expiresAt time.Time
+}
+
+func newItemCache(options ItemCacheOptions) *itemCache {
+	if options.TTL <= 0 {
+		return nil
+	}
+	maxItems := options.MaxItems
+	if maxItems == 0 {
+		maxItems = DefaultItemCacheMaxItems
+	}
+	return &itemCache{
+		ttl:      options.TTL,
+		maxItems: maxItems,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		all:      make(map[string]cachedAll),
+	}
+}
+
+func cacheID(namespace, key string) string {
+	return namespace + ":" + key
+}
+
+func (c *itemCache) get(namespace, key string, now time.Time) (ldstoretypes.SerializedItemDescriptor, bool) {
+	if c == nil {
+		return ldstoretypes.SerializedItemDescriptor{}, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if elem, ok := c.items[cacheID(namespace, key)]; ok {
+		entry := elem.Value.(*cachedItem)
+		if now.Before(entry.expiresAt) {
+			c.order.MoveToFront(elem)
+			c.stats.Hits++
+			return entry.item, true
+		}
+		c.removeElement(elem)
+	}
+	c.stats.Misses++
+	return ldstoretypes.SerializedItemDescriptor{}, false
+}
+
+func (c *itemCache) put(namespace, key string, item ldstoretypes.SerializedItemDescriptor, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	id := cacheID(namespace, key)
+	entry := &cachedItem{id: id, namespace: namespace, item: item, expiresAt: now.Add(c.ttl)}
+	if elem, ok := c.items[id]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[id] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxItems {
+		c.removeElement(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *itemCache) getAll(namespace string, now time.Time) ([]ldstoretypes.KeyedSerializedItemDescriptor, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if entry, ok := c.all[namespace]; ok && now.Before(entry.expiresAt) {
+		c.stats.Hits++
+		return slices.Clone(entry.items), true
+	}
+	delete(c.all, namespace)
+	c.stats.Misses++
+	return nil, false
+}
+
+func (c *itemCache) putAll(namespace string, items []ldstoretypes.KeyedSerializedItemDescriptor, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	c.all[namespace] = cachedAll{items: slices.Clone(items), expiresAt: now.Add(c.ttl)}
+	c.lock.Unlock()
+}
+
+// invalidate removes an item, and the result of GetAll for its data kind, from the cache. If key is
+// empty, every item of the data kind is removed.
+func (c *itemCache) invalidate(namespace, key string) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.all, namespace)
+	if key != "" {
+		if elem, ok := c.items[cacheID(namespace, key)]; ok {
+			c.removeElement(elem)
+		}
+		return
+	}
+	for elem := c.order.Front(); elem != nil; {
+		next := elem.Next()
+		if elem.Value.(*cachedItem).namespace == namespace {
+			c.removeElement(elem)
+		}
+		elem = next
+	}
+}
+
+func (c *itemCache) clear() {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	c.all = make(map[string]cachedAll)
+	c.lock.Unlock()
+}
+
+func (c *itemCache) removeElement(elem *list.Element) {
+	delete(c.items, elem.Value.(*cachedItem).id)
+	c.order.Remove(elem)
+}
+
+func (c *itemCache) getStats() ItemCacheStats {
+	if c == nil {
+		return ItemCacheStats{}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.stats
+}
+
+func (store *firestoreDataStore) InvalidateItemCache(kind ldstoretypes.DataKind, key string) {
+	store.cache.invalidate(store.namespaceForKind(kind), key)
+}
+
+func (store *firestoreDataStore) ClearItemCache() {
+	store.cache.clear()
+}
+
+func (store *firestoreDataStore) ItemCacheStats() ItemCacheStats {
+	return store.cache.getStats()
+}
@@ -0,0 +1,149 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	item := func(version int) ldstoretypes.SerializedItemDescriptor {
+		return ldstoretypes.SerializedItemDescriptor{Version: version, SerializedItem: []byte(`{}`)}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		c := newItemCache(ItemCacheOptions{})
+		assert.Nil(t, c)
+		c.put("features", "flag1", item(1), now)
+		_, ok := c.get("features", "flag1", now)
+		assert.False(t, ok)
+		assert.Equal(t, ItemCacheStats{}, c.getStats())
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		c := newItemCache(ItemCacheOptions{TTL: time.Minute})
+		_, ok := c.get("features", "flag1", now)
+		assert.False(t, ok)
+
+		c.put("features", "flag1", item(1), now)
+		got, ok := c.get("features", "flag1", now.Add(time.Second))
+		require.True(t, ok)
+		assert.Equal(t, item(1), got)
+
+		_, ok = c.get("features", "flag1", now.Add(time.Minute))
+		assert.False(t, ok)
+		assert.Equal(t, ItemCacheStats{Hits: 1, Misses: 2}, c.getStats())
+	})
+
+	t.Run("least recently used items are evicted", func(t *testing.T) {
+		c := newItemCache(ItemCacheOptions{TTL: time.Minute, MaxItems: 2})
+		c.put("features", "flag1", item(1), now)
+		c.put("features", "flag2", item(1), now)
+		_, _ = c.get("features", "flag1", now)
+		c.put("features", "flag3", item(1), now)
+
+		_, ok := c.get("features", "flag2", now)
+		assert.False(t, ok)
+		_, ok = c.get("features", "flag1", now)
+		assert.True(t, ok)
+		_, ok = c.get("features", "flag3", now)
+		assert.True(t, ok)
+		assert.Equal(t, 1, c.getStats().Evictions)
+	})
+
+	t.Run("GetAll results", func(t *testing.T) {
+		c := newItemCache(ItemCacheOptions{TTL: time.Minute})
+		all := []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "flag1", Item: item(1)}}
+		c.putAll("features", all, now)
+		got, ok := c.getAll("features", now)
+		require.True(t, ok)
+		assert.Equal(t, all, got)
+		_, ok = c.getAll("segments", now)
+		assert.False(t, ok)
+		_, ok = c.getAll("features", now.Add(time.Minute))
+		assert.False(t, ok)
+	})
+
+	t.Run("invalidation", func(t *testing.T) {
+		c := newItemCache(ItemCacheOptions{TTL: time.Minute})
+		fill := func() {
+			c.put("features", "flag1", item(1), now)
+			c.put("features", "flag2", item(1), now)
+			c.put("segments", "segment1", item(1), now)
+			c.putAll("features", nil, now)
+		}
+		cached := func(namespace, key string) bool {
+			_, ok := c.get(namespace, key, now)
+			return ok
+		}
+
+		fill()
+		c.invalidate("features", "flag1")
+		assert.False(t, cached("features", "flag1"))
+		assert.True(t, cached("features", "flag2"))
+		_, ok := c.getAll("features", now)
+		assert.False(t, ok)
+
+		fill()
+		c.invalidate("features", "")
+		assert.False(t, cached("features", "flag1"))
+		assert.False(t, cached("features", "flag2"))
+		assert.True(t, cached("segments", "segment1"))
+
+		fill()
+		c.clear()
+		assert.False(t, cached("segments", "segment1"))
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		assert.NoError(t, validateItemCache(ItemCacheOptions{TTL: time.Minute}))
+		assert.Error(t, validateItemCache(ItemCacheOptions{TTL: -time.Minute}))
+		assert.Error(t, validateItemCache(ItemCacheOptions{TTL: time.Minute, MaxItems: -1}))
+	})
+}
+
+func TestStoreItemCache(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close() // every request will fail, so reads can only succeed from the cache
+
+	ds, err := DataStore("my-project", "my-collection").FirestoreClient(client).
+		ItemCache(ItemCacheOptions{TTL: time.Hour}).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+	store := ds.(*firestoreDataStore)
+
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)}
+	store.cache.put("features", "flag1", item, store.now())
+	store.cache.putAll("features", []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "flag1", Item: item}},
+		store.now())
+
+	got, err := store.Get(ldstoreimpl.Features(), "flag1")
+	require.NoError(t, err)
+	assert.Equal(t, item, got)
+	all, err := store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+
+	// A write invalidates the item even if it fails, since its outcome may be unknown
+	_, err = store.Upsert(ldstoreimpl.Features(), "flag1", ldstoretypes.SerializedItemDescriptor{Version: 2})
+	assert.Error(t, err)
+	_, err = store.Get(ldstoreimpl.Features(), "flag1")
+	assert.Error(t, err)
+	_, err = store.GetAll(ldstoreimpl.Features())
+	assert.Error(t, err)
+
+	assert.Equal(t, ItemCacheStats{Hits: 2, Misses: 2}, store.ItemCacheStats())
+
+	store.cache.put("features", "flag1", item, store.now())
+	store.InvalidateItemCache(ldstoreimpl.Features(), "flag1")
+	_, ok := store.cache.get("features", "flag1", store.now())
+	assert.False(t, ok)
+}
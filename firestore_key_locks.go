@@ -0,0 +1,45 @@
+package ldfirestore
+
+import "sync"
+
+// keyLocks serializes updates to the same document within this process. Firestore transactions
+// already guarantee correctness when there are concurrent updates, but concurrent transactions on the
+// same document abort and retry each other, so it is cheaper to make them wait locally instead. The
+// zero value is ready to use.
+type keyLocks struct {
+	locks map[string]*keyLock
+	lock  sync.Mutex
+}
+
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// acquire waits until no other caller holds the lock for the given document path, and returns a
+// function that releases it. Locks are removed once nobody is using them, so the number of locks
+// stays proportional to the number of concurrent updates rather than the number of keys.
+func (k *keyLocks) acquire(path string) func() {
+	k.lock.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyLock)
+	}
+	l, ok := k.locks[path]
+	if !ok {
+		l = &keyLock{}
+		k.locks[path] = l
+	}
+	l.refs++
+	k.lock.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		k.lock.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(k.locks, path)
+		}
+		k.lock.Unlock()
+	}
+}
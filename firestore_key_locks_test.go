@@ -0,0 +1,62 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyLocks(t *testing.T) {
+	t.Run("serializes the same key", func(t *testing.T) {
+		var locks keyLocks
+		release := locks.acquire("a")
+
+		acquired := make(chan struct{})
+		go func() {
+			release2 := locks.acquire("a")
+			close(acquired)
+			release2()
+		}()
+
+		select {
+		case <-acquired:
+			require.Fail(t, "second caller should have waited")
+		case <-time.After(50 * time.Millisecond):
+		}
+		release()
+		select {
+		case <-acquired:
+		case <-time.After(time.Second):
+			require.Fail(t, "second caller was not released")
+		}
+	})
+
+	t.Run("does not serialize different keys", func(t *testing.T) {
+		var locks keyLocks
+		release := locks.acquire("a")
+		defer release()
+
+		acquired := make(chan struct{})
+		go func() {
+			locks.acquire("b")()
+			close(acquired)
+		}()
+		select {
+		case <-acquired:
+		case <-time.After(time.Second):
+			require.Fail(t, "different key should not have waited")
+		}
+	})
+
+	t.Run("removes unused locks", func(t *testing.T) {
+		var locks keyLocks
+		release1 := locks.acquire("a")
+		release2 := locks.acquire("b")
+		assert.Len(t, locks.locks, 2)
+		release1()
+		release2()
+		assert.Len(t, locks.locks, 0)
+	})
+}
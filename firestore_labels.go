@@ -0,0 +1,57 @@
+package ldfirestore
+
+import (
+	"fmt"
+	"maps"
+)
+
+// reservedFieldNames are the fields that the store itself writes, which cannot be used as labels.
+var reservedFieldNames = map[string]bool{
+	fieldNamespace:          true,
+	fieldKey:                true,
+	fieldVersion:            true,
+	fieldItem:               true,
+	fieldEncoding:           true,
+	fieldChecksum:           true,
+	fieldUpdatedAt:          true,
+	fieldLastUpdated:        true,
+	fieldExpireAt:           true,
+	fieldDeleted:            true,
+	fieldSchemaVersion:      true,
+	fieldDegraded:           true,
+	fieldOverflow:           true,
+	fieldPayloadFilter:      true,
+	fieldSDKVersion:         true,
+	fieldIntegrationVersion: true,
+	fieldItemCount:          true,
+	fieldMaxVersion:         true,
+	fieldLeaseHolder:        true,
+	fieldLeaseExpiresAt:     true,
+}
+
+func validateDocumentLabels(labels map[string]string) error {
+	for name := range labels {
+		if name == "" {
+			return fmt.Errorf("document label names must not be empty")
+		}
+		if reservedFieldNames[name] {
+			return fmt.Errorf("document label %q has the same name as a field used by the store", name)
+		}
+	}
+	return nil
+}
+
+// addLabels adds the configured document labels to the fields of a document that is being written.
+func (store *firestoreDataStore) addLabels(data map[string]any) map[string]any {
+	for name, value := range store.labels {
+		data[name] = value
+	}
+	return data
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	return maps.Clone(labels)
+}
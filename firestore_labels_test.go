@@ -0,0 +1,56 @@
+package ldfirestore
+
+import (
+	"testing"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentLabels(t *testing.T) {
+	labels := map[string]string{"team": "platform", "cost-center": "1234"}
+
+	t.Run("labels are added to written documents", func(t *testing.T) {
+		client, err := createTestClient()
+		require.NoError(t, err)
+		defer func() { _ = client.Close() }()
+		store := &firestoreDataStore{
+			client:     client,
+			collection: "my-collection",
+			labels:     labels,
+			loggers:    ldlogtest.NewMockLog().Loggers,
+		}
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1",
+			ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)})
+		require.NoError(t, err)
+		assert.Equal(t, "platform", data["team"])
+		assert.Equal(t, "1234", data["cost-center"])
+		assert.Equal(t, "flag1", data[fieldKey])
+
+		inited := store.initedOperation()
+		assert.Equal(t, "platform", inited.data["team"])
+	})
+
+	t.Run("builder copies labels", func(t *testing.T) {
+		input := map[string]string{"team": "platform"}
+		b := DataStore("my-project", "my-collection").DocumentLabels(input)
+		input["team"] = "changed"
+		assert.Equal(t, map[string]string{"team": "platform"}, b.labels)
+	})
+
+	t.Run("error for reserved or empty name", func(t *testing.T) {
+		assert.NoError(t, validateDocumentLabels(labels))
+		assert.Error(t, validateDocumentLabels(map[string]string{"": "x"}))
+
+		ds, err := DataStore("my-project", "my-collection").
+			DocumentLabels(map[string]string{fieldVersion: "x"}).
+			Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, ds)
+		assert.Contains(t, err.Error(), "same name as a field")
+	})
+}
@@ -0,0 +1,15 @@
+package ldfirestore
+
+import (
+	"cloud.google.com/go/firestore"
+)
+
+// itemsSubcollection is the name of the subcollection that holds the items of a data kind, if
+// StoreBuilder.SubcollectionPerKind is enabled.
+const itemsSubcollection = "items"
+
+// kindSubcollection returns the subcollection for the items of a data kind in the subcollection-per-kind
+// layout: {collection}/{namespace}/items. The namespace document itself does not need to exist.
+func kindSubcollection(coll *firestore.CollectionRef, namespaceID string) *firestore.CollectionRef {
+	return coll.Doc(namespaceID).Collection(itemsSubcollection)
+}
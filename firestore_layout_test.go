@@ -0,0 +1,85 @@
+package ldfirestore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubcollectionPerKind(t *testing.T) {
+	t.Run("document paths", func(t *testing.T) {
+		client, err := createTestClient()
+		require.NoError(t, err)
+		defer func() { _ = client.Close() }()
+
+		store := &firestoreDataStore{
+			client:         client,
+			collection:     "launchdarkly",
+			prefix:         "p",
+			subcollections: true,
+			loggers:        ldlogtest.NewMockLog().Loggers,
+		}
+		path := store.docRef(ldstoreimpl.Features(), "flag1").Path
+		assert.True(t, strings.HasSuffix(path, "/documents/launchdarkly/p:features/items/flag1"), path)
+
+		initedPath := store.collectionRef(store.collection).Doc(store.initedDocID()).Path
+		assert.True(t, strings.HasSuffix(initedPath, "/documents/launchdarkly/p:p:$inited:p:$inited"), initedPath)
+
+		key, _, ok := store.decodeLenient(ldstoreimpl.Features(), "flag1", map[string]any{
+			fieldVersion: int64(1), fieldItem: `{"key":"flag1"}`,
+		})
+		assert.True(t, ok)
+		assert.Equal(t, "flag1", key)
+	})
+
+	t.Run("builder", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").SubcollectionPerKind(true)
+		assert.True(t, b.subcollections)
+	})
+
+	t.Run("store round trip", func(t *testing.T) {
+		if !isEmulatorAvailable() {
+			t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+		}
+		require.NoError(t, clearTestData(""))
+
+		store, err := baseDataStoreBuilder().SubcollectionPerKind(true).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		defer func() { _ = store.Close() }()
+
+		flag := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)}
+		segment := ldstoretypes.SerializedItemDescriptor{Version: 2, SerializedItem: []byte(`{"key":"seg1"}`)}
+		require.NoError(t, store.Init([]ldstoretypes.SerializedCollection{
+			{Kind: ldstoreimpl.Features(), Items: []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "flag1", Item: flag}}},
+			{Kind: ldstoreimpl.Segments(), Items: []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "seg1", Item: segment}}},
+		}))
+		assert.True(t, store.IsInitialized())
+
+		flags, err := store.GetAll(ldstoreimpl.Features())
+		require.NoError(t, err)
+		assert.Equal(t, []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "flag1", Item: flag}}, flags)
+
+		updated := ldstoretypes.SerializedItemDescriptor{Version: 3, SerializedItem: []byte(`{"key":"seg1"}`)}
+		ok, err := store.Upsert(ldstoreimpl.Segments(), "seg1", updated)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		result, err := store.Get(ldstoreimpl.Segments(), "seg1")
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Version)
+
+		// Init removes items that are no longer present
+		require.NoError(t, store.Init([]ldstoretypes.SerializedCollection{
+			{Kind: ldstoreimpl.Features(), Items: nil},
+			{Kind: ldstoreimpl.Segments(), Items: nil},
+		}))
+		flags, err = store.GetAll(ldstoreimpl.Features())
+		require.NoError(t, err)
+		assert.Len(t, flags, 0)
+	})
+}
@@ -0,0 +1,164 @@
+package ldfirestore
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	manifestNamespace = "$manifest"
+	fieldVersions     = "versions"
+	fieldItemFormat   = "itemFormat"
+)
+
+// manifestDocRef returns the manifest document for a data kind. Like the summary document, it is in
+// the same collection as the items of that kind, but has a different namespace so that queries for
+// the items do not see it.
+func (store *firestoreDataStore) manifestDocRef(kind ldstoretypes.DataKind) *firestore.DocumentRef {
+	prefix := store.kindPrefix(kind)
+	return store.collectionForKind(kind).
+		Doc(store.ids.makeID(prefix, prefixedNamespace(prefix, manifestNamespace), kind.GetName()))
+}
+
+func (store *firestoreDataStore) manifestFields(kind ldstoretypes.DataKind, versions map[string]any) map[string]any {
+	return store.addExpiry(map[string]any{
+		fieldNamespace:     prefixedNamespace(store.kindPrefix(kind), manifestNamespace),
+		fieldKey:           kind.GetName(),
+		fieldSchemaVersion: currentSchemaVersion,
+		fieldVersions:      versions,
+		fieldItemFormat:    store.itemFormat(),
+		fieldUpdatedAt:     store.now(),
+	})
+}
+
+// itemFormat describes how the store's configuration affects the fields of the item documents that it
+// writes. It is stored in each manifest, since DifferentialInit can only trust a manifest's versions
+// to skip items if the items were written the same way as they would be now.
+func (store *firestoreDataStore) itemFormat() string {
+	codec := ""
+	if c := store.payloadCodec(); c != nil {
+		codec = c.Format()
+	}
+	return fmt.Sprintf("schema=%d structured=%t codec=%q checksum=%s labels=%v",
+		currentSchemaVersion, store.structured, codec, store.checksum, store.labels)
+}
+
+// manifestOperation replaces the manifest document for a data kind.
+func (store *firestoreDataStore) manifestOperation(kind ldstoretypes.DataKind, versions map[string]int) setOperation {
+	values := make(map[string]any, len(versions))
+	for key, version := range versions {
+		values[key] = version
+	}
+	return setOperation{ref: store.manifestDocRef(kind), data: store.manifestFields(kind, values)}
+}
+
+// updateManifestInTransaction sets the version of one item in the manifest document for a data kind,
+// or removes the item if version is negative. It only writes the manifest, so unlike
+// updateSummaryInTransaction it can be called after the transaction's own reads.
+func (store *firestoreDataStore) updateManifestInTransaction(
+	tx *firestore.Transaction,
+	kind ldstoretypes.DataKind,
+	key string,
+	version int,
+) error {
+	var value any = version
+	if version < 0 {
+		value = firestore.Delete
+	}
+	return tx.Set(store.manifestDocRef(kind), store.manifestFields(kind, map[string]any{key: value}),
+		firestore.MergeAll)
+}
+
+// readManifest reads the manifest document for a data kind, and returns its versions and item format.
+// The third return value is false if there is no manifest, or it was written with a different schema
+// version, whose documents the manifest cannot vouch for.
+func (store *firestoreDataStore) readManifest(
+	ctx context.Context,
+	kind ldstoretypes.DataKind,
+) (map[string]int, string, bool, error) {
+	ctx, cancel := withOperationTimeout(ctx, store.operationTimeout)
+	defer cancel()
+
+	doc, err := store.manifestDocRef(kind).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, "", false, nil
+		}
+		return nil, "", false, fmt.Errorf("could not get manifest for %s: %w", kind, err)
+	}
+	data := doc.Data()
+	if documentSchemaVersion(data) != currentSchemaVersion {
+		return nil, "", false, nil
+	}
+	values, _ := data[fieldVersions].(map[string]any)
+	versions := make(map[string]int, len(values))
+	for key, value := range values {
+		if version, ok := value.(int64); ok {
+			versions[key] = int(version)
+		}
+	}
+	format, _ := data[fieldItemFormat].(string)
+	return versions, format, true, nil
+}
+
+// readManifestVersions is like readExistingVersions, but reads the manifest document of each data
+// kind instead of querying its items. It returns false if any of the data kinds has no manifest, or,
+// with DifferentialInit, if any manifest was written by a store that stored items differently.
+func (store *firestoreDataStore) readManifestVersions(
+	ctx context.Context,
+	newData []ldstoretypes.SerializedCollection,
+) (map[string]*firestore.DocumentRef, map[string]existingItem, bool, error) {
+	docRefs := make(map[string]*firestore.DocumentRef)
+	existing := make(map[string]existingItem)
+
+	for _, coll := range newData {
+		manifest, format, found, err := store.readManifest(ctx, coll.Kind)
+		if err != nil || !found || (store.differentialInit && format != store.itemFormat()) {
+			return nil, nil, false, err
+		}
+		for key, version := range manifest {
+			ref := store.docRef(coll.Kind, key)
+			docRefs[ref.Path] = ref
+			existing[ref.Path] = existingItem{version: version}
+		}
+	}
+
+	return docRefs, existing, true, nil
+}
+
+func (store *firestoreDataStore) NamespaceManifest(kind ldstoretypes.DataKind) (map[string]int, bool, error) {
+	done, err := store.beginOperation(true)
+	if err != nil {
+		return nil, false, err
+	}
+	defer done()
+	store.countRead()
+
+	var manifest map[string]int
+	var found bool
+	err = store.retry.do(store.context, store.onRetry, func() (err error) {
+		manifest, _, found, err = store.readManifest(store.context, kind)
+		return err
+	})
+	if err != nil {
+		return nil, false, newStoreError("NamespaceManifest", kind, "", err)
+	}
+	store.countUsage("NamespaceManifest", DocumentOperations{Reads: 1})
+	return manifest, found, nil
+}
+
+// discardManifests deletes the manifest documents of the given data kinds after an Init in which
+// some writes failed, since they would otherwise list versions that were not written. The next Init
+// then queries the items instead, and writes new manifests.
+func (store *firestoreDataStore) discardManifests(allData []ldstoretypes.SerializedCollection) {
+	for _, coll := range allData {
+		if _, err := store.manifestDocRef(coll.Kind).Delete(store.context); err != nil {
+			store.loggers.Warnf("Failed to delete the manifest for %s after an incomplete Init: %s", coll.Kind, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package ldfirestore
+
+import (
+	"testing"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestDocument(t *testing.T) {
+	store, err := baseDataStoreBuilder().Prefix("p").NamespaceManifests(true).Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	impl := store.(*firestoreDataStore)
+
+	op := impl.manifestOperation(ldstoreimpl.Features(), map[string]int{"flag1": 3})
+	assert.Equal(t, "p:p:$manifest:features", op.ref.ID)
+	assert.Equal(t, "p:$manifest", op.data[fieldNamespace])
+	assert.Equal(t, map[string]any{"flag1": 3}, op.data[fieldVersions])
+	assert.NotEqual(t, impl.summaryDocRef(ldstoreimpl.Features()).ID, op.ref.ID)
+}
+
+func TestNamespaceManifestsBuilder(t *testing.T) {
+	b := DataStore("my-project", "my-collection").NamespaceManifests(true)
+	assert.True(t, b.namespaceManifests)
+
+	ds, err := b.PreconditionUpserts(true).Build(subsystems.BasicClientContext{})
+	assert.Error(t, err)
+	assert.Nil(t, ds)
+	assert.Contains(t, err.Error(), "cannot be combined with NamespaceManifests")
+}
+
+func TestNamespaceManifests(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := baseDataStoreBuilder().NamespaceManifests(true).DifferentialInit(true).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	ext := store.(ExtendedDataStore)
+
+	_, found, err := ext.NamespaceManifest(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	flag := func(version int) ldstoretypes.SerializedItemDescriptor {
+		return ldstoretypes.SerializedItemDescriptor{Version: version, SerializedItem: []byte(`{}`)}
+	}
+	allData := func(flags map[string]int) []ldstoretypes.SerializedCollection {
+		coll := ldstoretypes.SerializedCollection{Kind: ldstoreimpl.Features()}
+		for key, version := range flags {
+			coll.Items = append(coll.Items, ldstoretypes.KeyedSerializedItemDescriptor{Key: key, Item: flag(version)})
+		}
+		return []ldstoretypes.SerializedCollection{coll, {Kind: ldstoreimpl.Segments()}}
+	}
+	require.NoError(t, store.Init(allData(map[string]int{"flag1": 3, "flag2": 7})))
+
+	manifest, found, err := ext.NamespaceManifest(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, map[string]int{"flag1": 3, "flag2": 7}, manifest)
+
+	_, err = store.Upsert(ldstoreimpl.Features(), "flag3", flag(9))
+	require.NoError(t, err)
+	_, err = ext.Delete(ldstoreimpl.Features(), "flag2", ldvalue.OptionalInt{})
+	require.NoError(t, err)
+
+	manifest, _, err = ext.NamespaceManifest(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"flag1": 3, "flag3": 9}, manifest)
+
+	// Init uses the manifest to find the unchanged and obsolete items
+	require.NoError(t, store.Init(allData(map[string]int{"flag1": 3, "flag4": 1})))
+	assert.Equal(t, InitReport{ItemsWritten: 1, ItemsUnchanged: 1, ItemsDeleted: 1}, ext.LastInitReport())
+	all, err := store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	manifest, _, err = ext.NamespaceManifest(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"flag1": 3, "flag4": 1}, manifest)
+}
@@ -0,0 +1,39 @@
+package ldfirestore
+
+import (
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+// OperationHook is called before and after each store operation. See [StoreBuilder.OperationHook].
+type OperationHook interface {
+	// BeforeOperation is called before an operation starts. If it returns an error, the operation is
+	// not performed, and fails with that error; this can be used to inject faults in tests.
+	BeforeOperation(info OperationInfo) error
+
+	// AfterOperation is called when an operation has finished, with its Duration and Err set. It is
+	// also called for an operation that BeforeOperation failed.
+	AfterOperation(info OperationInfo)
+}
+
+// OperationInfo describes a store operation that is passed to an [OperationHook].
+type OperationInfo struct {
+	// Operation is the name of the method that was called: "Get", "GetAll", "Upsert", "Init", or
+	// "IsInitialized" for the data store, and "GetMembership" or "GetMetadata" for the Big Segment
+	// store. Upsert includes calls to [ExtendedDataStore.UpsertWithResult].
+	Operation string
+
+	// Kind is the data kind of a Get, GetAll, or Upsert, and nil for other operations.
+	Kind ldstoretypes.DataKind
+
+	// Key is the item key of a Get or Upsert, and for GetMembership, the same prefix of the hashed
+	// context key as [BigSegmentCallInfo.ContextHash]. It is empty for other operations.
+	Key string
+
+	// Duration is how long the operation took. It is zero in BeforeOperation.
+	Duration time.Duration
+
+	// Err is the error that the operation failed with, if any. It is nil in BeforeOperation.
+	Err error
+}
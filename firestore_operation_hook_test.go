@@ -0,0 +1,96 @@
+package ldfirestore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingOperationHook struct {
+	before    []OperationInfo
+	after     []OperationInfo
+	beforeErr error
+}
+
+func (h *recordingOperationHook) BeforeOperation(info OperationInfo) error {
+	h.before = append(h.before, info)
+	return h.beforeErr
+}
+
+func (h *recordingOperationHook) AfterOperation(info OperationInfo) {
+	h.after = append(h.after, info)
+}
+
+func TestOperationHook(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close() // every operation will fail
+
+	t.Run("data store", func(t *testing.T) {
+		hook := &recordingOperationHook{}
+		ds, err := DataStore("my-project", "my-collection").FirestoreClient(client).OperationHook(hook).
+			Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		defer func() { _ = ds.Close() }()
+
+		_, err = ds.Get(ldstoreimpl.Features(), "flag1")
+		require.Error(t, err)
+		assert.False(t, ds.IsInitialized())
+
+		require.Len(t, hook.before, 2)
+		assert.Equal(t, OperationInfo{Operation: "Get", Kind: ldstoreimpl.Features(), Key: "flag1"}, hook.before[0])
+		assert.Equal(t, OperationInfo{Operation: "IsInitialized"}, hook.before[1])
+
+		require.Len(t, hook.after, 2)
+		assert.Equal(t, "Get", hook.after[0].Operation)
+		assert.Equal(t, "flag1", hook.after[0].Key)
+		assert.Equal(t, err, hook.after[0].Err)
+		assert.Greater(t, hook.after[0].Duration, time.Duration(0))
+		assert.Equal(t, "IsInitialized", hook.after[1].Operation)
+	})
+
+	t.Run("fault injection", func(t *testing.T) {
+		injected := errors.New("injected")
+		hook := &recordingOperationHook{beforeErr: injected}
+		ds, err := baseDataStoreBuilder().OperationHook(hook).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		defer func() { _ = ds.Close() }()
+
+		item, err := ds.Get(ldstoreimpl.Features(), "flag1")
+		assert.Equal(t, injected, err)
+		assert.Equal(t, -1, item.Version)
+		_, err = ds.GetAll(ldstoreimpl.Features())
+		assert.Equal(t, injected, err)
+		_, err = ds.Upsert(ldstoreimpl.Features(), "flag1", ldstoretypes.SerializedItemDescriptor{Version: 1})
+		assert.Equal(t, injected, err)
+		assert.Equal(t, injected, ds.Init(nil))
+		assert.False(t, ds.IsInitialized())
+
+		require.Len(t, hook.after, 5)
+		for _, info := range hook.after {
+			assert.Equal(t, injected, info.Err)
+		}
+	})
+
+	t.Run("Big Segments", func(t *testing.T) {
+		hook := &recordingOperationHook{}
+		bs, err := BigSegmentStore("my-project", "my-collection").FirestoreClient(client).OperationHook(hook).
+			Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		defer func() { _ = bs.Close() }()
+
+		_, err = bs.GetMembership("0123456789abcdef")
+		assert.Error(t, err)
+
+		require.Len(t, hook.after, 1)
+		assert.Equal(t, "GetMembership", hook.after[0].Operation)
+		assert.Equal(t, "01234567", hook.after[0].Key)
+		assert.Error(t, hook.after[0].Err)
+	})
+}
@@ -0,0 +1,255 @@
+package ldfirestore
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+// DefaultOutageBufferReplayInterval is the default value for [OutageBufferOptions.ReplayInterval].
+const DefaultOutageBufferReplayInterval = 5 * time.Second
+
+// OutageBufferOptions configures the buffering of updates that is enabled with
+// [StoreBuilder.OutageBuffer].
+type OutageBufferOptions struct {
+	// MaxItems is the largest number of items whose updates are held in memory at once. Only the
+	// newest version of each item is held. If it is zero, updates are not buffered.
+	MaxItems int
+
+	// ReplayInterval is the time between attempts to write the buffered updates to Firestore. If it
+	// is zero, DefaultOutageBufferReplayInterval is used.
+	ReplayInterval time.Duration
+}
+
+func validateOutageBuffer(options OutageBufferOptions) error {
+	if options.MaxItems < 0 {
+		return errors.New("outage buffer size must not be negative")
+	}
+	if options.ReplayInterval < 0 {
+		return errors.New("outage buffer replay interval must not be negative")
+	}
+	return nil
+}
+
+type bufferedItemKey struct {
+	namespace string
+	key       string
+}
+
+type bufferedUpsert struct {
+	kind ldstoretypes.DataKind
+	key  string
+	item ldstoretypes.SerializedItemDescriptor
+}
+
+// outageBuffer holds the updates that could not be written because Firestore was unavailable.
+type outageBuffer struct {
+	maxItems int
+	interval time.Duration
+	items    map[bufferedItemKey]bufferedUpsert
+	lock     sync.Mutex
+}
+
+func newOutageBuffer(options OutageBufferOptions) *outageBuffer {
+	if options.MaxItems <= 0 {
+		return nil
+	}
+	interval := options.ReplayInterval
+	if interval == 0 {
+		interval = DefaultOutageBufferReplayInterval
+	}
+	return &outageBuffer{
+		maxItems: options.MaxItems,
+		interval: interval,
+		items:    make(map[bufferedItemKey]bufferedUpsert),
+	}
+}
+
+// add buffers an update, unless the buffer is full. An update that is not newer than the one that is
+// already buffered for the same item is accepted, but has no effect.
+func (b *outageBuffer) add(id bufferedItemKey, upsert bufferedUpsert) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if existing, ok := b.items[id]; ok {
+		if upsert.item.Version > existing.item.Version {
+			b.items[id] = upsert
+		}
+		return true
+	}
+	if len(b.items) >= b.maxItems {
+		return false
+	}
+	b.items[id] = upsert
+	return true
+}
+
+func (b *outageBuffer) get(id bufferedItemKey) (ldstoretypes.SerializedItemDescriptor, bool) {
+	if b == nil {
+		return ldstoretypes.SerializedItemDescriptor{}, false
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	upsert, ok := b.items[id]
+	return upsert.item, ok
+}
+
+// forNamespace returns the buffered updates for one namespace.
+func (b *outageBuffer) forNamespace(namespace string) []bufferedUpsert {
+	if b == nil {
+		return nil
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var upserts []bufferedUpsert
+	for id, upsert := range b.items {
+		if id.namespace == namespace {
+			upserts = append(upserts, upsert)
+		}
+	}
+	return upserts
+}
+
+// snapshot returns every buffered update.
+func (b *outageBuffer) snapshot() map[bufferedItemKey]bufferedUpsert {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	items := make(map[bufferedItemKey]bufferedUpsert, len(b.items))
+	for id, upsert := range b.items {
+		items[id] = upsert
+	}
+	return items
+}
+
+// remove discards a buffered update, unless a newer one has been buffered for the same item since
+// the given version was read.
+func (b *outageBuffer) remove(id bufferedItemKey, version int) {
+	b.lock.Lock()
+	if upsert, ok := b.items[id]; ok && upsert.item.Version == version {
+		delete(b.items, id)
+	}
+	b.lock.Unlock()
+}
+
+// clear discards every buffered update, since Init replaces all of the data.
+func (b *outageBuffer) clear() {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	clear(b.items)
+	b.lock.Unlock()
+}
+
+func (b *outageBuffer) len() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.items)
+}
+
+// bufferUpsert holds an update that failed because Firestore was unavailable, so that it can be
+// written later. It returns false if the update was not buffered.
+func (store *firestoreDataStore) bufferUpsert(
+	kind ldstoretypes.DataKind,
+	key string,
+	item ldstoretypes.SerializedItemDescriptor,
+	err error,
+) bool {
+	if store.outage == nil || !isTransientError(err) {
+		return false
+	}
+	id := bufferedItemKey{namespace: store.namespaceForKind(kind), key: key}
+	if !store.outage.add(id, bufferedUpsert{kind: kind, key: key, item: item}) {
+		store.loggers.Errorf("Could not buffer the update of %q in namespace %q, since %d update(s) are "+
+			"already waiting for Firestore to be available", key, id.namespace, store.outage.maxItems)
+		return false
+	}
+	store.loggers.Warnf("Buffered the update of %q in namespace %q until Firestore is available: %s",
+		key, id.namespace, err)
+	return true
+}
+
+// bufferedGet returns the buffered update of an item, if there is one.
+func (store *firestoreDataStore) bufferedGet(
+	kind ldstoretypes.DataKind,
+	key string,
+) (ldstoretypes.SerializedItemDescriptor, bool) {
+	return store.outage.get(bufferedItemKey{namespace: store.namespaceForKind(kind), key: key})
+}
+
+// withBufferedItems returns the items of a data kind with any buffered updates applied. The given
+// slice is not modified, since it may be shared with the item cache.
+func (store *firestoreDataStore) withBufferedItems(
+	kind ldstoretypes.DataKind,
+	items []ldstoretypes.KeyedSerializedItemDescriptor,
+) []ldstoretypes.KeyedSerializedItemDescriptor {
+	upserts := store.outage.forNamespace(store.namespaceForKind(kind))
+	if len(upserts) == 0 {
+		return items
+	}
+	result := make([]ldstoretypes.KeyedSerializedItemDescriptor, len(items), len(items)+len(upserts))
+	copy(result, items)
+	indexes := make(map[string]int, len(result))
+	for i, item := range result {
+		indexes[item.Key] = i
+	}
+	for _, upsert := range upserts {
+		if i, ok := indexes[upsert.key]; ok {
+			if upsert.item.Version > result[i].Item.Version {
+				result[i].Item = upsert.item
+			}
+			continue
+		}
+		result = append(result, ldstoretypes.KeyedSerializedItemDescriptor{Key: upsert.key, Item: upsert.item})
+	}
+	return result
+}
+
+// runOutageReplay periodically writes the buffered updates to Firestore until the store is closed.
+func (store *firestoreDataStore) runOutageReplay() {
+	ticker := time.NewTicker(store.outage.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-store.context.Done():
+			if n := store.outage.len(); n > 0 {
+				store.loggers.Warnf("Discarded %d buffered update(s) that were not written before the store was closed",
+					n)
+			}
+			return
+		case <-ticker.C:
+			store.replayBufferedUpserts()
+		}
+	}
+}
+
+// replayBufferedUpserts writes the buffered updates, with the same version check as any other update,
+// until one fails because Firestore is still unavailable.
+func (store *firestoreDataStore) replayBufferedUpserts() {
+	pending := store.outage.snapshot()
+	if len(pending) == 0 {
+		return
+	}
+	replayed := 0
+	for id, upsert := range pending {
+		_, err := store.upsertWithResult(upsert.kind, upsert.key, upsert.item)
+		if errors.Is(err, ErrStoreClosed) {
+			return
+		}
+		if err != nil && isTransientError(err) {
+			if log, ok := store.debugLog(LogWrites); ok {
+				log.Debugf("Firestore is still unavailable; %d buffered update(s) are waiting: %s",
+					len(pending)-replayed, err)
+			}
+			return
+		}
+		if err != nil {
+			store.loggers.Errorf("Discarded the buffered update of %q in namespace %q: %s",
+				upsert.key, id.namespace, err)
+		}
+		store.outage.remove(id, upsert.item.Version)
+		replayed++
+	}
+	store.loggers.Infof("Replayed %d buffered update(s) now that Firestore is available", replayed)
+}
@@ -0,0 +1,159 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+func TestValidateOutageBuffer(t *testing.T) {
+	assert.NoError(t, validateOutageBuffer(OutageBufferOptions{}))
+	assert.NoError(t, validateOutageBuffer(OutageBufferOptions{MaxItems: 10}))
+	assert.Error(t, validateOutageBuffer(OutageBufferOptions{MaxItems: -1}))
+	assert.Error(t, validateOutageBuffer(OutageBufferOptions{MaxItems: 10, ReplayInterval: -1}))
+}
+
+func TestOutageBuffer(t *testing.T) {
+	assert.Nil(t, newOutageBuffer(OutageBufferOptions{}))
+
+	b := newOutageBuffer(OutageBufferOptions{MaxItems: 1})
+	require.NotNil(t, b)
+	assert.Equal(t, DefaultOutageBufferReplayInterval, b.interval)
+
+	id := bufferedItemKey{namespace: "features", key: "flag1"}
+	item := func(version int) bufferedUpsert {
+		return bufferedUpsert{kind: ldstoreimpl.Features(), key: "flag1",
+			item: ldstoretypes.SerializedItemDescriptor{Version: version}}
+	}
+	assert.True(t, b.add(id, item(2)))
+	assert.True(t, b.add(id, item(1))) // accepted, but the newer version is kept
+	got, ok := b.get(id)
+	assert.True(t, ok)
+	assert.Equal(t, 2, got.Version)
+	assert.False(t, b.add(bufferedItemKey{namespace: "features", key: "flag2"}, item(1)))
+
+	b.remove(id, 1) // a newer version is buffered, so this has no effect
+	assert.Equal(t, 1, b.len())
+	b.remove(id, 2)
+	assert.Equal(t, 0, b.len())
+}
+
+func TestWithBufferedItems(t *testing.T) {
+	store := &firestoreDataStore{outage: newOutageBuffer(OutageBufferOptions{MaxItems: 10})}
+	features := ldstoreimpl.Features()
+	stored := []ldstoretypes.KeyedSerializedItemDescriptor{
+		{Key: "flag1", Item: ldstoretypes.SerializedItemDescriptor{Version: 1}},
+		{Key: "flag2", Item: ldstoretypes.SerializedItemDescriptor{Version: 5}},
+	}
+	assert.Equal(t, stored, store.withBufferedItems(features, stored))
+
+	for _, u := range []bufferedUpsert{
+		{kind: features, key: "flag1", item: ldstoretypes.SerializedItemDescriptor{Version: 2}},
+		{kind: features, key: "flag2", item: ldstoretypes.SerializedItemDescriptor{Version: 4}},
+		{kind: features, key: "flag3", item: ldstoretypes.SerializedItemDescriptor{Version: 1}},
+	} {
+		store.outage.add(bufferedItemKey{namespace: store.namespaceForKind(features), key: u.key}, u)
+	}
+	assert.ElementsMatch(t, []ldstoretypes.KeyedSerializedItemDescriptor{
+		{Key: "flag1", Item: ldstoretypes.SerializedItemDescriptor{Version: 2}},
+		{Key: "flag2", Item: ldstoretypes.SerializedItemDescriptor{Version: 5}},
+		{Key: "flag3", Item: ldstoretypes.SerializedItemDescriptor{Version: 1}},
+	}, store.withBufferedItems(features, stored))
+	assert.Equal(t, 1, stored[0].Item.Version) // the original is not modified
+	assert.Equal(t, stored, store.withBufferedItems(ldstoreimpl.Segments(), stored))
+}
+
+func TestUpsertIsBufferedWhenFirestoreIsUnavailable(t *testing.T) {
+	ds, err := DataStore("my-project", "my-collection").
+		ClientOptions(option.WithEndpoint("localhost:1"), option.WithoutAuthentication()).
+		Retry(RetryPolicy{}).
+		OperationTimeout(200 * time.Millisecond).
+		OutageBuffer(OutageBufferOptions{MaxItems: 1, ReplayInterval: time.Hour}).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+	ext := ds.(ExtendedDataStore)
+
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)}
+	result, err := ext.UpsertWithResult(ldstoreimpl.Features(), "flag1", item)
+	require.NoError(t, err)
+	assert.Equal(t, UpsertResult{Updated: true, PreviousVersion: -1, Buffered: true}, result)
+	assert.Equal(t, 1, ext.Health().ConsecutiveFailures) // the failed write is still reported
+
+	got, err := ds.Get(ldstoreimpl.Features(), "flag1")
+	require.NoError(t, err)
+	assert.Equal(t, item, got)
+
+	_, err = ds.Upsert(ldstoreimpl.Features(), "flag2", item)
+	assert.Error(t, err, "buffer is full")
+}
+
+func TestReplayBufferedUpserts(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	ds, err := baseDataStoreBuilder().OutageBuffer(OutageBufferOptions{MaxItems: 10, ReplayInterval: time.Hour}).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+	store := ds.(*firestoreDataStore)
+
+	features := ldstoreimpl.Features()
+	_, err = store.Upsert(features, "flag2", ldstoretypes.SerializedItemDescriptor{Version: 5,
+		SerializedItem: []byte(`{"key":"flag2","version":5}`)})
+	require.NoError(t, err)
+	for key, version := range map[string]int{"flag1": 1, "flag2": 3} {
+		store.outage.add(bufferedItemKey{namespace: store.namespaceForKind(features), key: key}, bufferedUpsert{
+			kind: features, key: key,
+			item: ldstoretypes.SerializedItemDescriptor{Version: version, SerializedItem: []byte(`{}`)},
+		})
+	}
+
+	store.replayBufferedUpserts()
+	assert.Equal(t, 0, store.outage.len())
+	item, err := store.getItem(features, "flag1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, item.Version)
+	item, err = store.getItem(features, "flag2")
+	require.NoError(t, err)
+	assert.Equal(t, 5, item.Version) // the stored version was newer
+}
+
+func TestGetPrefersNewerStoredItemToBufferedUpdate(t *testing.T) {
+	ds, err := DataStore("my-project", "my-collection").
+		ClientOptions(option.WithEndpoint("localhost:1"), option.WithoutAuthentication()).
+		Retry(RetryPolicy{}).
+		OperationTimeout(200 * time.Millisecond).
+		ItemCache(ItemCacheOptions{TTL: time.Hour}).
+		OutageBuffer(OutageBufferOptions{MaxItems: 10, ReplayInterval: time.Hour}).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+	store := ds.(*firestoreDataStore)
+
+	features := ldstoreimpl.Features()
+	id := bufferedItemKey{namespace: store.namespaceForKind(features), key: "flag1"}
+	newer := ldstoretypes.SerializedItemDescriptor{Version: 5, SerializedItem: []byte(`{"key":"flag1","version":5}`)}
+	store.cache.put(id.namespace, "flag1", newer, store.now())
+	store.outage.add(id, bufferedUpsert{kind: features, key: "flag1",
+		item: ldstoretypes.SerializedItemDescriptor{Version: 3, SerializedItem: []byte(`{}`)}})
+
+	// Another instance has written a newer version since the update was buffered
+	got, err := ds.Get(features, "flag1")
+	require.NoError(t, err)
+	assert.Equal(t, newer, got)
+
+	buffered := ldstoretypes.SerializedItemDescriptor{Version: 7, SerializedItem: []byte(`{}`)}
+	store.outage.add(id, bufferedUpsert{kind: features, key: "flag1", item: buffered})
+	got, err = ds.Get(features, "flag1")
+	require.NoError(t, err)
+	assert.Equal(t, buffered, got)
+}
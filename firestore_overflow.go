@@ -0,0 +1,97 @@
+package ldfirestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// fieldOverflow is set to true on documents whose item is kept in the OverflowStore, in place of the
+// item field.
+const fieldOverflow = "overflow"
+
+// OverflowStore holds items that are too large to store in a Firestore document, for the
+// [OversizedItemOverflow] policy. It could be implemented with Cloud Storage, for instance. See
+// [StoreBuilder.OverflowStore].
+//
+// Items are identified by their namespace (the data kind, with the store's prefix if any), key, and
+// version. Put is called before the Firestore document that refers to the item is written, so if that
+// write fails or is rejected by the version check, the overflow store has an item that nothing refers
+// to; an implementation may overwrite older versions of an item, since only the newest one is read
+// once it has been written to Firestore.
+type OverflowStore interface {
+	// Put saves the serialized item.
+	Put(ctx context.Context, namespace, key string, version int, item []byte) error
+
+	// Get returns the serialized item that was saved by Put.
+	Get(ctx context.Context, namespace, key string, version int) ([]byte, error)
+}
+
+func (b builderOptions) validateOverflowStore() error {
+	if b.overflowStore != nil {
+		return nil
+	}
+	if b.oversizedPolicy == OversizedItemOverflow {
+		return errors.New("the OversizedItemOverflow policy requires an OverflowStore")
+	}
+	for kind, limit := range b.kindLimits {
+		if limit.policy == OversizedItemOverflow {
+			return fmt.Errorf("the OversizedItemOverflow policy for %q requires an OverflowStore", kind)
+		}
+	}
+	return nil
+}
+
+// overflowItem saves the item of an encoded document in the OverflowStore, and returns a copy of the
+// document that refers to it instead of containing it.
+func (store *firestoreDataStore) overflowItem(data map[string]any) (map[string]any, error) {
+	itemJSON, err := store.itemPayload(data)
+	if err != nil {
+		return nil, err
+	}
+	namespace, key, version := overflowID(data)
+	ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+	defer cancel()
+	if err := store.overflow.Put(ctx, namespace, key, version, itemJSON); err != nil {
+		return nil, fmt.Errorf("could not save the item %q in namespace %q in the overflow store: %w",
+			key, namespace, err)
+	}
+
+	stub := make(map[string]any, len(data))
+	for k, v := range data {
+		stub[k] = v
+	}
+	delete(stub, fieldItem)
+	delete(stub, fieldEncoding)
+	stub[fieldOverflow] = true
+	return stub, nil
+}
+
+// overflowPayload reads the item of a document that was written with overflowItem.
+func (store *firestoreDataStore) overflowPayload(data map[string]any) ([]byte, error) {
+	namespace, key, version := overflowID(data)
+	if store.overflow == nil {
+		return nil, fmt.Errorf("the item %q in namespace %q is in an overflow store, but none is configured",
+			key, namespace)
+	}
+	ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+	defer cancel()
+	itemJSON, err := store.overflow.Get(ctx, namespace, key, version)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the item %q in namespace %q from the overflow store: %w",
+			key, namespace, err)
+	}
+	return itemJSON, nil
+}
+
+func overflowID(data map[string]any) (namespace, key string, version int) {
+	namespace, _ = data[fieldNamespace].(string)
+	key, _ = data[fieldKey].(string)
+	switch v := data[fieldVersion].(type) {
+	case int:
+		version = v
+	case int64:
+		version = int(v)
+	}
+	return namespace, key, version
+}
@@ -0,0 +1,96 @@
+package ldfirestore
+
+import (
+	"errors"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+// defaultItemPageSize is the page size that ForEachItem uses if none is specified.
+const defaultItemPageSize = 500
+
+func (b builderOptions) validateGetAllPaging() error {
+	if b.getAllPageSize > 0 && b.getAllConcurrency > 1 {
+		return errors.New("GetAllPageSize cannot be combined with ParallelGetAll")
+	}
+	return nil
+}
+
+func (store *firestoreDataStore) ForEachItem(
+	kind ldstoretypes.DataKind,
+	pageSize int,
+	fn func(items []ldstoretypes.KeyedSerializedItemDescriptor) error,
+) error {
+	done, err := store.beginOperation(true)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if pageSize <= 0 {
+		pageSize = defaultItemPageSize
+	}
+	return store.forEachItemPage("ForEachItem", kind, pageSize, fn)
+}
+
+// getAllPaged implements GetAll when the store is configured with GetAllPageSize.
+func (store *firestoreDataStore) getAllPaged(
+	kind ldstoretypes.DataKind,
+) ([]ldstoretypes.KeyedSerializedItemDescriptor, error) {
+	var results []ldstoretypes.KeyedSerializedItemDescriptor
+	appendItems := func(items []ldstoretypes.KeyedSerializedItemDescriptor) error {
+		results = append(results, items...)
+		return nil
+	}
+	if err := store.forEachItemPage("GetAll", kind, store.getAllPageSize, appendItems); err != nil {
+		return nil, err
+	}
+	if log, ok := store.debugLog(LogReads); ok {
+		log.Debugf("Read %d item(s) in pages (namespace=%s)", len(results), store.namespaceForKind(kind))
+	}
+	return results, nil
+}
+
+// forEachItemPage reads the items of a data kind with a separate query for each page of pageSize
+// documents, and calls fn with the items in each page. Each page is read, and if necessary retried,
+// on its own, so only one page of documents is held in memory at a time. op is the name of the
+// store method, for errors from Firestore.
+func (store *firestoreDataStore) forEachItemPage(
+	op string,
+	kind ldstoretypes.DataKind,
+	pageSize int,
+	fn func(items []ldstoretypes.KeyedSerializedItemDescriptor) error,
+) error {
+	pageQuery := store.getAllQuery(kind).OrderBy(firestore.DocumentID, firestore.Asc).Limit(pageSize)
+	for {
+		var docs []*firestore.DocumentSnapshot
+		err := store.retry.do(store.context, store.onRetry, func() (err error) {
+			ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+			defer cancel()
+			docs, err = pageQuery.Documents(ctx).GetAll()
+			return err
+		})
+		if err != nil {
+			return newStoreError(op, kind, "", err)
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		items := make([]ldstoretypes.KeyedSerializedItemDescriptor, 0, len(docs))
+		for _, doc := range docs {
+			if key, item, ok := store.decodeDocumentWithRepair(kind, doc); ok {
+				store.observeVersion(kind, key, item.Version)
+				items = append(items, ldstoretypes.KeyedSerializedItemDescriptor{Key: key, Item: item})
+			}
+		}
+		if err := fn(items); err != nil {
+			return err
+		}
+		if len(docs) < pageSize {
+			return nil
+		}
+		pageQuery = pageQuery.StartAfter(docs[len(docs)-1])
+	}
+}
@@ -0,0 +1,76 @@
+package ldfirestore
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+)
+
+// ErrPanic is wrapped by the error that a store operation returns if it panicked, for instance
+// because of a bug in decoding a document or in the Firestore client library. The panic is recovered
+// so that it does not crash the application.
+var ErrPanic = errors.New("recovered from a panic")
+
+// panicSites remembers where panics have happened, so that the stack trace of each is logged only
+// once. The zero value is ready to use.
+type panicSites struct {
+	seen map[string]bool
+	lock sync.Mutex
+}
+
+// protect calls fn, and turns a panic in it into an error that wraps ErrPanic.
+func (p *panicSites) protect(loggers ldlog.Loggers, operation string, fn func() error) (err error) {
+	defer p.recover(loggers, operation, &err)
+	return fn()
+}
+
+// recover must be deferred directly. If the function is panicking, it recovers, logs the panic, and
+// sets *err (if err is not nil) to an error that wraps ErrPanic.
+func (p *panicSites) recover(loggers ldlog.Loggers, operation string, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	site := panicSite()
+	p.lock.Lock()
+	first := !p.seen[site]
+	if p.seen == nil {
+		p.seen = make(map[string]bool)
+	}
+	p.seen[site] = true
+	p.lock.Unlock()
+
+	if first {
+		loggers.Errorf("Recovered from a panic in %s at %s: %v\n%s", operation, site, r, debug.Stack())
+	} else {
+		loggers.Errorf("Recovered from a panic in %s at %s: %v", operation, site, r)
+	}
+	if err != nil {
+		*err = fmt.Errorf("%w in %s: %v", ErrPanic, operation, r)
+	}
+}
+
+// panicSite returns the function and line where the current panic happened, which is the first frame
+// after the runtime's panic handling.
+func panicSite() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	afterPanic := false
+	for {
+		frame, more := frames.Next()
+		if afterPanic && frame.Function != "" && !strings.HasPrefix(frame.Function, "runtime.") {
+			return fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line)
+		}
+		if frame.Function == "runtime.gopanic" {
+			afterPanic = true
+		}
+		if !more {
+			return "unknown location"
+		}
+	}
+}
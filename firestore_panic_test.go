@@ -0,0 +1,67 @@
+package ldfirestore
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPanicSites(t *testing.T) {
+	mockLog := ldlogtest.NewMockLog()
+	var panics panicSites
+	panicky := func() error {
+		var m map[string]int
+		m["x"] = 1 // panics
+		return nil
+	}
+
+	for i := 0; i < 2; i++ {
+		err := panics.protect(mockLog.Loggers, "Get", panicky)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrPanic))
+		assert.Contains(t, err.Error(), "in Get")
+	}
+
+	messages := mockLog.GetOutput(ldlog.Error)
+	require.Len(t, messages, 2)
+	assert.Contains(t, messages[0], "TestPanicSites")
+	assert.Contains(t, messages[0], "goroutine") // the stack trace is logged the first time only
+	assert.NotContains(t, messages[1], "goroutine")
+
+	assert.NoError(t, panics.protect(mockLog.Loggers, "Get", func() error { return nil }))
+}
+
+func TestUpsertRecoversFromPanic(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close()
+
+	mockLog := ldlogtest.NewMockLog()
+	ds, err := DataStore("my-project", "my-collection").FirestoreClient(client).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+	store := ds.(*firestoreDataStore)
+	store.loggers = mockLog.Loggers
+	store.testUpdateHook = func() { panic("boom") }
+
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)}
+	updated, err := store.Upsert(ldstoreimpl.Features(), "flag1", item)
+	assert.False(t, updated)
+	assert.True(t, errors.Is(err, ErrPanic))
+	assert.Equal(t, 1, store.Stats().Writes.Errors)
+	assert.True(t, strings.Contains(mockLog.GetOutput(ldlog.Error)[0], "boom"))
+
+	// the operation slot and the item's lock were released, so another update can run
+	store.testUpdateHook = nil
+	_, err = store.Upsert(ldstoreimpl.Features(), "flag1", item)
+	assert.False(t, errors.Is(err, ErrPanic))
+}
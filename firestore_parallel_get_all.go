@@ -0,0 +1,115 @@
+package ldfirestore
+
+import (
+	"context"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"google.golang.org/api/iterator"
+)
+
+// getAllPartitioned reads all items of a data kind like GetAll, but divides the documents into
+// partitions with a Firestore partition query and reads them concurrently. See
+// [StoreBuilder.ParallelGetAll]. Each partition is read with documents. If sequential is true, the
+// partitions are read one at a time instead, for a documents function that is not safe for concurrent
+// use, such as one that reads in a transaction.
+func (store *firestoreDataStore) getAllPartitioned(
+	ctx context.Context,
+	kind ldstoretypes.DataKind,
+	documents queryDocumentsFunc,
+	sequential bool,
+) ([]ldstoretypes.KeyedSerializedItemDescriptor, error) {
+	// Firestore can only partition collection group queries, which is why this is only used with
+	// CollectionGroupQueries; the namespace filter then finds the same documents as queryForKind.
+	groupID := store.collectionNameForKind(kind)
+	if store.subcollections {
+		groupID = itemsSubcollection
+	}
+
+	partitions, err := store.firestoreClient().CollectionGroup(groupID).GetPartitionedQueries(ctx, store.getAllConcurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := store.namespaceForKind(kind)
+	results := make([][]ldstoretypes.KeyedSerializedItemDescriptor, len(partitions))
+	errs := make([]error, len(partitions))
+	var wg sync.WaitGroup
+	for i, partition := range partitions {
+		query := partition.Where(fieldNamespace, "==", namespace)
+		if store.excludeDeleted {
+			query = query.Where(fieldDeleted, "==", false)
+		}
+		if sequential {
+			results[i], errs[i] = store.readPartition(documents, kind, query)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = store.readPartition(documents, kind, query)
+		}()
+	}
+	wg.Wait()
+
+	// The partitions are in document order, so the results are in the same order as with a single query
+	var all []ldstoretypes.KeyedSerializedItemDescriptor
+	for i := range partitions {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		all = append(all, results[i]...)
+	}
+	return all, nil
+}
+
+func (store *firestoreDataStore) readPartition(
+	documents queryDocumentsFunc,
+	kind ldstoretypes.DataKind,
+	query firestore.Query,
+) ([]ldstoretypes.KeyedSerializedItemDescriptor, error) {
+	iter := documents(query)
+	defer iter.Stop()
+
+	var results []ldstoretypes.KeyedSerializedItemDescriptor
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return results, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if key, item, ok := store.decodeDocumentWithRepair(kind, doc); ok {
+			store.observeVersion(kind, key, item.Version)
+			results = append(results, ldstoretypes.KeyedSerializedItemDescriptor{Key: key, Item: item})
+		}
+	}
+}
+
+// usePartitionedGetAll returns true if GetAll should read the items with partitioned queries.
+// Without CollectionGroupQueries, a partitioned query would read the documents of every collection in
+// the database with the same ID, so a single query is used instead.
+func (store *firestoreDataStore) usePartitionedGetAll() bool {
+	if store.getAllConcurrency <= 1 || !store.collectionGroup {
+		return false
+	}
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	return !store.partitionQueriesFailed
+}
+
+// disablePartitionedGetAll makes GetAll use a single query from now on, after a partitioned query
+// failed with FailedPrecondition. This is the error that Firestore returns when a query needs an
+// index that does not exist, which the partition queries do unless the collection group index that
+// is described in [StoreBuilder.ParallelGetAll] has been created.
+func (store *firestoreDataStore) disablePartitionedGetAll(err error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if !store.partitionQueriesFailed {
+		store.partitionQueriesFailed = true
+		store.loggers.Warnf("Partitioned GetAll queries failed, probably for lack of a collection group index; "+
+			"using a single query for each GetAll instead: %s", err)
+	}
+}
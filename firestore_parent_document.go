@@ -0,0 +1,69 @@
+package ldfirestore
+
+import (
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+)
+
+// prefixPlaceholder is replaced with the store's prefix in a parent document path.
+const prefixPlaceholder = "{prefix}"
+
+// normalizeParentDocument removes leading and trailing slashes from a parent document path, and
+// checks that it refers to a document rather than a collection.
+func normalizeParentDocument(path string) (string, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", nil
+	}
+	segments := strings.Split(path, "/")
+	for _, s := range segments {
+		if s == "" {
+			return "", fmt.Errorf("parent document path %q contains an empty segment", path)
+		}
+	}
+	if len(segments)%2 != 0 {
+		return "", fmt.Errorf("parent document path %q refers to a collection, not a document", path)
+	}
+	return path, nil
+}
+
+// resolveParentDocument substitutes the prefix into a parent document path that contains the
+// {prefix} placeholder.
+func resolveParentDocument(parentDoc, prefix string) string {
+	return strings.ReplaceAll(parentDoc, prefixPlaceholder, prefix)
+}
+
+// validateParentDocumentPrefix checks that a parent document path with the {prefix} placeholder will
+// resolve to a valid path.
+func validateParentDocumentPrefix(parentDoc, prefix string) error {
+	if !strings.Contains(parentDoc, prefixPlaceholder) {
+		return nil
+	}
+	if prefix == "" || strings.Contains(prefix, "/") {
+		return fmt.Errorf("parent document path %q requires a prefix that is not empty and does not contain \"/\"",
+			parentDoc)
+	}
+	return nil
+}
+
+// allPrefixes returns the store's prefix and any other prefixes that were specified with KindRoute.
+func (b builderOptions) allPrefixes() []string {
+	prefixes := []string{b.prefix}
+	for _, route := range b.kindRoutes {
+		if route.prefix != "" {
+			prefixes = append(prefixes, route.prefix)
+		}
+	}
+	return prefixes
+}
+
+// collectionRef returns the named collection, which is a subcollection of parentDoc if that is not
+// empty, or else a top-level collection.
+func collectionRef(client *firestore.Client, parentDoc, name string) *firestore.CollectionRef {
+	if parentDoc == "" {
+		return client.Collection(name)
+	}
+	return client.Doc(parentDoc).Collection(name)
+}
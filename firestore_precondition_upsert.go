@@ -0,0 +1,166 @@
+package ldfirestore
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// knownDocument is what the store remembers about an item document that it last read or wrote
+// without a transaction, so that the next update can be made conditional on the document not
+// having changed since then. A version of -1 means that the document did not exist.
+type knownDocument struct {
+	version    int
+	updateTime time.Time
+	fields     map[string]any // only the names are used
+}
+
+// knownDocuments holds the knownDocument for each document path. The zero value is ready to use.
+type knownDocuments struct {
+	docs map[string]knownDocument
+	lock sync.Mutex
+}
+
+func (k *knownDocuments) get(path string) (knownDocument, bool) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	doc, ok := k.docs[path]
+	return doc, ok
+}
+
+func (k *knownDocuments) put(path string, doc knownDocument) {
+	k.lock.Lock()
+	if k.docs == nil {
+		k.docs = make(map[string]knownDocument)
+	}
+	k.docs[path] = doc
+	k.lock.Unlock()
+}
+
+func (k *knownDocuments) remove(path string) {
+	k.lock.Lock()
+	delete(k.docs, path)
+	k.lock.Unlock()
+}
+
+// reset forgets every document, since Init rewrites all of them.
+func (k *knownDocuments) reset() {
+	k.lock.Lock()
+	k.docs = nil
+	k.lock.Unlock()
+}
+
+func (b builderOptions) validatePreconditionUpserts() error {
+	if b.preconditionUpserts && b.namespaceSummaries {
+		return errors.New("PreconditionUpserts cannot be combined with NamespaceSummaries")
+	}
+	if b.preconditionUpserts && b.namespaceManifests {
+		return errors.New("PreconditionUpserts cannot be combined with NamespaceManifests")
+	}
+	return nil
+}
+
+// upsertWithPreconditions tries to update an item without a transaction, by writing the document
+// with a precondition that it has not changed since the store last read or wrote it. If the store
+// has no record of the document, it reads it first, without a transaction. It returns false if the
+// outcome is uncertain, because the precondition failed, the cached version is not older than the
+// new one, or any request failed; the caller should then fall back to a transaction. Otherwise it
+// returns true along with the result of the update, which is errVersionCheckFailed if the existing
+// item was not older.
+func (store *firestoreDataStore) upsertWithPreconditions(
+	kind ldstoretypes.DataKind,
+	key string,
+	docRef *firestore.DocumentRef,
+	data map[string]any,
+	newVersion int,
+	result *UpsertResult,
+) (bool, error) {
+	known, cached := store.knownDocs.get(docRef.Path)
+	if !cached {
+		var ok bool
+		if known, ok = store.readKnownDocument(docRef); !ok {
+			return false, nil
+		}
+		store.countUsage("Upsert", DocumentOperations{Reads: 1})
+	}
+
+	if known.version >= newVersion {
+		if cached {
+			// The document may have been changed by another writer since we wrote it, possibly to an
+			// older version by an Init, so only a transaction can tell whether this update applies.
+			store.knownDocs.remove(docRef.Path)
+			return false, nil
+		}
+		result.PreviousVersion = known.version
+		if log, ok := store.debugLog(LogWrites); ok {
+			log.Debugf("Not updating item due to version check (namespace=%s key=%s version=%d, existing=%d)",
+				kind, key, newVersion, known.version)
+		}
+		return true, errVersionCheckFailed
+	}
+
+	ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+	defer cancel()
+	var wr *firestore.WriteResult
+	var err error
+	if known.version < 0 {
+		wr, err = docRef.Create(ctx, data)
+	} else {
+		wr, err = docRef.Update(ctx, replacementUpdates(known.fields, data),
+			firestore.LastUpdateTime(known.updateTime))
+	}
+	if err != nil {
+		store.knownDocs.remove(docRef.Path)
+		if log, ok := store.debugLog(LogWrites); ok {
+			log.Debugf("Conditional update failed, retrying in a transaction (namespace=%s key=%s): %s",
+				kind, key, err)
+		}
+		return false, nil
+	}
+
+	store.countUsage("Upsert", DocumentOperations{Writes: 1})
+	store.knownDocs.put(docRef.Path, knownDocument{
+		version:    newVersion,
+		updateTime: wr.UpdateTime,
+		fields:     fieldNames(data),
+	})
+	result.PreviousVersion = known.version
+	return true, nil
+}
+
+// readKnownDocument reads the current state of a document, without a transaction. It returns false
+// if the document could not be read, or was written by a newer version of the store while the store
+// is configured to become read-only in that case, so that the transaction can report it.
+func (store *firestoreDataStore) readKnownDocument(docRef *firestore.DocumentRef) (knownDocument, bool) {
+	ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+	defer cancel()
+	doc, err := docRef.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return knownDocument{version: -1}, true
+	}
+	if err != nil || !doc.Exists() {
+		return knownDocument{}, false
+	}
+	data := doc.Data()
+	if store.observeSchemaVersion(data) && store.readOnlyOnNewerSchema {
+		return knownDocument{}, false
+	}
+	known := knownDocument{updateTime: doc.UpdateTime, fields: fieldNames(data)}
+	if v, ok := data[fieldVersion].(int64); ok {
+		known.version = int(v)
+	}
+	return known, true
+}
+
+func fieldNames(data map[string]any) map[string]any {
+	names := make(map[string]any, len(data))
+	for name := range data {
+		names[name] = nil
+	}
+	return names
+}
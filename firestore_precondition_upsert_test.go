@@ -0,0 +1,86 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKnownDocuments(t *testing.T) {
+	var k knownDocuments
+	_, ok := k.get("a")
+	assert.False(t, ok)
+
+	doc := knownDocument{version: 2, updateTime: time.Unix(100, 0), fields: fieldNames(map[string]any{"x": 1})}
+	k.put("a", doc)
+	got, ok := k.get("a")
+	require.True(t, ok)
+	assert.Equal(t, doc, got)
+
+	k.remove("a")
+	_, ok = k.get("a")
+	assert.False(t, ok)
+
+	k.put("a", doc)
+	k.reset()
+	_, ok = k.get("a")
+	assert.False(t, ok)
+}
+
+func TestPreconditionUpsertsBuilder(t *testing.T) {
+	b := DataStore("my-project", "my-collection").PreconditionUpserts(true)
+	assert.True(t, b.preconditionUpserts)
+
+	ds, err := b.NamespaceSummaries(true).Build(subsystems.BasicClientContext{})
+	assert.Error(t, err)
+	assert.Nil(t, ds)
+	assert.Contains(t, err.Error(), "cannot be combined with NamespaceSummaries")
+}
+
+func TestPreconditionUpserts(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := baseDataStoreBuilder().PreconditionUpserts(true).Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	ext := store.(ExtendedDataStore)
+
+	otherStore, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = otherStore.Close() }()
+
+	item := func(version int) ldstoretypes.SerializedItemDescriptor {
+		return ldstoretypes.SerializedItemDescriptor{Version: version, SerializedItem: []byte(`{"key":"flag1"}`)}
+	}
+
+	result, err := ext.UpsertWithResult(ldstoreimpl.Features(), "flag1", item(1))
+	require.NoError(t, err)
+	assert.Equal(t, UpsertResult{Updated: true, PreviousVersion: -1}, result)
+
+	result, err = ext.UpsertWithResult(ldstoreimpl.Features(), "flag1", item(2))
+	require.NoError(t, err)
+	assert.Equal(t, UpsertResult{Updated: true, PreviousVersion: 1}, result)
+
+	// A change by another writer makes the precondition fail, so the transaction decides
+	_, err = otherStore.Upsert(ldstoreimpl.Features(), "flag1", item(5))
+	require.NoError(t, err)
+	result, err = ext.UpsertWithResult(ldstoreimpl.Features(), "flag1", item(3))
+	require.NoError(t, err)
+	assert.Equal(t, UpsertResult{Conflict: true, PreviousVersion: 5}, result)
+
+	result, err = ext.UpsertWithResult(ldstoreimpl.Features(), "flag1", item(6))
+	require.NoError(t, err)
+	assert.Equal(t, UpsertResult{Updated: true, PreviousVersion: 5}, result)
+
+	got, err := otherStore.Get(ldstoreimpl.Features(), "flag1")
+	require.NoError(t, err)
+	assert.Equal(t, 6, got.Version)
+}
@@ -0,0 +1,71 @@
+package ldfirestore
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// preflightNamespace is the namespace of the document that the preflight check writes and deletes.
+const preflightNamespace = "$preflight"
+
+// preflight verifies that the store can read from and (unless it is read-only) write to its
+// collection, and that the queries it uses for GetAll and Init can run, which is not the case if a
+// required index is missing. Errors are translated into messages that say what to fix.
+func (store *firestoreDataStore) preflight() error {
+	if err := store.checkConnection(store.operationTimeout); err != nil {
+		return preflightError("read from", store.collection, err)
+	}
+
+	for _, kind := range ldstoreimpl.AllKinds() {
+		ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+		iter := store.getAllQuery(kind).Select().Limit(1).Documents(ctx) // no fields are needed
+		_, err := iter.Next()
+		iter.Stop()
+		cancel()
+		if err != nil && err != iterator.Done {
+			return preflightError(fmt.Sprintf("query %s in", kind), store.collectionNameForKind(kind), err)
+		}
+	}
+
+	if store.readOnly {
+		return nil
+	}
+	ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+	defer cancel()
+	ref := store.collectionRef(store.collection).
+		Doc(store.ids.makeID(store.prefix, prefixedNamespace(store.prefix, preflightNamespace), "check"))
+	if _, err := ref.Set(ctx, map[string]any{
+		fieldNamespace: prefixedNamespace(store.prefix, preflightNamespace),
+		fieldKey:       "check",
+		fieldUpdatedAt: firestore.ServerTimestamp,
+	}); err != nil {
+		return preflightError("write to", store.collection, err)
+	}
+	if _, err := ref.Delete(ctx); err != nil {
+		return preflightError("delete from", store.collection, err)
+	}
+	return nil
+}
+
+func preflightError(action, collection string, err error) error {
+	var hint string
+	switch status.Code(err) {
+	case codes.PermissionDenied, codes.Unauthenticated:
+		hint = "check that the service account has a role that allows this, such as roles/datastore.user " +
+			"(or roles/datastore.viewer for a read-only store), and that security rules do not block it"
+	case codes.FailedPrecondition:
+		hint = "a required index is probably missing; the error below includes a link for creating it"
+	case codes.NotFound:
+		hint = "check that the project and database exist and that Firestore is enabled for the project"
+	case codes.Unavailable, codes.DeadlineExceeded:
+		hint = "check the network connection to Firestore and any private endpoint configuration"
+	default:
+		return fmt.Errorf("preflight check could not %s collection %q: %w", action, collection, err)
+	}
+	return fmt.Errorf("preflight check could not %s collection %q; %s: %w", action, collection, hint, err)
+}
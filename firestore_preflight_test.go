@@ -0,0 +1,49 @@
+package ldfirestore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPreflightCheck(t *testing.T) {
+	t.Run("error messages", func(t *testing.T) {
+		err := preflightError("write to", "flags", status.Error(codes.PermissionDenied, "denied"))
+		assert.Contains(t, err.Error(), `could not write to collection "flags"`)
+		assert.Contains(t, err.Error(), "roles/datastore.user")
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		err = preflightError("query features in", "flags", status.Error(codes.FailedPrecondition, "needs index"))
+		assert.Contains(t, err.Error(), "index")
+
+		plainErr := errors.New("sorry")
+		err = preflightError("read from", "flags", plainErr)
+		assert.ErrorIs(t, err, plainErr)
+	})
+
+	t.Run("Build fails if Firestore cannot be reached", func(t *testing.T) {
+		client, err := createTestClient()
+		require.NoError(t, err)
+		_ = client.Close()
+
+		store, err := DataStore("my-project", "my-collection").FirestoreClient(client).PreflightCheck(true).
+			Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, store)
+		assert.Contains(t, err.Error(), "preflight check could not read from collection")
+	})
+
+	t.Run("Build succeeds with emulator", func(t *testing.T) {
+		if !isEmulatorAvailable() {
+			t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+		}
+		store, err := baseDataStoreBuilder().PreflightCheck(true).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		assert.NoError(t, store.Close())
+	})
+}
@@ -0,0 +1,100 @@
+package ldfirestore
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PrivateEndpointOptions describes a private connection to Firestore, such as a regional endpoint or
+// a Private Service Connect endpoint. See [StoreBuilder.PrivateEndpoint].
+type PrivateEndpointOptions struct {
+	// Endpoint is the host name and port to connect to, such as
+	// "firestore-myendpoint.p.googleapis.com:443" for a Private Service Connect endpoint or
+	// "nam5-firestore.googleapis.com:443" for a regional endpoint. It must not include a URL scheme.
+	// This is required.
+	Endpoint string
+
+	// ClientCertSource, if not nil, provides a client certificate so that the connection uses mutual
+	// TLS.
+	ClientCertSource option.ClientCertSource
+}
+
+// validate checks that the options are usable, so that a misconfiguration is reported when the store
+// is built rather than as an Unavailable error from every operation.
+func (o PrivateEndpointOptions) validate() error {
+	if o.Endpoint == "" {
+		return fmt.Errorf("private endpoint must not be empty")
+	}
+	if strings.Contains(o.Endpoint, "://") {
+		return fmt.Errorf("private endpoint %q must be a host and port, not a URL", o.Endpoint)
+	}
+	host, port, err := net.SplitHostPort(o.Endpoint)
+	if err != nil {
+		return fmt.Errorf("private endpoint %q must be a host and port: %w", o.Endpoint, err)
+	}
+	if host == "" {
+		return fmt.Errorf("private endpoint %q has no host name", o.Endpoint)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return fmt.Errorf("private endpoint %q has an invalid port", o.Endpoint)
+	}
+	return nil
+}
+
+// withPrivateEndpoint returns a copy of the builder options whose client options connect to the
+// private endpoint, if one was configured. Unavailable errors from the endpoint are annotated with
+// its address, since the cause is usually the private connectivity setup rather than Firestore.
+func withPrivateEndpoint(builder builderOptions) builderOptions {
+	if builder.privateEndpoint == nil {
+		return builder
+	}
+	endpoint := builder.privateEndpoint.Endpoint
+	opts := []option.ClientOption{
+		option.WithEndpoint(endpoint),
+		option.WithGRPCDialOption(grpc.WithChainUnaryInterceptor(
+			func(
+				ctx context.Context,
+				method string,
+				req, reply any,
+				cc *grpc.ClientConn,
+				invoker grpc.UnaryInvoker,
+				callOpts ...grpc.CallOption,
+			) error {
+				return annotateUnavailable(invoker(ctx, method, req, reply, cc, callOpts...), endpoint)
+			})),
+		option.WithGRPCDialOption(grpc.WithChainStreamInterceptor(
+			func(
+				ctx context.Context,
+				desc *grpc.StreamDesc,
+				cc *grpc.ClientConn,
+				method string,
+				streamer grpc.Streamer,
+				callOpts ...grpc.CallOption,
+			) (grpc.ClientStream, error) {
+				stream, err := streamer(ctx, desc, cc, method, callOpts...)
+				return stream, annotateUnavailable(err, endpoint)
+			})),
+	}
+	if builder.privateEndpoint.ClientCertSource != nil {
+		opts = append(opts, option.WithClientCertSource(builder.privateEndpoint.ClientCertSource))
+	}
+	builder.clientOptions = slices.Concat(builder.clientOptions, opts)
+	return builder
+}
+
+func annotateUnavailable(err error, endpoint string) error {
+	if status.Code(err) != codes.Unavailable {
+		return err
+	}
+	return status.Errorf(codes.Unavailable, "%s (via private endpoint %s; check that the endpoint is "+
+		"reachable from this network and that its certificates are valid)", status.Convert(err).Message(), endpoint)
+}
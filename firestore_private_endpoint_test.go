@@ -0,0 +1,56 @@
+package ldfirestore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPrivateEndpoint(t *testing.T) {
+	t.Run("validation", func(t *testing.T) {
+		assert.NoError(t, PrivateEndpointOptions{Endpoint: "firestore-x.p.googleapis.com:443"}.validate())
+		for _, endpoint := range []string{
+			"",
+			"https://firestore-x.p.googleapis.com",
+			"firestore-x.p.googleapis.com",
+			":443",
+			"firestore-x.p.googleapis.com:https",
+			"firestore-x.p.googleapis.com:70000",
+		} {
+			assert.Error(t, PrivateEndpointOptions{Endpoint: endpoint}.validate(), endpoint)
+		}
+	})
+
+	t.Run("builder options", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection")
+		assert.Len(t, withPrivateEndpoint(b.builderOptions).clientOptions, 0)
+
+		b.PrivateEndpoint(PrivateEndpointOptions{Endpoint: "firestore-x.p.googleapis.com:443"})
+		assert.Equal(t, "firestore-x.p.googleapis.com:443", b.privateEndpoint.Endpoint)
+		assert.Len(t, withPrivateEndpoint(b.builderOptions).clientOptions, 3)
+		assert.Len(t, b.clientOptions, 0)
+	})
+
+	t.Run("error for invalid endpoint", func(t *testing.T) {
+		ds, err := DataStore("my-project", "my-collection").
+			PrivateEndpoint(PrivateEndpointOptions{Endpoint: "https://x"}).
+			Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, ds)
+	})
+
+	t.Run("annotates unavailable errors", func(t *testing.T) {
+		err := annotateUnavailable(status.Error(codes.Unavailable, "connection refused"), "x:443")
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Contains(t, err.Error(), "connection refused")
+		assert.Contains(t, err.Error(), "private endpoint x:443")
+
+		other := errors.New("other")
+		assert.Equal(t, other, annotateUnavailable(other, "x:443"))
+		assert.NoError(t, annotateUnavailable(nil, "x:443"))
+	})
+}
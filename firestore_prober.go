@@ -0,0 +1,138 @@
+package ldfirestore
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+)
+
+// DefaultProberInterval is the default value for [ProberOptions.Interval].
+const DefaultProberInterval = 30 * time.Second
+
+// ProberOptions configures a [Prober].
+type ProberOptions struct {
+	// Interval is the time between availability checks. If it is zero or less,
+	// DefaultProberInterval is used.
+	Interval time.Duration
+
+	// OnChange, if not nil, is called with the result of the first check, and again each time the
+	// availability of the store changes. It is called on the goroutine that performed the check.
+	OnChange func(available bool)
+
+	// Loggers is used for the prober's log output. If it is not set, the default ldlog behavior
+	// applies. Use ldlog.NewDisabledLoggers() to turn off logging.
+	Loggers ldlog.Loggers
+}
+
+// Prober periodically checks whether the Firestore backend for a data store is available, without
+// involving the SDK. This allows infrastructure health checks to monitor the LaunchDarkly data in
+// Firestore even in processes that do not use the SDK.
+//
+// The check is the same one the SDK uses for the data store's status: reading a single document in
+// the store's collection. A missing document still counts as available.
+type Prober struct {
+	store     *firestoreDataStore
+	interval  time.Duration
+	onChange  func(bool)
+	available bool
+	checked   bool
+	lock      sync.Mutex
+	closeOnce sync.Once
+	closer    chan struct{}
+	done      chan struct{}
+}
+
+// NewProber creates a [Prober] that uses the same project, collection, client, and other options as
+// the given builder, and starts checking immediately. Options that only apply to writing data, such
+// as WriterElection, are ignored. Call [Prober.Close] to stop it.
+//
+//	prober, err := ldfirestore.NewProber(ldfirestore.DataStore("my-project", "launchdarkly"),
+//		ldfirestore.ProberOptions{OnChange: func(available bool) { ... }})
+func NewProber[T any](builder *StoreBuilder[T], options ProberOptions) (*Prober, error) {
+	if builder == nil {
+		return nil, errors.New("builder is required")
+	}
+	storeOptions := builder.builderOptions
+	storeOptions.writerLeaseDuration = 0
+	storeOptions.rpcLogSampleRate = 0
+	storeOptions.availabilityGrace = 0
+	storeOptions.onChange = nil
+	storeOptions.itemCache = ItemCacheOptions{}
+	storeOptions.snapshotFallback = SnapshotFallbackOptions{}
+	storeOptions.outageBuffer = OutageBufferOptions{}
+
+	store, err := newFirestoreDataStoreImpl(storeOptions, options.Loggers)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := options.Interval
+	if interval <= 0 {
+		interval = DefaultProberInterval
+	}
+	p := &Prober{
+		store:    store,
+		interval: interval,
+		onChange: options.OnChange,
+		closer:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+	return p, nil
+}
+
+// Available returns the result of the most recent check. It returns false if no check has
+// completed yet.
+func (p *Prober) Available() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.available
+}
+
+// CheckNow performs a check immediately, in addition to the periodic checks, and returns its result.
+func (p *Prober) CheckNow() bool {
+	available := p.store.IsStoreAvailable()
+
+	p.lock.Lock()
+	changed := !p.checked || available != p.available
+	p.available = available
+	p.checked = true
+	p.lock.Unlock()
+
+	if changed {
+		if available {
+			p.store.loggers.Info("Firestore is available")
+		} else {
+			p.store.loggers.Warn("Firestore is unavailable")
+		}
+		if p.onChange != nil {
+			p.onChange(available)
+		}
+	}
+	return available
+}
+
+// Close stops the periodic checks and releases the prober's resources.
+func (p *Prober) Close() error {
+	p.closeOnce.Do(func() { close(p.closer) })
+	<-p.done
+	return p.store.Close()
+}
+
+func (p *Prober) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	p.CheckNow()
+	for {
+		select {
+		case <-p.closer:
+			return
+		case <-ticker.C:
+			p.CheckNow()
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProber(t *testing.T) {
+	t.Run("reports unavailable store", func(t *testing.T) {
+		client, err := createTestClient()
+		require.NoError(t, err)
+		_ = client.Close() // use a closed client so that every check fails without a server
+
+		changes := make(chan bool, 10)
+		prober, err := NewProber(DataStore(testProjectID, testCollectionName).FirestoreClient(client), ProberOptions{
+			Interval: time.Millisecond,
+			OnChange: func(available bool) { changes <- available },
+			Loggers:  ldlog.NewDisabledLoggers(),
+		})
+		require.NoError(t, err)
+
+		select {
+		case available := <-changes:
+			assert.False(t, available)
+		case <-time.After(time.Second):
+			require.Fail(t, "timed out waiting for first check")
+		}
+		assert.False(t, prober.CheckNow())
+		assert.False(t, prober.Available())
+
+		require.NoError(t, prober.Close())
+		assert.Len(t, changes, 0) // the state never changed after the first check
+	})
+
+	t.Run("reports available store", func(t *testing.T) {
+		if !isEmulatorAvailable() {
+			t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+		}
+		prober, err := NewProber(baseDataStoreBuilder(), ProberOptions{Loggers: ldlog.NewDisabledLoggers()})
+		require.NoError(t, err)
+		defer func() { _ = prober.Close() }()
+
+		assert.True(t, prober.CheckNow())
+		assert.True(t, prober.Available())
+	})
+
+	t.Run("error for invalid builder", func(t *testing.T) {
+		_, err := NewProber(DataStore("my-project", ""), ProberOptions{})
+		assert.Error(t, err)
+	})
+}
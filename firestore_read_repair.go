@@ -0,0 +1,96 @@
+package ldfirestore
+
+import (
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+// decodeDocumentWithRepair is like decodeDocument, but if read repair is enabled and the document
+// can't be decoded, it re-reads the document directly from Firestore and then tries more lenient
+// decodings before giving up. Every repair attempt is logged and counted in the Stats, so that
+// transient read anomalies can be told apart from documents that are actually corrupt.
+func (store *firestoreDataStore) decodeDocumentWithRepair(
+	kind ldstoretypes.DataKind,
+	doc *firestore.DocumentSnapshot,
+) (string, ldstoretypes.SerializedItemDescriptor, bool) {
+	if key, item, ok := store.decodeDocument(doc); ok || !store.readRepair {
+		return key, item, ok
+	}
+
+	docID := doc.Ref.ID
+	store.loggers.Warnf("Could not decode document %q; attempting read repair", docID)
+	store.countReadRepairAttempt()
+
+	data := doc.Data()
+	ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+	defer cancel()
+	fresh, err := doc.Ref.Get(ctx)
+	if err == nil && fresh.Exists() {
+		if key, item, ok := store.decodeDocument(fresh); ok {
+			store.loggers.Warnf("Read repair of document %q succeeded by re-reading it", docID)
+			store.countReadRepairResult(true)
+			return key, item, true
+		}
+		data = fresh.Data()
+	}
+
+	if key, item, ok := store.decodeLenient(kind, docID, data); ok {
+		store.loggers.Warnf("Read repair of document %q succeeded using an alternate decoding", docID)
+		store.countReadRepairResult(true)
+		return key, item, true
+	}
+
+	store.loggers.Errorf("Read repair of document %q failed; the stored data appears to be corrupt", docID)
+	store.countReadRepairResult(false)
+	return "", ldstoretypes.SerializedItemDescriptor{}, false
+}
+
+// decodeLenient accepts field types that a well-formed document would not have, such as a version
+// stored as a floating-point number or string, or an item stored as bytes. If the key field is
+// missing, the key is recovered from the document ID.
+func (store *firestoreDataStore) decodeLenient(
+	kind ldstoretypes.DataKind,
+	docID string,
+	data map[string]any,
+) (string, ldstoretypes.SerializedItemDescriptor, bool) {
+	if _, err := upgradeDocument(data, schemaMigrations, currentSchemaVersion); err != nil {
+		return "", ldstoretypes.SerializedItemDescriptor{}, false
+	}
+	key, _ := data[fieldKey].(string)
+	if key == "" {
+		if store.ids.hashed {
+			return "", ldstoretypes.SerializedItemDescriptor{}, false // a hashed ID does not contain the key
+		}
+		idPrefix := store.makeDocID(kind, "")
+		if !strings.HasPrefix(docID, idPrefix) || len(docID) == len(idPrefix) {
+			return "", ldstoretypes.SerializedItemDescriptor{}, false
+		}
+		key = store.ids.unescape(docID[len(idPrefix):])
+	}
+
+	var version int
+	switch v := data[fieldVersion].(type) {
+	case int64:
+		version = int(v)
+	case float64:
+		version = int(v)
+	case string:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return "", ldstoretypes.SerializedItemDescriptor{}, false
+		}
+		version = n
+	default:
+		return "", ldstoretypes.SerializedItemDescriptor{}, false
+	}
+
+	itemJSON, err := store.itemPayload(data)
+	if err != nil || len(itemJSON) == 0 {
+		return "", ldstoretypes.SerializedItemDescriptor{}, false
+	}
+
+	return key, ldstoretypes.SerializedItemDescriptor{Version: version, SerializedItem: itemJSON}, true
+}
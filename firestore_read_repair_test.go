@@ -0,0 +1,109 @@
+package ldfirestore
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeLenient(t *testing.T) {
+	store := &firestoreDataStore{prefix: "p", loggers: ldlogtest.NewMockLog().Loggers}
+	docID := store.makeDocID(ldstoreimpl.Features(), "flag1")
+	expected := ldstoretypes.SerializedItemDescriptor{Version: 3, SerializedItem: []byte(`{"key":"flag1"}`)}
+
+	t.Run("version stored as float", func(t *testing.T) {
+		key, item, ok := store.decodeLenient(ldstoreimpl.Features(), docID, map[string]any{
+			fieldKey: "flag1", fieldVersion: float64(3), fieldItem: `{"key":"flag1"}`,
+		})
+		assert.True(t, ok)
+		assert.Equal(t, "flag1", key)
+		assert.Equal(t, expected, item)
+	})
+
+	t.Run("version stored as string", func(t *testing.T) {
+		_, item, ok := store.decodeLenient(ldstoreimpl.Features(), docID, map[string]any{
+			fieldKey: "flag1", fieldVersion: "3", fieldItem: `{"key":"flag1"}`,
+		})
+		assert.True(t, ok)
+		assert.Equal(t, expected, item)
+	})
+
+	t.Run("item stored as bytes", func(t *testing.T) {
+		_, item, ok := store.decodeLenient(ldstoreimpl.Features(), docID, map[string]any{
+			fieldKey: "flag1", fieldVersion: int64(3), fieldItem: []byte(`{"key":"flag1"}`),
+		})
+		assert.True(t, ok)
+		assert.Equal(t, expected, item)
+	})
+
+	t.Run("key recovered from document ID", func(t *testing.T) {
+		key, _, ok := store.decodeLenient(ldstoreimpl.Features(), docID, map[string]any{
+			fieldVersion: int64(3), fieldItem: `{"key":"flag1"}`,
+		})
+		assert.True(t, ok)
+		assert.Equal(t, "flag1", key)
+	})
+
+	t.Run("unrecoverable", func(t *testing.T) {
+		_, _, ok := store.decodeLenient(ldstoreimpl.Features(), docID, map[string]any{
+			fieldKey: "flag1", fieldVersion: "abc", fieldItem: `{"key":"flag1"}`,
+		})
+		assert.False(t, ok)
+
+		_, _, ok = store.decodeLenient(ldstoreimpl.Features(), docID, map[string]any{
+			fieldKey: "flag1", fieldVersion: int64(3),
+		})
+		assert.False(t, ok)
+
+		_, _, ok = store.decodeLenient(ldstoreimpl.Segments(), docID, map[string]any{
+			fieldVersion: int64(3), fieldItem: `{"key":"flag1"}`,
+		})
+		assert.False(t, ok)
+	})
+}
+
+func TestReadRepairOfVersion(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	built, err := baseDataStoreBuilder().ReadRepair(true).Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = built.Close() }()
+	store := built.(*firestoreDataStore)
+	require.NoError(t, store.Init(nil))
+
+	for _, version := range []any{"3", 3.0} {
+		item := ldstoretypes.SerializedItemDescriptor{Version: 3, SerializedItem: []byte(`{"key":"flag1"}`)}
+		_, err := store.Upsert(ldstoreimpl.Features(), "flag1", item)
+		require.NoError(t, err)
+		_, err = store.docRef(ldstoreimpl.Features(), "flag1").Update(context.Background(),
+			[]firestore.Update{{Path: fieldVersion, Value: version}})
+		require.NoError(t, err)
+
+		result, err := store.Get(ldstoreimpl.Features(), "flag1")
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Version, "version stored as %T", version)
+		require.NoError(t, store.Init(nil))
+	}
+	assert.Equal(t, ReadRepairStats{Attempted: 2, Succeeded: 2}, store.Stats().ReadRepairs)
+
+	item := ldstoretypes.SerializedItemDescriptor{Version: 3, SerializedItem: []byte(`{"key":"flag1"}`)}
+	_, err = store.Upsert(ldstoreimpl.Features(), "flag1", item)
+	require.NoError(t, err)
+	_, err = store.docRef(ldstoreimpl.Features(), "flag1").Update(context.Background(),
+		[]firestore.Update{{Path: fieldVersion, Value: "three"}})
+	require.NoError(t, err)
+	result, err := store.Get(ldstoreimpl.Features(), "flag1")
+	require.NoError(t, err)
+	assert.Equal(t, -1, result.Version)
+	assert.Equal(t, ReadRepairStats{Attempted: 3, Succeeded: 2, Failed: 1}, store.Stats().ReadRepairs)
+}
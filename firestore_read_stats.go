@@ -0,0 +1,32 @@
+package ldfirestore
+
+// ReadStats describes how the store's reads were served, so that operators can check how much the
+// store's caching reduces reads from Firestore. See [ExtendedDataStore.ReadStats].
+type ReadStats struct {
+	// FirestoreReads is the number of calls to Get, GetAll, GetMany, and IsInitialized that read from
+	// Firestore, rather than being answered from a cache. Retries are not counted separately.
+	FirestoreReads int
+
+	// InitializedCacheHits is the number of calls to IsInitialized that returned true without reading
+	// from Firestore, because the store had already found that it was initialized.
+	InitializedCacheHits int
+
+	// ItemCache describes the use of the cache that is enabled with [StoreBuilder.ItemCache]. It is
+	// all zero if the cache is not enabled.
+	ItemCache ItemCacheStats
+}
+
+// countRead records a read from Firestore in the ReadStats.
+func (store *firestoreDataStore) countRead() {
+	store.lock.Lock()
+	store.readStats.FirestoreReads++
+	store.lock.Unlock()
+}
+
+func (store *firestoreDataStore) ReadStats() ReadStats {
+	store.lock.Lock()
+	stats := store.readStats
+	store.lock.Unlock()
+	stats.ItemCache = store.cache.getStats()
+	return stats
+}
@@ -0,0 +1,42 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadStats(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close() // reads fail, but are still counted
+
+	ds, err := DataStore("my-project", "my-collection").FirestoreClient(client).
+		ItemCache(ItemCacheOptions{TTL: time.Hour}).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+	store := ds.(*firestoreDataStore)
+	assert.Equal(t, ReadStats{}, store.ReadStats())
+
+	_, _ = store.Get(ldstoreimpl.Features(), "flag1")
+	_, _ = store.GetAll(ldstoreimpl.Features())
+	_, _ = store.GetMany(ldstoreimpl.Features(), []string{"flag1", "flag2"})
+	store.IsInitialized()
+
+	store.cache.put("features", "flag1", ldstoretypes.SerializedItemDescriptor{Version: 1}, store.now())
+	_, _ = store.Get(ldstoreimpl.Features(), "flag1")
+	store.initedCached = true
+	store.IsInitialized()
+
+	assert.Equal(t, ReadStats{
+		FirestoreReads:       4,
+		InitializedCacheHits: 1,
+		ItemCache:            ItemCacheStats{Hits: 1, Misses: 2},
+	}, store.ReadStats())
+}
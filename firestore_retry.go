@@ -0,0 +1,161 @@
+package ldfirestore
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// RetryPolicy configures retrying of individual store operations that fail with a transient Firestore
+// error, such as Unavailable, DeadlineExceeded, or ResourceExhausted. See [StoreBuilder.Retry].
+type RetryPolicy struct {
+	// MaxAttempts is the total number of times to try an operation, including the first attempt. If it
+	// is one or less, operations are not retried.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry. Each later retry waits twice as long as the
+	// previous one, up to MaxBackoff. If it is zero or less, DefaultRetryInitialBackoff is used.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the longest delay between retries. If it is zero or less, DefaultRetryMaxBackoff
+	// is used.
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of each delay that is randomized, between 0 and 1. For instance, with a
+	// jitter of 0.5, a delay of one second becomes a random delay between half a second and one
+	// second. Randomizing the delays keeps many instances from retrying at the same moment.
+	Jitter float64
+}
+
+const (
+	// DefaultRetryMaxAttempts is the value of [RetryPolicy.MaxAttempts] that is used if
+	// [StoreBuilder.Retry] is not called.
+	DefaultRetryMaxAttempts = 3
+
+	// DefaultRetryJitter is the value of [RetryPolicy.Jitter] that is used if [StoreBuilder.Retry] is
+	// not called.
+	DefaultRetryJitter = 0.5
+
+	// DefaultRetryInitialBackoff is the default value for [RetryPolicy.InitialBackoff].
+	DefaultRetryInitialBackoff = 100 * time.Millisecond
+
+	// DefaultRetryMaxBackoff is the default value for [RetryPolicy.MaxBackoff].
+	DefaultRetryMaxBackoff = 5 * time.Second
+)
+
+// defaultRetryPolicy returns the retry policy of a builder on which Retry has not been called.
+func defaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: DefaultRetryMaxAttempts, Jitter: DefaultRetryJitter}
+}
+
+// do calls fn until it succeeds, fails with an error that is not transient, or has been tried
+// MaxAttempts times. If Firestore suggests a retry delay for a quota error, that delay is used instead
+// of the backoff. The onRetry function, if not nil, is called with each error that will be retried.
+func (p RetryPolicy) do(ctx context.Context, onRetry func(err error), fn func() error) error {
+	backoff := p.InitialBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryInitialBackoff
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultRetryMaxBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !isTransientError(err) {
+			return err
+		}
+		if onRetry != nil {
+			onRetry(err)
+		}
+
+		delay, ok := serverRetryDelay(err)
+		if !ok {
+			delay = backoff
+			if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+				delay -= time.Duration(rand.Float64() * jitter * float64(delay))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// onRetry is called by the data store for each transient error that it is about to retry.
+func (store *firestoreDataStore) onRetry(err error) {
+	store.recordThrottle(err)
+	if store.loggers.IsDebugEnabled() {
+		store.loggers.Debugf("Retrying Firestore operation after transient error: %s", err)
+	}
+}
+
+// getDocument reads a document, retrying transient errors according to the retry policy.
+func (store *firestoreDataStore) getDocument(docRef *firestore.DocumentRef) (*firestore.DocumentSnapshot, error) {
+	var doc *firestore.DocumentSnapshot
+	err := store.retry.do(store.context, store.onRetry, func() (err error) {
+		ctx, cancel := withOperationTimeout(store.context, store.operationTimeout)
+		defer cancel()
+		doc, err = docRef.Get(ctx)
+		return err
+	})
+	return doc, err
+}
+
+// retryFailedWrites retries the operations that failed with a transient error during a batch write,
+// according to the retry policy. It returns the failures that remain, with their original indexes.
+func (store *firestoreDataStore) retryFailedWrites(
+	ctx context.Context,
+	failures []operationFailure,
+) []operationFailure {
+	if store.retry.MaxAttempts <= 1 {
+		return failures
+	}
+	var final []operationFailure
+	pending := failures
+	firstAttempt := true
+	_ = store.retry.do(ctx, store.onRetry, func() error {
+		var retryable []operationFailure
+		for _, f := range pending {
+			if isTransientError(f.err) {
+				retryable = append(retryable, f)
+			} else {
+				final = append(final, f)
+			}
+		}
+		pending = retryable
+		if len(pending) == 0 {
+			return nil
+		}
+		if firstAttempt {
+			// The writes were already attempted once by the caller, which counts as the first attempt
+			firstAttempt = false
+			return pending[0].err
+		}
+
+		ops := make([]firestoreOperation, 0, len(pending))
+		for _, f := range pending {
+			ops = append(ops, f.op)
+		}
+		retried, err := batchWriteOperations(ctx, store.firestoreClient(), ops)
+		if err != nil {
+			return err
+		}
+		remaining := make([]operationFailure, 0, len(retried))
+		for _, r := range retried {
+			remaining = append(remaining, operationFailure{index: pending[r.index].index, op: r.op, err: r.err})
+		}
+		pending = remaining
+		if len(pending) > 0 {
+			return pending[0].err
+		}
+		return nil
+	})
+	return append(final, pending...)
+}
@@ -0,0 +1,129 @@
+package ldfirestore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	fastPolicy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+	t.Run("retries transient errors up to MaxAttempts", func(t *testing.T) {
+		calls, retries := 0, 0
+		err := fastPolicy.do(context.Background(), func(error) { retries++ }, func() error {
+			calls++
+			return status.Error(codes.Unavailable, "down")
+		})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, 2, retries)
+	})
+
+	t.Run("stops after success", func(t *testing.T) {
+		calls := 0
+		err := fastPolicy.do(context.Background(), nil, func() error {
+			calls++
+			if calls < 2 {
+				return status.Error(codes.DeadlineExceeded, "slow")
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("retries an attempt that ran out of time", func(t *testing.T) {
+		calls := 0
+		err := fastPolicy.do(context.Background(), nil, func() error {
+			calls++
+			if calls < 2 {
+				return context.DeadlineExceeded
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("does not retry permanent errors", func(t *testing.T) {
+		for _, code := range []codes.Code{codes.PermissionDenied, codes.InvalidArgument} {
+			calls := 0
+			_ = fastPolicy.do(context.Background(), nil, func() error {
+				calls++
+				return status.Error(code, "no")
+			})
+			assert.Equal(t, 1, calls, code.String())
+		}
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		calls := 0
+		fakeErr := errors.New("sorry")
+		err := fastPolicy.do(context.Background(), nil, func() error {
+			calls++
+			return fakeErr
+		})
+		assert.Equal(t, fakeErr, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("zero value does not retry", func(t *testing.T) {
+		calls := 0
+		_ = RetryPolicy{}.do(context.Background(), nil, func() error {
+			calls++
+			return status.Error(codes.Unavailable, "down")
+		})
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("uses server retry delay", func(t *testing.T) {
+		policy := RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Hour}
+		calls := 0
+		start := time.Now()
+		err := policy.do(context.Background(), nil, func() error {
+			calls++
+			if calls == 1 {
+				return makeQuotaError(t, 10*time.Millisecond)
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Less(t, time.Since(start), time.Minute)
+	})
+
+	t.Run("stops when context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		policy := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour}
+		calls := 0
+		err := policy.do(ctx, nil, func() error {
+			calls++
+			return status.Error(codes.Unavailable, "down")
+		})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestRetryFailedWritesWithoutPolicy(t *testing.T) {
+	store := &firestoreDataStore{loggers: ldlogtest.NewMockLog().Loggers, context: context.Background()}
+	failures := []operationFailure{{index: 2, err: status.Error(codes.Unavailable, "down")}}
+	assert.Equal(t, failures, store.retryFailedWrites(store.context, failures))
+}
+
+func TestRetryFailedWritesKeepsPermanentFailures(t *testing.T) {
+	store := &firestoreDataStore{
+		loggers: ldlogtest.NewMockLog().Loggers,
+		context: context.Background(),
+		retry:   RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+	}
+	failures := []operationFailure{{index: 1, err: status.Error(codes.InvalidArgument, "bad")}}
+	assert.Equal(t, failures, store.retryFailedWrites(store.context, failures))
+}
@@ -0,0 +1,134 @@
+package ldfirestore
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// rpcLogger logs a random sample of the gRPC calls made by a Firestore client.
+type rpcLogger struct {
+	sampleRate float64
+	loggers    ldlog.Loggers
+}
+
+// withRPCLogging returns a copy of the builder options whose client options include interceptors for
+// RPC logging, if that is enabled. It has no effect on a client that was provided with FirestoreClient.
+func withRPCLogging(builder builderOptions, loggers ldlog.Loggers, prefix string) builderOptions {
+	if builder.rpcLogSampleRate <= 0 {
+		return builder
+	}
+	logger := &rpcLogger{sampleRate: builder.rpcLogSampleRate, loggers: loggers}
+	logger.loggers.SetPrefix(prefix)
+	builder.clientOptions = slices.Concat(builder.clientOptions, []option.ClientOption{
+		option.WithGRPCDialOption(grpc.WithChainUnaryInterceptor(logger.interceptUnary)),
+		option.WithGRPCDialOption(grpc.WithChainStreamInterceptor(logger.interceptStream)),
+	})
+	return builder
+}
+
+func (l *rpcLogger) sampled() bool {
+	return l.sampleRate >= 1 || rand.Float64() < l.sampleRate
+}
+
+func (l *rpcLogger) log(method string, req any, start time.Time, err error) {
+	l.loggers.Infof("RPC %s path=%q latency=%s status=%s", method, requestPath(req), time.Since(start),
+		status.Code(err))
+}
+
+func (l *rpcLogger) interceptUnary(
+	ctx context.Context,
+	method string,
+	req, reply any,
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	if !l.sampled() {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	l.log(method, req, start, err)
+	return err
+}
+
+func (l *rpcLogger) interceptStream(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	cc *grpc.ClientConn,
+	method string,
+	streamer grpc.Streamer,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	if !l.sampled() {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	start := time.Now()
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		l.log(method, nil, start, err)
+		return nil, err
+	}
+	return &loggedStream{ClientStream: stream, logger: l, method: method, start: start}, nil
+}
+
+// loggedStream logs a streaming call once the response stream has ended.
+type loggedStream struct {
+	grpc.ClientStream
+	logger *rpcLogger
+	method string
+	start  time.Time
+	req    any
+	done   bool
+}
+
+func (s *loggedStream) SendMsg(m any) error {
+	if s.req == nil {
+		s.req = m
+	}
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *loggedStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil && !s.done {
+		s.done = true
+		if err == io.EOF {
+			s.logger.log(s.method, s.req, s.start, nil)
+		} else {
+			s.logger.log(s.method, s.req, s.start, err)
+		}
+	}
+	return err
+}
+
+// requestPath returns the document or collection path that a Firestore request refers to, if any.
+func requestPath(req any) string {
+	switch r := req.(type) {
+	case interface{ GetName() string }:
+		return r.GetName()
+	case interface{ GetParent() string }:
+		return r.GetParent()
+	case interface{ GetDocuments() []string }:
+		if docs := r.GetDocuments(); len(docs) > 0 {
+			return docs[0]
+		}
+	case *firestorepb.CommitRequest:
+		if writes := r.GetWrites(); len(writes) > 0 {
+			return writes[0].GetUpdate().GetName() + writes[0].GetDelete()
+		}
+		return r.GetDatabase()
+	case interface{ GetDatabase() string }:
+		return r.GetDatabase()
+	}
+	return ""
+}
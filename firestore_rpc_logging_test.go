@@ -0,0 +1,66 @@
+package ldfirestore
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRPCLogging(t *testing.T) {
+	docPath := "projects/p/databases/(default)/documents/c/features:flag1"
+
+	t.Run("request path", func(t *testing.T) {
+		assert.Equal(t, docPath, requestPath(&firestorepb.GetDocumentRequest{Name: docPath}))
+		assert.Equal(t, "projects/p/databases/(default)/documents",
+			requestPath(&firestorepb.RunQueryRequest{Parent: "projects/p/databases/(default)/documents"}))
+		assert.Equal(t, docPath, requestPath(&firestorepb.BatchGetDocumentsRequest{
+			Database: "projects/p/databases/(default)", Documents: []string{docPath},
+		}))
+		assert.Equal(t, docPath, requestPath(&firestorepb.CommitRequest{
+			Writes: []*firestorepb.Write{{Operation: &firestorepb.Write_Delete{Delete: docPath}}},
+		}))
+		assert.Equal(t, "", requestPath(nil))
+	})
+
+	t.Run("builder options", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection")
+		assert.Len(t, withRPCLogging(b.builderOptions, ldlog.NewDisabledLoggers(), "x:").clientOptions, 0)
+
+		b.RPCLogging(0.5)
+		assert.Equal(t, 0.5, b.rpcLogSampleRate)
+		assert.Len(t, withRPCLogging(b.builderOptions, ldlog.NewDisabledLoggers(), "x:").clientOptions, 2)
+		assert.Len(t, b.clientOptions, 0)
+	})
+
+	t.Run("unary call is logged", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		logger := &rpcLogger{sampleRate: 1, loggers: mockLog.Loggers}
+		invoker := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+			return status.Error(codes.NotFound, "no")
+		}
+		err := logger.interceptUnary(context.Background(), "/google.firestore.v1.Firestore/GetDocument",
+			&firestorepb.GetDocumentRequest{Name: docPath}, nil, nil, invoker)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		mockLog.AssertMessageMatch(t, true, ldlog.Info, regexp.QuoteMeta(`RPC /google.firestore.v1.Firestore/GetDocument path="`+
+			docPath+`"`)+` latency=.* status=NotFound`)
+	})
+
+	t.Run("unsampled call is not logged", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		logger := &rpcLogger{sampleRate: 0, loggers: mockLog.Loggers}
+		invoker := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+			return errors.New("x")
+		}
+		_ = logger.interceptUnary(context.Background(), "m", nil, nil, nil, invoker)
+		assert.Len(t, mockLog.GetAllOutput(), 0)
+	})
+}
@@ -0,0 +1,125 @@
+package ldfirestore
+
+import (
+	"context"
+	"sync"
+)
+
+// SchedulingPolicy determines which waiting operation is started first when the data store is at its
+// limit for concurrent operations. See [StoreBuilder.MaxConcurrentOperations].
+type SchedulingPolicy int
+
+const (
+	// SchedulingFIFO starts waiting operations in the order they were requested. This is the default.
+	SchedulingFIFO SchedulingPolicy = iota
+
+	// SchedulingReadsFirst starts any waiting read before any waiting write, so that flag evaluations
+	// are not delayed behind a large Init or a burst of updates. Writes can be delayed indefinitely if
+	// there is a steady stream of reads.
+	SchedulingReadsFirst
+)
+
+// operationScheduler limits the number of concurrent Firestore operations. A nil scheduler has no
+// limit.
+type operationScheduler struct {
+	capacity int
+	policy   SchedulingPolicy
+	active   int
+	waiting  []*scheduledOperation
+	lock     sync.Mutex
+}
+
+type scheduledOperation struct {
+	isRead bool
+	ready  chan struct{}
+}
+
+func newOperationScheduler(capacity int, policy SchedulingPolicy) *operationScheduler {
+	if capacity <= 0 {
+		return nil
+	}
+	return &operationScheduler{capacity: capacity, policy: policy}
+}
+
+// acquire waits until the operation can start. If it returns nil, the caller must call release when
+// the operation is done.
+func (s *operationScheduler) acquire(ctx context.Context, isRead bool) error {
+	if s == nil {
+		return nil
+	}
+
+	s.lock.Lock()
+	if s.active < s.capacity && len(s.waiting) == 0 {
+		s.active++
+		s.lock.Unlock()
+		return nil
+	}
+	op := &scheduledOperation{isRead: isRead, ready: make(chan struct{})}
+	s.waiting = append(s.waiting, op)
+	s.lock.Unlock()
+
+	select {
+	case <-op.ready:
+		return nil
+	case <-ctx.Done():
+		s.lock.Lock()
+		for i, w := range s.waiting {
+			if w == op {
+				s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+				s.lock.Unlock()
+				return ctx.Err()
+			}
+		}
+		s.lock.Unlock()
+		// We were started just as the context was canceled, so give the slot back
+		s.release()
+		return ctx.Err()
+	}
+}
+
+// release ends an operation and starts the next waiting one, if any.
+func (s *operationScheduler) release() {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.waiting) == 0 {
+		s.active--
+		return
+	}
+	next := 0
+	if s.policy == SchedulingReadsFirst {
+		for i, w := range s.waiting {
+			if w.isRead {
+				next = i
+				break
+			}
+		}
+	}
+	op := s.waiting[next]
+	s.waiting = append(s.waiting[:next], s.waiting[next+1:]...)
+	close(op.ready) // the slot passes directly to this operation, so active is unchanged
+}
+
+// beginOperation waits for the scheduler to allow an operation, and returns a function that must be
+// called when the operation is done. It returns ErrStoreClosed if the store is being closed.
+func (store *firestoreDataStore) beginOperation(isRead bool) (func(), error) {
+	store.lock.Lock()
+	if store.closed {
+		store.lock.Unlock()
+		return nil, ErrStoreClosed
+	}
+	store.inFlight.Add(1)
+	store.lock.Unlock()
+
+	if err := store.scheduler.acquire(store.context, isRead); err != nil {
+		store.inFlight.Done()
+		return nil, err
+	}
+	return func() {
+		store.scheduler.release()
+		store.inFlight.Done()
+	}, nil
+}
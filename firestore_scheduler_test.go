@@ -0,0 +1,73 @@
+package ldfirestore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationScheduler(t *testing.T) {
+	t.Run("no limit", func(t *testing.T) {
+		s := newOperationScheduler(0, SchedulingFIFO)
+		assert.Nil(t, s)
+		require.NoError(t, s.acquire(context.Background(), true))
+		s.release()
+	})
+
+	// startWaiter queues an operation and reports its name on the channel once it has started
+	startWaiter := func(s *operationScheduler, isRead bool, name string, started chan<- string) {
+		go func() {
+			if s.acquire(context.Background(), isRead) == nil {
+				started <- name
+			}
+		}()
+		// give the goroutine time to join the queue so that the order is deterministic
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	runOrderTest := func(t *testing.T, policy SchedulingPolicy, expected []string) {
+		s := newOperationScheduler(1, policy)
+		require.NoError(t, s.acquire(context.Background(), false))
+
+		started := make(chan string, 3)
+		startWaiter(s, false, "write1", started)
+		startWaiter(s, true, "read1", started)
+		startWaiter(s, false, "write2", started)
+
+		var order []string
+		for range expected {
+			s.release()
+			select {
+			case name := <-started:
+				order = append(order, name)
+			case <-time.After(time.Second):
+				require.Fail(t, "timed out waiting for operation to start")
+			}
+		}
+		assert.Equal(t, expected, order)
+	}
+
+	t.Run("FIFO", func(t *testing.T) {
+		runOrderTest(t, SchedulingFIFO, []string{"write1", "read1", "write2"})
+	})
+
+	t.Run("reads first", func(t *testing.T) {
+		runOrderTest(t, SchedulingReadsFirst, []string{"read1", "write1", "write2"})
+	})
+
+	t.Run("canceled wait", func(t *testing.T) {
+		s := newOperationScheduler(1, SchedulingFIFO)
+		require.NoError(t, s.acquire(context.Background(), false))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.Error(t, s.acquire(ctx, true))
+		assert.Empty(t, s.waiting)
+
+		s.release()
+		assert.Equal(t, 0, s.active)
+	})
+}
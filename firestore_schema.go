@@ -0,0 +1,62 @@
+package ldfirestore
+
+import (
+	"errors"
+)
+
+const (
+	// fieldSchemaVersion records the version of the document format that a document was written with.
+	fieldSchemaVersion = "schemaVersion"
+
+	// currentSchemaVersion is the newest document format that this version of the package understands.
+	// Documents without a schemaVersion field are treated as version 1.
+	currentSchemaVersion = 1
+)
+
+// ErrReadOnly is returned by operations that would modify the data store when the store is not
+// allowed to write. See [StoreBuilder.ReadOnly] and [StoreBuilder.ReadOnlyOnNewerSchema].
+var ErrReadOnly = errors.New("the Firestore data store is read-only")
+
+// observeSchemaVersion checks the schema version of a document that has been read. If the document
+// was written by a newer version of this package, it logs a warning the first time this happens and,
+// if so configured, switches the store to read-only. It returns true if the document is newer.
+func (store *firestoreDataStore) observeSchemaVersion(data map[string]any) bool {
+	version, _ := data[fieldSchemaVersion].(int64)
+	if version <= currentSchemaVersion {
+		return false
+	}
+
+	store.lock.Lock()
+	alreadyReported := store.newerSchemaReported
+	store.newerSchemaReported = true
+	if store.readOnlyOnNewerSchema {
+		store.readOnly = true
+	}
+	store.lock.Unlock()
+
+	if !alreadyReported {
+		if store.readOnlyOnNewerSchema {
+			store.loggers.Errorf("Collection %q contains data written with schema version %d, but this version "+
+				"of the Firestore integration only understands up to version %d. The data store is now read-only, "+
+				"to avoid overwriting newer data. Upgrade this application to restore writes.",
+				store.collection, version, currentSchemaVersion)
+		} else {
+			store.loggers.Warnf("Collection %q contains data written with schema version %d, but this version "+
+				"of the Firestore integration only understands up to version %d. Writes from this application may "+
+				"downgrade or corrupt data written by newer versions; upgrade this application as soon as possible.",
+				store.collection, version, currentSchemaVersion)
+		}
+	}
+	return true
+}
+
+// checkWritable returns ErrReadOnly if the store is configured as read-only or has been switched to
+// read-only.
+func (store *firestoreDataStore) checkWritable() error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.readOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package ldfirestore
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"google.golang.org/api/iterator"
+)
+
+// schemaMigration upgrades the fields of a document from one schema version to the next, in place.
+type schemaMigration func(data map[string]any) error
+
+// schemaMigrations holds the migration from each schema version to the next, keyed by the version that
+// it upgrades from. Every version below currentSchemaVersion must have an entry. There are none yet,
+// since all documents so far use version 1: the optional encodings of the item field are recorded in
+// the encoding field of each document, and do not need a new schema version.
+var schemaMigrations = map[int]schemaMigration{}
+
+// documentSchemaVersion returns the schema version of a document. Documents written before the
+// schemaVersion field was added are version 1.
+func documentSchemaVersion(data map[string]any) int {
+	if version, _ := data[fieldSchemaVersion].(int64); version > 1 {
+		return int(version)
+	}
+	return 1
+}
+
+// upgradeDocument applies the migrations needed to bring a document that has been read up to the
+// target schema version. It returns true if the document was changed. Documents that are newer than
+// the target are left as they are; see observeSchemaVersion.
+func upgradeDocument(data map[string]any, migrations map[int]schemaMigration, target int) (bool, error) {
+	version := documentSchemaVersion(data)
+	if version >= target {
+		return false, nil
+	}
+	for ; version < target; version++ {
+		migrate, ok := migrations[version]
+		if !ok {
+			return false, fmt.Errorf("no migration from schema version %d", version)
+		}
+		if err := migrate(data); err != nil {
+			return false, fmt.Errorf("failed to migrate document from schema version %d: %w", version, err)
+		}
+	}
+	data[fieldSchemaVersion] = target
+	return true, nil
+}
+
+// MigrateSchema rewrites every document of the given data kind that was written with an older schema
+// version in the current format, and returns the number of documents that were rewritten. It is
+// safe to call this more than once, and while other instances are writing: a document that changes
+// after it has been read is left as it is.
+func (store *firestoreDataStore) MigrateSchema(kind ldstoretypes.DataKind) (int, error) {
+	if err := store.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	done, err := store.beginOperation(false)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	query := store.collectionForKind(kind).Where(fieldNamespace, "==", store.namespaceForKind(kind))
+	iter := query.Documents(store.context)
+	defer iter.Stop()
+
+	var updates []firestoreOperation
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s documents: %w", kind, err)
+		}
+		data := doc.Data()
+		upgraded, err := upgradeDocument(data, schemaMigrations, currentSchemaVersion)
+		if err != nil {
+			store.loggers.Warnf("Not migrating document %q: %s", doc.Ref.ID, err)
+			continue
+		}
+		if upgraded {
+			updates = append(updates, updateOperation{
+				ref:      doc.Ref,
+				updates:  replacementUpdates(doc.Data(), data),
+				preconds: []firestore.Precondition{firestore.LastUpdateTime(doc.UpdateTime)},
+			})
+		}
+	}
+
+	failures, err := batchWriteOperations(store.context, store.firestoreClient(), updates)
+	if err != nil {
+		return 0, fmt.Errorf("failed to migrate %s documents: %w", kind, err)
+	}
+	migrated := len(updates) - len(failures)
+	if len(failures) > 0 {
+		return migrated, fmt.Errorf("failed to migrate %d %s document(s): %w", len(failures), kind, failures[0].err)
+	}
+	return migrated, nil
+}
+
+// replacementUpdates returns the updates that change a document's fields from oldData to newData.
+func replacementUpdates(oldData, newData map[string]any) []firestore.Update {
+	updates := make([]firestore.Update, 0, len(newData))
+	for name, value := range newData {
+		updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{name}, Value: value})
+	}
+	for name := range oldData {
+		if _, ok := newData[name]; !ok {
+			updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{name}, Value: firestore.Delete})
+		}
+	}
+	return updates
+}
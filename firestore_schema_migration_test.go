@@ -0,0 +1,100 @@
+package ldfirestore
+
+import (
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpgradeDocument(t *testing.T) {
+	migrations := map[int]schemaMigration{
+		1: func(data map[string]any) error {
+			data["renamed"] = data["old"]
+			delete(data, "old")
+			return nil
+		},
+		2: func(data map[string]any) error {
+			data["added"] = true
+			return nil
+		},
+	}
+
+	t.Run("document without version is upgraded from version 1", func(t *testing.T) {
+		data := map[string]any{"old": "x"}
+		upgraded, err := upgradeDocument(data, migrations, 3)
+		require.NoError(t, err)
+		assert.True(t, upgraded)
+		assert.Equal(t, map[string]any{"renamed": "x", "added": true, fieldSchemaVersion: 3}, data)
+	})
+
+	t.Run("only later migrations are applied", func(t *testing.T) {
+		data := map[string]any{"old": "x", fieldSchemaVersion: int64(2)}
+		upgraded, err := upgradeDocument(data, migrations, 3)
+		require.NoError(t, err)
+		assert.True(t, upgraded)
+		assert.Equal(t, "x", data["old"])
+		assert.Equal(t, true, data["added"])
+	})
+
+	t.Run("current and newer documents are unchanged", func(t *testing.T) {
+		for _, version := range []int64{3, 4} {
+			data := map[string]any{fieldSchemaVersion: version}
+			upgraded, err := upgradeDocument(data, migrations, 3)
+			require.NoError(t, err)
+			assert.False(t, upgraded)
+			assert.Equal(t, map[string]any{fieldSchemaVersion: version}, data)
+		}
+	})
+
+	t.Run("error for missing or failed migration", func(t *testing.T) {
+		_, err := upgradeDocument(map[string]any{}, migrations, 4)
+		assert.Error(t, err)
+
+		failing := map[int]schemaMigration{1: func(map[string]any) error { return errors.New("bad") }}
+		_, err = upgradeDocument(map[string]any{}, failing, 2)
+		assert.ErrorContains(t, err, "bad")
+	})
+
+	t.Run("every older schema version has a migration", func(t *testing.T) {
+		for version := 1; version < currentSchemaVersion; version++ {
+			assert.Contains(t, schemaMigrations, version)
+		}
+	})
+}
+
+func TestReplacementUpdates(t *testing.T) {
+	updates := replacementUpdates(map[string]any{"a": 1, "b": 2}, map[string]any{"a": 3, "cost-center": "x"})
+	assert.ElementsMatch(t, []firestore.Update{
+		{FieldPath: firestore.FieldPath{"a"}, Value: 3},
+		{FieldPath: firestore.FieldPath{"cost-center"}, Value: "x"},
+		{FieldPath: firestore.FieldPath{"b"}, Value: firestore.Delete},
+	}, updates)
+}
+
+func TestMigrateSchema(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := baseDataStoreBuilder().Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1","version":1}`)}
+	_, err = store.Upsert(ldstoreimpl.Features(), "flag1", item)
+	require.NoError(t, err)
+
+	migrated, err := store.(ExtendedDataStore).MigrateSchema(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, 0, migrated)
+
+	result, err := store.Get(ldstoreimpl.Features(), "flag1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Version)
+}
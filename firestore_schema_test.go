@@ -0,0 +1,75 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveSchemaVersion(t *testing.T) {
+	newerData := map[string]any{fieldSchemaVersion: int64(currentSchemaVersion + 1)}
+
+	t.Run("current or missing schema version", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := &firestoreDataStore{loggers: mockLog.Loggers}
+		assert.False(t, store.observeSchemaVersion(map[string]any{fieldSchemaVersion: int64(currentSchemaVersion)}))
+		assert.False(t, store.observeSchemaVersion(map[string]any{}))
+		assert.Len(t, mockLog.GetAllOutput(), 0)
+	})
+
+	t.Run("newer schema version logs warning once", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := &firestoreDataStore{loggers: mockLog.Loggers}
+		assert.True(t, store.observeSchemaVersion(newerData))
+		assert.True(t, store.observeSchemaVersion(newerData))
+		assert.Len(t, mockLog.GetOutput(ldlog.Warn), 1)
+		assert.NoError(t, store.checkWritable())
+	})
+
+	t.Run("newer schema version switches to read-only", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := &firestoreDataStore{loggers: mockLog.Loggers, readOnlyOnNewerSchema: true}
+		assert.NoError(t, store.checkWritable())
+		assert.True(t, store.observeSchemaVersion(newerData))
+		assert.Equal(t, ErrReadOnly, store.checkWritable())
+		mockLog.AssertMessageMatch(t, true, ldlog.Error, "The data store is now read-only")
+
+		_, err := store.Upsert(ldstoreimpl.Features(), "key", ldstoretypes.SerializedItemDescriptor{Version: 1})
+		assert.Equal(t, ErrReadOnly, err)
+	})
+}
+
+func TestReadOnly(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close() // no operation should reach Firestore
+
+	store, err := DataStore("my-project", "my-collection").FirestoreClient(client).
+		ReadOnly(true).
+		WriterElection("instance1", time.Minute).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	assert.Nil(t, store.(*firestoreDataStore).election)
+
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)}
+	assert.Equal(t, ErrReadOnly, store.Init(nil))
+	_, err = store.Upsert(ldstoreimpl.Features(), "flag1", item)
+	assert.Equal(t, ErrReadOnly, err)
+
+	ext := store.(ExtendedDataStore)
+	_, err = ext.Delete(ldstoreimpl.Features(), "flag1", ldvalue.OptionalInt{})
+	assert.Equal(t, ErrReadOnly, err)
+	_, err = ext.DeleteAll(ldstoreimpl.Features(), nil)
+	assert.Equal(t, ErrReadOnly, err)
+	_, err = ext.MigrateSchema(ldstoreimpl.Features())
+	assert.Equal(t, ErrReadOnly, err)
+}
@@ -0,0 +1,87 @@
+package ldfirestore
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/option"
+)
+
+// SharedClient is a Firestore client that can be used by several stores, such as the main data store
+// and the Big Segment store, so that they share one connection pool and one set of credentials. It
+// keeps count of the stores that use it, and the underlying client is closed when the last of them
+// is closed and [SharedClient.Close] has been called.
+//
+//	shared, err := ldfirestore.NewSharedClient("my-project")
+//	if err != nil { ... }
+//	defer shared.Close() // the client stays open until the stores are closed too
+//	config.DataStore = ldcomponents.PersistentDataStore(
+//		ldfirestore.DataStore("my-project", "launchdarkly").SharedClient(shared))
+//	config.BigSegments = ldcomponents.BigSegments(
+//		ldfirestore.BigSegmentStore("my-project", "launchdarkly-big-segments").SharedClient(shared))
+type SharedClient struct {
+	client *firestore.Client
+	refs   int
+	closed bool
+	lock   sync.Mutex
+}
+
+var errSharedClientClosed = errors.New("shared Firestore client has been closed")
+
+// NewSharedClient creates a [SharedClient] for the given project, with the same client options that
+// could otherwise be passed to [StoreBuilder.ClientOptions].
+func NewSharedClient(projectID string, options ...option.ClientOption) (*SharedClient, error) {
+	if projectID == "" {
+		return nil, errors.New("project ID is required")
+	}
+	client, err := firestore.NewClient(context.Background(), projectID, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &SharedClient{client: client, refs: 1}, nil
+}
+
+// Client returns the underlying Firestore client, for use in application code. It must not be
+// closed directly.
+func (c *SharedClient) Client() *firestore.Client {
+	return c.client
+}
+
+// Close releases the reference that was held by the caller of [NewSharedClient]. Calling it more than
+// once has no further effect. Stores that are already using the client can still use it, but new
+// stores cannot be built with it.
+func (c *SharedClient) Close() error {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.lock.Unlock()
+	return c.release()
+}
+
+// acquire adds a reference for a store that is being built.
+func (c *SharedClient) acquire() (*firestore.Client, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return nil, errSharedClientClosed
+	}
+	c.refs++
+	return c.client, nil
+}
+
+// release removes a reference, closing the client if it was the last one.
+func (c *SharedClient) release() error {
+	c.lock.Lock()
+	c.refs--
+	last := c.refs == 0
+	c.lock.Unlock()
+	if last {
+		return c.client.Close()
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package ldfirestore
+
+import (
+	"testing"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedClient(t *testing.T) {
+	t.Run("stores share the client", func(t *testing.T) {
+		shared, err := NewSharedClient(testProjectID, makeTestOptions()...)
+		require.NoError(t, err)
+
+		ds, err := DataStore("", testCollectionName).SharedClient(shared).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		bs, err := BigSegmentStore("", testCollectionName).SharedClient(shared).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+
+		dsImpl := ds.(*firestoreDataStore)
+		bsImpl := bs.(*firestoreBigSegmentStoreImpl)
+		assert.Same(t, shared.Client(), dsImpl.client)
+		assert.Same(t, shared.Client(), bsImpl.client)
+		assert.False(t, dsImpl.ownsClient)
+		assert.Equal(t, 3, shared.refs)
+
+		require.NoError(t, shared.Close())
+		require.NoError(t, shared.Close())
+		assert.Equal(t, 2, shared.refs)
+
+		require.NoError(t, ds.Close())
+		require.NoError(t, ds.Close())
+		assert.Equal(t, 1, shared.refs)
+		require.NoError(t, bs.Close())
+		assert.Equal(t, 0, shared.refs)
+	})
+
+	t.Run("cannot build a store after Close", func(t *testing.T) {
+		shared, err := NewSharedClient(testProjectID, makeTestOptions()...)
+		require.NoError(t, err)
+		require.NoError(t, shared.Close())
+
+		ds, err := DataStore("", testCollectionName).SharedClient(shared).Build(subsystems.BasicClientContext{})
+		assert.Equal(t, errSharedClientClosed, err)
+		assert.Nil(t, ds)
+	})
+
+	t.Run("project ID is required", func(t *testing.T) {
+		_, err := NewSharedClient("")
+		assert.Error(t, err)
+	})
+
+	t.Run("credentials cannot be combined with shared client", func(t *testing.T) {
+		shared, err := NewSharedClient(testProjectID, makeTestOptions()...)
+		require.NoError(t, err)
+		defer func() { _ = shared.Close() }()
+
+		ds, err := DataStore("", testCollectionName).SharedClient(shared).CredentialsFile("/path/to/key.json").
+			Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, ds)
+	})
+}
@@ -0,0 +1,127 @@
+package ldfirestore
+
+import (
+	"sync"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+// itemSizeBucketBounds are the upper bounds, in bytes, of the buckets in an [ItemSizeHistogram]. The
+// last bucket has no upper bound.
+var itemSizeBucketBounds = []int{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 512 << 10, 768 << 10}
+
+// ItemSizeBucket is one bucket of an [ItemSizeHistogram].
+type ItemSizeBucket struct {
+	// UpperBound is the largest document size, in bytes, that is counted in this bucket. It is -1 for
+	// the last bucket, which has no upper bound.
+	UpperBound int
+
+	// Count is the number of documents written whose size was in this bucket.
+	Count int
+}
+
+// ItemSizeHistogram describes the sizes of the item documents that the store has written. See
+// [ExtendedDataStore.ItemSizeHistogram].
+type ItemSizeHistogram struct {
+	// Buckets is the number of documents in each size range, in increasing order of size.
+	Buckets []ItemSizeBucket
+
+	// MaxSize is the size of the largest document written, in bytes.
+	MaxSize int
+}
+
+// ItemSizeWarning describes an item whose document is approaching the size limit for its data kind.
+// See [StoreBuilder.ItemSizeWarning].
+type ItemSizeWarning struct {
+	// Namespace is the namespace of the item, which is the name of its data kind plus any prefix.
+	Namespace string
+
+	// Key is the item's key.
+	Key string
+
+	// Size is the estimated size of the item's document, in bytes.
+	Size int
+
+	// Limit is the size limit for the item's data kind, in bytes. Items larger than this are handled
+	// according to the policy for the kind; by default they are dropped.
+	Limit int
+}
+
+// itemSizeStats accumulates the item size histogram. The zero value is ready to use.
+type itemSizeStats struct {
+	counts  []int
+	maxSize int
+	warned  map[string]bool
+	lock    sync.Mutex
+}
+
+// record adds a document size to the histogram. If the size is over the warning threshold, it returns
+// true the first time that happens for the item, so that a warning is not repeated every time an
+// unchanged item is written; if the item later gets smaller again, it can be warned about again.
+func (s *itemSizeStats) record(id string, size int, overThreshold bool) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.counts == nil {
+		s.counts = make([]int, len(itemSizeBucketBounds)+1)
+	}
+	bucket := len(itemSizeBucketBounds)
+	for i, bound := range itemSizeBucketBounds {
+		if size <= bound {
+			bucket = i
+			break
+		}
+	}
+	s.counts[bucket]++
+	s.maxSize = max(s.maxSize, size)
+
+	if !overThreshold {
+		delete(s.warned, id)
+		return false
+	}
+	if s.warned[id] {
+		return false
+	}
+	if s.warned == nil {
+		s.warned = make(map[string]bool)
+	}
+	s.warned[id] = true
+	return true
+}
+
+// recordItemSize updates the histogram with the size of an encoded item document, and warns if the
+// size is over the configured fraction of the limit for its data kind.
+func (store *firestoreDataStore) recordItemSize(kind ldstoretypes.DataKind, data map[string]any, size int) {
+	limit := store.sizeLimitForKind(kind).maxBytes
+	overThreshold := store.sizeWarningFraction > 0 && float64(size) >= store.sizeWarningFraction*float64(limit)
+	namespace, _ := data[fieldNamespace].(string)
+	key, _ := data[fieldKey].(string)
+	if !store.itemSizes.record(namespace+":"+key, size, overThreshold) {
+		return
+	}
+
+	store.loggers.Warnf("The item %q in namespace %q is %d bytes, which is %.0f%% of the size limit of %d bytes",
+		key, namespace, size, 100*float64(size)/float64(limit), limit)
+	if store.sizeWarningHook != nil {
+		store.sizeWarningHook(ItemSizeWarning{Namespace: namespace, Key: key, Size: size, Limit: limit})
+	}
+}
+
+func (store *firestoreDataStore) ItemSizeHistogram() ItemSizeHistogram {
+	s := &store.itemSizes
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	result := ItemSizeHistogram{MaxSize: s.maxSize}
+	for i := 0; i <= len(itemSizeBucketBounds); i++ {
+		bucket := ItemSizeBucket{UpperBound: -1}
+		if i < len(itemSizeBucketBounds) {
+			bucket.UpperBound = itemSizeBucketBounds[i]
+		}
+		if s.counts != nil {
+			bucket.Count = s.counts[i]
+		}
+		result.Buckets = append(result.Buckets, bucket)
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+package ldfirestore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemSizeHistogram(t *testing.T) {
+	item := func(size int) ldstoretypes.SerializedItemDescriptor {
+		return ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(strings.Repeat("x", size))}
+	}
+
+	t.Run("counts sizes", func(t *testing.T) {
+		store := &firestoreDataStore{loggers: ldlogtest.NewMockLog().Loggers}
+		assert.Equal(t, 0, store.ItemSizeHistogram().Buckets[0].Count)
+
+		for _, size := range []int{100, 200, 3000, 2000000} {
+			data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", item(size))
+			require.NoError(t, err)
+			_, _, err = store.applySizeLimit(ldstoreimpl.Features(), data)
+			require.NoError(t, err)
+		}
+
+		histogram := store.ItemSizeHistogram()
+		require.Len(t, histogram.Buckets, len(itemSizeBucketBounds)+1)
+		assert.Equal(t, ItemSizeBucket{UpperBound: 1024, Count: 2}, histogram.Buckets[0])
+		assert.Equal(t, ItemSizeBucket{UpperBound: 4096, Count: 1}, histogram.Buckets[1])
+		assert.Equal(t, ItemSizeBucket{UpperBound: -1, Count: 1}, histogram.Buckets[len(itemSizeBucketBounds)])
+		assert.Greater(t, histogram.MaxSize, 2000000)
+	})
+
+	t.Run("warns once near the limit", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		var warnings []ItemSizeWarning
+		store := &firestoreDataStore{
+			loggers:             mockLog.Loggers,
+			kindLimits:          map[string]kindSizeLimit{"features": {maxBytes: 1000}},
+			sizeWarningFraction: 0.8,
+			sizeWarningHook:     func(w ItemSizeWarning) { warnings = append(warnings, w) },
+		}
+		write := func(size int) {
+			data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", item(size))
+			require.NoError(t, err)
+			_, _, err = store.applySizeLimit(ldstoreimpl.Features(), data)
+			require.NoError(t, err)
+		}
+
+		write(500)
+		assert.Len(t, warnings, 0)
+
+		write(850)
+		write(850)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, "features", warnings[0].Namespace)
+		assert.Equal(t, "flag1", warnings[0].Key)
+		assert.Equal(t, 1000, warnings[0].Limit)
+		assert.Len(t, mockLog.GetOutput(ldlog.Warn), 1)
+
+		write(500)
+		write(850)
+		assert.Len(t, warnings, 2)
+	})
+
+	t.Run("builder", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").ItemSizeWarning(0.8, nil)
+		assert.Equal(t, 0.8, b.sizeWarningFraction)
+	})
+}
@@ -0,0 +1,155 @@
+package ldfirestore
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+// OversizedItemPolicy determines what the store does with an item whose document would exceed the
+// size limit for its data kind. See [StoreBuilder.OversizedItems] and [StoreBuilder.KindSizeLimit].
+type OversizedItemPolicy int
+
+const (
+	// OversizedItemDrop skips storing the item and logs an error. This is the default behavior.
+	OversizedItemDrop OversizedItemPolicy = iota
+
+	// OversizedItemError causes the operation that tried to store the item to fail with an error.
+	// For Init, this means that nothing is written.
+	OversizedItemError
+
+	// OversizedItemTruncateRules applies only to feature flags. The flag's targeting rules are
+	// removed so that the rest of the flag can still be stored, and the stored document is marked
+	// as degraded. A flag stored this way will not evaluate its rules, so it will fall through to
+	// the flag's default rule for every context. If the flag is still too large after truncation,
+	// or if the item is not a flag, it is dropped as with OversizedItemDrop.
+	OversizedItemTruncateRules
+
+	// OversizedItemOverflow saves the item in the [OverflowStore] that is set with
+	// [StoreBuilder.OverflowStore], and stores a document that refers to it in place of the item, so
+	// that reads of the item get it from the overflow store.
+	OversizedItemOverflow
+)
+
+const (
+	// fieldDegraded is set to true on documents whose item was modified to fit within the size limit.
+	fieldDegraded = "degraded"
+
+	featuresKindName = "features"
+)
+
+// kindSizeLimit is the size configuration for a single data kind.
+type kindSizeLimit struct {
+	maxBytes int
+	policy   OversizedItemPolicy
+}
+
+func (store *firestoreDataStore) sizeLimitForKind(kind ldstoretypes.DataKind) kindSizeLimit {
+	if limit, ok := store.kindLimits[kind.GetName()]; ok {
+		if limit.maxBytes <= 0 {
+			limit.maxBytes = firestoreMaxDocSize
+		}
+		return limit
+	}
+	return kindSizeLimit{maxBytes: firestoreMaxDocSize, policy: store.oversizedPolicy}
+}
+
+// applySizeLimit checks the encoded document against the size limit for its data kind. It returns
+// the data to be written (which may have been modified by the truncation policy), and false if the
+// item should not be written at all. An error is returned for OversizedItemError, or if the item
+// could not be saved in the OverflowStore.
+func (store *firestoreDataStore) applySizeLimit(
+	kind ldstoretypes.DataKind,
+	data map[string]any,
+) (map[string]any, bool, error) {
+	limit := store.sizeLimitForKind(kind)
+	size := estimateDocSize(data)
+	store.recordItemSize(kind, data, size)
+	if size <= limit.maxBytes {
+		return data, true, nil
+	}
+
+	switch limit.policy {
+	case OversizedItemError:
+		return nil, false, fmt.Errorf("the item %q in namespace %q was too large to store in Firestore",
+			data[fieldKey], data[fieldNamespace])
+
+	case OversizedItemTruncateRules:
+		if kind.GetName() == featuresKindName {
+			if truncated, ok := store.truncateFlagRules(data); ok && estimateDocSize(truncated) <= limit.maxBytes {
+				store.loggers.Warnf("The flag %q in namespace %q was too large to store in Firestore; "+
+					"its rules were removed and it was stored in a degraded state",
+					data[fieldKey], data[fieldNamespace])
+				return truncated, true, nil
+			}
+		}
+
+	case OversizedItemOverflow:
+		stub, err := store.overflowItem(data)
+		if err != nil {
+			return nil, false, err
+		}
+		return stub, true, nil
+	}
+
+	store.loggers.Errorf("The item %q in namespace %q was too large to store in Firestore and was dropped",
+		data[fieldKey], data[fieldNamespace])
+	return nil, false, nil
+}
+
+// truncateFlagRules returns a copy of an encoded flag document with the flag's rules removed and
+// the degraded field set. It returns false if the serialized flag could not be parsed.
+func (store *firestoreDataStore) truncateFlagRules(data map[string]any) (map[string]any, bool) {
+	itemJSON, err := store.itemPayload(data)
+	if err != nil {
+		return nil, false
+	}
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(itemJSON, &props); err != nil {
+		return nil, false
+	}
+	props["rules"] = json.RawMessage("[]")
+	newJSON, err := json.Marshal(props)
+	if err != nil {
+		return nil, false
+	}
+
+	truncated := make(map[string]any, len(data)+1)
+	for k, v := range data {
+		truncated[k] = v
+	}
+	if err := store.setItemPayload(truncated, newJSON); err != nil {
+		return nil, false
+	}
+	truncated[fieldDegraded] = true
+	return truncated, true
+}
+
+// estimateDocSize returns a rough estimate of the stored size of a document.
+func estimateDocSize(data map[string]any) int {
+	size := 0
+	for key, value := range data {
+		size += len(key) + estimateValueSize(value)
+	}
+	return size
+}
+
+func estimateValueSize(value any) int {
+	switch v := value.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	case map[string]any:
+		return estimateDocSize(v)
+	case []any:
+		size := 0
+		for _, elem := range v {
+			size += estimateValueSize(elem)
+		}
+		return size
+	default:
+		return 8 // rough estimate for numeric values
+	}
+}
@@ -0,0 +1,192 @@
+package ldfirestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKindSizeLimits(t *testing.T) {
+	makeStore := func(mockLog *ldlogtest.MockLog, limits map[string]kindSizeLimit) *firestoreDataStore {
+		return &firestoreDataStore{kindLimits: limits, loggers: mockLog.Loggers}
+	}
+
+	bigRules := `[{"id":"r1","clauses":[{"attribute":"key","op":"in","values":["` +
+		strings.Repeat("x", 2000) + `"]}]}]`
+	flagJSON := `{"key":"flag1","version":1,"on":true,"rules":` + bigRules + `}`
+	flagItem := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(flagJSON)}
+
+	t.Run("item within default limit is unchanged", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := makeStore(mockLog, nil)
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", flagItem)
+		require.NoError(t, err)
+		result, ok, err := store.applySizeLimit(ldstoreimpl.Features(), data)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, data, result)
+	})
+
+	t.Run("drop policy", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := makeStore(mockLog, map[string]kindSizeLimit{
+			"features": {maxBytes: 1000, policy: OversizedItemDrop},
+		})
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", flagItem)
+		require.NoError(t, err)
+		_, ok, err := store.applySizeLimit(ldstoreimpl.Features(), data)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		mockLog.AssertMessageMatch(t, true, ldlog.Error, "was too large to store in Firestore and was dropped")
+	})
+
+	t.Run("error policy", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := makeStore(mockLog, map[string]kindSizeLimit{
+			"segments": {maxBytes: 10, policy: OversizedItemError},
+		})
+		item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"s1"}`)}
+		data, err := store.encodeItem(ldstoreimpl.Segments(), "s1", item)
+		require.NoError(t, err)
+		_, ok, err := store.applySizeLimit(ldstoreimpl.Segments(), data)
+		assert.Error(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("limits are per kind", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := makeStore(mockLog, map[string]kindSizeLimit{
+			"segments": {maxBytes: 10, policy: OversizedItemError},
+		})
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", flagItem)
+		require.NoError(t, err)
+		_, ok, err := store.applySizeLimit(ldstoreimpl.Features(), data)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("truncate rules policy", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := makeStore(mockLog, map[string]kindSizeLimit{
+			"features": {maxBytes: 1000, policy: OversizedItemTruncateRules},
+		})
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", flagItem)
+		require.NoError(t, err)
+		result, ok, err := store.applySizeLimit(ldstoreimpl.Features(), data)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, true, result[fieldDegraded])
+
+		var props map[string]any
+		require.NoError(t, json.Unmarshal([]byte(result[fieldItem].(string)), &props))
+		assert.Equal(t, []any{}, props["rules"])
+		assert.Equal(t, "flag1", props["key"])
+		assert.Equal(t, true, props["on"])
+		mockLog.AssertMessageMatch(t, true, ldlog.Warn, "stored in a degraded state")
+	})
+
+	t.Run("truncate rules policy drops non-flag items", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := makeStore(mockLog, map[string]kindSizeLimit{
+			"segments": {maxBytes: 10, policy: OversizedItemTruncateRules},
+		})
+		item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"s1"}`)}
+		data, err := store.encodeItem(ldstoreimpl.Segments(), "s1", item)
+		require.NoError(t, err)
+		_, ok, err := store.applySizeLimit(ldstoreimpl.Segments(), data)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		mockLog.AssertMessageMatch(t, true, ldlog.Error, "was too large to store in Firestore and was dropped")
+	})
+
+	t.Run("default policy applies to kinds without their own", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := makeStore(mockLog, map[string]kindSizeLimit{
+			"segments": {maxBytes: 10, policy: OversizedItemDrop},
+		})
+		store.oversizedPolicy = OversizedItemError
+		assert.Equal(t, kindSizeLimit{maxBytes: firestoreMaxDocSize, policy: OversizedItemError},
+			store.sizeLimitForKind(ldstoreimpl.Features()))
+		assert.Equal(t, kindSizeLimit{maxBytes: 10, policy: OversizedItemDrop},
+			store.sizeLimitForKind(ldstoreimpl.Segments()))
+	})
+
+	t.Run("overflow policy", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := makeStore(mockLog, map[string]kindSizeLimit{
+			"features": {maxBytes: 1000, policy: OversizedItemOverflow},
+		})
+		overflow := &testOverflowStore{items: make(map[string][]byte)}
+		store.context = context.Background()
+		store.overflow = overflow
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", flagItem)
+		require.NoError(t, err)
+		result, ok, err := store.applySizeLimit(ldstoreimpl.Features(), data)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, true, result[fieldOverflow])
+		assert.NotContains(t, result, fieldItem)
+		assert.Equal(t, flagJSON, string(overflow.items["features/flag1/1"]))
+
+		itemJSON, err := store.itemPayload(result)
+		require.NoError(t, err)
+		assert.Equal(t, flagJSON, string(itemJSON))
+
+		overflow.err = errors.New("unavailable")
+		_, ok, err = store.applySizeLimit(ldstoreimpl.Features(), data)
+		assert.ErrorIs(t, err, overflow.err)
+		assert.False(t, ok)
+		_, err = store.itemPayload(result)
+		assert.ErrorIs(t, err, overflow.err)
+
+		store.overflow = nil
+		_, err = store.itemPayload(result)
+		assert.Error(t, err)
+	})
+}
+
+func TestValidateOverflowStore(t *testing.T) {
+	assert.NoError(t, builderOptions{}.validateOverflowStore())
+	assert.Error(t, builderOptions{oversizedPolicy: OversizedItemOverflow}.validateOverflowStore())
+	assert.Error(t, builderOptions{kindLimits: map[string]kindSizeLimit{
+		"features": {policy: OversizedItemOverflow},
+	}}.validateOverflowStore())
+	assert.NoError(t, builderOptions{
+		oversizedPolicy: OversizedItemOverflow,
+		overflowStore:   &testOverflowStore{},
+	}.validateOverflowStore())
+}
+
+type testOverflowStore struct {
+	items map[string][]byte
+	err   error
+}
+
+func (s *testOverflowStore) Put(_ context.Context, namespace, key string, version int, item []byte) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.items[fmt.Sprintf("%s/%s/%d", namespace, key, version)] = item
+	return nil
+}
+
+func (s *testOverflowStore) Get(_ context.Context, namespace, key string, version int) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	item, ok := s.items[fmt.Sprintf("%s/%s/%d", namespace, key, version)]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return item, nil
+}
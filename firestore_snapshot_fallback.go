@@ -0,0 +1,227 @@
+package ldfirestore
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+// DefaultSnapshotFallbackInterval is the default value for [SnapshotFallbackOptions.Interval].
+const DefaultSnapshotFallbackInterval = 5 * time.Minute
+
+// SnapshotFallbackOptions configures the local snapshot that is enabled with
+// [StoreBuilder.SnapshotFallback].
+type SnapshotFallbackOptions struct {
+	// Path is the file that the snapshot is saved to. Its directory must exist and be writable.
+	Path string
+
+	// Interval is the time between saves of the snapshot. If it is zero, DefaultSnapshotFallbackInterval
+	// is used.
+	Interval time.Duration
+}
+
+func validateSnapshotFallback(options SnapshotFallbackOptions) error {
+	if options.Interval < 0 {
+		return errors.New("snapshot fallback interval must not be negative")
+	}
+	if options.Interval > 0 && options.Path == "" {
+		return errors.New("snapshot fallback path is required")
+	}
+	return nil
+}
+
+// snapshotFile is the format of the snapshot file.
+type snapshotFile struct {
+	SavedAt    time.Time                          `json:"savedAt"`
+	Collection string                             `json:"collection"`
+	Items      map[string]map[string]snapshotItem `json:"items"` // by namespace, then key
+}
+
+type snapshotItem struct {
+	Version int    `json:"version"`
+	Item    string `json:"item"`
+}
+
+// snapshotFallback holds the most recently saved or loaded snapshot, from which reads are served
+// when Firestore cannot be read.
+type snapshotFallback struct {
+	path     string
+	interval time.Duration
+	snapshot *snapshotFile
+	serving  bool // true if a read has been served from the snapshot since it was last saved
+	lock     sync.Mutex
+}
+
+func newSnapshotFallback(options SnapshotFallbackOptions) *snapshotFallback {
+	if options.Path == "" {
+		return nil
+	}
+	interval := options.Interval
+	if interval == 0 {
+		interval = DefaultSnapshotFallbackInterval
+	}
+	return &snapshotFallback{path: options.Path, interval: interval}
+}
+
+// runSnapshotFallback loads any existing snapshot, and then saves a new one periodically until the
+// store is closed.
+func (store *firestoreDataStore) runSnapshotFallback() {
+	store.loadSnapshot()
+	ticker := time.NewTicker(store.fallback.interval)
+	defer ticker.Stop()
+	for {
+		store.saveSnapshot()
+		select {
+		case <-store.context.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// loadSnapshot reads the snapshot file, if there is one, so that reads can be served from it even if
+// Firestore cannot be read when the store starts.
+func (store *firestoreDataStore) loadSnapshot() {
+	data, err := os.ReadFile(store.fallback.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	var snapshot snapshotFile
+	if err == nil {
+		err = json.Unmarshal(data, &snapshot)
+	}
+	if err != nil {
+		store.loggers.Warnf("Could not read the snapshot file %q: %s", store.fallback.path, err)
+		return
+	}
+	if snapshot.Collection != store.collection {
+		store.loggers.Warnf("Ignoring the snapshot file %q, since it was saved from collection %q",
+			store.fallback.path, snapshot.Collection)
+		return
+	}
+	store.fallback.lock.Lock()
+	if store.fallback.snapshot == nil {
+		store.fallback.snapshot = &snapshot
+	}
+	store.fallback.lock.Unlock()
+}
+
+// saveSnapshot reads every flag and segment from Firestore and writes them to the snapshot file. The
+// file is replaced atomically, so a reader never sees a partly written file.
+func (store *firestoreDataStore) saveSnapshot() {
+	snapshot := &snapshotFile{
+		SavedAt:    store.now(),
+		Collection: store.collection,
+		Items:      make(map[string]map[string]snapshotItem),
+	}
+	for _, kind := range ldstoreimpl.AllKinds() {
+		items, err := store.getAllItems(kind)
+		if err != nil {
+			store.loggers.Warnf("Could not read %s to save a snapshot: %s", kind, err)
+			return
+		}
+		kindItems := make(map[string]snapshotItem, len(items))
+		for _, item := range items {
+			kindItems[item.Key] = snapshotItem{Version: item.Item.Version, Item: string(item.Item.SerializedItem)}
+		}
+		snapshot.Items[store.namespaceForKind(kind)] = kindItems
+	}
+
+	if err := writeSnapshotFile(store.fallback.path, snapshot); err != nil {
+		store.loggers.Warnf("Could not save the snapshot file %q: %s", store.fallback.path, err)
+		return
+	}
+
+	store.fallback.lock.Lock()
+	store.fallback.snapshot = snapshot
+	wasServing := store.fallback.serving
+	store.fallback.serving = false
+	store.fallback.lock.Unlock()
+	if wasServing {
+		store.loggers.Infof("Firestore can be read again; no longer serving data from the snapshot file %q",
+			store.fallback.path)
+	}
+}
+
+func writeSnapshotFile(path string, snapshot *snapshotFile) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }() // fails harmlessly once the file has been renamed
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// fallbackSnapshot returns the snapshot to serve a read from, after a read from Firestore failed
+// with readErr, or nil if there is none.
+func (store *firestoreDataStore) fallbackSnapshot(readErr error) *snapshotFile {
+	if store.fallback == nil {
+		return nil
+	}
+	store.fallback.lock.Lock()
+	snapshot := store.fallback.snapshot
+	alreadyServing := store.fallback.serving
+	if snapshot != nil {
+		store.fallback.serving = true
+	}
+	store.fallback.lock.Unlock()
+	if snapshot != nil && !alreadyServing {
+		store.loggers.Warnf("Could not read from Firestore; serving data from the snapshot saved at %s until "+
+			"it can be read again: %s", snapshot.SavedAt.Format(time.RFC3339), readErr)
+	}
+	return snapshot
+}
+
+// fallbackGet returns an item from the snapshot, if there is one.
+func (store *firestoreDataStore) fallbackGet(
+	kind ldstoretypes.DataKind,
+	key string,
+	readErr error,
+) (ldstoretypes.SerializedItemDescriptor, bool) {
+	snapshot := store.fallbackSnapshot(readErr)
+	if snapshot == nil {
+		return ldstoretypes.SerializedItemDescriptor{}, false
+	}
+	item, ok := snapshot.Items[store.namespaceForKind(kind)][key]
+	if !ok {
+		return ldstoretypes.SerializedItemDescriptor{}.NotFound(), true
+	}
+	return ldstoretypes.SerializedItemDescriptor{Version: item.Version, SerializedItem: []byte(item.Item)}, true
+}
+
+// fallbackGetAll returns every item of a data kind from the snapshot, if there is one.
+func (store *firestoreDataStore) fallbackGetAll(
+	kind ldstoretypes.DataKind,
+	readErr error,
+) ([]ldstoretypes.KeyedSerializedItemDescriptor, bool) {
+	snapshot := store.fallbackSnapshot(readErr)
+	if snapshot == nil {
+		return nil, false
+	}
+	kindItems := snapshot.Items[store.namespaceForKind(kind)]
+	items := make([]ldstoretypes.KeyedSerializedItemDescriptor, 0, len(kindItems))
+	for key, item := range kindItems {
+		items = append(items, ldstoretypes.KeyedSerializedItemDescriptor{
+			Key:  key,
+			Item: ldstoretypes.SerializedItemDescriptor{Version: item.Version, SerializedItem: []byte(item.Item)},
+		})
+	}
+	return items, true
+}
@@ -0,0 +1,147 @@
+package ldfirestore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+func TestSnapshotFallback(t *testing.T) {
+	makeStore := func(t *testing.T, options SnapshotFallbackOptions) *firestoreDataStore {
+		client, err := createTestClient()
+		require.NoError(t, err)
+		_ = client.Close() // every read will fail
+
+		ds, err := DataStore("my-project", "my-collection").FirestoreClient(client).
+			SnapshotFallback(options).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = ds.Close() })
+		return ds.(*firestoreDataStore)
+	}
+	snapshot := &snapshotFile{
+		SavedAt:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Collection: "my-collection",
+		Items: map[string]map[string]snapshotItem{
+			ldstoreimpl.Features().GetName(): {"flag1": {Version: 2, Item: `{"key":"flag1","version":2}`}},
+		},
+	}
+
+	t.Run("validation", func(t *testing.T) {
+		assert.NoError(t, validateSnapshotFallback(SnapshotFallbackOptions{}))
+		assert.NoError(t, validateSnapshotFallback(SnapshotFallbackOptions{Path: "snapshot.json"}))
+		assert.Error(t, validateSnapshotFallback(SnapshotFallbackOptions{Interval: time.Minute}))
+		assert.Error(t, validateSnapshotFallback(SnapshotFallbackOptions{Path: "snapshot.json", Interval: -1}))
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		store := makeStore(t, SnapshotFallbackOptions{})
+		assert.Nil(t, store.fallback)
+		_, err := store.Get(ldstoreimpl.Features(), "flag1")
+		assert.Error(t, err)
+	})
+
+	t.Run("reads are served from a saved snapshot", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snapshot.json")
+		require.NoError(t, writeSnapshotFile(path, snapshot))
+
+		store := makeStore(t, SnapshotFallbackOptions{Path: path, Interval: time.Hour})
+		require.Eventually(t, func() bool {
+			store.fallback.lock.Lock()
+			defer store.fallback.lock.Unlock()
+			return store.fallback.snapshot != nil
+		}, time.Second, 10*time.Millisecond)
+
+		item, err := store.Get(ldstoreimpl.Features(), "flag1")
+		require.NoError(t, err)
+		assert.Equal(t, 2, item.Version)
+		assert.JSONEq(t, `{"key":"flag1","version":2}`, string(item.SerializedItem))
+
+		item, err = store.Get(ldstoreimpl.Features(), "flag2")
+		require.NoError(t, err)
+		assert.Equal(t, ldstoretypes.SerializedItemDescriptor{}.NotFound(), item)
+
+		items, err := store.GetAll(ldstoreimpl.Features())
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, "flag1", items[0].Key)
+
+		items, err = store.GetAll(ldstoreimpl.Segments())
+		require.NoError(t, err)
+		assert.Len(t, items, 0)
+
+		// The client is closed rather than unreachable, so this is not an availability error
+		assert.False(t, store.IsInitialized())
+	})
+
+	t.Run("initialized from a snapshot while Firestore is unreachable", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snapshot.json")
+		require.NoError(t, writeSnapshotFile(path, snapshot))
+
+		ds, err := DataStore("my-project", "my-collection").
+			ClientOptions(option.WithEndpoint("localhost:1"), option.WithoutAuthentication()).
+			Retry(RetryPolicy{}).
+			OperationTimeout(200 * time.Millisecond).
+			SnapshotFallback(SnapshotFallbackOptions{Path: path, Interval: time.Hour}).
+			Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		defer func() { _ = ds.Close() }()
+		store := ds.(*firestoreDataStore)
+		store.loadSnapshot()
+
+		assert.True(t, store.IsInitialized())
+	})
+
+	t.Run("snapshot from another collection is ignored", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snapshot.json")
+		other := *snapshot
+		other.Collection = "other-collection"
+		require.NoError(t, writeSnapshotFile(path, &other))
+
+		store := makeStore(t, SnapshotFallbackOptions{Path: path, Interval: time.Hour})
+		store.loadSnapshot()
+		_, err := store.Get(ldstoreimpl.Features(), "flag1")
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid file is ignored", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snapshot.json")
+		require.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+
+		store := makeStore(t, SnapshotFallbackOptions{Path: path, Interval: time.Hour})
+		store.loadSnapshot()
+		assert.False(t, store.IsInitialized())
+	})
+}
+
+func TestSnapshotFallbackDoesNotInitializeEmptyCollection(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	require.NoError(t, writeSnapshotFile(path, &snapshotFile{
+		SavedAt:    time.Now(),
+		Collection: testCollectionName,
+		Items: map[string]map[string]snapshotItem{
+			ldstoreimpl.Features().GetName(): {"flag1": {Version: 1, Item: `{"key":"flag1","version":1}`}},
+		},
+	}))
+
+	ds, err := baseDataStoreBuilder().
+		SnapshotFallback(SnapshotFallbackOptions{Path: path, Interval: time.Hour}).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+	ds.(*firestoreDataStore).loadSnapshot()
+
+	assert.False(t, ds.IsInitialized())
+}
@@ -0,0 +1,195 @@
+package ldfirestore
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// latencySampleSize is the number of the most recent operations of each category whose durations
+// are kept for the latency percentiles in [Stats].
+const latencySampleSize = 1000
+
+// Stats is a snapshot of what the store has done since it was created. See [ExtendedDataStore.Stats].
+type Stats struct {
+	// Reads describes the calls to Get, GetAll, and IsInitialized, including ones that were answered
+	// from a cache.
+	Reads OperationStats
+
+	// Writes describes the calls to Upsert, UpsertWithResult, and Init.
+	Writes OperationStats
+
+	// Transactions is the number of Firestore transactions that the store has run, for updating items,
+	// deleting them, reading them consistently (see [StoreBuilder.ConsistentGetAll]), or electing a
+	// writer. A transaction that Firestore retried because of contention is counted once.
+	Transactions int
+
+	// VersionConflicts is the number of upserts that were skipped because the stored item's version
+	// was the same as or greater than the new one.
+	VersionConflicts int
+
+	// Errors is the total number of reads and writes that returned an error.
+	Errors int
+
+	// ReadRepairs counts the attempts to recover documents that could not be decoded, if
+	// [StoreBuilder.ReadRepair] is enabled.
+	ReadRepairs ReadRepairStats
+}
+
+// ReadRepairStats counts the read repairs in [Stats]. A repair that is still in progress is counted in
+// Attempted but in neither of the other fields.
+type ReadRepairStats struct {
+	// Attempted is the number of documents that could not be decoded and that the store tried to
+	// repair.
+	Attempted int
+
+	// Succeeded is the number of repairs that recovered the item, either by re-reading the document or
+	// by decoding it more leniently.
+	Succeeded int
+
+	// Failed is the number of repairs after which the item was treated as invalid. The document is
+	// probably corrupt.
+	Failed int
+}
+
+// OperationStats describes one category of store operations in [Stats].
+type OperationStats struct {
+	// Count is the number of operations.
+	Count int
+
+	// Errors is the number of operations that returned an error.
+	Errors int
+
+	// Latency describes how long the most recent operations took, successful or not. It is based on
+	// at most the last 1000 operations, so that it reflects current conditions rather than the whole
+	// lifetime of the store.
+	Latency LatencyStats
+}
+
+// LatencyStats gives percentiles of the durations of store operations. All of the fields are zero
+// if there have been no operations.
+type LatencyStats struct {
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// operationStats accumulates the counts and recent durations of one category of operations.
+type operationStats struct {
+	count     int
+	errors    int
+	durations []time.Duration // a ring buffer of at most latencySampleSize durations
+	next      int
+}
+
+func (s *operationStats) record(duration time.Duration, err error) {
+	s.count++
+	if err != nil {
+		s.errors++
+	}
+	if len(s.durations) < latencySampleSize {
+		s.durations = append(s.durations, duration)
+		return
+	}
+	s.durations[s.next] = duration
+	s.next = (s.next + 1) % latencySampleSize
+}
+
+func (s *operationStats) snapshot() OperationStats {
+	result := OperationStats{Count: s.count, Errors: s.errors}
+	if len(s.durations) == 0 {
+		return result
+	}
+	sorted := slices.Clone(s.durations)
+	slices.Sort(sorted)
+	percentile := func(p int) time.Duration {
+		return sorted[(len(sorted)-1)*p/100]
+	}
+	result.Latency = LatencyStats{
+		P50: percentile(50),
+		P90: percentile(90),
+		P99: percentile(99),
+		Max: sorted[len(sorted)-1],
+	}
+	return result
+}
+
+// storeStats accumulates the operation statistics of a store. The zero value is ready to use.
+type storeStats struct {
+	reads  operationStats
+	writes operationStats
+	lock   sync.Mutex
+}
+
+// record adds an operation to the statistics, if it is one of the operations that [Stats] describes.
+func (s *storeStats) record(operation string, duration time.Duration, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch operation {
+	case "Get", "GetAll", "IsInitialized":
+		s.reads.record(duration, err)
+	case "Upsert", "Init":
+		s.writes.record(duration, err)
+	}
+}
+
+// runTransaction runs a Firestore transaction, counting it in the Stats.
+func (store *firestoreDataStore) runTransaction(
+	ctx context.Context,
+	fn func(context.Context, *firestore.Transaction) error,
+	opts ...firestore.TransactionOption,
+) error {
+	store.lock.Lock()
+	store.transactions++
+	store.lock.Unlock()
+	return store.firestoreClient().RunTransaction(ctx, fn, opts...)
+}
+
+// countVersionConflict records an upsert that was skipped because of the stored version.
+func (store *firestoreDataStore) countVersionConflict() {
+	store.lock.Lock()
+	store.versionConflicts++
+	store.lock.Unlock()
+}
+
+// countReadRepairAttempt records the start of a read repair.
+func (store *firestoreDataStore) countReadRepairAttempt() {
+	store.lock.Lock()
+	store.readRepairs.Attempted++
+	store.lock.Unlock()
+}
+
+// countReadRepairResult records the outcome of a read repair.
+func (store *firestoreDataStore) countReadRepairResult(succeeded bool) {
+	store.lock.Lock()
+	if succeeded {
+		store.readRepairs.Succeeded++
+	} else {
+		store.readRepairs.Failed++
+	}
+	store.lock.Unlock()
+}
+
+func (store *firestoreDataStore) Stats() Stats {
+	store.lock.Lock()
+	stats := Stats{
+		Transactions:     store.transactions,
+		VersionConflicts: store.versionConflicts,
+		ReadRepairs:      store.readRepairs,
+	}
+	store.lock.Unlock()
+
+	if t := store.telemetry; t != nil {
+		t.stats.lock.Lock()
+		stats.Reads = t.stats.reads.snapshot()
+		stats.Writes = t.stats.writes.snapshot()
+		t.stats.lock.Unlock()
+	}
+	stats.Errors = stats.Reads.Errors + stats.Writes.Errors
+	return stats
+}
@@ -0,0 +1,109 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationStatsLatency(t *testing.T) {
+	var s operationStats
+	assert.Equal(t, OperationStats{}, s.snapshot())
+
+	for i := 100; i >= 1; i-- {
+		s.record(time.Duration(i)*time.Millisecond, nil)
+	}
+	assert.Equal(t, OperationStats{
+		Count: 100,
+		Latency: LatencyStats{
+			P50: 50 * time.Millisecond,
+			P90: 90 * time.Millisecond,
+			P99: 99 * time.Millisecond,
+			Max: 100 * time.Millisecond,
+		},
+	}, s.snapshot())
+
+	// only the most recent durations are kept
+	for i := 0; i < latencySampleSize; i++ {
+		s.record(time.Second, nil)
+	}
+	snapshot := s.snapshot()
+	assert.Equal(t, 100+latencySampleSize, snapshot.Count)
+	assert.Equal(t, LatencyStats{P50: time.Second, P90: time.Second, P99: time.Second, Max: time.Second},
+		snapshot.Latency)
+}
+
+func TestStatsCountsErrors(t *testing.T) {
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close() // every operation will fail
+
+	ds, err := DataStore("my-project", "my-collection").FirestoreClient(client).Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+	ext := ds.(ExtendedDataStore)
+
+	_, err = ds.Get(ldstoreimpl.Features(), "flag1")
+	assert.Error(t, err)
+	_, err = ds.GetAll(ldstoreimpl.Features())
+	assert.Error(t, err)
+	_, err = ds.Upsert(ldstoreimpl.Features(), "flag1", ldstoretypes.SerializedItemDescriptor{Version: 1})
+	assert.Error(t, err)
+
+	stats := ext.Stats()
+	assert.Equal(t, 2, stats.Reads.Count)
+	assert.Equal(t, 2, stats.Reads.Errors)
+	assert.Equal(t, 1, stats.Writes.Count)
+	assert.Equal(t, 1, stats.Writes.Errors)
+	assert.Equal(t, 3, stats.Errors)
+	assert.Equal(t, 1, stats.Transactions)
+	assert.Greater(t, stats.Reads.Latency.Max, time.Duration(0))
+}
+
+func TestStatsCountsReadRepairs(t *testing.T) {
+	store := &firestoreDataStore{}
+	store.countReadRepairAttempt()
+	store.countReadRepairAttempt()
+	store.countReadRepairAttempt()
+	store.countReadRepairResult(true)
+	store.countReadRepairResult(false)
+
+	// One repair is still in progress
+	assert.Equal(t, ReadRepairStats{Attempted: 3, Succeeded: 1, Failed: 1}, store.Stats().ReadRepairs)
+}
+
+func TestStats(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := baseDataStoreBuilder().Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	ext := store.(ExtendedDataStore)
+
+	flag := func(version int) ldstoretypes.SerializedItemDescriptor {
+		return ldstoretypes.SerializedItemDescriptor{Version: version, SerializedItem: []byte(`{}`)}
+	}
+	require.NoError(t, store.Init(nil))
+	_, err = store.Upsert(ldstoreimpl.Features(), "flag1", flag(2))
+	require.NoError(t, err)
+	updated, err := store.Upsert(ldstoreimpl.Features(), "flag1", flag(1))
+	require.NoError(t, err)
+	assert.False(t, updated)
+	_, err = store.Get(ldstoreimpl.Features(), "flag1")
+	require.NoError(t, err)
+
+	stats := ext.Stats()
+	assert.Equal(t, 1, stats.Reads.Count)
+	assert.Equal(t, 3, stats.Writes.Count)
+	assert.Equal(t, 2, stats.Transactions)
+	assert.Equal(t, 1, stats.VersionConflicts)
+	assert.Equal(t, 0, stats.Errors)
+}
@@ -0,0 +1,74 @@
+package ldfirestore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// encodingMap is the value of the encoding field for items that are stored as Firestore maps. See
+// [StoreBuilder.StructuredItems].
+const encodingMap = "map"
+
+func (b builderOptions) validateStructuredItems() error {
+	if b.structured && (b.compression != CompressionNone || b.itemBytes) {
+		return errors.New("StructuredItems cannot be combined with Compression or StoreItemsAsBytes")
+	}
+	return nil
+}
+
+// structuredItem converts a serialized item to the map that is stored in Firestore when the
+// StructuredItems option is enabled. It returns false if the item is not a JSON object or cannot be
+// represented exactly as a Firestore value; for instance, Firestore does not allow an array to
+// contain another array, which is possible in a flag variation.
+func structuredItem(itemJSON []byte) (map[string]any, bool) {
+	dec := json.NewDecoder(bytes.NewReader(itemJSON))
+	dec.UseNumber()
+	var props map[string]any
+	if err := dec.Decode(&props); err != nil || props == nil {
+		return nil, false
+	}
+	value, ok := toFirestoreValue(props, false)
+	if !ok {
+		return nil, false
+	}
+	return value.(map[string]any), true
+}
+
+func toFirestoreValue(value any, inArray bool) (any, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		// Integers are stored as Firestore integers, so that large ones do not lose precision.
+		if n, err := v.Int64(); err == nil {
+			return n, true
+		}
+		f, err := v.Float64()
+		return f, err == nil
+	case []any:
+		if inArray {
+			return nil, false
+		}
+		for i, elem := range v {
+			converted, ok := toFirestoreValue(elem, true)
+			if !ok {
+				return nil, false
+			}
+			v[i] = converted
+		}
+		return v, true
+	case map[string]any:
+		for name, prop := range v {
+			if name == "" {
+				return nil, false
+			}
+			converted, ok := toFirestoreValue(prop, false)
+			if !ok {
+				return nil, false
+			}
+			v[name] = converted
+		}
+		return v, true
+	default:
+		return v, true
+	}
+}
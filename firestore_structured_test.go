@@ -0,0 +1,65 @@
+package ldfirestore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructuredItems(t *testing.T) {
+	store := &firestoreDataStore{structured: true}
+	encode := func(t *testing.T, itemJSON string) map[string]any {
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1",
+			ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(itemJSON)})
+		require.NoError(t, err)
+		return data
+	}
+
+	t.Run("item is stored as a map", func(t *testing.T) {
+		itemJSON := `{"key":"flag1","version":9007199254740993,"on":true,"weight":0.5,` +
+			`"variations":[{"a":[1,2]},null,"x"],"salt":""}`
+		data := encode(t, itemJSON)
+		assert.Equal(t, encodingMap, data[fieldEncoding])
+		require.IsType(t, map[string]any{}, data[fieldItem])
+		props := data[fieldItem].(map[string]any)
+		assert.Equal(t, true, props["on"])
+		assert.Equal(t, int64(9007199254740993), props["version"])
+		assert.Equal(t, 0.5, props["weight"])
+
+		decoded, err := store.itemPayload(data)
+		require.NoError(t, err)
+		assert.JSONEq(t, itemJSON, string(decoded))
+		assert.Contains(t, string(decoded), "9007199254740993")
+	})
+
+	t.Run("item that cannot be a Firestore map is stored as a string", func(t *testing.T) {
+		for _, itemJSON := range []string{
+			`{"key":"flag1","variations":[[1,2],[3]]}`,
+			`{"key":"flag1","variations":[{"":1}]}`,
+			`"not an object"`,
+		} {
+			data := encode(t, itemJSON)
+			assert.Equal(t, itemJSON, data[fieldItem])
+			assert.NotContains(t, data, fieldEncoding)
+		}
+	})
+
+	t.Run("size estimate includes map contents", func(t *testing.T) {
+		data := encode(t, `{"key":"flag1","description":"`+strings.Repeat("x", 1000)+`"}`)
+		require.Equal(t, encodingMap, data[fieldEncoding])
+		assert.Greater(t, estimateDocSize(data), 1000)
+	})
+
+	t.Run("error when combined with compression", func(t *testing.T) {
+		ds, err := DataStore("my-project", "my-collection").StructuredItems(true).Compression(CompressionGzip).
+			Build(subsystems.BasicClientContext{})
+		assert.Error(t, err)
+		assert.Nil(t, ds)
+		assert.Contains(t, err.Error(), "StructuredItems cannot be combined")
+	})
+}
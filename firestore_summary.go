@@ -0,0 +1,103 @@
+package ldfirestore
+
+import (
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NamespaceSummary is the content of the summary document that the store maintains for each data
+// kind if [StoreBuilder.NamespaceSummaries] is enabled.
+type NamespaceSummary struct {
+	// ItemCount is the number of items of this kind that are stored, including placeholders for
+	// deleted items.
+	ItemCount int
+
+	// MaxVersion is the highest version number of any item of this kind that has been stored.
+	MaxVersion int
+
+	// UpdatedAt is the time when the summary was last changed.
+	UpdatedAt time.Time
+}
+
+const (
+	summaryNamespace = "$summary"
+	fieldItemCount   = "itemCount"
+	fieldMaxVersion  = "maxVersion"
+)
+
+// summaryDocRef returns the summary document for a data kind. It is in the same collection as the
+// items of that kind, but has a different namespace so that queries for the items do not see it.
+func (store *firestoreDataStore) summaryDocRef(kind ldstoretypes.DataKind) *firestore.DocumentRef {
+	prefix := store.kindPrefix(kind)
+	return store.collectionForKind(kind).
+		Doc(store.ids.makeID(prefix, prefixedNamespace(prefix, summaryNamespace), kind.GetName()))
+}
+
+func (store *firestoreDataStore) summaryOperation(kind ldstoretypes.DataKind, summary NamespaceSummary) setOperation {
+	return setOperation{
+		ref: store.summaryDocRef(kind),
+		data: store.addExpiry(map[string]any{
+			fieldNamespace:  prefixedNamespace(store.kindPrefix(kind), summaryNamespace),
+			fieldKey:        kind.GetName(),
+			fieldItemCount:  summary.ItemCount,
+			fieldMaxVersion: summary.MaxVersion,
+			fieldUpdatedAt:  summary.UpdatedAt,
+		}),
+	}
+}
+
+func decodeSummary(doc *firestore.DocumentSnapshot) NamespaceSummary {
+	data := doc.Data()
+	count, _ := data[fieldItemCount].(int64)
+	maxVersion, _ := data[fieldMaxVersion].(int64)
+	updatedAt, _ := data[fieldUpdatedAt].(time.Time)
+	return NamespaceSummary{ItemCount: int(count), MaxVersion: int(maxVersion), UpdatedAt: updatedAt}
+}
+
+// updateSummaryInTransaction reads the summary document for a data kind and writes it back with the
+// item count changed by countDelta and the maximum version raised to version if that is higher. It
+// must be called before the transaction does any writes of its own.
+func (store *firestoreDataStore) updateSummaryInTransaction(
+	tx *firestore.Transaction,
+	kind ldstoretypes.DataKind,
+	countDelta int,
+	version int,
+) error {
+	ref := store.summaryDocRef(kind)
+	var summary NamespaceSummary
+	doc, err := tx.Get(ref)
+	if err == nil {
+		summary = decodeSummary(doc)
+	} else if status.Code(err) != codes.NotFound {
+		return err
+	}
+	summary.ItemCount = max(summary.ItemCount+countDelta, 0)
+	summary.MaxVersion = max(summary.MaxVersion, version)
+	summary.UpdatedAt = store.now()
+	op := store.summaryOperation(kind, summary)
+	return tx.Set(op.ref, op.data)
+}
+
+func (store *firestoreDataStore) NamespaceSummary(kind ldstoretypes.DataKind) (NamespaceSummary, bool, error) {
+	done, err := store.beginOperation(true)
+	if err != nil {
+		return NamespaceSummary{}, false, err
+	}
+	defer done()
+
+	doc, err := store.getDocument(store.summaryDocRef(kind))
+	if err == nil || status.Code(err) == codes.NotFound {
+		store.countUsage("NamespaceSummary", DocumentOperations{Reads: 1})
+	}
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return NamespaceSummary{}, false, nil
+		}
+		return NamespaceSummary{}, false, newStoreError("NamespaceSummary", kind, "", err)
+	}
+	return decodeSummary(doc), true, nil
+}
@@ -0,0 +1,79 @@
+package ldfirestore
+
+import (
+	"testing"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummaryDocument(t *testing.T) {
+	store, err := baseDataStoreBuilder().Prefix("p").NamespaceSummaries(true).Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	impl := store.(*firestoreDataStore)
+
+	op := impl.summaryOperation(ldstoreimpl.Features(), NamespaceSummary{ItemCount: 2, MaxVersion: 5})
+	assert.Equal(t, "p:p:$summary:features", op.ref.ID)
+	assert.Equal(t, "p:$summary", op.data[fieldNamespace])
+	assert.NotEqual(t, impl.namespaceForKind(ldstoreimpl.Features()), op.data[fieldNamespace])
+}
+
+func TestNamespaceSummaries(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := baseDataStoreBuilder().NamespaceSummaries(true).Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	ext := store.(ExtendedDataStore)
+
+	_, found, err := ext.NamespaceSummary(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	flag := func(version int) ldstoretypes.SerializedItemDescriptor {
+		return ldstoretypes.SerializedItemDescriptor{Version: version, SerializedItem: []byte(`{}`)}
+	}
+	require.NoError(t, store.Init([]ldstoretypes.SerializedCollection{
+		{Kind: ldstoreimpl.Features(), Items: []ldstoretypes.KeyedSerializedItemDescriptor{
+			{Key: "flag1", Item: flag(3)},
+			{Key: "flag2", Item: flag(7)},
+		}},
+		{Kind: ldstoreimpl.Segments(), Items: nil},
+	}))
+	assert.Equal(t, InitReport{ItemsWritten: 2}, ext.LastInitReport())
+
+	summary, found, err := ext.NamespaceSummary(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 2, summary.ItemCount)
+	assert.Equal(t, 7, summary.MaxVersion)
+
+	all, err := store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	_, err = store.Upsert(ldstoreimpl.Features(), "flag3", flag(9))
+	require.NoError(t, err)
+	_, err = store.Upsert(ldstoreimpl.Features(), "flag1", flag(4))
+	require.NoError(t, err)
+	_, err = ext.Delete(ldstoreimpl.Features(), "flag2", ldvalue.OptionalInt{})
+	require.NoError(t, err)
+
+	summary, _, err = ext.NamespaceSummary(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.ItemCount)
+	assert.Equal(t, 9, summary.MaxVersion)
+
+	summary, found, err = ext.NamespaceSummary(ldstoreimpl.Segments())
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 0, summary.ItemCount)
+}
@@ -0,0 +1,200 @@
+package ldfirestore
+
+import (
+	"context"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName is the instrumentation scope of the spans and metrics that the stores create.
+// Each span is named "ldfirestore." followed by the operation, such as "ldfirestore.Get".
+const instrumentationName = "github.com/launchdarkly/go-server-sdk-firestore"
+
+const (
+	attrDBSystem      = attribute.Key("db.system.name")
+	attrDBCollection  = attribute.Key("db.collection.name")
+	attrDBOperation   = attribute.Key("db.operation.name")
+	attrKind          = attribute.Key("ldfirestore.kind")
+	attrKey           = attribute.Key("ldfirestore.key")
+	attrDocumentCount = attribute.Key("ldfirestore.document_count")
+	attrContextHash   = attribute.Key("ldfirestore.context_hash")
+	attrInitialized   = attribute.Key("ldfirestore.initialized")
+	attrResult        = attribute.Key("ldfirestore.result")
+)
+
+// Names of the metrics; see [StoreBuilder.MeterProvider].
+const (
+	metricOperationDuration = "ldfirestore.operation.duration"
+	metricOperations        = "ldfirestore.operations"
+	metricDocuments         = "ldfirestore.documents"
+)
+
+// telemetry creates the spans, records the metrics, the Stats, and the HealthStatus, and calls the
+// OperationHook for the operations of a store. A nil *telemetry does nothing, as for a store that was not created by a
+// builder.
+type telemetry struct {
+	context    context.Context
+	collection string
+	tracer     trace.Tracer
+	duration   metric.Float64Histogram
+	operations metric.Int64Counter
+	documents  metric.Int64Counter
+	hook       OperationHook
+	stats      storeStats
+	health     healthTracker
+	clock      Clock       // if not nil, the source of the times recorded in the HealthStatus
+	observe    func(error) // if not nil, called with the outcome of every operation and availability check
+}
+
+func newTelemetry(ctx context.Context, builder builderOptions, loggers ldlog.Loggers) *telemetry {
+	tracerProvider := builder.tracerProvider
+	if tracerProvider == nil {
+		tracerProvider = tracenoop.NewTracerProvider()
+	}
+	meterProvider := builder.meterProvider
+	if meterProvider == nil {
+		meterProvider = metricnoop.NewMeterProvider()
+	}
+	t := &telemetry{
+		context:    ctx,
+		collection: builder.collection,
+		tracer:     tracerProvider.Tracer(instrumentationName, trace.WithInstrumentationVersion(Version)),
+		hook:       builder.operationHook,
+	}
+
+	meter := meterProvider.Meter(instrumentationName, metric.WithInstrumentationVersion(Version))
+	var err error
+	if t.duration, err = meter.Float64Histogram(metricOperationDuration, metric.WithUnit("s"),
+		metric.WithDescription("Duration of data store and Big Segment store operations")); err != nil {
+		loggers.Warnf("Could not create the %s metric: %s", metricOperationDuration, err)
+		t.duration = metricnoop.Float64Histogram{}
+	}
+	if t.operations, err = meter.Int64Counter(metricOperations, metric.WithUnit("{operation}"),
+		metric.WithDescription("Number of data store and Big Segment store operations")); err != nil {
+		loggers.Warnf("Could not create the %s metric: %s", metricOperations, err)
+		t.operations = metricnoop.Int64Counter{}
+	}
+	if t.documents, err = meter.Int64Counter(metricDocuments, metric.WithUnit("{document}"),
+		metric.WithDescription("Number of documents read or written by store operations")); err != nil {
+		loggers.Warnf("Could not create the %s metric: %s", metricDocuments, err)
+		t.documents = metricnoop.Int64Counter{}
+	}
+	return t
+}
+
+// storeOperation is an operation of a store that is in progress.
+type storeOperation struct {
+	telemetry *telemetry
+	info      OperationInfo
+	span      trace.Span
+	start     time.Time
+}
+
+// start starts an operation, with a span whose parent is whatever span is in the store's context
+// (see [StoreBuilder.Context]), if any, since the SDK does not give the store a context. The kind may
+// be nil, and the key may be empty. spanAttrs are only added to the span, not to the metrics. If the
+// OperationHook returns an error, the caller must not perform the operation, but must still call end.
+func (t *telemetry) start(
+	name string,
+	kind ldstoretypes.DataKind,
+	key string,
+	spanAttrs ...attribute.KeyValue,
+) (*storeOperation, error) {
+	if t == nil {
+		return nil, nil
+	}
+	op := &storeOperation{telemetry: t, info: OperationInfo{Operation: name, Kind: kind, Key: key}, start: time.Now()}
+	attrs := append([]attribute.KeyValue{
+		attrDBSystem.String("firestore"),
+		attrDBCollection.String(t.collection),
+		attrDBOperation.String(name),
+	}, spanAttrs...)
+	if kind != nil {
+		attrs = append(attrs, attrKind.String(kind.GetName()))
+		if key != "" {
+			attrs = append(attrs, attrKey.String(key))
+		}
+	}
+	_, op.span = t.tracer.Start(t.context, "ldfirestore."+name,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+
+	if t.hook != nil {
+		if err := t.hook.BeforeOperation(op.info); err != nil {
+			return op, err
+		}
+	}
+	return op, nil
+}
+
+// setAttributes adds attributes to the operation's span.
+func (op *storeOperation) setAttributes(attrs ...attribute.KeyValue) {
+	if op != nil {
+		op.span.SetAttributes(attrs...)
+	}
+}
+
+// end records the outcome of an operation and ends its span. A negative documentCount is not
+// recorded.
+func (op *storeOperation) end(documentCount int, err error) {
+	if op == nil {
+		return
+	}
+	t := op.telemetry
+	duration := time.Since(op.start)
+	t.stats.record(op.info.Operation, duration, err)
+	t.recordHealth(err)
+	if t.hook != nil {
+		info := op.info
+		info.Duration = duration
+		info.Err = err
+		t.hook.AfterOperation(info)
+	}
+
+	result := "success"
+	if err != nil {
+		result = "error"
+		op.span.RecordError(err)
+		op.span.SetStatus(otelcodes.Error, err.Error())
+	}
+	metricAttrs := []attribute.KeyValue{
+		attrDBCollection.String(t.collection),
+		attrDBOperation.String(op.info.Operation),
+		attrResult.String(result),
+	}
+	if op.info.Kind != nil {
+		metricAttrs = append(metricAttrs, attrKind.String(op.info.Kind.GetName()))
+	}
+	attrSet := metric.WithAttributeSet(attribute.NewSet(metricAttrs...))
+	t.duration.Record(t.context, duration.Seconds(), attrSet)
+	t.operations.Add(t.context, 1, attrSet)
+	if documentCount >= 0 {
+		op.span.SetAttributes(attrDocumentCount.Int(documentCount))
+		t.documents.Add(t.context, int64(documentCount), attrSet)
+	}
+	op.span.End()
+}
+
+// countItems returns the number of items in all of the collections given to Init.
+func countItems(allData []ldstoretypes.SerializedCollection) int {
+	n := 0
+	for _, coll := range allData {
+		n += len(coll.Items)
+	}
+	return n
+}
+
+// foundCount returns the document count for an operation that reads or writes one document.
+func foundCount(found bool) int {
+	if found {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,150 @@
+package ldfirestore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close() // every operation will fail
+
+	ds, err := DataStore("my-project", "my-collection").FirestoreClient(client).TracerProvider(provider).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+
+	_, err = ds.Get(ldstoreimpl.Features(), "flag1")
+	assert.Error(t, err)
+	_, err = ds.GetAll(ldstoreimpl.Segments())
+	assert.Error(t, err)
+	assert.False(t, ds.IsInitialized())
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{}`)}
+	_, err = ds.Upsert(ldstoreimpl.Features(), "flag1", item)
+	assert.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	attrs := func(i int) map[attribute.Key]attribute.Value {
+		m := make(map[attribute.Key]attribute.Value)
+		for _, kv := range spans[i].Attributes() {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+
+	assert.Equal(t, "ldfirestore.Get", spans[0].Name())
+	assert.Equal(t, "firestore", attrs(0)[attrDBSystem].AsString())
+	assert.Equal(t, "my-collection", attrs(0)[attrDBCollection].AsString())
+	assert.Equal(t, "features", attrs(0)[attrKind].AsString())
+	assert.Equal(t, "flag1", attrs(0)[attrKey].AsString())
+	assert.Equal(t, int64(0), attrs(0)[attrDocumentCount].AsInt64())
+	assert.Equal(t, otelcodes.Error, spans[0].Status().Code)
+
+	assert.Equal(t, "ldfirestore.GetAll", spans[1].Name())
+	assert.Equal(t, "segments", attrs(1)[attrKind].AsString())
+	assert.Equal(t, otelcodes.Error, spans[1].Status().Code)
+
+	assert.Equal(t, "ldfirestore.IsInitialized", spans[2].Name())
+	assert.False(t, attrs(2)[attrInitialized].AsBool())
+
+	assert.Equal(t, "ldfirestore.Upsert", spans[3].Name())
+	assert.Equal(t, otelcodes.Error, spans[3].Status().Code)
+
+	t.Run("Big Segments", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		bs, err := BigSegmentStore("my-project", "my-collection").FirestoreClient(client).TracerProvider(provider).
+			Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		defer func() { _ = bs.Close() }()
+
+		_, err = bs.GetMembership("0123456789abcdef")
+		assert.Error(t, err)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "ldfirestore.GetMembership", spans[0].Name())
+		assert.Contains(t, spans[0].Attributes(), attrContextHash.String("01234567"))
+	})
+
+	t.Run("no provider", func(t *testing.T) {
+		var tel *telemetry
+		op, err := tel.start("Get", ldstoreimpl.Features(), "flag1")
+		assert.NoError(t, err)
+		assert.Nil(t, op)
+		op.setAttributes(attrInitialized.Bool(true))
+		op.end(1, nil)
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	client, err := createTestClient()
+	require.NoError(t, err)
+	_ = client.Close() // every operation will fail
+
+	ds, err := DataStore("my-project", "my-collection").FirestoreClient(client).MeterProvider(provider).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = ds.Close() }()
+
+	_, err = ds.Get(ldstoreimpl.Features(), "flag1")
+	assert.Error(t, err)
+	_, err = ds.Get(ldstoreimpl.Features(), "flag2")
+	assert.Error(t, err)
+	assert.False(t, ds.IsInitialized())
+
+	var data metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &data))
+	require.Len(t, data.ScopeMetrics, 1)
+	metrics := make(map[string]metricdata.Metrics)
+	for _, m := range data.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+
+	getAttrs := attribute.NewSet(
+		attrDBCollection.String("my-collection"),
+		attrDBOperation.String("Get"),
+		attrResult.String("error"),
+		attrKind.String("features"),
+	)
+	operations, ok := metrics[metricOperations].Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	counts := make(map[string]int64)
+	for _, point := range operations.DataPoints {
+		op, _ := point.Attributes.Value(attrDBOperation)
+		counts[op.AsString()] = point.Value
+		if op.AsString() == "Get" {
+			assert.Equal(t, getAttrs, point.Attributes)
+		}
+	}
+	assert.Equal(t, map[string]int64{"Get": 2, "IsInitialized": 1}, counts)
+
+	duration, ok := metrics[metricOperationDuration].Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	assert.Len(t, duration.DataPoints, 2)
+
+	documents, ok := metrics[metricDocuments].Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, documents.DataPoints, 1) // IsInitialized does not count documents
+	assert.Equal(t, int64(0), documents.DataPoints[0].Value)
+}
@@ -2,6 +2,8 @@ package ldfirestore
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
@@ -10,6 +12,7 @@ import (
 	"cloud.google.com/go/firestore"
 	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
 	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
 	"github.com/launchdarkly/go-server-sdk-evaluation/v3/ldbuilders"
 	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
 	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
@@ -20,6 +23,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -186,6 +191,594 @@ func TestDataStoreSkipsAndLogsTooLargeItem(t *testing.T) {
 	})
 }
 
+func TestUpsertWithResult(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	ext, ok := store.(ExtendedDataStore)
+	require.True(t, ok)
+
+	makeItem := func(version int) ldstoretypes.SerializedItemDescriptor {
+		return ldstoretypes.SerializedItemDescriptor{Version: version, SerializedItem: []byte(`{"key":"flag1"}`)}
+	}
+
+	result, err := ext.UpsertWithResult(ldstoreimpl.Features(), "flag1", makeItem(1))
+	require.NoError(t, err)
+	assert.Equal(t, UpsertResult{Updated: true, PreviousVersion: -1}, result)
+
+	result, err = ext.UpsertWithResult(ldstoreimpl.Features(), "flag1", makeItem(3))
+	require.NoError(t, err)
+	assert.Equal(t, UpsertResult{Updated: true, PreviousVersion: 1}, result)
+
+	result, err = ext.UpsertWithResult(ldstoreimpl.Features(), "flag1", makeItem(2))
+	require.NoError(t, err)
+	assert.Equal(t, UpsertResult{Conflict: true, PreviousVersion: 3}, result)
+}
+
+func TestDelete(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	ext := store.(ExtendedDataStore)
+
+	item := ldstoretypes.SerializedItemDescriptor{Version: 2, SerializedItem: []byte(`{"key":"flag1"}`)}
+	_, err = store.Upsert(ldstoreimpl.Features(), "flag1", item)
+	require.NoError(t, err)
+
+	deleted, err := ext.Delete(ldstoreimpl.Features(), "flag1", ldvalue.NewOptionalInt(1))
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	deleted, err = ext.Delete(ldstoreimpl.Features(), "flag1", ldvalue.NewOptionalInt(2))
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	result, err := store.Get(ldstoreimpl.Features(), "flag1")
+	require.NoError(t, err)
+	assert.Equal(t, -1, result.Version)
+
+	deleted, err = ext.Delete(ldstoreimpl.Features(), "flag1", ldvalue.OptionalInt{})
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+func TestDeleteAll(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	ext := store.(ExtendedDataStore)
+
+	var flags []ldstoretypes.KeyedSerializedItemDescriptor
+	for i := 0; i < deletePageSize+10; i++ {
+		flags = append(flags, ldstoretypes.KeyedSerializedItemDescriptor{
+			Key:  fmt.Sprintf("flag%d", i),
+			Item: ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{}`)},
+		})
+	}
+	segment := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{}`)}
+	require.NoError(t, store.Init([]ldstoretypes.SerializedCollection{
+		{Kind: ldstoreimpl.Features(), Items: flags},
+		{Kind: ldstoreimpl.Segments(), Items: []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "s1", Item: segment}}},
+	}))
+
+	var progress []int
+	deleted, err := ext.DeleteAll(ldstoreimpl.Features(), func(n int) { progress = append(progress, n) })
+	require.NoError(t, err)
+	assert.Equal(t, len(flags), deleted)
+	assert.Equal(t, []int{deletePageSize, len(flags)}, progress)
+
+	all, err := store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Len(t, all, 0)
+
+	segments, err := store.GetAll(ldstoreimpl.Segments())
+	require.NoError(t, err)
+	assert.Len(t, segments, 1)
+}
+
+func TestTouch(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	ext := store.(ExtendedDataStore)
+
+	for _, key := range []string{"flag1", "flag2"} {
+		item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"` + key + `"}`)}
+		_, err = store.Upsert(ldstoreimpl.Features(), key, item)
+		require.NoError(t, err)
+	}
+
+	touched, err := ext.Touch(ldstoreimpl.Features(), "flag1")
+	require.NoError(t, err)
+	assert.True(t, touched)
+
+	touched, err = ext.Touch(ldstoreimpl.Features(), "unknown")
+	require.NoError(t, err)
+	assert.False(t, touched)
+
+	count, err := ext.TouchAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	item, err := store.Get(ldstoreimpl.Features(), "flag2")
+	require.NoError(t, err)
+	assert.Equal(t, 1, item.Version)
+}
+
+func TestLastUpdated(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	before := time.Now().Add(-time.Minute)
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)}
+	require.NoError(t, store.Init([]ldstoretypes.SerializedCollection{
+		{Kind: ldstoreimpl.Segments(), Items: []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "s1", Item: item}}},
+	}))
+	_, err = store.Upsert(ldstoreimpl.Features(), "flag1", item)
+	require.NoError(t, err)
+
+	fs := store.(*firestoreDataStore)
+	for _, ref := range []*firestore.DocumentRef{
+		fs.docRef(ldstoreimpl.Segments(), "s1"),
+		fs.docRef(ldstoreimpl.Features(), "flag1"),
+	} {
+		doc, err := ref.Get(context.Background())
+		require.NoError(t, err)
+		lastUpdated, ok := doc.Data()[fieldLastUpdated].(time.Time)
+		require.True(t, ok)
+		assert.True(t, lastUpdated.After(before))
+	}
+}
+
+func TestParallelGetAll(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("flag%02d", i)
+		item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"` + key + `"}`)}
+		_, err = store.Upsert(ldstoreimpl.Features(), key, item)
+		require.NoError(t, err)
+	}
+	expected, err := store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	require.Len(t, expected, 50)
+
+	parallelStore, err := baseDataStoreBuilder().CollectionGroupQueries(true).ParallelGetAll(4).Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = parallelStore.Close() }()
+	actual, err := parallelStore.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	segments, err := parallelStore.GetAll(ldstoreimpl.Segments())
+	require.NoError(t, err)
+	assert.Len(t, segments, 0)
+}
+
+func TestPagedReads(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := baseDataStoreBuilder().GetAllPageSize(3).Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	var expected []ldstoretypes.KeyedSerializedItemDescriptor
+	for i := 0; i < 7; i++ {
+		key := fmt.Sprintf("flag%d", i)
+		item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"` + key + `"}`)}
+		_, err = store.Upsert(ldstoreimpl.Features(), key, item)
+		require.NoError(t, err)
+		expected = append(expected, ldstoretypes.KeyedSerializedItemDescriptor{Key: key, Item: item})
+	}
+
+	all, err := store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, expected, all)
+
+	var pageSizes []int
+	err = store.(ExtendedDataStore).ForEachItem(ldstoreimpl.Features(), 4,
+		func(items []ldstoretypes.KeyedSerializedItemDescriptor) error {
+			pageSizes = append(pageSizes, len(items))
+			return nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, []int{4, 3}, pageSizes)
+
+	stop := errors.New("stop")
+	err = store.(ExtendedDataStore).ForEachItem(ldstoreimpl.Features(), 4,
+		func([]ldstoretypes.KeyedSerializedItemDescriptor) error { return stop })
+	assert.Equal(t, stop, err)
+}
+
+func TestConsistentGetAll(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	for i := 0; i < 7; i++ {
+		key := fmt.Sprintf("flag%d", i)
+		item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"` + key + `"}`)}
+		_, err = store.Upsert(ldstoreimpl.Features(), key, item)
+		require.NoError(t, err)
+	}
+	expected, err := store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+
+	for name, builder := range map[string]*StoreBuilder[subsystems.PersistentDataStore]{
+		"single query": baseDataStoreBuilder(),
+		"paged":        baseDataStoreBuilder().GetAllPageSize(3),
+		"parallel":     baseDataStoreBuilder().CollectionGroupQueries(true).ParallelGetAll(2),
+	} {
+		t.Run(name, func(t *testing.T) {
+			consistentStore, err := builder.ConsistentGetAll(true).Build(subsystems.BasicClientContext{})
+			require.NoError(t, err)
+			defer func() { _ = consistentStore.Close() }()
+
+			actual, err := consistentStore.GetAll(ldstoreimpl.Features())
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestGetAllVersions(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	for i, version := range []int{3, 5, 7} {
+		key := fmt.Sprintf("flag%d", i)
+		item := ldstoretypes.SerializedItemDescriptor{Version: version, SerializedItem: []byte(`{"key":"` + key + `"}`)}
+		_, err = store.Upsert(ldstoreimpl.Features(), key, item)
+		require.NoError(t, err)
+	}
+	deleted := ldstoretypes.SerializedItemDescriptor{Version: 8, Deleted: true,
+		SerializedItem: []byte(`{"key":"flag2","deleted":true}`)}
+	_, err = store.Upsert(ldstoreimpl.Features(), "flag2", deleted)
+	require.NoError(t, err)
+
+	versions, err := store.(ExtendedDataStore).GetAllVersions(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"flag0": 3, "flag1": 5, "flag2": 8}, versions)
+
+	versions, err = store.(ExtendedDataStore).GetAllVersions(ldstoreimpl.Segments())
+	require.NoError(t, err)
+	assert.Len(t, versions, 0)
+}
+
+func TestGetMany(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	ext := store.(ExtendedDataStore)
+
+	var keys []string
+	for i := 0; i < getManyBatchSize+5; i++ {
+		key := fmt.Sprintf("flag%03d", i)
+		keys = append(keys, key)
+		if i%2 == 0 {
+			item := ldstoretypes.SerializedItemDescriptor{Version: i, SerializedItem: []byte(`{"key":"` + key + `"}`)}
+			_, err = store.Upsert(ldstoreimpl.Features(), key, item)
+			require.NoError(t, err)
+		}
+	}
+
+	items, err := ext.GetMany(ldstoreimpl.Features(), keys)
+	require.NoError(t, err)
+	require.Len(t, items, len(keys))
+	for i, item := range items {
+		if i%2 == 0 {
+			assert.Equal(t, i, item.Version)
+			assert.Equal(t, `{"key":"`+keys[i]+`"}`, string(item.SerializedItem))
+		} else {
+			assert.Equal(t, ldstoretypes.SerializedItemDescriptor{}.NotFound(), item)
+		}
+	}
+
+	items, err = ext.GetMany(ldstoreimpl.Features(), nil)
+	require.NoError(t, err)
+	assert.Len(t, items, 0)
+}
+
+func TestParallelGetAllFallsBackWithoutIndex(t *testing.T) {
+	mockLog := ldlogtest.NewMockLog()
+	store := &firestoreDataStore{loggers: mockLog.Loggers, getAllConcurrency: 4, collectionGroup: true}
+	assert.True(t, store.usePartitionedGetAll())
+
+	err := status.Error(codes.FailedPrecondition, "The query requires an index")
+	store.disablePartitionedGetAll(err)
+	store.disablePartitionedGetAll(err)
+	assert.False(t, store.usePartitionedGetAll())
+	assert.Len(t, mockLog.GetOutput(ldlog.Warn), 1)
+
+	assert.False(t, (&firestoreDataStore{getAllConcurrency: 1, collectionGroup: true}).usePartitionedGetAll())
+	assert.False(t, (&firestoreDataStore{getAllConcurrency: 4}).usePartitionedGetAll())
+}
+
+type customDataKind struct{ name string }
+
+func (k customDataKind) GetName() string { return k.name }
+func (k customDataKind) String() string  { return k.name }
+func (k customDataKind) Serialize(item ldstoretypes.ItemDescriptor) []byte {
+	return nil
+}
+func (k customDataKind) Deserialize(data []byte) (ldstoretypes.ItemDescriptor, error) {
+	return ldstoretypes.ItemDescriptor{}, nil
+}
+
+func TestKindRouting(t *testing.T) {
+	otherKind := customDataKind{name: "overrides"}
+
+	store, err := baseDataStoreBuilder().Prefix("p").
+		KindRoute(otherKind, "other-collection", "q").
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	impl := store.(*firestoreDataStore)
+
+	flagRef := impl.docRef(ldstoreimpl.Features(), "flag1")
+	assert.Equal(t, testCollectionName, flagRef.Parent.ID)
+	assert.Equal(t, "p:p:features:flag1", flagRef.ID)
+
+	otherRef := impl.docRef(otherKind, "item1")
+	assert.Equal(t, "other-collection", otherRef.Parent.ID)
+	assert.Equal(t, "q:q:overrides:item1", otherRef.ID)
+	assert.Equal(t, "q:overrides", impl.namespaceForKind(otherKind))
+
+	assert.Equal(t, "p:p:$inited:p:$inited", impl.initedDocID())
+}
+
+func TestParentDocument(t *testing.T) {
+	otherKind := customDataKind{name: "overrides"}
+
+	store, err := baseDataStoreBuilder().ParentDocument("/apps/myapp/").
+		KindRoute(otherKind, "other-collection", "").
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	impl := store.(*firestoreDataStore)
+
+	flagRef := impl.docRef(ldstoreimpl.Features(), "flag1")
+	assert.Equal(t, testCollectionName, flagRef.Parent.ID)
+	assert.Equal(t, "myapp", flagRef.Parent.Parent.ID)
+	assert.Equal(t, "apps", flagRef.Parent.Parent.Parent.ID)
+
+	otherRef := impl.docRef(otherKind, "item1")
+	assert.Equal(t, "other-collection", otherRef.Parent.ID)
+	assert.Equal(t, "myapp", otherRef.Parent.Parent.ID)
+}
+
+func TestParentDocumentTemplate(t *testing.T) {
+	otherKind := customDataKind{name: "overrides"}
+
+	store, err := baseDataStoreBuilder().Prefix("tenant1").ParentDocument("tenants/{prefix}").
+		KindRoute(otherKind, "", "shared").
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	impl := store.(*firestoreDataStore)
+
+	flagRef := impl.docRef(ldstoreimpl.Features(), "flag1")
+	assert.Equal(t, testCollectionName, flagRef.Parent.ID)
+	assert.Equal(t, "tenant1", flagRef.Parent.Parent.ID)
+	assert.Equal(t, "tenant1", impl.collectionRef(impl.collection).Parent.ID)
+
+	otherRef := impl.docRef(otherKind, "item1")
+	assert.Equal(t, "shared", otherRef.Parent.Parent.ID)
+
+	_, err = baseDataStoreBuilder().ParentDocument("tenants/{prefix}").Build(subsystems.BasicClientContext{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "requires a prefix")
+}
+
+func TestCollectionGroupQueries(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+
+	makeTenantStore := func(prefix string) subsystems.PersistentDataStore {
+		store, err := baseDataStoreBuilder().Prefix(prefix).ParentDocument("tenants/{prefix}").
+			CollectionGroupQueries(true).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = store.Close() })
+		return store
+	}
+	store1, store2 := makeTenantStore("tenant1"), makeTenantStore("tenant2")
+
+	item1 := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)}
+	item2 := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag2"}`)}
+	require.NoError(t, store1.Init([]ldstoretypes.SerializedCollection{
+		{Kind: ldstoreimpl.Features(), Items: []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "flag1", Item: item1}}},
+	}))
+	require.NoError(t, store2.Init([]ldstoretypes.SerializedCollection{
+		{Kind: ldstoreimpl.Features(), Items: []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "flag2", Item: item2}}},
+	}))
+
+	flags, err := store1.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "flag1", Item: item1}}, flags)
+
+	flags, err = store2.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "flag2", Item: item2}}, flags)
+
+	// Bulk operations find the same documents as GetAll
+	touched, err := store1.(ExtendedDataStore).TouchAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, 1, touched)
+	deleted, err := store1.(ExtendedDataStore).DeleteAll(ldstoreimpl.Features(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	flags, err = store2.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Len(t, flags, 1)
+}
+
+func TestExcludeDeletedFromGetAll(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := baseDataStoreBuilder().ExcludeDeletedFromGetAll(true).Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	live := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)}
+	deleted := ldstoretypes.SerializedItemDescriptor{Version: 2, Deleted: true,
+		SerializedItem: []byte(`{"key":"flag2","deleted":true}`)}
+	require.NoError(t, store.Init([]ldstoretypes.SerializedCollection{
+		{Kind: ldstoreimpl.Features(), Items: []ldstoretypes.KeyedSerializedItemDescriptor{
+			{Key: "flag1", Item: live},
+			{Key: "flag2", Item: deleted},
+		}},
+	}))
+
+	all, err := store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "flag1", Item: live}}, all)
+
+	tombstone, err := store.Get(ldstoreimpl.Features(), "flag2")
+	require.NoError(t, err)
+	assert.Equal(t, 2, tombstone.Version)
+}
+
+func TestExcludeDeletedFromGetAllWithDifferentialInit(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	// A document written before the deleted field existed
+	client, err := createTestClient()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+	_, err = client.Collection(testCollectionName).Doc(makeTestDocID("", ldstoreimpl.Features().GetName(), "flag1")).
+		Set(context.Background(), map[string]any{
+			fieldNamespace:     ldstoreimpl.Features().GetName(),
+			fieldKey:           "flag1",
+			fieldVersion:       1,
+			fieldItem:          `{"key":"flag1"}`,
+			fieldSchemaVersion: currentSchemaVersion,
+			fieldLastUpdated:   firestore.ServerTimestamp,
+		})
+	require.NoError(t, err)
+
+	store, err := baseDataStoreBuilder().ExcludeDeletedFromGetAll(true).DifferentialInit(true).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	live := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)}
+	require.NoError(t, store.Init([]ldstoretypes.SerializedCollection{
+		{Kind: ldstoreimpl.Features(), Items: []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "flag1", Item: live}}},
+	}))
+	assert.Equal(t, InitReport{ItemsWritten: 1}, store.(ExtendedDataStore).LastInitReport())
+
+	all, err := store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "flag1", Item: live}}, all)
+}
+
+func TestPreserveExistingItems(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := baseDataStoreBuilder().PreserveExistingItems(true).Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	item1 := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)}
+	item2 := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag2"}`)}
+	require.NoError(t, store.Init([]ldstoretypes.SerializedCollection{
+		{Kind: ldstoreimpl.Features(), Items: []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "flag1", Item: item1}}},
+	}))
+	require.NoError(t, store.Init([]ldstoretypes.SerializedCollection{
+		{Kind: ldstoreimpl.Features(), Items: []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "flag2", Item: item2}}},
+	}))
+
+	all, err := store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []ldstoretypes.KeyedSerializedItemDescriptor{
+		{Key: "flag1", Item: item1},
+		{Key: "flag2", Item: item2},
+	}, all)
+}
+
+func TestCustomDataKind(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	otherKind := customDataKind{name: "overrides"}
+	store, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"item1"}`)}
+	require.NoError(t, store.Init([]ldstoretypes.SerializedCollection{
+		{Kind: otherKind, Items: []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "item1", Item: item}}},
+	}))
+
+	all, err := store.GetAll(otherKind)
+	require.NoError(t, err)
+	assert.Equal(t, []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "item1", Item: item}}, all)
+
+	flags, err := store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Len(t, flags, 0)
+}
+
 func baseDataStoreBuilder() *StoreBuilder[subsystems.PersistentDataStore] {
 	return DataStore(testProjectID, testCollectionName).ClientOptions(makeTestOptions()...)
 }
@@ -0,0 +1,78 @@
+package ldfirestore
+
+import (
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ThrottleStats describes how often Firestore has rejected the store's requests for exceeding a
+// quota. See [ExtendedDataStore.ThrottleStats].
+type ThrottleStats struct {
+	// ThrottledRequests is the number of failed requests or document writes that Firestore rejected
+	// with RESOURCE_EXHAUSTED.
+	ThrottledRequests int
+
+	// LastRetryDelay is the retry delay that Firestore suggested in the most recent such error, or
+	// zero if it did not suggest one.
+	LastRetryDelay time.Duration
+
+	// LastThrottledAt is the time of the most recent such error, or the zero time if there has not
+	// been one.
+	LastThrottledAt time.Time
+}
+
+// serverRetryDelay returns the retry delay that Firestore attached to a RESOURCE_EXHAUSTED error, if
+// any.
+func serverRetryDelay(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.ResourceExhausted {
+		return 0, false
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+			if delay := info.GetRetryDelay().AsDuration(); delay > 0 {
+				return delay, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// recordThrottle updates the throttling statistics if err is a RESOURCE_EXHAUSTED error. It returns
+// the retry delay suggested by Firestore, or zero if there was none.
+func (store *firestoreDataStore) recordThrottle(err error) time.Duration {
+	if status.Code(err) != codes.ResourceExhausted {
+		return 0
+	}
+	delay, _ := serverRetryDelay(err)
+
+	store.lock.Lock()
+	store.throttleStats.ThrottledRequests++
+	store.throttleStats.LastRetryDelay = delay
+	store.throttleStats.LastThrottledAt = store.now()
+	store.lock.Unlock()
+
+	return delay
+}
+
+// recordThrottles calls recordThrottle for each failure, and returns the longest suggested delay.
+func (store *firestoreDataStore) recordThrottles(failures []operationFailure) time.Duration {
+	var longest time.Duration
+	for _, f := range failures {
+		longest = max(longest, store.recordThrottle(f.err))
+	}
+	if longest > 0 {
+		store.loggers.Warnf("Firestore quota exceeded for collection %q; Firestore asked for a retry delay of %s",
+			store.collection, longest)
+	}
+	return longest
+}
+
+func (store *firestoreDataStore) ThrottleStats() ThrottleStats {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	return store.throttleStats
+}
@@ -0,0 +1,58 @@
+package ldfirestore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func makeQuotaError(t *testing.T, delay time.Duration) error {
+	st, err := status.New(codes.ResourceExhausted, "quota exceeded").
+		WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	require.NoError(t, err)
+	return st.Err()
+}
+
+func TestServerRetryDelay(t *testing.T) {
+	delay, ok := serverRetryDelay(makeQuotaError(t, 3*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, delay)
+
+	_, ok = serverRetryDelay(status.Error(codes.ResourceExhausted, "no details"))
+	assert.False(t, ok)
+
+	_, ok = serverRetryDelay(status.Error(codes.Unavailable, "x"))
+	assert.False(t, ok)
+
+	_, ok = serverRetryDelay(errors.New("not a gRPC error"))
+	assert.False(t, ok)
+}
+
+func TestThrottleStats(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := &firestoreDataStore{loggers: ldlogtest.NewMockLog().Loggers, clock: fixedClock(now)}
+	assert.Equal(t, ThrottleStats{}, store.ThrottleStats())
+
+	assert.Equal(t, time.Duration(0), store.recordThrottle(status.Error(codes.Unavailable, "x")))
+	assert.Equal(t, ThrottleStats{}, store.ThrottleStats())
+
+	retryAfter := store.recordThrottles([]operationFailure{
+		{err: makeQuotaError(t, time.Second)},
+		{err: makeQuotaError(t, 5*time.Second)},
+		{err: status.Error(codes.Internal, "x")},
+	})
+	assert.Equal(t, 5*time.Second, retryAfter)
+	assert.Equal(t, ThrottleStats{
+		ThrottledRequests: 2,
+		LastRetryDelay:    5 * time.Second,
+		LastThrottledAt:   now,
+	}, store.ThrottleStats())
+}
@@ -0,0 +1,38 @@
+package ldfirestore
+
+import (
+	"errors"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// fieldExpireAt is the time after which a Firestore TTL policy may delete a document. It is only
+// written if [StoreBuilder.ExpireAfter] is set.
+const fieldExpireAt = "expireAt"
+
+func validateExpireAfter(d time.Duration) error {
+	if d < 0 {
+		return errors.New("ExpireAfter duration must not be negative")
+	}
+	return nil
+}
+
+// addExpiry adds the expiration time to the fields of a document that is being written, if the store
+// is configured with one.
+func (store *firestoreDataStore) addExpiry(data map[string]any) map[string]any {
+	if store.expireAfter > 0 {
+		data[fieldExpireAt] = store.now().Add(store.expireAfter)
+	}
+	return data
+}
+
+// touchUpdates returns the updates that Touch and TouchAll make to each document. If the store is
+// configured with an expiration time, touching a document also extends it.
+func (store *firestoreDataStore) touchUpdates(now time.Time) []firestore.Update {
+	updates := []firestore.Update{{Path: fieldUpdatedAt, Value: now}}
+	if store.expireAfter > 0 {
+		updates = append(updates, firestore.Update{Path: fieldExpireAt, Value: now.Add(store.expireAfter)})
+	}
+	return updates
+}
@@ -0,0 +1,50 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpireAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)}
+
+	t.Run("expiration time is written", func(t *testing.T) {
+		client, err := createTestClient()
+		require.NoError(t, err)
+		defer func() { _ = client.Close() }()
+		store := &firestoreDataStore{
+			client:      client,
+			collection:  "my-collection",
+			clock:       fixedClock(now),
+			expireAfter: 24 * time.Hour,
+		}
+
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", item)
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(24*time.Hour), data[fieldExpireAt])
+
+		assert.Equal(t, now.Add(24*time.Hour), store.initedOperation().data[fieldExpireAt])
+		assert.Equal(t, now.Add(24*time.Hour),
+			store.summaryOperation(ldstoreimpl.Features(), NamespaceSummary{}).data[fieldExpireAt])
+
+		assert.Equal(t, []firestore.Update{
+			{Path: fieldUpdatedAt, Value: now},
+			{Path: fieldExpireAt, Value: now.Add(24 * time.Hour)},
+		}, store.touchUpdates(now))
+	})
+
+	t.Run("no expiration time by default", func(t *testing.T) {
+		store := &firestoreDataStore{clock: fixedClock(now)}
+		data, err := store.encodeItem(ldstoreimpl.Features(), "flag1", item)
+		require.NoError(t, err)
+		assert.NotContains(t, data, fieldExpireAt)
+		assert.Equal(t, []firestore.Update{{Path: fieldUpdatedAt, Value: now}}, store.touchUpdates(now))
+	})
+}
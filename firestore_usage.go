@@ -0,0 +1,102 @@
+package ldfirestore
+
+import (
+	"maps"
+)
+
+// DocumentOperations counts the document operations that Firestore bills for. See [UsageStats].
+type DocumentOperations struct {
+	// Reads is the number of documents read. Firestore bills a query that returns no documents as
+	// one read, and so does this count.
+	Reads int
+
+	// Writes is the number of documents created or updated.
+	Writes int
+
+	// Deletes is the number of documents deleted.
+	Deletes int
+}
+
+func (d DocumentOperations) add(other DocumentOperations) DocumentOperations {
+	return DocumentOperations{
+		Reads:   d.Reads + other.Reads,
+		Writes:  d.Writes + other.Writes,
+		Deletes: d.Deletes + other.Deletes,
+	}
+}
+
+// UsageStats describes the billable document operations that the store has performed since it was
+// created, so that the cost of the store's use of Firestore can be attributed and forecast. See
+// [ExtendedDataStore.Usage].
+//
+// The counts are the store's own estimate, based on the documents that each request read or
+// wrote. Requests that were retried (see [StoreBuilder.Retry]) and the background retries of Init
+// writes (see [StoreBuilder.InitRetry]) are counted once, and reads of the documents that hold
+// namespace summaries and manifests within a transaction are not counted, so Firestore's own usage
+// figures may be somewhat higher.
+type UsageStats struct {
+	// Total is the sum of all the operations in ByOperation.
+	Total DocumentOperations
+
+	// ByOperation breaks the counts down by the name of the store method that performed them, such
+	// as "Get", "GetAll", "Upsert", or "Init". UpsertWithResult is counted as "Upsert".
+	ByOperation map[string]DocumentOperations
+}
+
+// queryReads returns the number of reads that Firestore bills for a query that returned n documents.
+func queryReads(n int) int {
+	return max(n, 1)
+}
+
+// countUsage records billable document operations in the UsageStats.
+func (store *firestoreDataStore) countUsage(operation string, usage DocumentOperations) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.usage == nil {
+		store.usage = make(map[string]DocumentOperations)
+	}
+	store.usage[operation] = store.usage[operation].add(usage)
+}
+
+// operationsUsage returns the billable document operations of the operations that did not fail.
+func operationsUsage(operations []firestoreOperation, failed map[int]bool) DocumentOperations {
+	var usage DocumentOperations
+	for i, op := range operations {
+		if failed[i] {
+			continue
+		}
+		if _, isDelete := op.(deleteOperation); isDelete {
+			usage.Deletes++
+		} else {
+			usage.Writes++
+		}
+	}
+	return usage
+}
+
+// metadataWrites returns the number of summary and manifest documents that are written along with
+// an item.
+func (store *firestoreDataStore) metadataWrites() int {
+	n := 0
+	if store.namespaceSummaries {
+		n++
+	}
+	if store.namespaceManifests {
+		n++
+	}
+	return n
+}
+
+func (store *firestoreDataStore) Usage() UsageStats {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	stats := UsageStats{ByOperation: maps.Clone(store.usage)}
+	if stats.ByOperation == nil {
+		stats.ByOperation = make(map[string]DocumentOperations)
+	}
+	for _, usage := range store.usage {
+		stats.Total = stats.Total.add(usage)
+	}
+	return stats
+}
@@ -0,0 +1,71 @@
+package ldfirestore
+
+import (
+	"testing"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageStats(t *testing.T) {
+	store := &firestoreDataStore{}
+	assert.Equal(t, UsageStats{ByOperation: map[string]DocumentOperations{}}, store.Usage())
+
+	store.countUsage("Get", DocumentOperations{Reads: 1})
+	store.countUsage("Get", DocumentOperations{Reads: 1})
+	store.countUsage("Init", operationsUsage([]firestoreOperation{
+		setOperation{}, setOperation{}, deleteOperation{}, deleteOperation{},
+	}, map[int]bool{1: true}))
+
+	usage := store.Usage()
+	assert.Equal(t, DocumentOperations{Reads: 2, Writes: 1, Deletes: 2}, usage.Total)
+	assert.Equal(t, map[string]DocumentOperations{
+		"Get":  {Reads: 2},
+		"Init": {Writes: 1, Deletes: 2},
+	}, usage.ByOperation)
+
+	// the snapshot is not changed by later operations
+	store.countUsage("Get", DocumentOperations{Reads: 1})
+	assert.Equal(t, DocumentOperations{Reads: 2}, usage.ByOperation["Get"])
+
+	assert.Equal(t, 1, queryReads(0))
+	assert.Equal(t, 5, queryReads(5))
+}
+
+func TestUsage(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	store, err := baseDataStoreBuilder().Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	ext := store.(ExtendedDataStore)
+
+	flag := func(version int) ldstoretypes.SerializedItemDescriptor {
+		return ldstoretypes.SerializedItemDescriptor{Version: version, SerializedItem: []byte(`{}`)}
+	}
+	require.NoError(t, store.Init([]ldstoretypes.SerializedCollection{
+		{Kind: ldstoreimpl.Features(), Items: []ldstoretypes.KeyedSerializedItemDescriptor{
+			{Key: "flag1", Item: flag(1)},
+			{Key: "flag2", Item: flag(1)},
+		}},
+	}))
+	_, err = store.Upsert(ldstoreimpl.Features(), "flag1", flag(2))
+	require.NoError(t, err)
+	_, err = store.Get(ldstoreimpl.Features(), "flag3")
+	require.NoError(t, err)
+	_, err = store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+
+	usage := ext.Usage()
+	assert.Equal(t, DocumentOperations{Reads: 1, Writes: 3}, usage.ByOperation["Init"])
+	assert.Equal(t, DocumentOperations{Reads: 1, Writes: 1}, usage.ByOperation["Upsert"])
+	assert.Equal(t, DocumentOperations{Reads: 1}, usage.ByOperation["Get"])
+	assert.Equal(t, DocumentOperations{Reads: 2}, usage.ByOperation["GetAll"])
+	assert.Equal(t, DocumentOperations{Reads: 5, Writes: 4}, usage.Total)
+}
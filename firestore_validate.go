@@ -0,0 +1,187 @@
+package ldfirestore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+)
+
+// validateProbeTimeout is the timeout for the connectivity check in Validate, if no operation
+// timeout was configured.
+const validateProbeTimeout = 10 * time.Second
+
+// Validate checks the builder's configuration and reports everything that is wrong with it, so that
+// problems can be found before the builder is passed to the SDK, which only logs the first error from
+// Build. The returned error joins one error for each problem (see [errors.Join]); it is nil if there
+// are none.
+//
+// Besides the conditions that make Build fail, Validate reports options that would be silently
+// ignored, such as ClientOptions together with FirestoreClient. If probe is true and the configuration
+// is otherwise valid, Validate also connects to Firestore and reads from the collection, using the
+// same client options that Build would use.
+//
+//	builder := ldfirestore.DataStore("my-project", "launchdarkly").Prefix("prod")
+//	if err := builder.Validate(true); err != nil {
+//		log.Fatalf("invalid Firestore configuration:\n%s", err)
+//	}
+func (b *StoreBuilder[T]) Validate(probe bool) error {
+	_, bigSegments := any(b).(*StoreBuilder[subsystems.BigSegmentStore])
+	var errs []error
+	if b.client == nil && b.sharedClient == nil && b.projectID == "" {
+		errs = append(errs, errors.New("project ID is required"))
+	}
+	if bigSegments {
+		errs = append(errs, b.validateBigSegmentStore()...)
+	} else {
+		errs = append(errs, b.validate()...)
+	}
+	if strings.Contains(b.collection, "/") {
+		errs = append(errs, fmt.Errorf("collection name %q must not contain \"/\"; use ParentDocument "+
+			"to nest the collection under a document", b.collection))
+	}
+	if b.client != nil && b.sharedClient != nil {
+		errs = append(errs, errors.New("FirestoreClient and SharedClient cannot both be specified"))
+	}
+	providedClient := b.client != nil || b.sharedClient != nil
+	if providedClient && len(b.clientOptions) > 0 {
+		errs = append(errs, errors.New("ClientOptions has no effect when FirestoreClient or SharedClient is specified"))
+	}
+	if providedClient && b.databaseID != "" {
+		errs = append(errs, errors.New("DatabaseID has no effect when FirestoreClient or SharedClient is specified"))
+	}
+	if strings.Contains(b.prefix, "/") && b.docIDs.escaping == DocumentIDEscapingNone && !b.docIDs.hashed {
+		errs = append(errs, fmt.Errorf("prefix %q must not contain \"/\" unless document IDs are escaped; "+
+			"see DocumentIDFormat", b.prefix))
+	}
+	if !bigSegments && b.getAllConcurrency > 1 && !b.collectionGroup {
+		errs = append(errs, errors.New("ParallelGetAll has no effect unless CollectionGroupQueries is enabled"))
+	}
+	if providedClient && b.connPoolSize > 0 {
+		errs = append(errs, errors.New("ConnectionPoolSize has no effect when FirestoreClient or SharedClient "+
+			"is specified"))
+	}
+
+	if probe && len(errs) == 0 {
+		if err := b.probe(bigSegments); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateClient checks the options that every kind of store uses to find its collection and to
+// create its client. The parent document is checked against each of the given prefixes.
+func (b builderOptions) validateClient(prefixes []string) []error {
+	var errs []error
+	if b.collection == "" {
+		errs = append(errs, errors.New("collection name is required"))
+	}
+	if parentDoc, err := normalizeParentDocument(b.parentDocument); err != nil {
+		errs = append(errs, err)
+	} else {
+		for _, prefix := range prefixes {
+			if err := validateParentDocumentPrefix(parentDoc, prefix); err != nil {
+				errs = append(errs, err)
+				break
+			}
+		}
+	}
+	if err := b.validateCredentials(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := b.docIDs.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if b.privateEndpoint != nil {
+		if err := b.privateEndpoint.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := validateConnectionPoolSize(b.connPoolSize); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// validate checks the options of a data store, and returns an error for each problem that would make
+// Build fail. It is used both by Build and by Validate, so that they cannot disagree.
+func (b builderOptions) validate() []error {
+	errs := b.validateClient(b.allPrefixes())
+	for _, err := range []error{
+		validateDocumentLabels(b.labels),
+		validateCompression(b.compression),
+		b.validateStructuredItems(),
+		b.validateCodec(),
+		validateChecksum(b.checksum),
+		validateExpireAfter(b.expireAfter),
+		b.validateGetAllPaging(),
+		b.validateAtomicInit(),
+		b.validatePreconditionUpserts(),
+		b.validateDifferentialInit(),
+		validateItemCache(b.itemCache),
+		validateSnapshotFallback(b.snapshotFallback),
+		validateOutageBuffer(b.outageBuffer),
+		b.validateOverflowStore(),
+		b.validateAuditTrail(),
+	} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// validateBigSegmentStore is like validate, but for a Big Segment store.
+func (b builderOptions) validateBigSegmentStore() []error {
+	errs := b.validateClient([]string{b.prefix})
+	if err := validateMembershipCache(b.membershipCache); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// probe connects to Firestore with the builder's options and performs the same check as
+// IsStoreAvailable, with a store of the kind that the builder creates. Options that would make the
+// store write to Firestore, start background work, or use the process-wide client are turned off.
+func (b *StoreBuilder[T]) probe(bigSegments bool) error {
+	storeOptions := b.builderOptions
+	storeOptions.writerLeaseDuration = 0
+	storeOptions.rpcLogSampleRate = 0
+	storeOptions.availabilityGrace = 0
+	storeOptions.onChange = nil
+	storeOptions.itemCache = ItemCacheOptions{}
+	storeOptions.snapshotFallback = SnapshotFallbackOptions{}
+	storeOptions.outageBuffer = OutageBufferOptions{}
+	storeOptions.preflight = false
+	storeOptions.coldStart = false
+	storeOptions.watchMetadata = false
+	storeOptions.membershipCache = MembershipCacheOptions{}
+	if storeOptions.effectiveOperationTimeout() == 0 {
+		storeOptions.operationTimeout = validateProbeTimeout
+	}
+
+	var err error
+	if bigSegments {
+		var store *firestoreBigSegmentStoreImpl
+		if store, err = newFirestoreBigSegmentStoreImpl(storeOptions, ldlog.NewDisabledLoggers()); err != nil {
+			return err
+		}
+		defer func() { _ = store.Close() }()
+		err = store.checkConnection(store.operationTimeout)
+	} else {
+		var store *firestoreDataStore
+		if store, err = newFirestoreDataStoreImpl(storeOptions, ldlog.NewDisabledLoggers()); err != nil {
+			return err
+		}
+		defer func() { _ = store.Close() }()
+		err = store.checkConnection(store.operationTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("could not read from collection %q: %w", b.collection, err)
+	}
+	return nil
+}
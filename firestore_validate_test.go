@@ -0,0 +1,119 @@
+package ldfirestore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("valid configuration", func(t *testing.T) {
+		assert.NoError(t, DataStore("my-project", "my-collection").Prefix("p").Validate(false))
+		assert.NoError(t, BigSegmentStore("my-project", "my-collection").Validate(false))
+	})
+
+	t.Run("reports every problem", func(t *testing.T) {
+		err := DataStore("", "").
+			Prefix("a/b").
+			ParentDocument("apps").
+			CredentialsFile("/path/to/key.json").
+			CredentialsJSON([]byte("{}")).
+			DocumentIDFormat("/", DocumentIDEscapingNone).
+			Validate(false)
+		require.Error(t, err)
+		for _, message := range []string{
+			"project ID is required",
+			"collection name is required",
+			"prefix \"a/b\"",
+			"refers to a collection",
+			"only one of CredentialsFile and CredentialsJSON",
+			"document ID separator",
+		} {
+			assert.Contains(t, err.Error(), message)
+		}
+	})
+
+	t.Run("options ignored with FirestoreClient", func(t *testing.T) {
+		client, err := createTestClient()
+		require.NoError(t, err)
+		defer func() { _ = client.Close() }()
+
+		err = DataStore("", "my-collection").FirestoreClient(client).
+			ClientOptions(makeTestOptions()...).
+			DatabaseID("flags-db").
+			Validate(false)
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "project ID")
+		assert.Contains(t, err.Error(), "ClientOptions has no effect")
+		assert.Contains(t, err.Error(), "DatabaseID has no effect")
+	})
+
+	t.Run("ParallelGetAll without CollectionGroupQueries", func(t *testing.T) {
+		err := DataStore("my-project", "my-collection").ParallelGetAll(4).Validate(false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "ParallelGetAll has no effect")
+
+		assert.NoError(t, DataStore("my-project", "my-collection").ParallelGetAll(4).
+			CollectionGroupQueries(true).Validate(false))
+	})
+
+	t.Run("collection name with slash", func(t *testing.T) {
+		err := DataStore("my-project", "apps/myapp/flags").Validate(false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "ParentDocument")
+	})
+
+	t.Run("probe fails if Firestore cannot be reached", func(t *testing.T) {
+		client, err := createTestClient()
+		require.NoError(t, err)
+		_ = client.Close()
+
+		err = DataStore("my-project", "my-collection").FirestoreClient(client).Validate(true)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "could not read from collection")
+	})
+
+	t.Run("checks the options of the store type", func(t *testing.T) {
+		err := BigSegmentStore("my-project", "my-collection").
+			MembershipCache(MembershipCacheOptions{TTL: -1}).
+			Validate(false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "membership cache TTL")
+
+		assert.NoError(t, DataStore("my-project", "my-collection").
+			MembershipCache(MembershipCacheOptions{TTL: -1}).
+			Validate(false))
+		assert.NoError(t, BigSegmentStore("my-project", "my-collection").
+			ExpireAfter(-1).
+			Validate(false))
+	})
+
+	t.Run("probe does not run the preflight check", func(t *testing.T) {
+		client, err := createTestClient()
+		require.NoError(t, err)
+		_ = client.Close()
+
+		err = DataStore("my-project", "my-collection").FirestoreClient(client).PreflightCheck(true).Validate(true)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "could not read from collection")
+		assert.NotContains(t, err.Error(), "preflight")
+	})
+
+	t.Run("probe uses a Big Segment store", func(t *testing.T) {
+		client, err := createTestClient()
+		require.NoError(t, err)
+		_ = client.Close()
+
+		err = BigSegmentStore("my-project", "my-collection").FirestoreClient(client).WatchMetadata(true).Validate(true)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "could not read from collection")
+	})
+
+	t.Run("probe succeeds with emulator", func(t *testing.T) {
+		if !isEmulatorAvailable() {
+			t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+		}
+		assert.NoError(t, baseDataStoreBuilder().Validate(true))
+	})
+}
@@ -0,0 +1,64 @@
+package ldfirestore
+
+import (
+	"sync"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+// versionTracker remembers the highest version of each item that the store has read or written, so
+// that a read returning an older version can be reported. That should never happen with a single
+// writer, so it indicates competing writers or a replication problem. See
+// [StoreBuilder.VersionAnomalyDetection].
+type versionTracker struct {
+	versions  map[string]int
+	anomalies int
+	lock      sync.Mutex
+}
+
+func newVersionTracker() *versionTracker {
+	return &versionTracker{versions: make(map[string]int)}
+}
+
+// reset forgets every version, since Init replaces all of the data and may legitimately store older
+// versions than were there before.
+func (t *versionTracker) reset() {
+	t.lock.Lock()
+	t.versions = make(map[string]int)
+	t.lock.Unlock()
+}
+
+// record updates the highest known version for an item, and returns the previous highest version if
+// the new one is lower.
+func (t *versionTracker) record(namespace, key string, version int) (int, bool) {
+	id := namespace + ":" + key
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if seen, ok := t.versions[id]; ok && version < seen {
+		t.anomalies++
+		return seen, true
+	}
+	t.versions[id] = version
+	return 0, false
+}
+
+// observeVersion records the version of an item that was read or written, and logs a warning if it
+// is older than a version that was seen earlier.
+func (store *firestoreDataStore) observeVersion(kind ldstoretypes.DataKind, key string, version int) {
+	if store.versions == nil || version < 0 {
+		return
+	}
+	if seen, anomaly := store.versions.record(store.namespaceForKind(kind), key, version); anomaly {
+		store.loggers.Warnf("Read version %d of %s key %q, but version %d was seen earlier; this may mean "+
+			"that more than one writer is updating collection %q", version, kind, key, seen, store.collection)
+	}
+}
+
+func (store *firestoreDataStore) VersionAnomalies() int {
+	if store.versions == nil {
+		return 0
+	}
+	store.versions.lock.Lock()
+	defer store.versions.lock.Unlock()
+	return store.versions.anomalies
+}
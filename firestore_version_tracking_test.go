@@ -0,0 +1,54 @@
+package ldfirestore
+
+import (
+	"testing"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldlog"
+	"github.com/launchdarkly/go-sdk-common/v3/ldlogtest"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionAnomalyDetection(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := &firestoreDataStore{loggers: mockLog.Loggers}
+		store.observeVersion(ldstoreimpl.Features(), "flag1", 2)
+		store.observeVersion(ldstoreimpl.Features(), "flag1", 1)
+		assert.Equal(t, 0, store.VersionAnomalies())
+		assert.Len(t, mockLog.GetOutput(ldlog.Warn), 0)
+	})
+
+	t.Run("reports older version", func(t *testing.T) {
+		mockLog := ldlogtest.NewMockLog()
+		store := &firestoreDataStore{loggers: mockLog.Loggers, versions: newVersionTracker(), collection: "c"}
+		store.observeVersion(ldstoreimpl.Features(), "flag1", 2)
+		store.observeVersion(ldstoreimpl.Features(), "flag1", 3)
+		store.observeVersion(ldstoreimpl.Segments(), "flag1", 1) // different kind, so not an anomaly
+		assert.Equal(t, 0, store.VersionAnomalies())
+
+		store.observeVersion(ldstoreimpl.Features(), "flag1", 2)
+		assert.Equal(t, 1, store.VersionAnomalies())
+		mockLog.AssertMessageMatch(t, true, ldlog.Warn, `Read version 2 of features key "flag1", but version 3`)
+	})
+
+	t.Run("ignores missing items", func(t *testing.T) {
+		store := &firestoreDataStore{loggers: ldlogtest.NewMockLog().Loggers, versions: newVersionTracker()}
+		store.observeVersion(ldstoreimpl.Features(), "flag1", 2)
+		store.observeVersion(ldstoreimpl.Features(), "flag1", -1)
+		assert.Equal(t, 0, store.VersionAnomalies())
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		store := &firestoreDataStore{loggers: ldlogtest.NewMockLog().Loggers, versions: newVersionTracker()}
+		store.observeVersion(ldstoreimpl.Features(), "flag1", 2)
+		store.versions.reset()
+		store.observeVersion(ldstoreimpl.Features(), "flag1", 1)
+		assert.Equal(t, 0, store.VersionAnomalies())
+	})
+
+	t.Run("builder", func(t *testing.T) {
+		b := DataStore("my-project", "my-collection").VersionAnomalyDetection(true)
+		assert.True(t, b.versionAnomalies)
+	})
+}
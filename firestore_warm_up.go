@@ -0,0 +1,45 @@
+package ldfirestore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+)
+
+func (store *firestoreDataStore) WarmUp(ctx context.Context) error {
+	result := make(chan error, 1)
+	go func() { result <- store.warmUp() }()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// warmUp reads every flag and segment, and whether the store is initialized, putting the results in
+// the item cache if there is one.
+func (store *firestoreDataStore) warmUp() error {
+	total := 0
+	for _, kind := range ldstoreimpl.AllKinds() {
+		items, err := store.getAllItems(kind)
+		if err != nil {
+			return fmt.Errorf("failed to warm up %s: %w", kind, err)
+		}
+		namespace := store.namespaceForKind(kind)
+		now := store.now()
+		store.cache.putAll(namespace, items, now)
+		for _, item := range items {
+			store.cache.put(namespace, item.Key, item.Item, now)
+		}
+		total += len(items)
+	}
+	if !store.IsInitialized() {
+		store.loggers.Warnf("Warmed up %d item(s), but collection %q has not been initialized", total,
+			store.collection)
+		return nil
+	}
+	store.loggers.Infof("Warmed up %d item(s) from collection %q", total, store.collection)
+	return nil
+}
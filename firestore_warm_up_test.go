@@ -0,0 +1,54 @@
+package ldfirestore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmUpError(t *testing.T) {
+	store, err := makeFailedStore().Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	err = store.(ExtendedDataStore).WarmUp(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to warm up")
+}
+
+func TestWarmUp(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	writer, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = writer.Close() }()
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)}
+	require.NoError(t, writer.Init([]ldstoretypes.SerializedCollection{{
+		Kind:  ldstoreimpl.Features(),
+		Items: []ldstoretypes.KeyedSerializedItemDescriptor{{Key: "flag1", Item: item}},
+	}}))
+
+	store, err := baseDataStoreBuilder().ItemCache(ItemCacheOptions{TTL: time.Hour}).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+	ext := store.(ExtendedDataStore)
+
+	require.NoError(t, ext.WarmUp(context.Background()))
+
+	got, err := store.Get(ldstoreimpl.Features(), "flag1")
+	require.NoError(t, err)
+	assert.Equal(t, item, got)
+	_, err = store.GetAll(ldstoreimpl.Features())
+	require.NoError(t, err)
+	assert.Equal(t, ItemCacheStats{Hits: 2}, ext.ItemCacheStats())
+}
@@ -0,0 +1,122 @@
+package ldfirestore
+
+import (
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+)
+
+const (
+	watchInitialBackoff = time.Second
+	watchMaxBackoff     = 30 * time.Second
+)
+
+// ItemChange describes a change to an item in Firestore that was seen by a store with
+// [StoreBuilder.WatchChanges] enabled.
+type ItemChange struct {
+	// Kind is the data kind of the item, such as flags or segments.
+	Kind ldstoretypes.DataKind
+
+	// Key is the key of the item.
+	Key string
+
+	// Version is the version of the item after the change. It is -1 if the document was removed.
+	Version int
+
+	// Deleted is true if the item is now a placeholder for a deleted item.
+	Deleted bool
+
+	// Removed is true if the item's document was removed from Firestore, rather than replaced with a
+	// deleted-item placeholder.
+	Removed bool
+}
+
+// startWatching starts a snapshot listener for each data kind, which handles every change until the
+// store is closed.
+func (store *firestoreDataStore) startWatching() {
+	for _, kind := range ldstoreimpl.AllKinds() {
+		go store.watchKind(kind)
+	}
+}
+
+// watchKind listens for changes to the documents of one data kind. The first snapshot only reports
+// the documents that already exist, so it is not passed on. If the listener fails, it is restarted
+// with exponential backoff. Since changes may have been missed while it was not running, every
+// document in the first snapshot after a restart is reported as a change.
+func (store *firestoreDataStore) watchKind(kind ldstoretypes.DataKind) {
+	backoff := watchInitialBackoff
+	skipFirst := true
+	for {
+		received, err := store.listen(kind, skipFirst)
+		if store.context.Err() != nil {
+			return
+		}
+		if received {
+			skipFirst = false
+			backoff = watchInitialBackoff
+		}
+		store.loggers.Warnf("Snapshot listener for %s in collection %q failed; restarting in %s: %s",
+			kind, store.collection, backoff, err)
+		select {
+		case <-store.context.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, watchMaxBackoff)
+	}
+}
+
+// listen runs a snapshot listener until it fails, and returns the error along with whether any
+// snapshot was received. If skipFirst is true, the changes in the first snapshot are not reported.
+func (store *firestoreDataStore) listen(kind ldstoretypes.DataKind, skipFirst bool) (bool, error) {
+	iter := store.queryForKind(kind).Snapshots(store.context)
+	defer iter.Stop()
+
+	received := false
+	for {
+		snap, err := iter.Next()
+		if err != nil {
+			return received, err
+		}
+		if !received && skipFirst {
+			received = true
+			continue
+		}
+		received = true
+		for _, change := range snap.Changes {
+			store.handleChange(itemChangeFromData(kind, change.Kind, change.Doc.Data()))
+		}
+	}
+}
+
+// handleChange removes a changed item from the item cache, if it is invalidated on changes, and
+// calls the WatchChanges callback, if any.
+func (store *firestoreDataStore) handleChange(change ItemChange) {
+	if store.invalidateOnChange {
+		store.cache.invalidate(store.namespaceForKind(change.Kind), change.Key)
+	}
+	if store.onChange != nil {
+		store.onChange(change)
+	}
+}
+
+// itemChangeFromData returns the ItemChange for a document that was added, modified, or removed.
+func itemChangeFromData(
+	kind ldstoretypes.DataKind,
+	changeKind firestore.DocumentChangeKind,
+	data map[string]any,
+) ItemChange {
+	change := ItemChange{Kind: kind, Version: -1}
+	change.Key, _ = data[fieldKey].(string)
+	if changeKind == firestore.DocumentRemoved {
+		change.Removed = true
+		return change
+	}
+	if version, ok := data[fieldVersion].(int64); ok {
+		change.Version = int(version)
+	}
+	change.Deleted, _ = data[fieldDeleted].(bool)
+	return change
+}
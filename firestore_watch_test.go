@@ -0,0 +1,69 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemChangeFromData(t *testing.T) {
+	data := map[string]any{fieldKey: "flag1", fieldVersion: int64(3), fieldDeleted: false}
+	assert.Equal(t, ItemChange{Kind: ldstoreimpl.Features(), Key: "flag1", Version: 3},
+		itemChangeFromData(ldstoreimpl.Features(), firestore.DocumentModified, data))
+
+	data[fieldDeleted] = true
+	assert.Equal(t, ItemChange{Kind: ldstoreimpl.Features(), Key: "flag1", Version: 3, Deleted: true},
+		itemChangeFromData(ldstoreimpl.Features(), firestore.DocumentAdded, data))
+
+	assert.Equal(t, ItemChange{Kind: ldstoreimpl.Features(), Key: "flag1", Version: -1, Removed: true},
+		itemChangeFromData(ldstoreimpl.Features(), firestore.DocumentRemoved, data))
+}
+
+func TestWatchChanges(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	writer, err := makeTestStore("").Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = writer.Close() }()
+	item := func(key string, version int) ldstoretypes.SerializedItemDescriptor {
+		return ldstoretypes.SerializedItemDescriptor{Version: version, SerializedItem: []byte(`{"key":"` + key + `"}`)}
+	}
+	_, err = writer.Upsert(ldstoreimpl.Features(), "existing", item("existing", 1))
+	require.NoError(t, err)
+
+	changes := make(chan ItemChange, 10)
+	watcher, err := baseDataStoreBuilder().WatchChanges(func(c ItemChange) { changes <- c }).
+		Build(subsystems.BasicClientContext{})
+	require.NoError(t, err)
+	defer func() { _ = watcher.Close() }()
+	time.Sleep(500 * time.Millisecond) // let the listeners receive their first snapshots
+
+	_, err = writer.Upsert(ldstoreimpl.Features(), "flag1", item("flag1", 2))
+	require.NoError(t, err)
+	select {
+	case c := <-changes:
+		assert.Equal(t, ItemChange{Kind: ldstoreimpl.Features(), Key: "flag1", Version: 2}, c)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for change")
+	}
+
+	_, err = writer.(ExtendedDataStore).Delete(ldstoreimpl.Features(), "flag1", ldvalue.OptionalInt{})
+	require.NoError(t, err)
+	select {
+	case c := <-changes:
+		assert.Equal(t, ItemChange{Kind: ldstoreimpl.Features(), Key: "flag1", Version: -1, Removed: true}, c)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for change")
+	}
+	assert.Len(t, changes, 0)
+}
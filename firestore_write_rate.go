@@ -0,0 +1,16 @@
+package ldfirestore
+
+import "golang.org/x/time/rate"
+
+// writeLimiterBurst is the most writes that the limiter lets through at once. It matches the size of
+// the batches that the BulkWriter sends, so that limiting does not break up batches needlessly.
+const writeLimiterBurst = 20
+
+// newWriteLimiter returns a limiter for the given number of writes per second, or nil if there is
+// no limit.
+func newWriteLimiter(writesPerSecond int) *rate.Limiter {
+	if writesPerSecond <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(writesPerSecond), min(writesPerSecond, writeLimiterBurst))
+}
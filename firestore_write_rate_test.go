@@ -0,0 +1,27 @@
+package ldfirestore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestWriteLimiter(t *testing.T) {
+	assert.Nil(t, newWriteLimiter(0))
+	assert.Nil(t, newWriteLimiter(-1))
+
+	limiter := newWriteLimiter(5)
+	require.NotNil(t, limiter)
+	assert.Equal(t, rate.Limit(5), limiter.Limit())
+	assert.Equal(t, 5, limiter.Burst())
+
+	limiter = newWriteLimiter(1000)
+	require.NotNil(t, limiter)
+	assert.Equal(t, rate.Limit(1000), limiter.Limit())
+	assert.Equal(t, writeLimiterBurst, limiter.Burst())
+
+	b := DataStore("my-project", "my-collection").InitMaxWritesPerSecond(100)
+	assert.Equal(t, 100, b.initWriteRate)
+}
@@ -0,0 +1,151 @@
+package ldfirestore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Fields of the lease document used for writer election
+	fieldLeaseHolder    = "holder"
+	fieldLeaseExpiresAt = "expiresAt"
+)
+
+// writerElection keeps track of whether this store instance holds the writer lease.
+type writerElection struct {
+	instanceID    string
+	leaseDuration time.Duration
+	attempted     bool
+	leaseExpiry   time.Time // zero if we do not hold the lease
+}
+
+func newInstanceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (store *firestoreDataStore) leaseDocRef() *firestore.DocumentRef {
+	return store.collectionRef(store.collection).
+		Doc(store.ids.makeID(store.prefix, prefixedNamespace(store.prefix, "$lease"), "writer"))
+}
+
+// isWriter returns true if this store should perform writes. If writer election is not enabled, this
+// is always true. Otherwise, it is true only while this instance holds an unexpired lease.
+func (store *firestoreDataStore) isWriter() bool {
+	if store.election == nil {
+		return true
+	}
+
+	store.lock.Lock()
+	attempted := store.election.attempted
+	store.lock.Unlock()
+	if !attempted {
+		// Find out right away, rather than skipping writes until the background renewal runs
+		store.tryAcquireLease(store.context)
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	return store.now().Before(store.election.leaseExpiry)
+}
+
+// tryAcquireLease acquires or renews the writer lease if it is free, expired, or already ours.
+func (store *firestoreDataStore) tryAcquireLease(ctx context.Context) {
+	election := store.election
+	leaseRef := store.leaseDocRef()
+	var expiry time.Time
+
+	err := store.runTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		expiry = time.Time{}
+		now := store.now()
+		doc, err := tx.Get(leaseRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil && doc.Exists() {
+			holder, _ := doc.Data()[fieldLeaseHolder].(string)
+			expiresAt, _ := doc.Data()[fieldLeaseExpiresAt].(time.Time)
+			if holder != election.instanceID && now.Before(expiresAt) {
+				return nil // someone else holds a valid lease
+			}
+		}
+		newExpiry := now.Add(election.leaseDuration)
+		if err := tx.Set(leaseRef, map[string]any{
+			fieldLeaseHolder:    election.instanceID,
+			fieldLeaseExpiresAt: newExpiry,
+		}); err != nil {
+			return err
+		}
+		expiry = newExpiry
+		return nil
+	})
+
+	store.lock.Lock()
+	wasWriter := store.now().Before(election.leaseExpiry)
+	election.attempted = true
+	if err != nil {
+		// Keep whatever lease we had; it will lapse on its own if we can't renew it
+		store.lock.Unlock()
+		store.loggers.Warnf("Failed to renew writer lease: %s", err)
+		return
+	}
+	election.leaseExpiry = expiry
+	store.lock.Unlock()
+
+	isWriter := !expiry.IsZero()
+	if isWriter != wasWriter {
+		if isWriter {
+			store.loggers.Infof("This instance (%s) is now the writer for collection %q", election.instanceID,
+				store.collection)
+		} else {
+			store.loggers.Infof("This instance (%s) is no longer the writer for collection %q; "+
+				"it will not write to the store", election.instanceID, store.collection)
+		}
+	}
+}
+
+// runLeaseRenewal renews the lease periodically until the store is closed.
+func (store *firestoreDataStore) runLeaseRenewal() {
+	ticker := time.NewTicker(store.election.leaseDuration / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-store.context.Done():
+			return
+		case <-ticker.C:
+			store.tryAcquireLease(store.context)
+		}
+	}
+}
+
+// releaseLease gives up the lease if we hold it, so that another instance can take over immediately.
+func (store *firestoreDataStore) releaseLease() {
+	store.lock.Lock()
+	held := store.now().Before(store.election.leaseExpiry)
+	store.election.leaseExpiry = time.Time{}
+	store.lock.Unlock()
+	if !held {
+		return
+	}
+
+	ctx, cancel := withOperationTimeout(context.Background(), store.operationTimeout)
+	defer cancel()
+	leaseRef := store.leaseDocRef()
+	_ = store.runTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(leaseRef)
+		if err != nil {
+			return err
+		}
+		if holder, _ := doc.Data()[fieldLeaseHolder].(string); holder != store.election.instanceID {
+			return nil
+		}
+		return tx.Delete(leaseRef)
+	})
+}
@@ -0,0 +1,63 @@
+package ldfirestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoreimpl"
+	"github.com/launchdarkly/go-server-sdk/v7/subsystems/ldstoretypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterElection(t *testing.T) {
+	if !isEmulatorAvailable() {
+		t.Skip("Firestore emulator is not available. Set FIRESTORE_EMULATOR_HOST to run these tests.")
+	}
+	require.NoError(t, clearTestData(""))
+
+	makeStore := func(instanceID string) *firestoreDataStore {
+		store, err := makeTestStore("").(*StoreBuilder[subsystems.PersistentDataStore]).
+			WriterElection(instanceID, time.Minute).Build(subsystems.BasicClientContext{})
+		require.NoError(t, err)
+		return store.(*firestoreDataStore)
+	}
+	item := ldstoretypes.SerializedItemDescriptor{Version: 1, SerializedItem: []byte(`{"key":"flag1"}`)}
+
+	store1 := makeStore("instance1")
+	store2 := makeStore("instance2")
+	defer func() { _ = store2.Close() }()
+
+	assert.True(t, store1.isWriter())
+	assert.False(t, store2.isWriter())
+
+	// A skipped write is reported as applied, so that the SDK caches the item rather than rereading it
+	result, err := store2.UpsertWithResult(ldstoreimpl.Features(), "flag1", item)
+	require.NoError(t, err)
+	assert.Equal(t, UpsertResult{Updated: true, PreviousVersion: -1}, result)
+	stored, err := store2.Get(ldstoreimpl.Features(), "flag1")
+	require.NoError(t, err)
+	assert.Equal(t, -1, stored.Version)
+
+	updated, err := store1.Upsert(ldstoreimpl.Features(), "flag1", item)
+	require.NoError(t, err)
+	assert.True(t, updated)
+
+	// Administrative writes are skipped too
+	touched, err := store2.Touch(ldstoreimpl.Features(), "flag1")
+	require.NoError(t, err)
+	assert.False(t, touched)
+	deleted, err := store2.Delete(ldstoreimpl.Features(), "flag1", ldvalue.OptionalInt{})
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	stored, err = store1.Get(ldstoreimpl.Features(), "flag1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.Version)
+
+	// Closing the writer releases the lease, so the other instance can take over
+	require.NoError(t, store1.Close())
+	store2.tryAcquireLease(store2.context)
+	assert.True(t, store2.isWriter())
+}
@@ -0,0 +1,4 @@
+package ldfirestore
+
+// Version is the current version string of the ldfirestore package. This is updated by our release scripts.
+const Version = "0.1.3" // {{ x-release-please-version }}